@@ -1,3 +1,9 @@
 module github.com/google/glazier
 
-go 1.16
\ No newline at end of file
+go 1.16
+
+require (
+	github.com/StackExchange/wmi v1.2.1
+	github.com/go-ole/go-ole v1.3.0
+	golang.org/x/sys v0.1.0
+)
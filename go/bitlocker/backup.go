@@ -0,0 +1,167 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitlocker
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/go-ole/go-ole"
+	"github.com/go-ole/go-ole/oleutil"
+	"github.com/google/logger"
+)
+
+var (
+	// ErrBackup indicates that recovery information for one or more volumes was not backed up.
+	ErrBackup = errors.New("recovery information backup failed")
+
+	// Test Helpers
+	funcDriveLetters = driveLetters
+	funcBackupVolume = backupVolume
+)
+
+// BackupResult reports the outcome of backing up the recovery information of a volume.
+type BackupResult struct {
+	DriveLetter string
+	// Target names the directory the volume was backed up to.
+	Target string
+	// ProtectorIDs lists the numerical password protectors that were backed up.
+	ProtectorIDs []string
+	// Skipped is set for volumes which are not fully encrypted.
+	Skipped bool
+	Err     error
+}
+
+// driveLetters lists the drive letters of all encryptable volumes.
+func driveLetters() ([]string, error) {
+	letters := []string{}
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	ole.CoInitialize(0)
+	defer ole.CoUninitialize()
+	w := &wmi{}
+	if err := w.connect(); err != nil {
+		return letters, fmt.Errorf("wmi.Connect: %w", err)
+	}
+	defer w.close()
+	raw, err := oleutil.CallMethod(w.svc, "ExecQuery", "SELECT DriveLetter FROM Win32_EncryptableVolume")
+	if err != nil {
+		return letters, fmt.Errorf("ExecQuery: %w", err)
+	}
+	result := raw.ToIDispatch()
+	defer result.Release()
+	err = oleutil.ForEach(result, func(item *ole.VARIANT) error {
+		defer item.Clear()
+		prop, err := oleutil.GetProperty(item.ToIDispatch(), "DriveLetter")
+		if err != nil {
+			return err
+		}
+		defer prop.Clear()
+		// Volumes without a drive letter (eg recovery partitions) report null.
+		if l, ok := prop.Value().(string); ok && l != "" {
+			letters = append(letters, l)
+		}
+		return nil
+	})
+	return letters, err
+}
+
+// backupVolume backs up every numerical password protector of a volume using the given
+// Win32_EncryptableVolume method.
+func backupVolume(driveLetter, method string) BackupResult {
+	res := BackupResult{DriveLetter: driveLetter, ProtectorIDs: []string{}}
+	v, err := Connect(driveLetter)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	defer v.Close()
+	st, err := v.Status()
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	if st.Conversion != FullyEncrypted {
+		res.Skipped = true
+		return res
+	}
+	protectors, err := v.KeyProtectors()
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	for _, p := range protectors {
+		if p.Type != ProtectorNumericalPassword {
+			continue
+		}
+		if err := v.call(method, p.ID); err != nil {
+			res.Err = fmt.Errorf("protector %s: %w", p.ID, err)
+			return res
+		}
+		res.ProtectorIDs = append(res.ProtectorIDs, p.ID)
+	}
+	return res
+}
+
+// backupAll backs up the recovery information of all encrypted volumes.
+func backupAll(method, target string) ([]BackupResult, error) {
+	results := []BackupResult{}
+	letters, err := funcDriveLetters()
+	if err != nil {
+		return results, err
+	}
+	failed := []string{}
+	for _, l := range letters {
+		res := funcBackupVolume(l, method)
+		res.Target = target
+		switch {
+		case res.Err != nil:
+			logger.Errorf("Backing up Bitlocker recovery information for drive %q to %s failed: %v", l, target, res.Err)
+			failed = append(failed, l)
+		case res.Skipped:
+			logger.Warningf("Skipping volume %s as it is not fully encrypted.", l)
+		default:
+			logger.Infof("Backed up Bitlocker recovery password for drive %q to %s.", l, target)
+		}
+		results = append(results, res)
+	}
+	if len(failed) > 0 {
+		return results, fmt.Errorf("%w: %s", ErrBackup, strings.Join(failed, ", "))
+	}
+	return results, nil
+}
+
+// BackupToAD backs up the recovery passwords of all encrypted volumes to Active Directory.
+// The device must be domain joined, and Group Policy must permit the backup.
+//
+// Every volume is attempted; if any fails, the error wraps ErrBackup and the results
+// detail the failures.
+func BackupToAD() ([]BackupResult, error) {
+	// https://docs.microsoft.com/en-us/windows/win32/secprov/backuprecoveryinformationtoactivedirectory-win32-encryptablevolume
+	return backupAll("BackupRecoveryInformationToActiveDirectory", "Active Directory")
+}
+
+// BackupToAAD backs up the recovery passwords of all encrypted volumes to Azure Active
+// Directory. The device must be Azure AD joined.
+//
+// Every volume is attempted; if any fails, the error wraps ErrBackup and the results
+// detail the failures.
+func BackupToAAD() ([]BackupResult, error) {
+	// BackupRecoveryInformationToCloudDomain is undocumented, but is what the BitLocker
+	// PowerShell module's BackupToAAD-BitLockerKeyProtector uses.
+	return backupAll("BackupRecoveryInformationToCloudDomain", "Azure AD")
+}
@@ -18,36 +18,10 @@ package bitlocker
 import (
 	"fmt"
 
-	"github.com/google/logger"
 	"github.com/go-ole/go-ole"
 	"github.com/go-ole/go-ole/oleutil"
-	"github.com/iamacarpet/go-win64api"
 )
 
-var (
-	// Test Helpers
-	funcBackup       = winapi.BackupBitLockerRecoveryKeys
-	funcRecoveryInfo = winapi.GetBitLockerRecoveryInfo
-)
-
-// BackupToAD backs up Bitlocker recovery keys to Active Directory.
-func BackupToAD() error {
-	infos, err := funcRecoveryInfo()
-	if err != nil {
-		return err
-	}
-	volIDs := []string{}
-	for _, i := range infos {
-		if i.ConversionStatus != 1 {
-			logger.Warningf("Skipping volume %s due to conversion status (%d).", i.DriveLetter, i.ConversionStatus)
-			continue
-		}
-		logger.Infof("Backing up Bitlocker recovery password for drive %q.", i.DriveLetter)
-		volIDs = append(volIDs, i.PersistentVolumeID)
-	}
-	return funcBackup(volIDs)
-}
-
 type wmi struct {
 	intf *ole.IDispatch
 	svc  *ole.IDispatch
@@ -115,43 +89,17 @@ func encryptErrHandler(val int32) error {
 //
 // Example: bitlocker.EncryptWithTPM("c:", bitlocker.XtsAES256, bitlocker.EncryptDataOnly)
 func EncryptWithTPM(driveLetter string, method int32, flags int32) error {
-	ole.CoInitialize(0)
-	defer ole.CoUninitialize()
-	w := &wmi{}
-	if err := w.connect(); err != nil {
-		return fmt.Errorf("wmi.Connect: %w", err)
-	}
-	defer w.close()
-	raw, err := oleutil.CallMethod(w.svc, "ExecQuery",
-		"SELECT * FROM Win32_EncryptableVolume WHERE DriveLetter = '"+driveLetter+"'")
+	v, err := Connect(driveLetter)
 	if err != nil {
-		return fmt.Errorf("ExecQuery: %w", err)
-	}
-	result := raw.ToIDispatch()
-	defer result.Release()
-
-	itemRaw, err := oleutil.CallMethod(result, "ItemIndex", 0)
-	if err != nil {
-		return fmt.Errorf("failed to fetch result row while processing BitLocker info: %w", err)
+		return err
 	}
-	item := itemRaw.ToIDispatch()
-	defer item.Release()
+	defer v.Close()
 
 	// https://docs.microsoft.com/en-us/windows/win32/secprov/protectkeywithtpm-win32-encryptablevolume
 	var volumeKeyProtectorID ole.VARIANT
 	ole.VariantInit(&volumeKeyProtectorID)
-	resultRaw, err := oleutil.CallMethod(item, "ProtectKeyWithTPM", nil, nil, &volumeKeyProtectorID)
-	if err != nil {
-		return fmt.Errorf("error calling ProtectKeyWithTPM(%s): %w", driveLetter, err)
-	} else if val, ok := resultRaw.Value().(int32); val != 0 || !ok {
-		return encryptErrHandler(val)
-	}
-
-	resultRaw, err = oleutil.CallMethod(item, "Encrypt", method, flags)
-	if err != nil {
-		return fmt.Errorf("error calling Encrypt(%s): %w", driveLetter, err)
-	} else if val, ok := resultRaw.Value().(int32); val != 0 || !ok {
-		return encryptErrHandler(val)
+	if err := v.call("ProtectKeyWithTPM", nil, nil, &volumeKeyProtectorID); err != nil {
+		return err
 	}
-	return nil
+	return v.call("Encrypt", method, flags)
 }
@@ -15,101 +15,278 @@
 package bitlocker
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/go-cmp/cmp"
-	so "github.com/iamacarpet/go-win64api/shared"
 )
 
-func TestBackupToAD(t *testing.T) {
-	backupErr := errors.New("backup failed")
-	infoErr := errors.New("info gathering failed")
+func TestWaitForEncryption(t *testing.T) {
+	statusErr := errors.New("status failed")
+	pollInterval = time.Millisecond
 	tests := []struct {
-		out        []*so.BitLockerDeviceInfo
-		outBackErr error
-		outInfoErr error
-		wantVols   []string
-		wantErr    error
+		desc    string
+		in      []Status
+		inErr   error
+		wantPct []int
+		wantErr error
 	}{
 		{
-			out: []*so.BitLockerDeviceInfo{&so.BitLockerDeviceInfo{
-				ConversionStatus:   1,
-				DriveLetter:        "C:",
-				PersistentVolumeID: "1-2-3-a-b-c",
-			}},
-			outBackErr: nil,
-			outInfoErr: nil,
-			wantVols:   []string{"1-2-3-a-b-c"},
-			wantErr:    nil,
+			desc:    "completes",
+			in:      []Status{{FullyDecrypted, 0, 0}, {EncryptionInProgress, 40, 0}, {FullyEncrypted, 100, 0}},
+			wantPct: []int{0, 40, 100},
 		},
-		{ // not converted
-			out: []*so.BitLockerDeviceInfo{&so.BitLockerDeviceInfo{
-				ConversionStatus:   0,
-				DriveLetter:        "C:",
-				PersistentVolumeID: "1-2-3-a-b-c",
-			}},
-			outBackErr: nil,
-			outInfoErr: nil,
-			wantVols:   []string{},
-			wantErr:    nil,
-		},
-		{ // skip 1
-			out: []*so.BitLockerDeviceInfo{
-				&so.BitLockerDeviceInfo{
-					ConversionStatus:   0,
-					DriveLetter:        "C:",
-					PersistentVolumeID: "1-2-3-a-b-c",
-				}, &so.BitLockerDeviceInfo{
-					ConversionStatus:   1,
-					DriveLetter:        "D:",
-					PersistentVolumeID: "4-5-6-d-e-f",
-				}},
-			outBackErr: nil,
-			outInfoErr: nil,
-			wantVols:   []string{"4-5-6-d-e-f"},
-			wantErr:    nil,
+		{
+			desc:    "decrypting",
+			in:      []Status{{EncryptionInProgress, 40, 0}, {DecryptionInProgress, 30, 0}},
+			wantPct: []int{40, 30},
+			wantErr: ErrDecrypting,
 		},
-		{ // backup err
-			out: []*so.BitLockerDeviceInfo{
-				&so.BitLockerDeviceInfo{
-					ConversionStatus:   1,
-					DriveLetter:        "C:",
-					PersistentVolumeID: "1-2-3-a-b-c",
-				}},
-			outBackErr: backupErr,
-			outInfoErr: nil,
-			wantVols:   []string{"1-2-3-a-b-c"},
-			wantErr:    backupErr,
+		{
+			desc:    "status error",
+			inErr:   statusErr,
+			wantPct: []int{},
+			wantErr: statusErr,
 		},
-		{ // info err
-			out: []*so.BitLockerDeviceInfo{
-				&so.BitLockerDeviceInfo{
-					ConversionStatus:   1,
-					DriveLetter:        "C:",
-					PersistentVolumeID: "1-2-3-a-b-c",
-				}},
-			outBackErr: nil,
-			outInfoErr: infoErr,
-			wantVols:   []string{"1-2-3-a-b-c"},
-			wantErr:    infoErr,
+		{
+			desc:    "timeout",
+			in:      []Status{{EncryptionPaused, 10, 0}},
+			wantErr: context.DeadlineExceeded,
 		},
 	}
-	vols := []string{}
 	for _, tt := range tests {
-		funcBackup = func(arg []string) error {
-			vols = arg
-			return tt.outBackErr
+		t.Run(tt.desc, func(t *testing.T) {
+			calls := 0
+			funcStatus = func(*Volume) (Status, error) {
+				if tt.inErr != nil {
+					return Status{}, tt.inErr
+				}
+				st := tt.in[len(tt.in)-1]
+				if calls < len(tt.in) {
+					st = tt.in[calls]
+				}
+				calls++
+				return st, nil
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+			got := []int{}
+			err := (&Volume{letter: "c:"}).WaitForEncryption(ctx, func(pct int) { got = append(got, pct) })
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("WaitForEncryption() returned unexpected error %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantPct != nil {
+				if diff := cmp.Diff(tt.wantPct, got); diff != "" {
+					t.Errorf("WaitForEncryption() reported unexpected progress (-want +got):\n%s", diff)
+				}
+			}
+		})
+	}
+}
+
+func TestKeyProtectorTypeString(t *testing.T) {
+	tests := []struct {
+		in   KeyProtectorType
+		want string
+	}{
+		{ProtectorTPM, "TPM"},
+		{ProtectorNumericalPassword, "NumericalPassword"},
+		{ProtectorPassphrase, "Passphrase"},
+		{KeyProtectorType(42), "Unknown(42)"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("KeyProtectorType(%d).String() = %q, want %q", int32(tt.in), got, tt.want)
 		}
-		funcRecoveryInfo = func() ([]*so.BitLockerDeviceInfo, error) {
-			return tt.out, tt.outInfoErr
+	}
+}
+
+func TestValidRecoveryPassword(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantErr error
+	}{
+		{"000000-111111-222222-333333-444444-555555-666666-719994", nil},
+		{"000000-111111-222222-333333-444444-555555-666666", ErrInvalidPassword},
+		{"000000-111111-222222-333333-444444-555555-666666-77777", ErrInvalidPassword},
+		{"000000-111111-222222-333333-444444-555555-666666-abcdef", ErrInvalidPassword},
+		{"000000-111111-222222-333333-444444-555555-666666-777777", ErrInvalidPassword},
+		{"000000-111111-222222-333333-444444-555555-666666-719995", ErrInvalidPassword},
+		{"000000-111111-222222-333333-444444-555555-666666-720907", ErrInvalidPassword},
+	}
+	for _, tt := range tests {
+		if err := validRecoveryPassword(tt.in); !errors.Is(err, tt.wantErr) {
+			t.Errorf("validRecoveryPassword(%q) returned unexpected error %v", tt.in, err)
+		}
+	}
+}
+
+func TestSuspendProtectionValidation(t *testing.T) {
+	for _, n := range []int{-1, 16} {
+		if err := (&Volume{letter: "c:"}).SuspendProtection(n); !errors.Is(err, ErrInvalidRebootCount) {
+			t.Errorf("SuspendProtection(%d) returned unexpected error %v", n, err)
 		}
-		err := BackupToAD()
-		if !cmp.Equal(vols, tt.wantVols) {
-			t.Errorf("BackupToAD(%v) produced unexpected differences (-want +got): %s", tt.out, cmp.Diff(tt.wantVols, vols))
+	}
+}
+
+func TestBackup(t *testing.T) {
+	listErr := errors.New("list failed")
+	volErr := errors.New("backup failed")
+	tests := []struct {
+		desc    string
+		letters []string
+		listErr error
+		results map[string]BackupResult
+		want    []BackupResult
+		wantErr error
+	}{
+		{
+			desc:    "success",
+			letters: []string{"C:", "D:"},
+			results: map[string]BackupResult{
+				"C:": {DriveLetter: "C:", ProtectorIDs: []string{"{1}"}},
+				"D:": {DriveLetter: "D:", ProtectorIDs: []string{}, Skipped: true},
+			},
+			want: []BackupResult{
+				{DriveLetter: "C:", ProtectorIDs: []string{"{1}"}},
+				{DriveLetter: "D:", ProtectorIDs: []string{}, Skipped: true},
+			},
+		},
+		{
+			desc:    "partial failure",
+			letters: []string{"C:", "D:"},
+			results: map[string]BackupResult{
+				"C:": {DriveLetter: "C:", ProtectorIDs: []string{}, Err: volErr},
+				"D:": {DriveLetter: "D:", ProtectorIDs: []string{"{2}"}},
+			},
+			want: []BackupResult{
+				{DriveLetter: "C:", ProtectorIDs: []string{}, Err: volErr},
+				{DriveLetter: "D:", ProtectorIDs: []string{"{2}"}},
+			},
+			wantErr: ErrBackup,
+		},
+		{
+			desc:    "list error",
+			listErr: listErr,
+			want:    []BackupResult{},
+			wantErr: listErr,
+		},
+	}
+	targets := []struct {
+		name   string
+		fn     func() ([]BackupResult, error)
+		method string
+		target string
+	}{
+		{"BackupToAD", BackupToAD, "BackupRecoveryInformationToActiveDirectory", "Active Directory"},
+		{"BackupToAAD", BackupToAAD, "BackupRecoveryInformationToCloudDomain", "Azure AD"},
+	}
+	for _, target := range targets {
+		for _, tt := range tests {
+			t.Run(target.name+"/"+tt.desc, func(t *testing.T) {
+				funcDriveLetters = func() ([]string, error) {
+					return tt.letters, tt.listErr
+				}
+				funcBackupVolume = func(letter, method string) BackupResult {
+					if method != target.method {
+						t.Errorf("backupVolume(%s) called with unexpected method %q", letter, method)
+					}
+					return tt.results[letter]
+				}
+				got, err := target.fn()
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("%s() returned unexpected error %v", target.name, err)
+				}
+				want := []BackupResult{}
+				for _, r := range tt.want {
+					r.Target = target.target
+					want = append(want, r)
+				}
+				if diff := cmp.Diff(want, got, cmp.Comparer(func(a, b error) bool { return a == b })); diff != "" {
+					t.Errorf("%s() returned unexpected diff (-want +got):\n%s", target.name, diff)
+				}
+			})
+		}
+	}
+}
+
+func TestSetPlatformValidationProfileValidation(t *testing.T) {
+	for _, in := range [][]int{{}, {0, 24}, {-1}} {
+		if err := (&Volume{letter: "c:"}).SetPlatformValidationProfile(in); !errors.Is(err, ErrInvalidProfile) {
+			t.Errorf("SetPlatformValidationProfile(%v) returned unexpected error %v", in, err)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	queryErr := errors.New("query failed")
+	funcDriveLetters = func() ([]string, error) {
+		return []string{"C:", "D:"}, nil
+	}
+	funcVolumeReport = func(letter string) (VolumeReport, error) {
+		r := VolumeReport{DriveLetter: letter, Protectors: []ProtectorReport{}, Escrowed: []string{}}
+		if letter == "D:" {
+			return r, queryErr
+		}
+		r.EncryptionMethod = methodName(XtsAES256)
+		r.Conversion = FullyEncrypted.String()
+		r.EncryptionPercentage = 100
+		r.Protection = ProtectionOn.String()
+		r.Protectors = []ProtectorReport{{"{1}", ProtectorTPM.String()}, {"{2}", ProtectorNumericalPassword.String()}}
+		return r, nil
+	}
+	backups := []BackupResult{
+		{DriveLetter: "c:", Target: "Active Directory", ProtectorIDs: []string{"{2}"}},
+		{DriveLetter: "C:", Target: "Azure AD", ProtectorIDs: []string{}, Err: queryErr},
+		{DriveLetter: "D:", Target: "Active Directory", ProtectorIDs: []string{}, Skipped: true},
+	}
+	want := []VolumeReport{
+		{
+			DriveLetter:          "C:",
+			EncryptionMethod:     "XtsAES256",
+			Conversion:           "FullyEncrypted",
+			EncryptionPercentage: 100,
+			Protection:           "On",
+			Protectors:           []ProtectorReport{{"{1}", "TPM"}, {"{2}", "NumericalPassword"}},
+			Escrowed:             []string{"Active Directory"},
+		},
+		{
+			DriveLetter: "D:",
+			Protectors:  []ProtectorReport{},
+			Escrowed:    []string{},
+			Error:       "query failed",
+		},
+	}
+	got, err := Report(backups...)
+	if err != nil {
+		t.Fatalf("Report() returned unexpected error %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Report() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestResultErr(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want string
+	}{
+		{int32(0), ""},
+		{int32(-2144272384), "Lock(c:): 0x80310000"},
+		{FVE_E_PROTECTOR_EXISTS, "Lock(c:): key protector cannot be added; only one key protector of this type is allowed for this drive"},
+		{"0", "Lock(c:): unexpected return value 0"},
+	}
+	v := &Volume{letter: "c:"}
+	for _, tt := range tests {
+		err := v.resultErr("Lock", tt.in)
+		got := ""
+		if err != nil {
+			got = err.Error()
 		}
-		if !errors.Is(err, tt.wantErr) {
-			t.Errorf("BackupToAD(%v) returned unexpected error %v", tt.out, err)
+		if got != tt.want {
+			t.Errorf("resultErr(%v) = %q, want %q", tt.in, got, tt.want)
 		}
 	}
 }
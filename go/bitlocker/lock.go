@@ -0,0 +1,72 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitlocker
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPassword indicates a malformed recovery password.
+	ErrInvalidPassword = errors.New("invalid recovery password")
+)
+
+// validRecoveryPassword checks the format of a recovery password: eight groups of six
+// digits separated by dashes, where each group is a multiple of 11 below 720896.
+func validRecoveryPassword(pw string) error {
+	groups := strings.Split(pw, "-")
+	if len(groups) != 8 {
+		return fmt.Errorf("%w: expected 8 groups, got %d", ErrInvalidPassword, len(groups))
+	}
+	for i, g := range groups {
+		n, err := strconv.Atoi(g)
+		if len(g) != 6 || err != nil {
+			return fmt.Errorf("%w: group %d is not six digits", ErrInvalidPassword, i+1)
+		}
+		if n%11 != 0 || n >= 720896 {
+			return fmt.Errorf("%w: group %d fails validation", ErrInvalidPassword, i+1)
+		}
+	}
+	return nil
+}
+
+// Lock locks the volume. If force is set, the volume is dismounted even if it is in use.
+//
+// https://docs.microsoft.com/en-us/windows/win32/secprov/lock-win32-encryptablevolume
+func (v *Volume) Lock(force bool) error {
+	return v.call("Lock", force)
+}
+
+// UnlockWithPassphrase unlocks the volume using a passphrase protector.
+//
+// https://docs.microsoft.com/en-us/windows/win32/secprov/unlockwithpassphrase-win32-encryptablevolume
+func (v *Volume) UnlockWithPassphrase(passphrase string) error {
+	return v.call("UnlockWithPassphrase", passphrase)
+}
+
+// UnlockWithNumericalPassword unlocks the volume using its 48 digit recovery password.
+//
+// Example: vol.UnlockWithNumericalPassword("111111-222222-...-888888")
+//
+// https://docs.microsoft.com/en-us/windows/win32/secprov/unlockwithnumericalpassword-win32-encryptablevolume
+func (v *Volume) UnlockWithNumericalPassword(password string) error {
+	if err := validRecoveryPassword(password); err != nil {
+		return err
+	}
+	return v.call("UnlockWithNumericalPassword", password)
+}
@@ -0,0 +1,185 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package policy stages the Bitlocker (FVE) Group Policy values that encryption depends on.
+//
+// Encrypting a volume in a way the local policy does not permit fails with FVE_E_POLICY_*
+// errors, so the relevant policy can be written ahead of calling Encrypt.
+//
+// https://docs.microsoft.com/en-us/windows/security/information-protection/bitlocker/bitlocker-group-policy-settings
+package policy
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/google/glazier/go/registry"
+)
+
+// Key is the policy key, relative to HKEY_LOCAL_MACHINE.
+const Key = `SOFTWARE\Policies\Microsoft\FVE`
+
+var (
+	// Test Helpers
+	funcCreate     = registry.Create
+	funcGetInteger = registry.GetInteger
+	funcSetInteger = registry.SetInteger
+)
+
+// Setting is a tri-state policy option, such as whether a TPM PIN may be used.
+type Setting int
+
+// Settings. The zero value leaves the option unconfigured.
+const (
+	NotConfigured Setting = iota
+	Disallowed
+	Required
+	Allowed
+)
+
+// Registry values for each configured Setting.
+var settingValues = map[Setting]int{
+	Disallowed: 0,
+	Required:   1,
+	Allowed:    2,
+}
+
+func (s Setting) String() string {
+	switch s {
+	case NotConfigured:
+		return "NotConfigured"
+	case Disallowed:
+		return "Disallowed"
+	case Required:
+		return "Required"
+	case Allowed:
+		return "Allowed"
+	}
+	return fmt.Sprintf("Unknown(%d)", int(s))
+}
+
+// Policy holds the FVE policy values. Unset fields (zero, nil or NotConfigured) are not
+// configured.
+type Policy struct {
+	// Encryption methods per drive type, as bitlocker.XtsAES256 etc.
+	OSEncryptionMethod        int32
+	FixedEncryptionMethod     int32
+	RemovableEncryptionMethod int32
+
+	// Startup authentication for the OS drive.
+	RequireAdditionalAuth *bool
+	AllowWithoutTPM       *bool
+	TPM                   Setting
+	TPMPIN                Setting
+	TPMKey                Setting
+	TPMKeyPIN             Setting
+
+	// Recovery options for the OS drive.
+	OSRecovery                     *bool
+	OSRecoveryPassword             Setting
+	OSRecoveryKey                  Setting
+	OSHideRecoveryPage             *bool
+	OSActiveDirectoryBackup        *bool
+	OSRequireActiveDirectoryBackup *bool
+}
+
+type value struct {
+	name string
+	ptr  interface{}
+}
+
+func (p *Policy) values() []value {
+	return []value{
+		{"EncryptionMethodWithXtsOs", &p.OSEncryptionMethod},
+		{"EncryptionMethodWithXtsFdv", &p.FixedEncryptionMethod},
+		{"EncryptionMethodWithXtsRdv", &p.RemovableEncryptionMethod},
+		{"UseAdvancedStartup", &p.RequireAdditionalAuth},
+		{"EnableBDEWithNoTPM", &p.AllowWithoutTPM},
+		{"UseTPM", &p.TPM},
+		{"UseTPMPIN", &p.TPMPIN},
+		{"UseTPMKey", &p.TPMKey},
+		{"UseTPMKeyPIN", &p.TPMKeyPIN},
+		{"OSRecovery", &p.OSRecovery},
+		{"OSRecoveryPassword", &p.OSRecoveryPassword},
+		{"OSRecoveryKey", &p.OSRecoveryKey},
+		{"OSHideRecoveryPage", &p.OSHideRecoveryPage},
+		{"OSActiveDirectoryBackup", &p.OSActiveDirectoryBackup},
+		{"OSRequireActiveDirectoryBackup", &p.OSRequireActiveDirectoryBackup},
+	}
+}
+
+// Get reads the current policy. Values which are absent are left unset.
+func Get() (Policy, error) {
+	p := Policy{}
+	for _, v := range p.values() {
+		n, err := funcGetInteger(Key, v.name)
+		if errors.Is(err, registry.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return p, fmt.Errorf("reading %s: %w", v.name, err)
+		}
+		switch ptr := v.ptr.(type) {
+		case *int32:
+			*ptr = int32(n)
+		case **bool:
+			b := n != 0
+			*ptr = &b
+		case *Setting:
+			*ptr = NotConfigured
+			for s, sv := range settingValues {
+				if uint64(sv) == n {
+					*ptr = s
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+// Apply writes the configured fields of p. Values for unset fields are left as they are.
+//
+// Example: policy.Apply(policy.Policy{OSEncryptionMethod: bitlocker.XtsAES256, TPMPIN: policy.Allowed})
+func Apply(p Policy) error {
+	if err := funcCreate(Key); err != nil {
+		return fmt.Errorf("creating %s: %w", Key, err)
+	}
+	for _, v := range p.values() {
+		var n int
+		switch ptr := v.ptr.(type) {
+		case *int32:
+			if *ptr == 0 {
+				continue
+			}
+			n = int(*ptr)
+		case **bool:
+			if *ptr == nil {
+				continue
+			}
+			if **ptr {
+				n = 1
+			}
+		case *Setting:
+			sv, ok := settingValues[*ptr]
+			if !ok {
+				continue
+			}
+			n = sv
+		}
+		if err := funcSetInteger(Key, v.name, n); err != nil {
+			return fmt.Errorf("writing %s: %w", v.name, err)
+		}
+	}
+	return nil
+}
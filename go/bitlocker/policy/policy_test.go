@@ -0,0 +1,79 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package policy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/glazier/go/registry"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestApplyAndGet(t *testing.T) {
+	values := map[string]uint64{}
+	funcCreate = func(string) error { return nil }
+	funcSetInteger = func(root, name string, value int) error {
+		values[name] = uint64(value)
+		return nil
+	}
+	funcGetInteger = func(root, name string) (uint64, error) {
+		v, ok := values[name]
+		if !ok {
+			return 0, registry.ErrNotExist
+		}
+		return v, nil
+	}
+
+	yes, no := true, false
+	in := Policy{
+		OSEncryptionMethod:    7,
+		RequireAdditionalAuth: &yes,
+		AllowWithoutTPM:       &no,
+		TPM:                   Allowed,
+		TPMPIN:                Disallowed,
+		OSRecoveryPassword:    Required,
+	}
+	if err := Apply(in); err != nil {
+		t.Fatalf("Apply() returned unexpected error %v", err)
+	}
+	want := map[string]uint64{
+		"EncryptionMethodWithXtsOs": 7,
+		"UseAdvancedStartup":        1,
+		"EnableBDEWithNoTPM":        0,
+		"UseTPM":                    2,
+		"UseTPMPIN":                 0,
+		"OSRecoveryPassword":        1,
+	}
+	if diff := cmp.Diff(want, values); diff != "" {
+		t.Errorf("Apply() wrote unexpected values (-want +got):\n%s", diff)
+	}
+	got, err := Get()
+	if err != nil {
+		t.Fatalf("Get() returned unexpected error %v", err)
+	}
+	if diff := cmp.Diff(in, got); diff != "" {
+		t.Errorf("Get() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestApplyError(t *testing.T) {
+	writeErr := errors.New("access denied")
+	funcCreate = func(string) error { return nil }
+	funcSetInteger = func(root, name string, value int) error { return writeErr }
+	if err := Apply(Policy{TPM: Required}); !errors.Is(err, writeErr) {
+		t.Errorf("Apply() returned unexpected error %v", err)
+	}
+}
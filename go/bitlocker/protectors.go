@@ -0,0 +1,188 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitlocker
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/go-ole/go-ole"
+)
+
+var (
+	// ErrInvalidRebootCount indicates a suspension reboot count outside the supported range.
+	ErrInvalidRebootCount = errors.New("reboot count must be between 0 and 15")
+)
+
+// KeyProtectorType identifies the kind of a key protector.
+//
+// https://docs.microsoft.com/en-us/windows/win32/secprov/getkeyprotectortype-win32-encryptablevolume
+type KeyProtectorType int32
+
+// Key protector types.
+const (
+	ProtectorUnknown KeyProtectorType = iota
+	ProtectorTPM
+	ProtectorExternalKey
+	ProtectorNumericalPassword
+	ProtectorTPMAndPIN
+	ProtectorTPMAndStartupKey
+	ProtectorTPMAndPINAndStartupKey
+	ProtectorPublicKey
+	ProtectorPassphrase
+	ProtectorTPMCertificate
+	ProtectorSID
+)
+
+var keyProtectorTypeNames = map[KeyProtectorType]string{
+	ProtectorUnknown:                "Unknown",
+	ProtectorTPM:                    "TPM",
+	ProtectorExternalKey:            "ExternalKey",
+	ProtectorNumericalPassword:      "NumericalPassword",
+	ProtectorTPMAndPIN:              "TPMAndPIN",
+	ProtectorTPMAndStartupKey:       "TPMAndStartupKey",
+	ProtectorTPMAndPINAndStartupKey: "TPMAndPINAndStartupKey",
+	ProtectorPublicKey:              "PublicKey",
+	ProtectorPassphrase:             "Passphrase",
+	ProtectorTPMCertificate:         "TPMCertificate",
+	ProtectorSID:                    "SID",
+}
+
+func (t KeyProtectorType) String() string {
+	if n, ok := keyProtectorTypeNames[t]; ok {
+		return n
+	}
+	return fmt.Sprintf("Unknown(%d)", int32(t))
+}
+
+// KeyProtector is a key protector present on a volume.
+type KeyProtector struct {
+	ID   string
+	Type KeyProtectorType
+}
+
+// variantStrings converts a string array output parameter to a slice.
+func variantStrings(v *ole.VARIANT) []string {
+	if v.VT&ole.VT_ARRAY == 0 {
+		return []string{}
+	}
+	return v.ToArray().ToStringArray()
+}
+
+// KeyProtectors lists the key protectors of the volume.
+func (v *Volume) KeyProtectors() ([]KeyProtector, error) {
+	protectors := []KeyProtector{}
+	var ids ole.VARIANT
+	ole.VariantInit(&ids)
+	defer ids.Clear()
+	// https://docs.microsoft.com/en-us/windows/win32/secprov/getkeyprotectors-win32-encryptablevolume
+	if err := v.call("GetKeyProtectors", int32(ProtectorUnknown), &ids); err != nil {
+		return protectors, err
+	}
+	for _, id := range variantStrings(&ids) {
+		t, err := v.keyProtectorType(id)
+		if err != nil {
+			return protectors, err
+		}
+		protectors = append(protectors, KeyProtector{ID: id, Type: t})
+	}
+	return protectors, nil
+}
+
+func (v *Volume) keyProtectorType(id string) (KeyProtectorType, error) {
+	var t ole.VARIANT
+	ole.VariantInit(&t)
+	defer t.Clear()
+	if err := v.call("GetKeyProtectorType", id, &t); err != nil {
+		return ProtectorUnknown, fmt.Errorf("protector %s: %w", id, err)
+	}
+	return KeyProtectorType(variantInt(&t)), nil
+}
+
+// DeleteKeyProtector removes the key protector with the given ID from the volume.
+//
+// https://docs.microsoft.com/en-us/windows/win32/secprov/deletekeyprotector-win32-encryptablevolume
+func (v *Volume) DeleteKeyProtector(id string) error {
+	if err := v.call("DeleteKeyProtector", id); err != nil {
+		return fmt.Errorf("protector %s: %w", id, err)
+	}
+	return nil
+}
+
+// DeleteKeyProtectors removes all key protectors from the volume. Protection is
+// suspended until a new protector is added.
+//
+// https://docs.microsoft.com/en-us/windows/win32/secprov/deletekeyprotectors-win32-encryptablevolume
+func (v *Volume) DeleteKeyProtectors() error {
+	return v.call("DeleteKeyProtectors")
+}
+
+// GetRecoveryPassword retrieves the 48 digit recovery password of a numerical password
+// protector, for escrow to systems other than Active Directory.
+//
+// https://docs.microsoft.com/en-us/windows/win32/secprov/getkeyprotectornumericalpassword-win32-encryptablevolume
+func (v *Volume) GetRecoveryPassword(protectorID string) (string, error) {
+	var pw ole.VARIANT
+	ole.VariantInit(&pw)
+	defer pw.Clear()
+	if err := v.call("GetKeyProtectorNumericalPassword", protectorID, &pw); err != nil {
+		return "", fmt.Errorf("protector %s: %w", protectorID, err)
+	}
+	return pw.ToString(), nil
+}
+
+// SuspendProtection suspends protection of the volume by disabling its key protectors, eg
+// during firmware updates. Protection resumes automatically after rebootCount restarts
+// (1-15), or only via ResumeProtection if rebootCount is 0.
+//
+// https://docs.microsoft.com/en-us/windows/win32/secprov/disablekeyprotectors-win32-encryptablevolume
+func (v *Volume) SuspendProtection(rebootCount int) error {
+	if rebootCount < 0 || rebootCount > 15 {
+		return fmt.Errorf("%w: %d", ErrInvalidRebootCount, rebootCount)
+	}
+	return v.call("DisableKeyProtectors", uint32(rebootCount))
+}
+
+// ResumeProtection re-enables the key protectors of a suspended volume.
+//
+// https://docs.microsoft.com/en-us/windows/win32/secprov/enablekeyprotectors-win32-encryptablevolume
+func (v *Volume) ResumeProtection() error {
+	return v.call("EnableKeyProtectors")
+}
+
+// ProtectWithExternalKey adds an external key protector to the volume, returning its ID.
+// Save the key with SaveExternalKeyToFile so the volume can be unlocked with it later.
+//
+// https://docs.microsoft.com/en-us/windows/win32/secprov/protectkeywithexternalkey-win32-encryptablevolume
+func (v *Volume) ProtectWithExternalKey() (string, error) {
+	var id ole.VARIANT
+	ole.VariantInit(&id)
+	defer id.Clear()
+	if err := v.call("ProtectKeyWithExternalKey", nil, nil, &id); err != nil {
+		return "", err
+	}
+	return id.ToString(), nil
+}
+
+// SaveExternalKeyToFile writes the key of an external key protector to the directory
+// path (eg a USB drive or network share). The file name is derived from the protector ID.
+//
+// https://docs.microsoft.com/en-us/windows/win32/secprov/saveexternalkeytofile-win32-encryptablevolume
+func (v *Volume) SaveExternalKeyToFile(protectorID, path string) error {
+	if err := v.call("SaveExternalKeyToFile", protectorID, path); err != nil {
+		return fmt.Errorf("protector %s: %w", protectorID, err)
+	}
+	return nil
+}
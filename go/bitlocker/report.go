@@ -0,0 +1,191 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitlocker
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/go-ole/go-ole"
+)
+
+var (
+	// Test Helpers
+	funcVolumeReport = volumeReport
+)
+
+var methodNames = map[int32]string{
+	None:               "None",
+	AES128WithDiffuser: "AES128WithDiffuser",
+	AES256WithDiffuser: "AES256WithDiffuser",
+	AES128:             "AES128",
+	AES256:             "AES256",
+	HardwareEncryption: "HardwareEncryption",
+	XtsAES128:          "XtsAES128",
+	XtsAES256:          "XtsAES256",
+}
+
+func methodName(m int32) string {
+	if n, ok := methodNames[m]; ok {
+		return n
+	}
+	return fmt.Sprintf("Unknown(%d)", m)
+}
+
+var conversionNames = map[ConversionStatus]string{
+	FullyDecrypted:       "FullyDecrypted",
+	FullyEncrypted:       "FullyEncrypted",
+	EncryptionInProgress: "EncryptionInProgress",
+	DecryptionInProgress: "DecryptionInProgress",
+	EncryptionPaused:     "EncryptionPaused",
+	DecryptionPaused:     "DecryptionPaused",
+}
+
+func (c ConversionStatus) String() string {
+	if n, ok := conversionNames[c]; ok {
+		return n
+	}
+	return fmt.Sprintf("Unknown(%d)", int32(c))
+}
+
+// ProtectionStatus reports whether the key protectors of a volume are in effect.
+//
+// https://docs.microsoft.com/en-us/windows/win32/secprov/getprotectionstatus-win32-encryptablevolume
+type ProtectionStatus int32
+
+// Protection statuses.
+const (
+	ProtectionOff ProtectionStatus = iota
+	ProtectionOn
+	ProtectionUnknown
+)
+
+func (p ProtectionStatus) String() string {
+	switch p {
+	case ProtectionOff:
+		return "Off"
+	case ProtectionOn:
+		return "On"
+	}
+	return "Unknown"
+}
+
+// ProtectionStatus retrieves the protection status of the volume.
+func (v *Volume) ProtectionStatus() (ProtectionStatus, error) {
+	var st ole.VARIANT
+	ole.VariantInit(&st)
+	defer st.Clear()
+	if err := v.call("GetProtectionStatus", &st); err != nil {
+		return ProtectionUnknown, err
+	}
+	return ProtectionStatus(variantInt(&st)), nil
+}
+
+// EncryptionMethod retrieves the encryption method of the volume (eg XtsAES256).
+//
+// https://docs.microsoft.com/en-us/windows/win32/secprov/getencryptionmethod-win32-encryptablevolume
+func (v *Volume) EncryptionMethod() (int32, error) {
+	var m ole.VARIANT
+	ole.VariantInit(&m)
+	defer m.Clear()
+	if err := v.call("GetEncryptionMethod", &m); err != nil {
+		return None, err
+	}
+	return int32(variantInt(&m)), nil
+}
+
+// ProtectorReport describes a key protector in a VolumeReport.
+type ProtectorReport struct {
+	ID   string
+	Type string
+}
+
+// VolumeReport summarizes the Bitlocker state of a volume. It is intended to be serialized
+// (eg with encoding/json), so enumerations are rendered as names.
+type VolumeReport struct {
+	DriveLetter          string
+	EncryptionMethod     string
+	Conversion           string
+	EncryptionPercentage int
+	Protection           string
+	Protectors           []ProtectorReport
+	// Escrowed lists the directories the recovery password was backed up to, per the
+	// backup results passed to Report.
+	Escrowed []string
+	// Error is set if the state of the volume could not be determined in full.
+	Error string `json:",omitempty"`
+}
+
+func volumeReport(driveLetter string) (VolumeReport, error) {
+	r := VolumeReport{DriveLetter: driveLetter, Protectors: []ProtectorReport{}, Escrowed: []string{}}
+	v, err := Connect(driveLetter)
+	if err != nil {
+		return r, err
+	}
+	defer v.Close()
+	m, err := v.EncryptionMethod()
+	if err != nil {
+		return r, err
+	}
+	r.EncryptionMethod = methodName(m)
+	st, err := v.Status()
+	if err != nil {
+		return r, err
+	}
+	r.Conversion = st.Conversion.String()
+	r.EncryptionPercentage = st.EncryptionPercentage
+	ps, err := v.ProtectionStatus()
+	if err != nil {
+		return r, err
+	}
+	r.Protection = ps.String()
+	protectors, err := v.KeyProtectors()
+	if err != nil {
+		return r, err
+	}
+	for _, p := range protectors {
+		r.Protectors = append(r.Protectors, ProtectorReport{ID: p.ID, Type: p.Type.String()})
+	}
+	return r, nil
+}
+
+// Report summarizes the Bitlocker state of every encryptable volume with a drive letter,
+// eg for logging or posting to an inventory service at the end of a build.
+//
+// Windows does not expose whether recovery information was escrowed, so escrow state is
+// taken from the results of BackupToAD or BackupToAAD, if any are passed in. Failures to
+// query individual volumes are recorded in their reports.
+//
+// Example: bitlocker.Report(append(adResults, aadResults...)...)
+func Report(backups ...BackupResult) ([]VolumeReport, error) {
+	reports := []VolumeReport{}
+	letters, err := funcDriveLetters()
+	if err != nil {
+		return reports, err
+	}
+	for _, l := range letters {
+		r, err := funcVolumeReport(l)
+		if err != nil {
+			r.Error = err.Error()
+		}
+		for _, b := range backups {
+			if strings.EqualFold(b.DriveLetter, l) && b.Err == nil && len(b.ProtectorIDs) > 0 {
+				r.Escrowed = append(r.Escrowed, b.Target)
+			}
+		}
+		reports = append(reports, r)
+	}
+	return reports, nil
+}
@@ -0,0 +1,134 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitlocker
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/go-ole/go-ole"
+)
+
+var (
+	// ErrInvalidProfile indicates a platform validation profile with unsupported PCRs.
+	ErrInvalidProfile = errors.New("invalid platform validation profile")
+	// ErrNoTPMProtector indicates a volume has no TPM based key protector.
+	ErrNoTPMProtector = errors.New("volume has no TPM protector")
+)
+
+// tpmProtector finds the TPM based key protector of the volume.
+func (v *Volume) tpmProtector() (KeyProtector, error) {
+	protectors, err := v.KeyProtectors()
+	if err != nil {
+		return KeyProtector{}, err
+	}
+	for _, p := range protectors {
+		switch p.Type {
+		case ProtectorTPM, ProtectorTPMAndPIN, ProtectorTPMAndStartupKey, ProtectorTPMAndPINAndStartupKey:
+			return p, nil
+		}
+	}
+	return KeyProtector{}, fmt.Errorf("%w: %s", ErrNoTPMProtector, v.letter)
+}
+
+// GetPlatformValidationProfile returns the PCR indices the volume's TPM protector is sealed
+// to, in ascending order.
+//
+// https://docs.microsoft.com/en-us/windows/win32/secprov/getkeyprotectorplatformvalidationprofile-win32-encryptablevolume
+func (v *Volume) GetPlatformValidationProfile() ([]int, error) {
+	p, err := v.tpmProtector()
+	if err != nil {
+		return []int{}, err
+	}
+	return v.validationProfile(p)
+}
+
+func (v *Volume) validationProfile(p KeyProtector) ([]int, error) {
+	pcrs := []int{}
+	var profile ole.VARIANT
+	ole.VariantInit(&profile)
+	defer profile.Clear()
+	if err := v.call("GetKeyProtectorPlatformValidationProfile", p.ID, &profile); err != nil {
+		return pcrs, fmt.Errorf("protector %s: %w", p.ID, err)
+	}
+	if profile.VT&ole.VT_ARRAY == 0 {
+		return pcrs, nil
+	}
+	for _, pcr := range profile.ToArray().ToValueArray() {
+		pcrs = append(pcrs, intValue(pcr))
+	}
+	sort.Ints(pcrs)
+	return pcrs, nil
+}
+
+// protectWithTPM adds a TPM protector sealed to pcrs, or to the default profile if pcrs is
+// empty.
+func (v *Volume) protectWithTPM(pcrs []int) error {
+	var profile interface{}
+	if len(pcrs) > 0 {
+		b := make([]byte, len(pcrs))
+		for i, pcr := range pcrs {
+			b[i] = byte(pcr)
+		}
+		profile = b
+	}
+	var id ole.VARIANT
+	ole.VariantInit(&id)
+	defer id.Clear()
+	return v.call("ProtectKeyWithTPM", nil, profile, &id)
+}
+
+// SetPlatformValidationProfile seals the volume's TPM protector to the given PCR indices.
+//
+// The profile of an existing protector cannot be changed, so the TPM protector is replaced:
+// it is deleted and a new TPM protector is added. If adding the new protector fails, a TPM
+// protector with the previous profile is added back. Only plain TPM protectors can be
+// replaced this way.
+//
+// Example: vol.SetPlatformValidationProfile([]int{0, 2, 4, 11})
+//
+// https://docs.microsoft.com/en-us/windows/win32/secprov/protectkeywithtpm-win32-encryptablevolume
+func (v *Volume) SetPlatformValidationProfile(pcrs []int) error {
+	for _, pcr := range pcrs {
+		if pcr < 0 || pcr > 23 {
+			return fmt.Errorf("%w: PCR %d out of range", ErrInvalidProfile, pcr)
+		}
+	}
+	if len(pcrs) == 0 {
+		return fmt.Errorf("%w: no PCRs specified", ErrInvalidProfile)
+	}
+	p, err := v.tpmProtector()
+	if err != nil {
+		return err
+	}
+	if p.Type != ProtectorTPM {
+		return fmt.Errorf("%w: cannot replace %s protector %s", ErrInvalidProfile, p.Type, p.ID)
+	}
+	old, err := v.validationProfile(p)
+	if err != nil {
+		return err
+	}
+	if err := v.DeleteKeyProtector(p.ID); err != nil {
+		return err
+	}
+	if err := v.protectWithTPM(pcrs); err != nil {
+		if restoreErr := v.protectWithTPM(old); restoreErr != nil {
+			return fmt.Errorf("%w; restoring TPM protector with PCRs %v also failed: %v", err, old, restoreErr)
+		}
+		return fmt.Errorf("%w; restored TPM protector with PCRs %v", err, old)
+	}
+	return nil
+}
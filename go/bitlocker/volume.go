@@ -0,0 +1,215 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bitlocker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/go-ole/go-ole"
+	"github.com/go-ole/go-ole/oleutil"
+)
+
+var (
+	// ErrDecrypting indicates a volume is being decrypted while waiting on encryption.
+	ErrDecrypting = errors.New("volume is being decrypted")
+
+	// Test Helpers
+	funcStatus   = (*Volume).Status
+	pollInterval = 10 * time.Second
+)
+
+// ConversionStatus describes the encryption state of a volume.
+//
+// https://docs.microsoft.com/en-us/windows/win32/secprov/getconversionstatus-win32-encryptablevolume
+type ConversionStatus int32
+
+// Conversion statuses.
+const (
+	FullyDecrypted ConversionStatus = iota
+	FullyEncrypted
+	EncryptionInProgress
+	DecryptionInProgress
+	EncryptionPaused
+	DecryptionPaused
+)
+
+// Status reports the progress of encryption on a volume.
+type Status struct {
+	Conversion           ConversionStatus
+	EncryptionPercentage int
+	WipingPercentage     int
+}
+
+// Volume is a connection to a single Win32_EncryptableVolume.
+//
+// COM is initialized on the calling goroutine's thread, which stays locked until Close is
+// called. A Volume must not be shared between goroutines.
+type Volume struct {
+	letter string
+	handle *ole.IDispatch
+	wmi    *wmi
+}
+
+// Connect connects to the encryptable volume with the given drive letter. The caller must
+// call Close once done with the volume.
+//
+// Example: vol, err := bitlocker.Connect("c:")
+func Connect(driveLetter string) (Volume, error) {
+	runtime.LockOSThread()
+	ole.CoInitialize(0)
+	v := Volume{letter: driveLetter, wmi: &wmi{}}
+	if err := v.wmi.connect(); err != nil {
+		ole.CoUninitialize()
+		runtime.UnlockOSThread()
+		return v, fmt.Errorf("wmi.Connect: %w", err)
+	}
+	handle, err := v.query()
+	if err != nil {
+		v.wmi.close()
+		ole.CoUninitialize()
+		runtime.UnlockOSThread()
+		return v, err
+	}
+	v.handle = handle
+	return v, nil
+}
+
+func (v *Volume) query() (*ole.IDispatch, error) {
+	raw, err := oleutil.CallMethod(v.wmi.svc, "ExecQuery",
+		"SELECT * FROM Win32_EncryptableVolume WHERE DriveLetter = '"+v.letter+"'")
+	if err != nil {
+		return nil, fmt.Errorf("ExecQuery: %w", err)
+	}
+	result := raw.ToIDispatch()
+	defer result.Release()
+
+	itemRaw, err := oleutil.CallMethod(result, "ItemIndex", 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch result row while processing BitLocker info: %w", err)
+	}
+	return itemRaw.ToIDispatch(), nil
+}
+
+// Close releases the volume and uninitializes COM.
+func (v *Volume) Close() {
+	if v.handle != nil {
+		v.handle.Release()
+	}
+	v.wmi.close()
+	ole.CoUninitialize()
+	runtime.UnlockOSThread()
+}
+
+// call invokes a Win32_EncryptableVolume method, translating its return code to an error.
+func (v *Volume) call(method string, params ...interface{}) error {
+	resultRaw, err := oleutil.CallMethod(v.handle, method, params...)
+	if err != nil {
+		return fmt.Errorf("error calling %s(%s): %w", method, v.letter, err)
+	}
+	defer resultRaw.Clear()
+	return v.resultErr(method, resultRaw.Value())
+}
+
+// resultErr translates the return value of a Win32_EncryptableVolume method to an error.
+func (v *Volume) resultErr(method string, result interface{}) error {
+	val, ok := result.(int32)
+	if !ok {
+		return fmt.Errorf("%s(%s): unexpected return value %v", method, v.letter, result)
+	}
+	switch val {
+	case 0:
+		return nil
+	case FVE_E_BOOTABLE_CDDVD, FVE_E_PROTECTOR_EXISTS:
+		return fmt.Errorf("%s(%s): %w", method, v.letter, encryptErrHandler(val))
+	default:
+		return fmt.Errorf("%s(%s): 0x%08X", method, v.letter, uint32(val))
+	}
+}
+
+// variantInt converts an integer output parameter to an int.
+func variantInt(v *ole.VARIANT) int {
+	return intValue(v.Value())
+}
+
+func intValue(v interface{}) int {
+	switch val := v.(type) {
+	case int8:
+		return int(val)
+	case uint8:
+		return int(val)
+	case int16:
+		return int(val)
+	case uint16:
+		return int(val)
+	case int32:
+		return int(val)
+	case uint32:
+		return int(val)
+	case int64:
+		return int(val)
+	case uint64:
+		return int(val)
+	}
+	return 0
+}
+
+// Status retrieves the encryption status of the volume.
+func (v *Volume) Status() (Status, error) {
+	var conversion, encPct, encFlags, wiping, wipePct ole.VARIANT
+	for _, o := range []*ole.VARIANT{&conversion, &encPct, &encFlags, &wiping, &wipePct} {
+		ole.VariantInit(o)
+		defer o.Clear()
+	}
+	if err := v.call("GetConversionStatus", &conversion, &encPct, &encFlags, &wiping, &wipePct); err != nil {
+		return Status{}, err
+	}
+	return Status{
+		Conversion:           ConversionStatus(variantInt(&conversion)),
+		EncryptionPercentage: variantInt(&encPct),
+		WipingPercentage:     variantInt(&wipePct),
+	}, nil
+}
+
+// WaitForEncryption polls the volume until it is fully encrypted, or ctx is done. If set,
+// progress is called with the encryption percentage after every poll.
+//
+// A volume which has not started encrypting yet or whose encryption is paused is waited
+// on; use a context with a deadline to bound the wait. Decryption fails with ErrDecrypting.
+func (v *Volume) WaitForEncryption(ctx context.Context, progress func(percent int)) error {
+	for {
+		st, err := funcStatus(v)
+		if err != nil {
+			return err
+		}
+		if progress != nil {
+			progress(st.EncryptionPercentage)
+		}
+		switch st.Conversion {
+		case FullyEncrypted:
+			return nil
+		case DecryptionInProgress, DecryptionPaused:
+			return fmt.Errorf("%w: %s", ErrDecrypting, v.letter)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dism
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// Capability is a Feature on Demand (eg OpenSSH.Client~~~~0.0.1.0).
+type Capability struct {
+	Name  string
+	State FeatureState
+}
+
+// CapabilityInfo describes a capability in detail.
+type CapabilityInfo struct {
+	Capability
+	DisplayName  string
+	Description  string
+	DownloadSize uint32
+	InstallSize  uint32
+}
+
+// Installed reports whether the capability is installed or pending installation.
+func (c Capability) Installed() bool {
+	return c.State.Installed()
+}
+
+// Capabilities returns the capabilities known to the image and their install state.
+func (s Session) Capabilities() ([]Capability, error) {
+	caps := []Capability{}
+	var p unsafe.Pointer
+	var count uint32
+	if err := call(func() error { return DismGetCapabilities(s.Handle, &p, &count) }); err != nil {
+		return caps, fmt.Errorf("DismGetCapabilities: %w", err)
+	}
+	defer DismDelete(p)
+
+	r := newRecord(p)
+	for i := uint32(0); i < count; i++ {
+		caps = append(caps, Capability{Name: r.string(), State: FeatureState(r.uint32())})
+	}
+	return caps, nil
+}
+
+// CapabilityInfo returns details of the named capability.
+//
+// Example: s.CapabilityInfo("Rsat.ActiveDirectory.DS-LDS.Tools~~~~0.0.1.0")
+func (s Session) CapabilityInfo(name string) (*CapabilityInfo, error) {
+	n, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return &CapabilityInfo{}, err
+	}
+	var p unsafe.Pointer
+	if err := call(func() error { return DismGetCapabilityInfo(s.Handle, n, &p) }); err != nil {
+		return &CapabilityInfo{}, fmt.Errorf("DismGetCapabilityInfo(%s): %w", name, err)
+	}
+	defer DismDelete(p)
+
+	r := newRecord(p)
+	return &CapabilityInfo{
+		Capability:   Capability{Name: r.string(), State: FeatureState(r.uint32())},
+		DisplayName:  r.string(),
+		Description:  r.string(),
+		DownloadSize: r.uint32(),
+		InstallSize:  r.uint32(),
+	}, nil
+}
+
+// AddCapability installs the named capability. Capabilities which are already installed are
+// left alone.
+//
+// sourcePaths optionally lists locations to obtain the capability from. With limitAccess,
+// Windows Update is not consulted.
+func (s Session) AddCapability(name string, sourcePaths []string, limitAccess bool) error {
+	info, err := s.CapabilityInfo(name)
+	if err != nil {
+		return err
+	}
+	if info.Installed() {
+		return nil
+	}
+	n, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	src, err := utf16Array(sourcePaths)
+	if err != nil {
+		return err
+	}
+	if err := call(func() error {
+		return DismAddCapability(s.Handle, n, limitAccess, src, uint32(len(sourcePaths)), 0, 0, nil)
+	}); err != nil {
+		return fmt.Errorf("DismAddCapability(%s): %w", name, err)
+	}
+	return nil
+}
+
+// RemoveCapability uninstalls the named capability. Capabilities which are not installed are
+// left alone.
+func (s Session) RemoveCapability(name string) error {
+	info, err := s.CapabilityInfo(name)
+	if err != nil {
+		return err
+	}
+	if !info.Installed() {
+		return nil
+	}
+	n, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	if err := call(func() error { return DismRemoveCapability(s.Handle, n, 0, 0, nil) }); err != nil {
+		return fmt.Errorf("DismRemoveCapability(%s): %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dism
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/glazier/go/helpers"
+)
+
+var (
+	// DismExe is the path to the DISM command line tool.
+	DismExe = os.ExpandEnv(`${windir}\System32\Dism.exe`)
+
+	// CleanupTimeout bounds component store cleanup, which can run for a long time on
+	// images with many superseded updates.
+	CleanupTimeout = 2 * time.Hour
+
+	// Test Helpers
+	funcExec = helpers.ExecWithVerify
+)
+
+// imageArg returns the dism.exe argument selecting the session's image.
+func (s Session) imageArg() string {
+	if s.imagePath == DismOnlineImage {
+		return "/Online"
+	}
+	return "/Image:" + s.imagePath
+}
+
+// Cleanup removes superseded components from the component store of the image
+// (/Cleanup-Image /StartComponentCleanup), reducing the size of WinSxS before capture. With
+// resetBase, all superseded versions are removed and installed updates can no longer be
+// uninstalled.
+//
+// The DISM API offers no equivalent, so this runs dism.exe against the session's image.
+func (s Session) Cleanup(resetBase bool) error {
+	args := []string{s.imageArg(), "/Cleanup-Image", "/StartComponentCleanup"}
+	if resetBase {
+		args = append(args, "/ResetBase")
+	}
+	if _, err := funcExec(DismExe, args, &CleanupTimeout, nil); err != nil {
+		return fmt.Errorf("component cleanup of %s: %w", s.imagePath, err)
+	}
+	return nil
+}
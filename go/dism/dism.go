@@ -0,0 +1,199 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dism provides an interface to the Deployment Image Servicing and Management API.
+//
+// https://docs.microsoft.com/en-us/windows-hardware/manufacture/desktop/dism/dism-api-reference
+package dism
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+//go:generate go run golang.org/x/sys/windows/mkwinsyscall -output zdism.go dism.go
+
+// DismOnlineImage is the image path denoting the running operating system.
+const DismOnlineImage = "DISM_{53BFAE52-B167-4E2F-A258-0A37B57FF845}"
+
+// LogLevel controls the verbosity of the DISM log.
+type LogLevel uint32
+
+// Log levels.
+const (
+	LogErrors                  LogLevel = 0
+	LogErrorsWarnings          LogLevel = 1
+	LogErrorsWarningsInfo      LogLevel = 2
+	LogErrorsWarningsInfoDebug LogLevel = 3
+)
+
+// PackageIdentifier specifies how a package is identified in calls scoped to a package.
+type PackageIdentifier uint32
+
+// Package identifiers.
+const (
+	PackageNone PackageIdentifier = 0
+	PackageName PackageIdentifier = 1
+	PackagePath PackageIdentifier = 2
+)
+
+//sys DismInitialize(logLevel LogLevel, logFilePath *uint16, scratchDirectory *uint16) (e error) = DismAPI.DismInitialize
+//sys DismShutdown() (e error) = DismAPI.DismShutdown
+//sys DismOpenSession(imagePath *uint16, windowsDirectory *uint16, systemDrive *uint16, session *uint32) (e error) = DismAPI.DismOpenSession
+//sys DismCloseSession(session uint32) (e error) = DismAPI.DismCloseSession
+//sys DismDelete(structure unsafe.Pointer) (e error) = DismAPI.DismDelete
+//sys DismGetLastErrorMessage(errorMessage *unsafe.Pointer) (e error) = DismAPI.DismGetLastErrorMessage
+//sys DismGetFeatures(session uint32, identifier *uint16, packageIdentifier PackageIdentifier, feature *unsafe.Pointer, count *uint32) (e error) = DismAPI.DismGetFeatures
+//sys DismGetPackages(session uint32, pkg *unsafe.Pointer, count *uint32) (e error) = DismAPI.DismGetPackages
+//sys DismGetPackageInfo(session uint32, identifier *uint16, packageIdentifier PackageIdentifier, packageInfo *unsafe.Pointer) (e error) = DismAPI.DismGetPackageInfo
+//sys DismGetCapabilities(session uint32, capability *unsafe.Pointer, count *uint32) (e error) = DismAPI.DismGetCapabilities
+//sys DismGetCapabilityInfo(session uint32, name *uint16, info *unsafe.Pointer) (e error) = DismAPI.DismGetCapabilityInfo
+//sys DismAddCapability(session uint32, name *uint16, limitAccess bool, sourcePaths **uint16, sourcePathCount uint32, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer) (e error) = DismAPI.DismAddCapability
+//sys DismRemoveCapability(session uint32, name *uint16, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer) (e error) = DismAPI.DismRemoveCapability
+//sys DismAddDriver(session uint32, driverPath *uint16, forceUnsigned bool) (e error) = DismAPI.DismAddDriver
+//sys DismRemoveDriver(session uint32, driverPath *uint16) (e error) = DismAPI.DismRemoveDriver
+//sys DismGetDrivers(session uint32, allDrivers bool, driverPackage *unsafe.Pointer, count *uint32) (e error) = DismAPI.DismGetDrivers
+//sys DismGetDriverInfo(session uint32, driverPath *uint16, driver *unsafe.Pointer, count *uint32, driverPackage *unsafe.Pointer) (e error) = DismAPI.DismGetDriverInfo
+//sys DismGetFeatureInfo(session uint32, featureName *uint16, identifier *uint16, packageIdentifier PackageIdentifier, featureInfo *unsafe.Pointer) (e error) = DismAPI.DismGetFeatureInfo
+//sys DismEnableFeature(session uint32, featureName *uint16, identifier *uint16, packageIdentifier PackageIdentifier, limitAccess bool, sourcePaths **uint16, sourcePathCount uint32, enableAll bool, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer) (e error) = DismAPI.DismEnableFeature
+//sys DismDisableFeature(session uint32, featureName *uint16, packageName *uint16, removePayload bool, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer) (e error) = DismAPI.DismDisableFeature
+//sys DismMountImage(imageFilePath *uint16, mountPath *uint16, imageIndex uint32, imageName *uint16, imageIdentifier uint32, flags uint32, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer) (e error) = DismAPI.DismMountImage
+//sys DismUnmountImage(mountPath *uint16, flags uint32, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer) (e error) = DismAPI.DismUnmountImage
+//sys DismRemountImage(mountPath *uint16) (e error) = DismAPI.DismRemountImage
+//sys DismGetImageInfo(imageFilePath *uint16, imageInfo *unsafe.Pointer, count *uint32) (e error) = DismAPI.DismGetImageInfo
+//sys DismGetMountedImageInfo(mountedImageInfo *unsafe.Pointer, count *uint32) (e error) = DismAPI.DismGetMountedImageInfo
+//sys DismCheckImageHealth(session uint32, scanImage bool, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer, imageHealth *HealthState) (e error) = DismAPI.DismCheckImageHealth
+//sys DismRestoreImageHealth(session uint32, sourcePaths **uint16, sourcePathCount uint32, limitAccess bool, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer) (e error) = DismAPI.DismRestoreImageHealth
+//sys DismApplyUnattend(session uint32, unattendFile *uint16, singleSession bool) (e error) = DismAPI.DismApplyUnattend
+//sys DismCommitImage(session uint32, flags uint32, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer) (e error) = DismAPI.DismCommitImage
+
+// Error is a failed DISM API call, with the message DISM recorded for it. DISM reports
+// most failures as generic HRESULTs, so the message is usually the more useful part.
+type Error struct {
+	Errno   syscall.Errno
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return e.Errno.Error()
+	}
+	return fmt.Sprintf("%v (%s)", e.Errno, e.Message)
+}
+
+// Unwrap returns the underlying Errno.
+func (e *Error) Unwrap() error {
+	return e.Errno
+}
+
+// call invokes a DISM API function, attaching DismGetLastErrorMessage to any failure. The
+// message is kept per thread, so the goroutine is locked to its thread for the duration.
+func call(fn func() error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	err := fn()
+	if err == nil {
+		return nil
+	}
+	errno, ok := err.(syscall.Errno)
+	if !ok {
+		return err
+	}
+	return &Error{Errno: errno, Message: lastErrorMessage()}
+}
+
+func lastErrorMessage() string {
+	var p unsafe.Pointer
+	if err := DismGetLastErrorMessage(&p); err != nil || p == nil {
+		return ""
+	}
+	defer DismDelete(p)
+	return strings.TrimSpace(newRecord(p).string())
+}
+
+// Session holds an open DISM session. Call Close to release it.
+type Session struct {
+	Handle    uint32
+	imagePath string
+}
+
+// utf16Ptr converts s for use as an optional string argument, where empty means NULL.
+func utf16Ptr(s string) (*uint16, error) {
+	if s == "" {
+		return nil, nil
+	}
+	return windows.UTF16PtrFromString(s)
+}
+
+// utf16Array converts strs to an array of strings as taken by DISM functions accepting
+// multiple paths. An empty list yields NULL.
+func utf16Array(strs []string) (**uint16, error) {
+	if len(strs) == 0 {
+		return nil, nil
+	}
+	arr := make([]*uint16, len(strs))
+	for i, s := range strs {
+		p, err := windows.UTF16PtrFromString(s)
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = p
+	}
+	return &arr[0], nil
+}
+
+// OpenSession initializes DISM and opens a session against the image at imagePath, which is
+// either the root directory of an offline image or DismOnlineImage.
+//
+// windowsDir, systemDrive, logFile and scratchDir are optional. Any number of sessions may
+// be open at once; logLevel, logFile and scratchDir take effect only if no other session is.
+//
+// Example: dism.OpenSession(dism.DismOnlineImage, "", "", dism.LogErrorsWarnings, "", "")
+func OpenSession(imagePath, windowsDir, systemDrive string, logLevel LogLevel, logFile, scratchDir string) (Session, error) {
+	s := Session{imagePath: imagePath}
+	ip, err := windows.UTF16PtrFromString(imagePath)
+	if err != nil {
+		return s, err
+	}
+	wd, err := utf16Ptr(windowsDir)
+	if err != nil {
+		return s, err
+	}
+	sys, err := utf16Ptr(systemDrive)
+	if err != nil {
+		return s, err
+	}
+	if err := acquire(logLevel, logFile, scratchDir); err != nil {
+		return s, err
+	}
+	if err := call(func() error { return DismOpenSession(ip, wd, sys, &s.Handle) }); err != nil {
+		release()
+		return s, fmt.Errorf("DismOpenSession(%s): %w", imagePath, err)
+	}
+	return s, nil
+}
+
+// Close closes the session, and shuts down DISM once no other sessions remain open. Close
+// must be called exactly once per session.
+func (s Session) Close() error {
+	if err := call(func() error { return DismCloseSession(s.Handle) }); err != nil {
+		return fmt.Errorf("DismCloseSession: %w", err)
+	}
+	return release()
+}
@@ -0,0 +1,591 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dism
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/google/glazier/go/helpers"
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/sys/windows"
+)
+
+// packed lays out values with 1-byte packing, as DISM does.
+type packed struct {
+	buf  []byte
+	keep []interface{}
+}
+
+func (p *packed) uint16(v uint16) {
+	p.buf = append(p.buf, byte(v), byte(v>>8))
+}
+
+func (p *packed) uint32(v uint32) {
+	b := (*[4]byte)(unsafe.Pointer(&v))
+	p.buf = append(p.buf, b[:]...)
+}
+
+func (p *packed) uint64(v uint64) {
+	p.uint32(uint32(v))
+	p.uint32(uint32(v >> 32))
+}
+
+func (p *packed) pointer(ptr unsafe.Pointer) {
+	p.keep = append(p.keep, ptr)
+	b := (*[unsafe.Sizeof(uintptr(0))]byte)(unsafe.Pointer(&ptr))
+	p.buf = append(p.buf, b[:]...)
+}
+
+func (p *packed) string(s string) {
+	var ptr *uint16
+	if s != "" {
+		ptr, _ = windows.UTF16PtrFromString(s)
+		p.keep = append(p.keep, ptr)
+	}
+	b := (*[unsafe.Sizeof(uintptr(0))]byte)(unsafe.Pointer(&ptr))
+	p.buf = append(p.buf, b[:]...)
+}
+
+func TestRecordArray(t *testing.T) {
+	p := &packed{}
+	p.string("NetFx3")
+	p.uint32(uint32(StateInstalled))
+	p.string("")
+	p.uint32(uint32(StateStaged))
+
+	r := newRecord(unsafe.Pointer(&p.buf[0]))
+	got := []Feature{
+		{Name: r.string(), State: FeatureState(r.uint32())},
+		{Name: r.string(), State: FeatureState(r.uint32())},
+	}
+	runtime.KeepAlive(p)
+	want := []Feature{{"NetFx3", StateInstalled}, {"", StateStaged}}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("feature %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestFeatureStateString(t *testing.T) {
+	tests := []struct {
+		in   FeatureState
+		want string
+	}{
+		{StateInstalled, "Installed"},
+		{StatePartiallyInstalled, "PartiallyInstalled"},
+		{FeatureState(42), "Unknown(42)"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("FeatureState(%d).String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestCustomProperties(t *testing.T) {
+	p := &packed{}
+	p.string("Name1")
+	p.string("Value1")
+	p.string(`\Path`)
+	p.string("Name2")
+	p.string("")
+	p.string("")
+
+	got := customProperties(unsafe.Pointer(&p.buf[0]), 2)
+	runtime.KeepAlive(p)
+	want := []CustomProperty{{"Name1", "Value1", `\Path`}, {"Name2", "", ""}}
+	if len(got) != len(want) {
+		t.Fatalf("customProperties() returned %d properties, want %d", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("property %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if got := customProperties(nil, 0); len(got) != 0 {
+		t.Errorf("customProperties(nil, 0) = %v, want empty", got)
+	}
+}
+
+func TestRecordPackage(t *testing.T) {
+	p := &packed{}
+	p.string("Package_for_KB1")
+	p.uint32(uint32(StateInstalled))
+	p.uint32(uint32(ReleaseSecurityUpdate))
+	for _, v := range []uint16{2021, 11, 2, 9, 13, 45, 30, 250} {
+		p.uint16(v)
+	}
+	p.string("Package_for_KB2")
+	p.uint32(uint32(StateStaged))
+	p.uint32(uint32(ReleaseUpdate))
+	for i := 0; i < 8; i++ {
+		p.uint16(0)
+	}
+
+	r := newRecord(unsafe.Pointer(&p.buf[0]))
+	got := []Package{r.pkg(), r.pkg()}
+	runtime.KeepAlive(p)
+	want := []Package{
+		{"Package_for_KB1", StateInstalled, ReleaseSecurityUpdate, time.Date(2021, 11, 9, 13, 45, 30, 250*int(time.Millisecond), time.UTC)},
+		{"Package_for_KB2", StateStaged, ReleaseUpdate, time.Time{}},
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("package %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestPackageIdentifier(t *testing.T) {
+	tests := []struct {
+		in   string
+		want PackageIdentifier
+	}{
+		{"Package_for_RollupFix~31bf3856ad364e35~amd64~~19041.1415.1.6", PackageName},
+		{`C:\updates\windows10.0-kb5008212-x64.cab`, PackagePath},
+		{"kb5008212.CAB", PackagePath},
+		{`D:\expanded`, PackagePath},
+	}
+	for _, tt := range tests {
+		if got := packageIdentifier(tt.in); got != tt.want {
+			t.Errorf("packageIdentifier(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHasPackage(t *testing.T) {
+	pkgs := []Package{
+		{Name: "Package_for_RollupFix~31bf3856ad364e35~amd64~~19041.1415.1.6", State: StateInstalled},
+		{Name: "Package_for_KB5005565~31bf3856ad364e35~amd64~~19041.1237.1.2", State: StateInstallPending},
+		{Name: "Package_for_KB5003791~31bf3856ad364e35~amd64~~19041.1052.1.0", State: StateStaged},
+	}
+	tests := []struct {
+		prefix string
+		want   bool
+	}{
+		{"Package_for_RollupFix~31bf3856ad364e35~amd64~~19041.1415", true},
+		{"package_for_rollupfix", true},
+		{"Package_for_KB5005565", true},
+		{"Package_for_KB5003791", false},
+		{"Package_for_KB0000000", false},
+	}
+	for _, tt := range tests {
+		if got := hasPackage(pkgs, tt.prefix); got != tt.want {
+			t.Errorf("hasPackage(%q) = %t, want %t", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestUTF16Array(t *testing.T) {
+	if p, err := utf16Array(nil); p != nil || err != nil {
+		t.Errorf("utf16Array(nil) = %v, %v, want nil, nil", p, err)
+	}
+	in := []string{`D:\sources\sxs`, `\\server\share\fod`}
+	p, err := utf16Array(in)
+	if err != nil {
+		t.Fatalf("utf16Array(%v) returned unexpected error %v", in, err)
+	}
+	arr := (*[2]*uint16)(unsafe.Pointer(p))
+	for i, want := range in {
+		if got := windows.UTF16PtrToString(arr[i]); got != want {
+			t.Errorf("utf16Array(%v)[%d] = %q, want %q", in, i, got, want)
+		}
+	}
+}
+
+func TestCapabilityInstalled(t *testing.T) {
+	tests := []struct {
+		in   FeatureState
+		want bool
+	}{
+		{StateInstalled, true},
+		{StateInstallPending, true},
+		{StateNotPresent, false},
+		{StateStaged, false},
+	}
+	for _, tt := range tests {
+		if got := (Capability{State: tt.in}).Installed(); got != tt.want {
+			t.Errorf("Capability{State: %v}.Installed() = %t, want %t", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRecordDriverPackage(t *testing.T) {
+	p := &packed{}
+	p.string("oem3.inf")
+	p.string(`C:\Windows\INF\net.inf`)
+	p.uint32(0)
+	p.string("net.cat")
+	p.string("Net")
+	p.string("{4d36e972-e325-11ce-bfc1-08002be10318}")
+	p.string("Network adapters")
+	p.uint32(1)
+	p.uint32(uint32(SignatureUnsigned))
+	p.string("Contoso")
+	for _, v := range []uint16{2020, 6, 0, 1, 0, 0, 0, 0} {
+		p.uint16(v)
+	}
+	for _, v := range []uint32{1, 2, 3, 4} {
+		p.uint32(v)
+	}
+
+	got := newRecord(unsafe.Pointer(&p.buf[0])).driverPackage()
+	runtime.KeepAlive(p)
+	want := DriverPackage{
+		PublishedName:    "oem3.inf",
+		OriginalFileName: `C:\Windows\INF\net.inf`,
+		CatalogFile:      "net.cat",
+		ClassName:        "Net",
+		ClassGUID:        "{4d36e972-e325-11ce-bfc1-08002be10318}",
+		ClassDescription: "Network adapters",
+		BootCritical:     true,
+		Signature:        SignatureUnsigned,
+		ProviderName:     "Contoso",
+		Date:             time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC),
+		MajorVersion:     1,
+		MinorVersion:     2,
+		Build:            3,
+		Revision:         4,
+	}
+	if got != want {
+		t.Errorf("driverPackage() = %+v, want %+v", got, want)
+	}
+	if v := got.Version(); v != "1.2.3.4" {
+		t.Errorf("Version() = %q, want %q", v, "1.2.3.4")
+	}
+}
+
+func TestInfFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, f := range []string{"a.inf", `sub\b.INF`, `sub\b.sys`, `sub\deeper\c.inf`} {
+		p := filepath.Join(dir, f)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := infFiles(dir)
+	if err != nil {
+		t.Fatalf("infFiles(%q) returned unexpected error %v", dir, err)
+	}
+	want := []string{filepath.Join(dir, "a.inf"), filepath.Join(dir, `sub\b.INF`), filepath.Join(dir, `sub\deeper\c.inf`)}
+	if len(got) != len(want) {
+		t.Fatalf("infFiles(%q) = %v, want %v", dir, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("infFiles(%q)[%d] = %q, want %q", dir, i, got[i], want[i])
+		}
+	}
+	single := filepath.Join(dir, "a.inf")
+	if got, err := infFiles(single); err != nil || len(got) != 1 || got[0] != single {
+		t.Errorf("infFiles(%q) = %v, %v, want [%s]", single, got, err, single)
+	}
+}
+
+func TestRecordImageInfo(t *testing.T) {
+	langs := &packed{}
+	langs.string("en-US")
+	langs.string("de-DE")
+
+	p := &packed{}
+	p.uint32(0)
+	p.uint32(3)
+	p.string("Windows 10 Enterprise")
+	p.string("Windows 10 Enterprise")
+	p.uint64(15 << 30)
+	p.uint32(uint32(ArchAMD64))
+	p.string("Microsoft Windows Operating System")
+	p.string("Enterprise")
+	p.string("Client")
+	p.string("acpiapic")
+	p.string("WinNT")
+	p.string("Terminal Server")
+	for _, v := range []uint32{10, 0, 19041, 1415, 0, 1} {
+		p.uint32(v)
+	}
+	p.string("WINDOWS")
+	p.pointer(unsafe.Pointer(&langs.buf[0]))
+	p.uint32(2)
+	p.uint32(1)
+	p.pointer(nil)
+
+	got := newRecord(unsafe.Pointer(&p.buf[0])).imageInfo()
+	runtime.KeepAlive(p)
+	runtime.KeepAlive(langs)
+	if got.Index != 3 || got.EditionID != "Enterprise" || got.Size != 15<<30 || got.Architecture != ArchAMD64 {
+		t.Errorf("imageInfo() = %+v", got)
+	}
+	if v := got.Version(); v != "10.0.19041.1415" {
+		t.Errorf("Version() = %q, want %q", v, "10.0.19041.1415")
+	}
+	if len(got.Languages) != 2 || got.Languages[1] != "de-DE" || got.DefaultLanguage != "de-DE" {
+		t.Errorf("imageInfo() languages = %v (default %q), want [en-US de-DE] (default de-DE)", got.Languages, got.DefaultLanguage)
+	}
+	if got.ProductType != "WinNT" || got.InstallationType != "Client" {
+		t.Errorf("imageInfo() product = %q/%q, want WinNT/Client", got.ProductType, got.InstallationType)
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	tests := []struct {
+		image     string
+		resetBase bool
+		want      []string
+	}{
+		{DismOnlineImage, false, []string{"/Online", "/Cleanup-Image", "/StartComponentCleanup"}},
+		{`C:\mount`, true, []string{`/Image:C:\mount`, "/Cleanup-Image", "/StartComponentCleanup", "/ResetBase"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		funcExec = func(path string, args []string, timeout *time.Duration, verifier *helpers.ExecVerifier) (helpers.ExecResult, error) {
+			got = args
+			return helpers.ExecResult{}, nil
+		}
+		s := Session{imagePath: tt.image}
+		if err := s.Cleanup(tt.resetBase); err != nil {
+			t.Errorf("Cleanup(%t) returned unexpected error %v", tt.resetBase, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("Cleanup(%t) ran unexpected args (-want +got):\n%s", tt.resetBase, diff)
+		}
+	}
+}
+
+func TestError(t *testing.T) {
+	errno := syscall.Errno(0x800F081F)
+	err := fmt.Errorf("DismAddCapability(x): %w", &Error{Errno: errno, Message: "The source files could not be found."})
+	if !errors.Is(err, errno) {
+		t.Errorf("errors.Is(%v, %v) = false, want true", err, errno)
+	}
+	if !strings.HasSuffix(err.Error(), "(The source files could not be found.)") {
+		t.Errorf("Error() = %q, want the DISM message appended", err.Error())
+	}
+	if got, want := (&Error{Errno: errno}).Error(), errno.Error(); got != want {
+		t.Errorf("Error() without message = %q, want %q", got, want)
+	}
+}
+
+func TestSetIntl(t *testing.T) {
+	tests := []struct {
+		image    string
+		settings IntlSettings
+		want     []string
+		wantErr  error
+	}{
+		{
+			image:    `C:\mount`,
+			settings: IntlSettings{UILanguage: "de-DE", InputLocale: "0407:00000407", TimeZone: "W. Europe Standard Time"},
+			want:     []string{`/Image:C:\mount`, "/Set-UILang:de-DE", "/Set-InputLocale:0407:00000407", "/Set-TimeZone:W. Europe Standard Time"},
+		},
+		{
+			image: `C:\mount`,
+		},
+		{
+			image:    DismOnlineImage,
+			settings: IntlSettings{UILanguage: "de-DE"},
+			wantErr:  ErrOnlineImage,
+		},
+	}
+	for _, tt := range tests {
+		var got []string
+		funcExec = func(path string, args []string, timeout *time.Duration, verifier *helpers.ExecVerifier) (helpers.ExecResult, error) {
+			got = args
+			return helpers.ExecResult{}, nil
+		}
+		s := Session{imagePath: tt.image}
+		if err := s.SetIntl(tt.settings); !errors.Is(err, tt.wantErr) {
+			t.Errorf("SetIntl(%+v) returned unexpected error %v", tt.settings, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("SetIntl(%+v) ran unexpected args (-want +got):\n%s", tt.settings, diff)
+		}
+	}
+}
+
+func TestEditions(t *testing.T) {
+	out := `
+Deployment Image Servicing and Management tool
+Version: 10.0.19041.844
+
+Image Version: 10.0.19041.1415
+
+Current Edition : Professional
+
+Editions that can be upgraded to:
+
+Target Edition : Education
+Target Edition : Enterprise
+
+The operation completed successfully.
+`
+	var gotArgs [][]string
+	funcExec = func(path string, args []string, timeout *time.Duration, verifier *helpers.ExecVerifier) (helpers.ExecResult, error) {
+		gotArgs = append(gotArgs, args)
+		return helpers.ExecResult{Stdout: []byte(out)}, nil
+	}
+	s := Session{imagePath: DismOnlineImage}
+	cur, err := s.GetCurrentEdition()
+	if err != nil || cur != "Professional" {
+		t.Errorf("GetCurrentEdition() = %q, %v, want Professional, nil", cur, err)
+	}
+	targets, err := s.GetTargetEditions()
+	if err != nil {
+		t.Errorf("GetTargetEditions() returned unexpected error %v", err)
+	}
+	if diff := cmp.Diff([]string{"Education", "Enterprise"}, targets); diff != "" {
+		t.Errorf("GetTargetEditions() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if err := s.SetEdition("Enterprise", "nppr9-fwdcx-d2c8j-h872k-2yt43"); err != nil {
+		t.Errorf("SetEdition() returned unexpected error %v", err)
+	}
+	if err := s.SetEdition("Enterprise", "bogus"); !errors.Is(err, ErrInvalidProductKey) {
+		t.Errorf("SetEdition(bogus key) returned %v, want %v", err, ErrInvalidProductKey)
+	}
+	if err := s.SetProductKey("NPPR9-FWDCX-D2C8J-H872K-2YT43"); !errors.Is(err, ErrOnlineImage) {
+		t.Errorf("SetProductKey(online) returned %v, want %v", err, ErrOnlineImage)
+	}
+	want := [][]string{
+		{"/Online", "/Get-CurrentEdition"},
+		{"/Online", "/Get-TargetEditions"},
+		{"/Online", "/Set-Edition:Enterprise", "/ProductKey:NPPR9-FWDCX-D2C8J-H872K-2YT43", "/AcceptEula"},
+	}
+	if diff := cmp.Diff(want, gotArgs); diff != "" {
+		t.Errorf("edition servicing ran unexpected args (-want +got):\n%s", diff)
+	}
+}
+
+func TestValidateUnattend(t *testing.T) {
+	dir := t.TempDir()
+	tests := []struct {
+		desc    string
+		content string
+		wantErr error
+	}{
+		{"valid", `<?xml version="1.0" encoding="utf-8"?><unattend xmlns="urn:schemas-microsoft-com:unattend"><settings pass="offlineServicing"/></unattend>`, nil},
+		{"malformed", `<unattend><settings></unattend>`, ErrInvalidUnattend},
+		{"wrong root", `<answers/>`, ErrInvalidUnattend},
+		{"empty", ``, ErrInvalidUnattend},
+	}
+	for _, tt := range tests {
+		p := filepath.Join(dir, tt.desc+".xml")
+		if err := os.WriteFile(p, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := validateUnattend(p); !errors.Is(err, tt.wantErr) {
+			t.Errorf("validateUnattend(%s) returned %v, want %v", tt.desc, err, tt.wantErr)
+		}
+	}
+	if err := validateUnattend(filepath.Join(dir, "missing.xml")); !errors.Is(err, ErrInvalidUnattend) {
+		t.Errorf("validateUnattend(missing) returned %v, want %v", err, ErrInvalidUnattend)
+	}
+}
+
+func TestLifecycle(t *testing.T) {
+	inits, shutdowns := 0, 0
+	fnInitialize = func(LogLevel, *uint16, *uint16) error {
+		inits++
+		return nil
+	}
+	fnShutdown = func() error {
+		shutdowns++
+		return nil
+	}
+	defer func() {
+		fnInitialize = DismInitialize
+		fnShutdown = DismShutdown
+	}()
+
+	for i := 0; i < 2; i++ {
+		if err := acquire(LogErrors, "", ""); err != nil {
+			t.Fatalf("acquire() returned unexpected error %v", err)
+		}
+	}
+	if err := release(); err != nil {
+		t.Fatalf("release() returned unexpected error %v", err)
+	}
+	if inits != 1 || shutdowns != 0 {
+		t.Errorf("with one reference held: %d initializations, %d shutdowns; want 1, 0", inits, shutdowns)
+	}
+	release()
+	release()
+	if inits != 1 || shutdowns != 1 {
+		t.Errorf("after all references released: %d initializations, %d shutdowns; want 1, 1", inits, shutdowns)
+	}
+
+	fnInitialize = func(LogLevel, *uint16, *uint16) error {
+		// HRESULT_FROM_WIN32(ERROR_ALREADY_INITIALIZED)
+		return syscall.Errno(0x800704DF)
+	}
+	if err := acquire(LogErrors, "", ""); err != nil {
+		t.Fatalf("acquire() with DISM already initialized returned %v", err)
+	}
+	release()
+	if shutdowns != 1 {
+		t.Errorf("release() shut down DISM initialized elsewhere")
+	}
+}
+
+func TestParseLog(t *testing.T) {
+	got, err := ParseLog(filepath.Join("testdata", "dism.log"))
+	if err != nil {
+		t.Fatalf("ParseLog() returned unexpected error %v", err)
+	}
+	ts := func(s string) time.Time {
+		v, _ := time.ParseInLocation(logTimeFormat, s, time.Local)
+		return v
+	}
+	want := []LogEntry{
+		{
+			Time:      ts("2019-05-22 10:39:28"),
+			Level:     "Warning",
+			Component: "DISM",
+			Message:   `DISM Provider Store: PID=2488 TID=2492 Failed to Load the provider: C:\Windows\TEMP\MsiProvider.dll. - CDISMProviderStore::Internal_GetProvider(hr:0x8007007e)`,
+			HResult:   "0x8007007e",
+		},
+		{
+			Time:              ts("2019-05-22 10:41:02"),
+			Level:             "Info",
+			Component:         "DISM",
+			Message:           "DISM Package Manager: PID=2488 TID=2492 Component change, requesting shutdown. - CPackageManagerCLIHandler::Private_ProcessFeatureChange",
+			ShutdownRequested: true,
+		},
+		{
+			Time:      ts("2019-05-22 10:41:05"),
+			Level:     "Error",
+			Component: "DISM",
+			Message:   "DISM Package Manager: PID=2488 TID=2492 Failed finalizing changes. - CDISMPackageManager::Internal_Finalize(hr:0x800F081F)\nThe source files could not be found.",
+			HResult:   "0x800f081f",
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ParseLog() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if _, err := ParseLog(filepath.Join("testdata", "missing.log")); err == nil {
+		t.Errorf("ParseLog(missing) returned nil error")
+	}
+}
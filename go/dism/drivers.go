@@ -0,0 +1,199 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dism
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// DriverSignature is the signing status of a driver package.
+type DriverSignature uint32
+
+// Driver signature states.
+const (
+	SignatureUnknown  DriverSignature = 0
+	SignatureUnsigned DriverSignature = 1
+	SignatureSigned   DriverSignature = 2
+)
+
+// DriverPackage describes a driver package (.inf) in the image.
+type DriverPackage struct {
+	PublishedName    string
+	OriginalFileName string
+	InBox            bool
+	CatalogFile      string
+	ClassName        string
+	ClassGUID        string
+	ClassDescription string
+	BootCritical     bool
+	Signature        DriverSignature
+	ProviderName     string
+	Date             time.Time
+	MajorVersion     uint32
+	MinorVersion     uint32
+	Build            uint32
+	Revision         uint32
+}
+
+// Version returns the driver version in dotted form.
+func (d DriverPackage) Version() string {
+	return fmt.Sprintf("%d.%d.%d.%d", d.MajorVersion, d.MinorVersion, d.Build, d.Revision)
+}
+
+// Driver describes a device supported by a driver package.
+type Driver struct {
+	ManufacturerName    string
+	HardwareDescription string
+	HardwareID          string
+	Architecture        uint32
+	ServiceName         string
+	CompatibleIDs       string
+	ExcludeIDs          string
+}
+
+func (r *record) driverPackage() DriverPackage {
+	return DriverPackage{
+		PublishedName:    r.string(),
+		OriginalFileName: r.string(),
+		InBox:            r.bool(),
+		CatalogFile:      r.string(),
+		ClassName:        r.string(),
+		ClassGUID:        r.string(),
+		ClassDescription: r.string(),
+		BootCritical:     r.bool(),
+		Signature:        DriverSignature(r.uint32()),
+		ProviderName:     r.string(),
+		Date:             r.systemTime(),
+		MajorVersion:     r.uint32(),
+		MinorVersion:     r.uint32(),
+		Build:            r.uint32(),
+		Revision:         r.uint32(),
+	}
+}
+
+// Drivers returns the driver packages in the image. Inbox drivers are only included when
+// allDrivers is set.
+func (s Session) Drivers(allDrivers bool) ([]DriverPackage, error) {
+	pkgs := []DriverPackage{}
+	var p unsafe.Pointer
+	var count uint32
+	if err := call(func() error { return DismGetDrivers(s.Handle, allDrivers, &p, &count) }); err != nil {
+		return pkgs, fmt.Errorf("DismGetDrivers: %w", err)
+	}
+	defer DismDelete(p)
+
+	r := newRecord(p)
+	for i := uint32(0); i < count; i++ {
+		pkgs = append(pkgs, r.driverPackage())
+	}
+	return pkgs, nil
+}
+
+// DriverInfo returns details of a driver package and the devices it supports. path is
+// either the published name of an installed driver (eg oem1.inf) or the path to an .inf.
+func (s Session) DriverInfo(path string) (*DriverPackage, []Driver, error) {
+	drivers := []Driver{}
+	dp, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return &DriverPackage{}, drivers, err
+	}
+	var dPtr, pkgPtr unsafe.Pointer
+	var count uint32
+	if err := call(func() error { return DismGetDriverInfo(s.Handle, dp, &dPtr, &count, &pkgPtr) }); err != nil {
+		return &DriverPackage{}, drivers, fmt.Errorf("DismGetDriverInfo(%s): %w", path, err)
+	}
+	defer DismDelete(dPtr)
+	defer DismDelete(pkgPtr)
+
+	pkg := newRecord(pkgPtr).driverPackage()
+	r := newRecord(dPtr)
+	for i := uint32(0); i < count; i++ {
+		drivers = append(drivers, Driver{
+			ManufacturerName:    r.string(),
+			HardwareDescription: r.string(),
+			HardwareID:          r.string(),
+			Architecture:        r.uint32(),
+			ServiceName:         r.string(),
+			CompatibleIDs:       r.string(),
+			ExcludeIDs:          r.string(),
+		})
+	}
+	return &pkg, drivers, nil
+}
+
+// infFiles returns the .inf files at or beneath path, which may be a single file.
+func infFiles(path string) ([]string, error) {
+	files := []string{}
+	err := filepath.Walk(path, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.IsDir() && strings.EqualFold(filepath.Ext(p), ".inf") {
+			files = append(files, p)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// AddDriver adds a driver package to the image. If path is a directory, every .inf beneath
+// it is added; failures are collected and returned together once all have been attempted.
+//
+// forceUnsigned permits unsigned drivers to be added to x64 images.
+//
+// Example: s.AddDriver(`D:\drivers\contoso`, false)
+func (s Session) AddDriver(path string, forceUnsigned bool) error {
+	infs, err := infFiles(path)
+	if err != nil {
+		return err
+	}
+	if len(infs) == 0 {
+		return fmt.Errorf("no driver packages found in %s", path)
+	}
+	failed := []string{}
+	for _, inf := range infs {
+		p, err := windows.UTF16PtrFromString(inf)
+		if err != nil {
+			return err
+		}
+		if err := call(func() error { return DismAddDriver(s.Handle, p, forceUnsigned) }); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", inf, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("DismAddDriver failed for %d of %d drivers: %s", len(failed), len(infs), strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// RemoveDriver removes a third-party driver package from the image, given its published
+// name (eg oem1.inf) as reported by Drivers.
+func (s Session) RemoveDriver(publishedName string) error {
+	p, err := windows.UTF16PtrFromString(publishedName)
+	if err != nil {
+		return err
+	}
+	if err := call(func() error { return DismRemoveDriver(s.Handle, p) }); err != nil {
+		return fmt.Errorf("DismRemoveDriver(%s): %w", publishedName, err)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dism
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// The DISM API offers no edition servicing, so the functions below run dism.exe against the
+// session's image and parse its output.
+//
+// https://docs.microsoft.com/en-us/windows-hardware/manufacture/desktop/dism-windows-edition-servicing-command-line-options
+
+var (
+	// ErrInvalidProductKey indicates a malformed product key.
+	ErrInvalidProductKey = errors.New("invalid product key")
+	// ErrParse indicates unexpected output from dism.exe.
+	ErrParse = errors.New("unable to parse dism output")
+
+	editionTimeout = 30 * time.Minute
+
+	currentEditionRe = regexp.MustCompile(`(?m)^\s*Current Edition\s*:\s*(\S+)\s*$`)
+	targetEditionRe  = regexp.MustCompile(`(?m)^\s*Target Edition\s*:\s*(\S+)\s*$`)
+	editionIDRe      = regexp.MustCompile(`^[A-Za-z0-9]+$`)
+	productKeyRe     = regexp.MustCompile(`^([A-Z0-9]{5}-){4}[A-Z0-9]{5}$`)
+)
+
+func (s Session) dismExe(args ...string) (string, error) {
+	res, err := funcExec(DismExe, append([]string{s.imageArg()}, args...), &editionTimeout, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(res.Stdout), nil
+}
+
+// GetCurrentEdition returns the edition ID of the image (eg "Professional").
+func (s Session) GetCurrentEdition() (string, error) {
+	out, err := s.dismExe("/Get-CurrentEdition")
+	if err != nil {
+		return "", fmt.Errorf("getting edition of %s: %w", s.imagePath, err)
+	}
+	m := currentEditionRe.FindStringSubmatch(out)
+	if m == nil {
+		return "", fmt.Errorf("%w: no current edition reported", ErrParse)
+	}
+	return m[1], nil
+}
+
+// GetTargetEditions returns the edition IDs the image can be upgraded to.
+func (s Session) GetTargetEditions() ([]string, error) {
+	editions := []string{}
+	out, err := s.dismExe("/Get-TargetEditions")
+	if err != nil {
+		return editions, fmt.Errorf("getting target editions of %s: %w", s.imagePath, err)
+	}
+	for _, m := range targetEditionRe.FindAllStringSubmatch(out, -1) {
+		editions = append(editions, m[1])
+	}
+	return editions, nil
+}
+
+// SetEdition upgrades the image to the given edition (eg "Enterprise"), which must be one of
+// GetTargetEditions. productKey is required when servicing the online image, and ignored
+// otherwise.
+//
+// Example: s.SetEdition("Enterprise", "NPPR9-FWDCX-D2C8J-H872K-2YT43")
+func (s Session) SetEdition(edition, productKey string) error {
+	if !editionIDRe.MatchString(edition) {
+		return fmt.Errorf("invalid edition %q", edition)
+	}
+	args := []string{"/Set-Edition:" + edition}
+	if s.imagePath == DismOnlineImage {
+		key := strings.ToUpper(productKey)
+		if !productKeyRe.MatchString(key) {
+			return ErrInvalidProductKey
+		}
+		args = append(args, "/ProductKey:"+key, "/AcceptEula")
+	}
+	if _, err := s.dismExe(args...); err != nil {
+		return fmt.Errorf("setting edition of %s to %s: %w", s.imagePath, edition, err)
+	}
+	return nil
+}
+
+// SetProductKey installs a product key in an offline image.
+func (s Session) SetProductKey(productKey string) error {
+	if s.imagePath == DismOnlineImage {
+		return fmt.Errorf("setting product key: %w", ErrOnlineImage)
+	}
+	key := strings.ToUpper(productKey)
+	if !productKeyRe.MatchString(key) {
+		return ErrInvalidProductKey
+	}
+	if _, err := s.dismExe("/Set-ProductKey:" + key); err != nil {
+		return fmt.Errorf("setting product key of %s: %w", s.imagePath, err)
+	}
+	return nil
+}
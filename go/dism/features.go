@@ -0,0 +1,202 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dism
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// FeatureState is the state of a feature or package in an image.
+type FeatureState uint32
+
+// Feature and package states.
+const (
+	StateNotPresent         FeatureState = 0
+	StateUninstallPending   FeatureState = 1
+	StateStaged             FeatureState = 2
+	StateRemoved            FeatureState = 3
+	StateInstalled          FeatureState = 4
+	StateInstallPending     FeatureState = 5
+	StateSuperseded         FeatureState = 6
+	StatePartiallyInstalled FeatureState = 7
+)
+
+var featureStateNames = map[FeatureState]string{
+	StateNotPresent:         "NotPresent",
+	StateUninstallPending:   "UninstallPending",
+	StateStaged:             "Staged",
+	StateRemoved:            "Removed",
+	StateInstalled:          "Installed",
+	StateInstallPending:     "InstallPending",
+	StateSuperseded:         "Superseded",
+	StatePartiallyInstalled: "PartiallyInstalled",
+}
+
+func (f FeatureState) String() string {
+	if n, ok := featureStateNames[f]; ok {
+		return n
+	}
+	return fmt.Sprintf("Unknown(%d)", uint32(f))
+}
+
+// RestartType indicates whether a change requires a restart to complete.
+type RestartType uint32
+
+// Restart types.
+const (
+	RestartNo       RestartType = 0
+	RestartPossible RestartType = 1
+	RestartRequired RestartType = 2
+)
+
+// CustomProperty is a name/value pair attached to a feature or package.
+type CustomProperty struct {
+	Name  string
+	Value string
+	Path  string
+}
+
+func customProperties(p unsafe.Pointer, count uint32) []CustomProperty {
+	props := []CustomProperty{}
+	r := newRecord(p)
+	for i := uint32(0); i < count; i++ {
+		props = append(props, CustomProperty{Name: r.string(), Value: r.string(), Path: r.string()})
+	}
+	return props
+}
+
+// Installed reports whether the state is installed or pending installation.
+func (f FeatureState) Installed() bool {
+	return f == StateInstalled || f == StateInstallPending
+}
+
+// Feature is a Windows feature in the image.
+type Feature struct {
+	Name  string
+	State FeatureState
+}
+
+// Features returns all features present in the image.
+func (s Session) Features() ([]Feature, error) {
+	var p unsafe.Pointer
+	var count uint32
+	if err := call(func() error { return DismGetFeatures(s.Handle, nil, PackageNone, &p, &count) }); err != nil {
+		return []Feature{}, fmt.Errorf("DismGetFeatures: %w", err)
+	}
+	defer DismDelete(p)
+	return features(p, count), nil
+}
+
+func features(p unsafe.Pointer, count uint32) []Feature {
+	features := []Feature{}
+	r := newRecord(p)
+	for i := uint32(0); i < count; i++ {
+		features = append(features, Feature{
+			Name:  r.string(),
+			State: FeatureState(r.uint32()),
+		})
+	}
+	return features
+}
+
+// FeatureInfo describes a feature in detail.
+type FeatureInfo struct {
+	Name             string
+	State            FeatureState
+	DisplayName      string
+	Description      string
+	RestartRequired  RestartType
+	CustomProperties []CustomProperty
+}
+
+// FeatureInfo returns details of the named feature, including whether enabling or disabling
+// it requires a restart.
+//
+// Example: s.FeatureInfo("Microsoft-Hyper-V")
+func (s Session) FeatureInfo(name string) (*FeatureInfo, error) {
+	fn, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return &FeatureInfo{}, err
+	}
+	var p unsafe.Pointer
+	if err := call(func() error { return DismGetFeatureInfo(s.Handle, fn, nil, PackageNone, &p) }); err != nil {
+		return &FeatureInfo{}, fmt.Errorf("DismGetFeatureInfo(%s): %w", name, err)
+	}
+	defer DismDelete(p)
+
+	r := newRecord(p)
+	info := &FeatureInfo{
+		Name:            r.string(),
+		State:           FeatureState(r.uint32()),
+		DisplayName:     r.string(),
+		Description:     r.string(),
+		RestartRequired: RestartType(r.uint32()),
+	}
+	props := r.pointer()
+	info.CustomProperties = customProperties(props, r.uint32())
+	return info, nil
+}
+
+// errSFalse is the HRESULT S_FALSE. On Windows Server 2019 DismEnableFeature returns it
+// even when the feature was enabled successfully, where it surfaces as "Incorrect function".
+const errSFalse = syscall.Errno(1)
+
+// EnableFeature enables the named feature.
+//
+// sourcePaths optionally lists locations to obtain the feature payload from. With
+// limitAccess, Windows Update is not consulted. With enableAll, parent features are enabled
+// as well.
+//
+// Example: s.EnableFeature("NetFx3", []string{`D:\sources\sxs`}, true, true)
+func (s Session) EnableFeature(feature string, sourcePaths []string, limitAccess, enableAll bool) error {
+	fn, err := windows.UTF16PtrFromString(feature)
+	if err != nil {
+		return err
+	}
+	src, err := utf16Array(sourcePaths)
+	if err != nil {
+		return err
+	}
+	err = call(func() error {
+		return DismEnableFeature(s.Handle, fn, nil, PackageNone, limitAccess, src, uint32(len(sourcePaths)), enableAll, 0, 0, nil)
+	})
+	if errors.Is(err, errSFalse) {
+		if info, ierr := s.FeatureInfo(feature); ierr == nil && info.State.Installed() {
+			return nil
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("DismEnableFeature(%s): %w", feature, err)
+	}
+	return nil
+}
+
+// DisableFeature disables the named feature. With removePayload, the feature's files are
+// removed from the image as well.
+func (s Session) DisableFeature(feature string, removePayload bool) error {
+	fn, err := windows.UTF16PtrFromString(feature)
+	if err != nil {
+		return err
+	}
+	if err := call(func() error { return DismDisableFeature(s.Handle, fn, nil, removePayload, 0, 0, nil) }); err != nil {
+		return fmt.Errorf("DismDisableFeature(%s): %w", feature, err)
+	}
+	return nil
+}
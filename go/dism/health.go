@@ -0,0 +1,69 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dism
+
+import (
+	"fmt"
+)
+
+// HealthState is the health of an image's component store.
+type HealthState uint32
+
+// Health states.
+const (
+	Healthy       HealthState = 0
+	Repairable    HealthState = 1
+	NonRepairable HealthState = 2
+)
+
+var healthStateNames = map[HealthState]string{
+	Healthy:       "Healthy",
+	Repairable:    "Repairable",
+	NonRepairable: "NonRepairable",
+}
+
+func (h HealthState) String() string {
+	if n, ok := healthStateNames[h]; ok {
+		return n
+	}
+	return fmt.Sprintf("Unknown(%d)", uint32(h))
+}
+
+// CheckImageHealth reports whether the component store of the image is corrupt. Without
+// scan, only corruption already recorded is reported (/CheckHealth); with scan, the store
+// is verified in full (/ScanHealth), which can take a long time.
+func (s Session) CheckImageHealth(scan bool) (HealthState, error) {
+	var state HealthState
+	if err := call(func() error { return DismCheckImageHealth(s.Handle, scan, 0, 0, nil, &state) }); err != nil {
+		return state, fmt.Errorf("DismCheckImageHealth(%s): %w", s.imagePath, err)
+	}
+	return state, nil
+}
+
+// RestoreImageHealth repairs corruption in the component store of the image
+// (/RestoreHealth). sourcePaths optionally lists repair sources; with limitAccess, Windows
+// Update is not consulted.
+func (s Session) RestoreImageHealth(sourcePaths []string, limitAccess bool) error {
+	src, err := utf16Array(sourcePaths)
+	if err != nil {
+		return err
+	}
+	if err := call(func() error {
+		return DismRestoreImageHealth(s.Handle, src, uint32(len(sourcePaths)), limitAccess, 0, 0, nil)
+	}); err != nil {
+		return fmt.Errorf("DismRestoreImageHealth(%s): %w", s.imagePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,278 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dism
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	imageIdentifierIndex = 0
+
+	mountReadWrite = 0x00000000
+	mountReadOnly  = 0x00000001
+
+	unmountCommit  = 0x00000000
+	unmountDiscard = 0x00000001
+
+	commitGenerateIntegrity = 0x00010000
+)
+
+// withDISM runs fn with the DISM API initialized. Image mounting operates outside of any
+// session, but still requires DismInitialize.
+func withDISM(fn func() error) error {
+	if err := acquire(LogErrorsWarnings, "", ""); err != nil {
+		return err
+	}
+	defer release()
+	return fn()
+}
+
+// MountImage mounts the image at index (starting from 1) of a .wim or .vhd(x) file to
+// mountPath, which must be an existing empty directory.
+//
+// Example: dism.MountImage(`C:\images\install.wim`, `C:\mount`, 1, false)
+func MountImage(imagePath, mountPath string, index uint32, readOnly bool) error {
+	ip, err := windows.UTF16PtrFromString(imagePath)
+	if err != nil {
+		return err
+	}
+	mp, err := windows.UTF16PtrFromString(mountPath)
+	if err != nil {
+		return err
+	}
+	flags := uint32(mountReadWrite)
+	if readOnly {
+		flags = mountReadOnly
+	}
+	return withDISM(func() error {
+		if err := call(func() error {
+			return DismMountImage(ip, mp, index, nil, imageIdentifierIndex, flags, 0, 0, nil)
+		}); err != nil {
+			return fmt.Errorf("DismMountImage(%s, %d): %w", imagePath, index, err)
+		}
+		return nil
+	})
+}
+
+// UnmountImage unmounts the image at mountPath, saving changes if commit is set and
+// discarding them otherwise.
+func UnmountImage(mountPath string, commit bool) error {
+	mp, err := windows.UTF16PtrFromString(mountPath)
+	if err != nil {
+		return err
+	}
+	flags := uint32(unmountDiscard)
+	if commit {
+		flags = unmountCommit
+	}
+	return withDISM(func() error {
+		if err := call(func() error { return DismUnmountImage(mp, flags, 0, 0, nil) }); err != nil {
+			return fmt.Errorf("DismUnmountImage(%s): %w", mountPath, err)
+		}
+		return nil
+	})
+}
+
+// RemountImage remounts an image left orphaned at mountPath, eg after a reboot.
+func RemountImage(mountPath string) error {
+	mp, err := windows.UTF16PtrFromString(mountPath)
+	if err != nil {
+		return err
+	}
+	return withDISM(func() error {
+		if err := call(func() error { return DismRemountImage(mp) }); err != nil {
+			return fmt.Errorf("DismRemountImage(%s): %w", mountPath, err)
+		}
+		return nil
+	})
+}
+
+// CommitImage saves changes made to the mounted image the session was opened on, leaving
+// it mounted.
+func (s Session) CommitImage() error {
+	if err := call(func() error { return DismCommitImage(s.Handle, commitGenerateIntegrity, 0, 0, nil) }); err != nil {
+		return fmt.Errorf("DismCommitImage(%s): %w", s.imagePath, err)
+	}
+	return nil
+}
+
+// Architecture is a processor architecture (PROCESSOR_ARCHITECTURE_*).
+type Architecture uint32
+
+// Architectures.
+const (
+	ArchX86     Architecture = 0
+	ArchARM     Architecture = 5
+	ArchIA64    Architecture = 6
+	ArchAMD64   Architecture = 9
+	ArchNeutral Architecture = 11
+	ArchARM64   Architecture = 12
+)
+
+var architectureNames = map[Architecture]string{
+	ArchX86:     "x86",
+	ArchARM:     "arm",
+	ArchIA64:    "ia64",
+	ArchAMD64:   "amd64",
+	ArchNeutral: "neutral",
+	ArchARM64:   "arm64",
+}
+
+func (a Architecture) String() string {
+	if n, ok := architectureNames[a]; ok {
+		return n
+	}
+	return fmt.Sprintf("Unknown(%d)", uint32(a))
+}
+
+// ImageInfo describes an image within a .wim or .vhd(x) file.
+type ImageInfo struct {
+	Index            uint32
+	Name             string
+	Description      string
+	Size             uint64
+	Architecture     Architecture
+	ProductName      string
+	EditionID        string
+	InstallationType string
+	ProductType      string
+	MajorVersion     uint32
+	MinorVersion     uint32
+	Build            uint32
+	SpBuild          uint32
+	Languages        []string
+	DefaultLanguage  string
+}
+
+// Version returns the image version in dotted form.
+func (i ImageInfo) Version() string {
+	return fmt.Sprintf("%d.%d.%d.%d", i.MajorVersion, i.MinorVersion, i.Build, i.SpBuild)
+}
+
+func (r *record) imageInfo() ImageInfo {
+	r.uint32() // ImageType
+	info := ImageInfo{
+		Index:        r.uint32(),
+		Name:         r.string(),
+		Description:  r.string(),
+		Size:         r.uint64(),
+		Architecture: Architecture(r.uint32()),
+		ProductName:  r.string(),
+		EditionID:    r.string(),
+	}
+	info.InstallationType = r.string()
+	r.string() // Hal
+	info.ProductType = r.string()
+	r.string() // ProductSuite
+	info.MajorVersion = r.uint32()
+	info.MinorVersion = r.uint32()
+	info.Build = r.uint32()
+	info.SpBuild = r.uint32()
+	r.uint32() // SpLevel
+	r.uint32() // Bootable
+	r.string() // SystemRoot
+	langs := r.pointer()
+	count := r.uint32()
+	def := r.uint32()
+	r.pointer() // CustomizedInfo
+
+	info.Languages = []string{}
+	lr := newRecord(langs)
+	for i := uint32(0); i < count; i++ {
+		info.Languages = append(info.Languages, lr.string())
+	}
+	if def < count {
+		info.DefaultLanguage = info.Languages[def]
+	}
+	return info
+}
+
+// GetImageInfo returns the images contained in a .wim or .vhd(x) file.
+//
+// Example: dism.GetImageInfo(`D:\sources\install.wim`)
+func GetImageInfo(imagePath string) ([]ImageInfo, error) {
+	images := []ImageInfo{}
+	ip, err := windows.UTF16PtrFromString(imagePath)
+	if err != nil {
+		return images, err
+	}
+	err = withDISM(func() error {
+		var p unsafe.Pointer
+		var count uint32
+		if err := call(func() error { return DismGetImageInfo(ip, &p, &count) }); err != nil {
+			return fmt.Errorf("DismGetImageInfo(%s): %w", imagePath, err)
+		}
+		defer DismDelete(p)
+		r := newRecord(p)
+		for i := uint32(0); i < count; i++ {
+			images = append(images, r.imageInfo())
+		}
+		return nil
+	})
+	return images, err
+}
+
+// MountStatus is the state of a mounted image.
+type MountStatus uint32
+
+// Mount states.
+const (
+	MountOK           MountStatus = 0
+	MountNeedsRemount MountStatus = 1
+	MountInvalid      MountStatus = 2
+)
+
+// MountedImageInfo describes an image mounted on the system.
+type MountedImageInfo struct {
+	MountPath string
+	ImagePath string
+	Index     uint32
+	ReadOnly  bool
+	Status    MountStatus
+}
+
+// Stale reports whether the mount needs to be remounted or cleaned up before use.
+func (m MountedImageInfo) Stale() bool {
+	return m.Status != MountOK
+}
+
+// GetMountedImageInfo returns the images currently mounted on the system.
+func GetMountedImageInfo() ([]MountedImageInfo, error) {
+	mounts := []MountedImageInfo{}
+	err := withDISM(func() error {
+		var p unsafe.Pointer
+		var count uint32
+		if err := call(func() error { return DismGetMountedImageInfo(&p, &count) }); err != nil {
+			return fmt.Errorf("DismGetMountedImageInfo: %w", err)
+		}
+		defer DismDelete(p)
+		r := newRecord(p)
+		for i := uint32(0); i < count; i++ {
+			mounts = append(mounts, MountedImageInfo{
+				MountPath: r.string(),
+				ImagePath: r.string(),
+				Index:     r.uint32(),
+				ReadOnly:  r.uint32() == mountReadOnly,
+				Status:    MountStatus(r.uint32()),
+			})
+		}
+		return nil
+	})
+	return mounts, err
+}
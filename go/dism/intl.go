@@ -0,0 +1,84 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dism
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	// ErrOnlineImage indicates an operation which is only supported on offline images.
+	ErrOnlineImage = errors.New("not supported on the online image")
+
+	intlTimeout = 30 * time.Minute
+)
+
+// IntlSettings holds the international settings of an image. Empty fields are left as they
+// are.
+//
+// https://docs.microsoft.com/en-us/windows-hardware/manufacture/desktop/dism-languages-and-international-servicing-command-line-options
+type IntlSettings struct {
+	// UILanguage is the default system UI language (eg "de-DE"). The language pack must
+	// already be installed in the image.
+	UILanguage string
+	// SystemLocale is the language for non-Unicode programs (eg "de-DE").
+	SystemLocale string
+	// UserLocale sets the standards and formats for dates, times and currency (eg "de-DE").
+	UserLocale string
+	// InputLocale sets the input method, either as a locale (eg "de-DE") or as a
+	// language:keyboard pair (eg "0407:00000407").
+	InputLocale string
+	// TimeZone is the Windows time zone name (eg "W. Europe Standard Time").
+	TimeZone string
+}
+
+func (i IntlSettings) args() []string {
+	args := []string{}
+	for _, a := range []struct{ opt, val string }{
+		{"/Set-UILang:", i.UILanguage},
+		{"/Set-SysLocale:", i.SystemLocale},
+		{"/Set-UserLocale:", i.UserLocale},
+		{"/Set-InputLocale:", i.InputLocale},
+		{"/Set-TimeZone:", i.TimeZone},
+	} {
+		if a.val != "" {
+			args = append(args, a.opt+a.val)
+		}
+	}
+	return args
+}
+
+// SetIntl applies international settings to an offline image, so regional images can be
+// produced from a single base image.
+//
+// The DISM API offers no equivalent, so this runs dism.exe against the session's image.
+//
+// Example: s.SetIntl(dism.IntlSettings{UILanguage: "de-DE", UserLocale: "de-DE", TimeZone: "W. Europe Standard Time"})
+func (s Session) SetIntl(settings IntlSettings) error {
+	if s.imagePath == DismOnlineImage {
+		return fmt.Errorf("setting international options: %w", ErrOnlineImage)
+	}
+	opts := settings.args()
+	if len(opts) == 0 {
+		return nil
+	}
+	args := append([]string{s.imageArg()}, opts...)
+	if _, err := funcExec(DismExe, args, &intlTimeout, nil); err != nil {
+		return fmt.Errorf("setting international options on %s: %w", s.imagePath, err)
+	}
+	return nil
+}
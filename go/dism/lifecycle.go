@@ -0,0 +1,85 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dism
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"syscall"
+)
+
+// DismInitialize and DismShutdown are process wide: shutting down invalidates every open
+// session. Users of the API therefore hold a reference, and DISM is shut down only once the
+// last one is released.
+
+// A repeated DismInitialize returns HRESULT_FROM_WIN32(ERROR_ALREADY_INITIALIZED).
+const errAlreadyInitialized = syscall.Errno(0x800704DF)
+
+var (
+	initMu   sync.Mutex
+	initRefs int
+	// owned is false if DISM was already initialized by someone else in the process, in
+	// which case it is theirs to shut down.
+	owned bool
+
+	// Test Helpers
+	fnInitialize = DismInitialize
+	fnShutdown   = DismShutdown
+)
+
+// acquire initializes DISM if this is the first reference.
+func acquire(logLevel LogLevel, logFile, scratchDir string) error {
+	initMu.Lock()
+	defer initMu.Unlock()
+	if initRefs == 0 {
+		lf, err := utf16Ptr(logFile)
+		if err != nil {
+			return err
+		}
+		sd, err := utf16Ptr(scratchDir)
+		if err != nil {
+			return err
+		}
+		err = fnInitialize(logLevel, lf, sd)
+		switch {
+		case err == nil:
+			owned = true
+		case errors.Is(err, errAlreadyInitialized):
+			owned = false
+		default:
+			return fmt.Errorf("DismInitialize: %w", err)
+		}
+	}
+	initRefs++
+	return nil
+}
+
+// release drops a reference, shutting DISM down when the last one is released.
+func release() error {
+	initMu.Lock()
+	defer initMu.Unlock()
+	if initRefs == 0 {
+		return nil
+	}
+	initRefs--
+	if initRefs > 0 || !owned {
+		return nil
+	}
+	if err := fnShutdown(); err != nil {
+		return fmt.Errorf("DismShutdown: %w", err)
+	}
+	return nil
+}
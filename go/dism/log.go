@@ -0,0 +1,96 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dism
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const logTimeFormat = "2006-01-02 15:04:05"
+
+var (
+	// DismLog is the default location of the DISM log.
+	DismLog = os.ExpandEnv(`${windir}\Logs\DISM\dism.log`)
+
+	logLineRe = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2}), (\w+)\s+(\w+)\s+(.*)$`)
+	hresultRe = regexp.MustCompile(`(?i)\bhr:?\s*(0x[0-9a-f]{8})\b`)
+	// The package manager logs this when a servicing operation leaves changes which can
+	// only complete after a restart, which often precedes failures on the next operation.
+	shutdownRe = regexp.MustCompile(`(?i)requesting shutdown`)
+)
+
+// LogEntry is a notable record from the DISM log.
+type LogEntry struct {
+	Time      time.Time
+	Level     string
+	Component string
+	Message   string
+	// HResult is the failure code mentioned in the message, if any (eg "0x800f081f").
+	HResult string
+	// ShutdownRequested marks entries where DISM requested a restart to complete changes.
+	ShutdownRequested bool
+}
+
+// ParseLog extracts errors, warnings and shutdown requests from a DISM log (see DismLog).
+// Continuation lines are folded into the entry they belong to. Times are as logged, in
+// local time.
+func ParseLog(path string) ([]LogEntry, error) {
+	entries := []LogEntry{}
+	f, err := os.Open(path)
+	if err != nil {
+		return entries, err
+	}
+	defer f.Close()
+
+	var cur *LogEntry
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		if m := hresultRe.FindStringSubmatch(cur.Message); m != nil {
+			cur.HResult = strings.ToLower(m[1])
+		}
+		cur.ShutdownRequested = shutdownRe.MatchString(cur.Message)
+		if cur.Level == "Error" || cur.Level == "Warning" || cur.ShutdownRequested {
+			entries = append(entries, *cur)
+		}
+		cur = nil
+	}
+
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for s.Scan() {
+		line := strings.TrimRight(s.Text(), "\r")
+		m := logLineRe.FindStringSubmatch(line)
+		if m == nil {
+			if cur != nil && strings.TrimSpace(line) != "" {
+				cur.Message += "\n" + strings.TrimSpace(line)
+			}
+			continue
+		}
+		flush()
+		t, err := time.ParseInLocation(logTimeFormat, m[1], time.Local)
+		if err != nil {
+			continue
+		}
+		cur = &LogEntry{Time: t, Level: m[2], Component: m[3], Message: strings.TrimSpace(m[4])}
+	}
+	flush()
+	return entries, s.Err()
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dism
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// ReleaseType is the kind of a package.
+type ReleaseType uint32
+
+// Release types.
+const (
+	ReleaseCriticalUpdate ReleaseType = 0
+	ReleaseDriver         ReleaseType = 1
+	ReleaseFeaturePack    ReleaseType = 2
+	ReleaseHotfix         ReleaseType = 3
+	ReleaseSecurityUpdate ReleaseType = 4
+	ReleaseSoftwareUpdate ReleaseType = 5
+	ReleaseUpdate         ReleaseType = 6
+	ReleaseUpdateRollup   ReleaseType = 7
+	ReleaseLanguagePack   ReleaseType = 8
+	ReleaseFoundation     ReleaseType = 9
+	ReleaseServicePack    ReleaseType = 10
+	ReleaseProduct        ReleaseType = 11
+	ReleaseLocalPack      ReleaseType = 12
+	ReleaseOther          ReleaseType = 13
+	ReleaseOnDemandPack   ReleaseType = 14
+)
+
+// OfflineInstallType indicates whether a package can be installed to an offline image
+// without booting it.
+type OfflineInstallType uint32
+
+// Offline install types.
+const (
+	OfflineInstallable    OfflineInstallType = 0
+	OfflineNotInstallable OfflineInstallType = 1
+	OfflineUndetermined   OfflineInstallType = 2
+)
+
+// Package is a package installed in the image.
+type Package struct {
+	Name        string
+	State       FeatureState
+	ReleaseType ReleaseType
+	InstallTime time.Time
+}
+
+// PackageInfo describes a package in detail.
+type PackageInfo struct {
+	Package
+	Applicable         bool
+	Copyright          string
+	Company            string
+	CreationTime       time.Time
+	DisplayName        string
+	Description        string
+	InstallClient      string
+	InstallPackageName string
+	LastUpdateTime     time.Time
+	ProductName        string
+	ProductVersion     string
+	RestartRequired    RestartType
+	FullyOffline       OfflineInstallType
+	SupportInformation string
+	CustomProperties   []CustomProperty
+	Features           []Feature
+}
+
+func (r *record) pkg() Package {
+	return Package{
+		Name:        r.string(),
+		State:       FeatureState(r.uint32()),
+		ReleaseType: ReleaseType(r.uint32()),
+		InstallTime: r.systemTime(),
+	}
+}
+
+// Packages returns the packages in the image.
+func (s Session) Packages() ([]Package, error) {
+	pkgs := []Package{}
+	var p unsafe.Pointer
+	var count uint32
+	if err := call(func() error { return DismGetPackages(s.Handle, &p, &count) }); err != nil {
+		return pkgs, fmt.Errorf("DismGetPackages: %w", err)
+	}
+	defer DismDelete(p)
+
+	r := newRecord(p)
+	for i := uint32(0); i < count; i++ {
+		pkgs = append(pkgs, r.pkg())
+	}
+	return pkgs, nil
+}
+
+// packageIdentifier determines whether identifier is a package name or a path to a .cab
+// file or expanded package.
+func packageIdentifier(identifier string) PackageIdentifier {
+	if strings.ContainsAny(identifier, `\/`) || strings.HasSuffix(strings.ToLower(identifier), ".cab") {
+		return PackagePath
+	}
+	return PackageName
+}
+
+// PackageInfo returns details of a package, identified either by package name or by path.
+//
+// Example: s.PackageInfo("Package_for_RollupFix~31bf3856ad364e35~amd64~~19041.1415.1.6")
+func (s Session) PackageInfo(identifier string) (*PackageInfo, error) {
+	id, err := windows.UTF16PtrFromString(identifier)
+	if err != nil {
+		return &PackageInfo{}, err
+	}
+	var p unsafe.Pointer
+	if err := call(func() error { return DismGetPackageInfo(s.Handle, id, packageIdentifier(identifier), &p) }); err != nil {
+		return &PackageInfo{}, fmt.Errorf("DismGetPackageInfo(%s): %w", identifier, err)
+	}
+	defer DismDelete(p)
+
+	r := newRecord(p)
+	info := &PackageInfo{
+		Package:            r.pkg(),
+		Applicable:         r.bool(),
+		Copyright:          r.string(),
+		Company:            r.string(),
+		CreationTime:       r.systemTime(),
+		DisplayName:        r.string(),
+		Description:        r.string(),
+		InstallClient:      r.string(),
+		InstallPackageName: r.string(),
+		LastUpdateTime:     r.systemTime(),
+		ProductName:        r.string(),
+		ProductVersion:     r.string(),
+		RestartRequired:    RestartType(r.uint32()),
+		FullyOffline:       OfflineInstallType(r.uint32()),
+		SupportInformation: r.string(),
+	}
+	props := r.pointer()
+	info.CustomProperties = customProperties(props, r.uint32())
+	feats := r.pointer()
+	info.Features = features(feats, r.uint32())
+	return info, nil
+}
+
+// HasPackage reports whether a package whose name starts with prefix is installed, eg to
+// verify that a cumulative update is present before sealing an image.
+//
+// Example: s.HasPackage("Package_for_RollupFix~31bf3856ad364e35~amd64~~19041.1415")
+func (s Session) HasPackage(prefix string) (bool, error) {
+	pkgs, err := s.Packages()
+	if err != nil {
+		return false, err
+	}
+	return hasPackage(pkgs, prefix), nil
+}
+
+func hasPackage(pkgs []Package, prefix string) bool {
+	for _, p := range pkgs {
+		if strings.HasPrefix(strings.ToLower(p.Name), strings.ToLower(prefix)) && p.State.Installed() {
+			return true
+		}
+	}
+	return false
+}
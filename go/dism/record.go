@@ -0,0 +1,82 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dism
+
+import (
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// DISM structures are declared with 1-byte packing (dismapi.h), which Go structs cannot
+// express. record decodes them field by field instead. As packed arrays have no padding
+// between elements either, a single record can walk an entire array returned by DISM.
+type record struct {
+	base unsafe.Pointer
+	off  uintptr
+}
+
+func newRecord(p unsafe.Pointer) *record {
+	return &record{base: p}
+}
+
+func (r *record) next(size uintptr) unsafe.Pointer {
+	p := unsafe.Pointer(uintptr(r.base) + r.off)
+	r.off += size
+	return p
+}
+
+func (r *record) uint16() uint16 {
+	return *(*uint16)(r.next(2))
+}
+
+func (r *record) uint32() uint32 {
+	return *(*uint32)(r.next(4))
+}
+
+func (r *record) uint64() uint64 {
+	return *(*uint64)(r.next(8))
+}
+
+// bool decodes a Win32 BOOL.
+func (r *record) bool() bool {
+	return r.uint32() != 0
+}
+
+func (r *record) pointer() unsafe.Pointer {
+	return *(*unsafe.Pointer)(r.next(unsafe.Sizeof(uintptr(0))))
+}
+
+// string decodes a PCWSTR, which may be NULL.
+func (r *record) string() string {
+	return windows.UTF16PtrToString((*uint16)(r.pointer()))
+}
+
+// systemTime decodes a SYSTEMTIME. An all zero SYSTEMTIME yields the zero time.
+func (r *record) systemTime() time.Time {
+	year := int(r.uint16())
+	month := time.Month(r.uint16())
+	r.uint16() // wDayOfWeek
+	day := int(r.uint16())
+	hour := int(r.uint16())
+	min := int(r.uint16())
+	sec := int(r.uint16())
+	ms := int(r.uint16())
+	if year == 0 {
+		return time.Time{}
+	}
+	return time.Date(year, month, day, hour, min, sec, ms*int(time.Millisecond), time.UTC)
+}
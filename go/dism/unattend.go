@@ -0,0 +1,82 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dism
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	// ErrInvalidUnattend indicates an unattend file which is missing or malformed.
+	ErrInvalidUnattend = errors.New("invalid unattend file")
+)
+
+// validateUnattend checks that path is a well-formed XML document rooted at <unattend>.
+func validateUnattend(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidUnattend, err)
+	}
+	defer f.Close()
+
+	d := xml.NewDecoder(f)
+	root := ""
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidUnattend, path, err)
+		}
+		if se, ok := tok.(xml.StartElement); ok && root == "" {
+			root = se.Name.Local
+		}
+	}
+	if root != "unattend" {
+		return fmt.Errorf("%w: %s: root element is %q, want \"unattend\"", ErrInvalidUnattend, path, root)
+	}
+	return nil
+}
+
+// ApplyUnattend validates and applies an unattend file to the image. Settings in the
+// offlineServicing pass are applied immediately.
+//
+// restart is true if a restart is required to complete the operation (online images only).
+//
+// Example: s.ApplyUnattend(`C:\unattend\offline.xml`)
+func (s Session) ApplyUnattend(path string) (restart bool, err error) {
+	if err := validateUnattend(path); err != nil {
+		return false, err
+	}
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return false, err
+	}
+	err = call(func() error { return DismApplyUnattend(s.Handle, p, false) })
+	if errors.Is(err, windows.ERROR_SUCCESS_REBOOT_REQUIRED) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("DismApplyUnattend(%s): %w", path, err)
+	}
+	return false, nil
+}
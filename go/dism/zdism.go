@@ -0,0 +1,340 @@
+// Code generated by 'go generate'; DO NOT EDIT.
+
+package dism
+
+import (
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var _ unsafe.Pointer
+
+// Do the interface allocations only once for common
+// Errno values.
+const (
+	errnoERROR_IO_PENDING = 997
+)
+
+var (
+	errERROR_IO_PENDING error = syscall.Errno(errnoERROR_IO_PENDING)
+	errERROR_EINVAL     error = syscall.EINVAL
+)
+
+// errnoErr returns common boxed Errno values, to prevent
+// allocations at runtime.
+func errnoErr(e syscall.Errno) error {
+	switch e {
+	case 0:
+		return errERROR_EINVAL
+	case errnoERROR_IO_PENDING:
+		return errERROR_IO_PENDING
+	}
+	// TODO: add more here, after collecting data on the common
+	// error values see on Windows. (perhaps when running
+	// all.bat?)
+	return e
+}
+
+var (
+	modDismAPI = windows.NewLazySystemDLL("DismAPI.dll")
+
+	procDismAddCapability       = modDismAPI.NewProc("DismAddCapability")
+	procDismAddDriver           = modDismAPI.NewProc("DismAddDriver")
+	procDismApplyUnattend       = modDismAPI.NewProc("DismApplyUnattend")
+	procDismCheckImageHealth    = modDismAPI.NewProc("DismCheckImageHealth")
+	procDismCloseSession        = modDismAPI.NewProc("DismCloseSession")
+	procDismCommitImage         = modDismAPI.NewProc("DismCommitImage")
+	procDismDelete              = modDismAPI.NewProc("DismDelete")
+	procDismDisableFeature      = modDismAPI.NewProc("DismDisableFeature")
+	procDismEnableFeature       = modDismAPI.NewProc("DismEnableFeature")
+	procDismGetCapabilities     = modDismAPI.NewProc("DismGetCapabilities")
+	procDismGetCapabilityInfo   = modDismAPI.NewProc("DismGetCapabilityInfo")
+	procDismGetDriverInfo       = modDismAPI.NewProc("DismGetDriverInfo")
+	procDismGetDrivers          = modDismAPI.NewProc("DismGetDrivers")
+	procDismGetFeatureInfo      = modDismAPI.NewProc("DismGetFeatureInfo")
+	procDismGetFeatures         = modDismAPI.NewProc("DismGetFeatures")
+	procDismGetImageInfo        = modDismAPI.NewProc("DismGetImageInfo")
+	procDismGetLastErrorMessage = modDismAPI.NewProc("DismGetLastErrorMessage")
+	procDismGetMountedImageInfo = modDismAPI.NewProc("DismGetMountedImageInfo")
+	procDismGetPackageInfo      = modDismAPI.NewProc("DismGetPackageInfo")
+	procDismGetPackages         = modDismAPI.NewProc("DismGetPackages")
+	procDismInitialize          = modDismAPI.NewProc("DismInitialize")
+	procDismMountImage          = modDismAPI.NewProc("DismMountImage")
+	procDismOpenSession         = modDismAPI.NewProc("DismOpenSession")
+	procDismRemountImage        = modDismAPI.NewProc("DismRemountImage")
+	procDismRemoveCapability    = modDismAPI.NewProc("DismRemoveCapability")
+	procDismRemoveDriver        = modDismAPI.NewProc("DismRemoveDriver")
+	procDismRestoreImageHealth  = modDismAPI.NewProc("DismRestoreImageHealth")
+	procDismShutdown            = modDismAPI.NewProc("DismShutdown")
+	procDismUnmountImage        = modDismAPI.NewProc("DismUnmountImage")
+)
+
+func DismAddCapability(session uint32, name *uint16, limitAccess bool, sourcePaths **uint16, sourcePathCount uint32, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer) (e error) {
+	var _p0 uint32
+	if limitAccess {
+		_p0 = 1
+	}
+	r0, _, _ := syscall.Syscall9(procDismAddCapability.Addr(), 8, uintptr(session), uintptr(unsafe.Pointer(name)), uintptr(_p0), uintptr(unsafe.Pointer(sourcePaths)), uintptr(sourcePathCount), uintptr(cancelEvent), uintptr(progress), uintptr(userData), 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismAddDriver(session uint32, driverPath *uint16, forceUnsigned bool) (e error) {
+	var _p0 uint32
+	if forceUnsigned {
+		_p0 = 1
+	}
+	r0, _, _ := syscall.Syscall(procDismAddDriver.Addr(), 3, uintptr(session), uintptr(unsafe.Pointer(driverPath)), uintptr(_p0))
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismApplyUnattend(session uint32, unattendFile *uint16, singleSession bool) (e error) {
+	var _p0 uint32
+	if singleSession {
+		_p0 = 1
+	}
+	r0, _, _ := syscall.Syscall(procDismApplyUnattend.Addr(), 3, uintptr(session), uintptr(unsafe.Pointer(unattendFile)), uintptr(_p0))
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismCheckImageHealth(session uint32, scanImage bool, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer, imageHealth *HealthState) (e error) {
+	var _p0 uint32
+	if scanImage {
+		_p0 = 1
+	}
+	r0, _, _ := syscall.Syscall6(procDismCheckImageHealth.Addr(), 6, uintptr(session), uintptr(_p0), uintptr(cancelEvent), uintptr(progress), uintptr(userData), uintptr(unsafe.Pointer(imageHealth)))
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismCloseSession(session uint32) (e error) {
+	r0, _, _ := syscall.Syscall(procDismCloseSession.Addr(), 1, uintptr(session), 0, 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismCommitImage(session uint32, flags uint32, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer) (e error) {
+	r0, _, _ := syscall.Syscall6(procDismCommitImage.Addr(), 5, uintptr(session), uintptr(flags), uintptr(cancelEvent), uintptr(progress), uintptr(userData), 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismDelete(structure unsafe.Pointer) (e error) {
+	r0, _, _ := syscall.Syscall(procDismDelete.Addr(), 1, uintptr(structure), 0, 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismDisableFeature(session uint32, featureName *uint16, packageName *uint16, removePayload bool, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer) (e error) {
+	var _p0 uint32
+	if removePayload {
+		_p0 = 1
+	}
+	r0, _, _ := syscall.Syscall9(procDismDisableFeature.Addr(), 7, uintptr(session), uintptr(unsafe.Pointer(featureName)), uintptr(unsafe.Pointer(packageName)), uintptr(_p0), uintptr(cancelEvent), uintptr(progress), uintptr(userData), 0, 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismEnableFeature(session uint32, featureName *uint16, identifier *uint16, packageIdentifier PackageIdentifier, limitAccess bool, sourcePaths **uint16, sourcePathCount uint32, enableAll bool, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer) (e error) {
+	var _p0 uint32
+	if limitAccess {
+		_p0 = 1
+	}
+	var _p1 uint32
+	if enableAll {
+		_p1 = 1
+	}
+	r0, _, _ := syscall.Syscall12(procDismEnableFeature.Addr(), 11, uintptr(session), uintptr(unsafe.Pointer(featureName)), uintptr(unsafe.Pointer(identifier)), uintptr(packageIdentifier), uintptr(_p0), uintptr(unsafe.Pointer(sourcePaths)), uintptr(sourcePathCount), uintptr(_p1), uintptr(cancelEvent), uintptr(progress), uintptr(userData), 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismGetCapabilities(session uint32, capability *unsafe.Pointer, count *uint32) (e error) {
+	r0, _, _ := syscall.Syscall(procDismGetCapabilities.Addr(), 3, uintptr(session), uintptr(unsafe.Pointer(capability)), uintptr(unsafe.Pointer(count)))
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismGetCapabilityInfo(session uint32, name *uint16, info *unsafe.Pointer) (e error) {
+	r0, _, _ := syscall.Syscall(procDismGetCapabilityInfo.Addr(), 3, uintptr(session), uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(info)))
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismGetDriverInfo(session uint32, driverPath *uint16, driver *unsafe.Pointer, count *uint32, driverPackage *unsafe.Pointer) (e error) {
+	r0, _, _ := syscall.Syscall6(procDismGetDriverInfo.Addr(), 5, uintptr(session), uintptr(unsafe.Pointer(driverPath)), uintptr(unsafe.Pointer(driver)), uintptr(unsafe.Pointer(count)), uintptr(unsafe.Pointer(driverPackage)), 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismGetDrivers(session uint32, allDrivers bool, driverPackage *unsafe.Pointer, count *uint32) (e error) {
+	var _p0 uint32
+	if allDrivers {
+		_p0 = 1
+	}
+	r0, _, _ := syscall.Syscall6(procDismGetDrivers.Addr(), 4, uintptr(session), uintptr(_p0), uintptr(unsafe.Pointer(driverPackage)), uintptr(unsafe.Pointer(count)), 0, 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismGetFeatureInfo(session uint32, featureName *uint16, identifier *uint16, packageIdentifier PackageIdentifier, featureInfo *unsafe.Pointer) (e error) {
+	r0, _, _ := syscall.Syscall6(procDismGetFeatureInfo.Addr(), 5, uintptr(session), uintptr(unsafe.Pointer(featureName)), uintptr(unsafe.Pointer(identifier)), uintptr(packageIdentifier), uintptr(unsafe.Pointer(featureInfo)), 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismGetFeatures(session uint32, identifier *uint16, packageIdentifier PackageIdentifier, feature *unsafe.Pointer, count *uint32) (e error) {
+	r0, _, _ := syscall.Syscall6(procDismGetFeatures.Addr(), 5, uintptr(session), uintptr(unsafe.Pointer(identifier)), uintptr(packageIdentifier), uintptr(unsafe.Pointer(feature)), uintptr(unsafe.Pointer(count)), 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismGetImageInfo(imageFilePath *uint16, imageInfo *unsafe.Pointer, count *uint32) (e error) {
+	r0, _, _ := syscall.Syscall(procDismGetImageInfo.Addr(), 3, uintptr(unsafe.Pointer(imageFilePath)), uintptr(unsafe.Pointer(imageInfo)), uintptr(unsafe.Pointer(count)))
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismGetLastErrorMessage(errorMessage *unsafe.Pointer) (e error) {
+	r0, _, _ := syscall.Syscall(procDismGetLastErrorMessage.Addr(), 1, uintptr(unsafe.Pointer(errorMessage)), 0, 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismGetMountedImageInfo(mountedImageInfo *unsafe.Pointer, count *uint32) (e error) {
+	r0, _, _ := syscall.Syscall(procDismGetMountedImageInfo.Addr(), 2, uintptr(unsafe.Pointer(mountedImageInfo)), uintptr(unsafe.Pointer(count)), 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismGetPackageInfo(session uint32, identifier *uint16, packageIdentifier PackageIdentifier, packageInfo *unsafe.Pointer) (e error) {
+	r0, _, _ := syscall.Syscall6(procDismGetPackageInfo.Addr(), 4, uintptr(session), uintptr(unsafe.Pointer(identifier)), uintptr(packageIdentifier), uintptr(unsafe.Pointer(packageInfo)), 0, 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismGetPackages(session uint32, pkg *unsafe.Pointer, count *uint32) (e error) {
+	r0, _, _ := syscall.Syscall(procDismGetPackages.Addr(), 3, uintptr(session), uintptr(unsafe.Pointer(pkg)), uintptr(unsafe.Pointer(count)))
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismInitialize(logLevel LogLevel, logFilePath *uint16, scratchDirectory *uint16) (e error) {
+	r0, _, _ := syscall.Syscall(procDismInitialize.Addr(), 3, uintptr(logLevel), uintptr(unsafe.Pointer(logFilePath)), uintptr(unsafe.Pointer(scratchDirectory)))
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismMountImage(imageFilePath *uint16, mountPath *uint16, imageIndex uint32, imageName *uint16, imageIdentifier uint32, flags uint32, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer) (e error) {
+	r0, _, _ := syscall.Syscall9(procDismMountImage.Addr(), 9, uintptr(unsafe.Pointer(imageFilePath)), uintptr(unsafe.Pointer(mountPath)), uintptr(imageIndex), uintptr(unsafe.Pointer(imageName)), uintptr(imageIdentifier), uintptr(flags), uintptr(cancelEvent), uintptr(progress), uintptr(userData))
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismOpenSession(imagePath *uint16, windowsDirectory *uint16, systemDrive *uint16, session *uint32) (e error) {
+	r0, _, _ := syscall.Syscall6(procDismOpenSession.Addr(), 4, uintptr(unsafe.Pointer(imagePath)), uintptr(unsafe.Pointer(windowsDirectory)), uintptr(unsafe.Pointer(systemDrive)), uintptr(unsafe.Pointer(session)), 0, 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismRemountImage(mountPath *uint16) (e error) {
+	r0, _, _ := syscall.Syscall(procDismRemountImage.Addr(), 1, uintptr(unsafe.Pointer(mountPath)), 0, 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismRemoveCapability(session uint32, name *uint16, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer) (e error) {
+	r0, _, _ := syscall.Syscall6(procDismRemoveCapability.Addr(), 5, uintptr(session), uintptr(unsafe.Pointer(name)), uintptr(cancelEvent), uintptr(progress), uintptr(userData), 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismRemoveDriver(session uint32, driverPath *uint16) (e error) {
+	r0, _, _ := syscall.Syscall(procDismRemoveDriver.Addr(), 2, uintptr(session), uintptr(unsafe.Pointer(driverPath)), 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismRestoreImageHealth(session uint32, sourcePaths **uint16, sourcePathCount uint32, limitAccess bool, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer) (e error) {
+	var _p0 uint32
+	if limitAccess {
+		_p0 = 1
+	}
+	r0, _, _ := syscall.Syscall9(procDismRestoreImageHealth.Addr(), 7, uintptr(session), uintptr(unsafe.Pointer(sourcePaths)), uintptr(sourcePathCount), uintptr(_p0), uintptr(cancelEvent), uintptr(progress), uintptr(userData), 0, 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismShutdown() (e error) {
+	r0, _, _ := syscall.Syscall(procDismShutdown.Addr(), 0, 0, 0, 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
+
+func DismUnmountImage(mountPath *uint16, flags uint32, cancelEvent windows.Handle, progress uintptr, userData unsafe.Pointer) (e error) {
+	r0, _, _ := syscall.Syscall6(procDismUnmountImage.Addr(), 5, uintptr(unsafe.Pointer(mountPath)), uintptr(flags), uintptr(cancelEvent), uintptr(progress), uintptr(userData), 0)
+	if r0 != 0 {
+		e = syscall.Errno(r0)
+	}
+	return
+}
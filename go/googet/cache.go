@@ -0,0 +1,113 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googet
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/logger"
+)
+
+// CacheEntry describes a package file, or an extracted package directory, in the googet
+// cache.
+type CacheEntry struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	IsDir   bool
+}
+
+// cacheDir returns the directory googet caches packages in.
+func cacheDir(conf *Config) string {
+	if conf == nil {
+		conf = NewConfig()
+	}
+	return filepath.Join(filepath.Dir(conf.GooGetExe), "cache")
+}
+
+// dirSize returns the total size of the files under dir.
+func dirSize(dir string) (int64, error) {
+	var size int64
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+// CacheContents lists the entries in the googet cache, with their sizes.
+func CacheContents(conf *Config) ([]CacheEntry, error) {
+	dir := cacheDir(conf)
+	fis, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []CacheEntry{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries := []CacheEntry{}
+	for _, fi := range fis {
+		e := CacheEntry{
+			Path:    filepath.Join(dir, fi.Name()),
+			Size:    fi.Size(),
+			ModTime: fi.ModTime(),
+			IsDir:   fi.IsDir(),
+		}
+		if e.IsDir {
+			if e.Size, err = dirSize(e.Path); err != nil {
+				return nil, fmt.Errorf("sizing %s: %w", e.Path, err)
+			}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// CleanOlderThan removes cache entries last modified more than age ago, and returns the
+// number of bytes freed. Unlike Clean, recently cached packages are kept.
+func CleanOlderThan(age time.Duration, conf *Config) (int64, error) {
+	entries, err := CacheContents(conf)
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-age)
+	var freed int64
+	failed := []string{}
+	for _, e := range entries {
+		if !e.ModTime.Before(cutoff) {
+			continue
+		}
+		if err := os.RemoveAll(e.Path); err != nil {
+			failed = append(failed, err.Error())
+			continue
+		}
+		logger.V(1).Infof("Removed cached %s (%d bytes).", e.Path, e.Size)
+		freed += e.Size
+	}
+	if len(failed) > 0 {
+		return freed, fmt.Errorf("cleaning cache: %s", strings.Join(failed, "; "))
+	}
+	return freed, nil
+}
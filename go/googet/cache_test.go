@@ -0,0 +1,93 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googet
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCache(t *testing.T) {
+	root := t.TempDir()
+	conf := &Config{GooGetExe: filepath.Join(root, "googet.exe")}
+	cache := filepath.Join(root, "cache")
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	files := []struct {
+		path string
+		size int
+		old  bool
+	}{
+		{"foo.x86_64.1.0.0@1.goo", 100, true},
+		{"foo.x86_64.2.0.0@1.goo", 200, false},
+		{filepath.Join("foo.x86_64.1.0.0@1", "foo.exe"), 50, true},
+		{filepath.Join("foo.x86_64.1.0.0@1", "foo.dll"), 25, true},
+	}
+	for _, f := range files {
+		p := filepath.Join(cache, f.path)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, make([]byte, f.size), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if f.old {
+			if err := os.Chtimes(p, old, old); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := os.Chtimes(filepath.Join(cache, "foo.x86_64.1.0.0@1"), old, old); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := CacheContents(conf)
+	if err != nil {
+		t.Fatalf("CacheContents() returned %v", err)
+	}
+	sizes := map[string]int64{}
+	for _, e := range entries {
+		sizes[filepath.Base(e.Path)] = e.Size
+	}
+	want := map[string]int64{"foo.x86_64.1.0.0@1.goo": 100, "foo.x86_64.2.0.0@1.goo": 200, "foo.x86_64.1.0.0@1": 75}
+	if diff := cmp.Diff(want, sizes); diff != "" {
+		t.Errorf("CacheContents() produced unexpected differences (-want +got): %s", diff)
+	}
+
+	freed, err := CleanOlderThan(7*24*time.Hour, conf)
+	if err != nil {
+		t.Fatalf("CleanOlderThan() returned %v", err)
+	}
+	if freed != 175 {
+		t.Errorf("CleanOlderThan() freed %d bytes, want 175", freed)
+	}
+	left, _ := filepath.Glob(filepath.Join(cache, "*"))
+	sort.Strings(left)
+	if diff := cmp.Diff([]string{filepath.Join(cache, "foo.x86_64.2.0.0@1.goo")}, left); diff != "" {
+		t.Errorf("CleanOlderThan() left unexpected entries (-want +got): %s", diff)
+	}
+}
+
+func TestCacheContentsMissing(t *testing.T) {
+	conf := &Config{GooGetExe: filepath.Join(t.TempDir(), "googet.exe")}
+	entries, err := CacheContents(conf)
+	if err != nil || len(entries) != 0 {
+		t.Errorf("CacheContents() = %v, %v, want an empty cache", entries, err)
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googet
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+var (
+	// ErrDependents indicates that a package could not be removed because others depend on it.
+	ErrDependents = errors.New("package has dependents")
+)
+
+// DependencyError lists the installed packages that depend on a package being removed.
+type DependencyError struct {
+	Package    string
+	Dependents []string
+}
+
+func (e *DependencyError) Error() string {
+	return fmt.Sprintf("%v: %s is required by %s", ErrDependents, e.Package, strings.Join(e.Dependents, ", "))
+}
+
+// Is reports whether target is ErrDependents.
+func (e *DependencyError) Is(target error) bool {
+	return target == ErrDependents
+}
+
+// baseName strips any architecture suffix from a package name.
+func baseName(pkg string) string {
+	if m := archRe.FindStringSubmatch(pkg); m != nil {
+		return m[1]
+	}
+	return pkg
+}
+
+// Dependents returns the names of the installed packages that depend on pkg, directly or
+// indirectly, sorted by name.
+func Dependents(pkg string, conf *Config) ([]string, error) {
+	res, err := run([]string{"installed", "-info"}, conf)
+	if err != nil {
+		return nil, err
+	}
+	rdeps := map[string][]string{}
+	for _, info := range parseInfos(res.Stdout) {
+		for dep := range info.Dependencies {
+			d := strings.ToLower(baseName(dep))
+			rdeps[d] = append(rdeps[d], info.Name)
+		}
+	}
+	root := strings.ToLower(baseName(pkg))
+	seen := map[string]bool{root: true}
+	deps := []string{}
+	queue := []string{root}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, d := range rdeps[cur] {
+			if seen[strings.ToLower(d)] {
+				continue
+			}
+			seen[strings.ToLower(d)] = true
+			deps = append(deps, d)
+			queue = append(queue, strings.ToLower(d))
+		}
+	}
+	sort.Strings(deps)
+	return deps, nil
+}
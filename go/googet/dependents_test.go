@@ -0,0 +1,85 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googet
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/glazier/go/helpers"
+	"github.com/google/go-cmp/cmp"
+)
+
+const installedInfo = `Installed packages:
+
+  Name:          base.x86_64
+  Version:       1.0.0@1
+
+  Name:          lib.noarch
+  Version:       1.0.0@1
+  Dependencies:  base.x86_64 - 1.0.0@1
+
+  Name:          app.x86_64
+  Version:       1.0.0@1
+  Dependencies:  lib - 1.0.0@1
+                 other
+
+  Name:          tool.x86_64
+  Version:       1.0.0@1
+  Dependencies:  base
+`
+
+func TestRemove(t *testing.T) {
+	tests := []struct {
+		pkg              string
+		dbOnly           bool
+		removeDependents bool
+		wantCalls        []string
+		wantDependents   []string
+	}{
+		{"app", false, false, []string{"installed -info", "-noconfirm remove app"}, nil},
+		{"lib.noarch", true, false, []string{"installed -info"}, []string{"app"}},
+		{"base", false, false, []string{"installed -info"}, []string{"app", "lib", "tool"}},
+		{"base", false, true, []string{"-noconfirm remove base"}, nil},
+		{"lib", true, true, []string{"-noconfirm remove -db_only lib"}, nil},
+	}
+	for _, tt := range tests {
+		calls := []string{}
+		funcExec = func(path string, args []string, timeout *time.Duration, v *helpers.ExecVerifier) (helpers.ExecResult, error) {
+			calls = append(calls, strings.Join(args, " "))
+			return helpers.ExecResult{Stdout: []byte(installedInfo)}, nil
+		}
+		err := Remove(tt.pkg, tt.dbOnly, tt.removeDependents, nil)
+		if diff := cmp.Diff(tt.wantCalls, calls); diff != "" {
+			t.Errorf("Remove(%s) produced unexpected calls (-want +got): %s", tt.pkg, diff)
+		}
+		if tt.wantDependents == nil {
+			if err != nil {
+				t.Errorf("Remove(%s) returned unexpected error %v", tt.pkg, err)
+			}
+			continue
+		}
+		var de *DependencyError
+		if !errors.As(err, &de) || !errors.Is(err, ErrDependents) {
+			t.Errorf("Remove(%s) returned %v, want a DependencyError", tt.pkg, err)
+			continue
+		}
+		if diff := cmp.Diff(tt.wantDependents, de.Dependents); diff != "" {
+			t.Errorf("Remove(%s) reported unexpected dependents (-want +got): %s", tt.pkg, diff)
+		}
+	}
+}
@@ -19,7 +19,9 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/google/glazier/go/helpers"
@@ -27,14 +29,24 @@ import (
 
 var (
 
+	// ErrInvalidFile indicates a package file that cannot be installed.
+	ErrInvalidFile = errors.New("invalid package file")
+
 	// Test Helpers
-	funcExec = helpers.ExecWithVerify
+	funcExec     = helpers.ExecWithVerify
+	funcExecConf = helpers.Exec
+	funcVerify   = helpers.VerifyFile
 )
 
 // Config provides the ability to customize GooGet behavior.
 type Config struct {
 	GooGetExe string
 	Timeout   time.Duration
+
+	// Progress, if set, receives progress updates parsed from googet output as commands
+	// run, eg to display status during large installs. Updates are dropped rather than
+	// stalling googet if the channel is not ready to receive them.
+	Progress chan<- Progress
 }
 
 // NewConfig generates a new Config object.
@@ -46,15 +58,31 @@ func NewConfig() *Config {
 }
 
 func call(args []string, conf *Config) error {
+	_, err := run(args, conf)
+	return err
+}
+
+// run executes googet and returns its output.
+func run(args []string, conf *Config) (helpers.ExecResult, error) {
 	if conf == nil {
 		conf = NewConfig()
 	}
 
-	_, err := funcExec(conf.GooGetExe, args, &conf.Timeout, nil)
+	var res helpers.ExecResult
+	var err error
+	if conf.Progress != nil {
+		res, err = funcExecConf(conf.GooGetExe, args, &helpers.ExecConfig{
+			Verifier:     helpers.NewExecVerifier(),
+			Timeout:      &conf.Timeout,
+			OnStdoutLine: newProgressParser(conf.Progress),
+		})
+	} else {
+		res, err = funcExec(conf.GooGetExe, args, &conf.Timeout, nil)
+	}
 	if err != nil && errors.Is(err, helpers.ErrTimeout) {
-		return fmt.Errorf("execution timed out after %v", conf.Timeout)
+		return res, fmt.Errorf("execution timed out after %v", conf.Timeout)
 	}
-	return err
+	return res, err
 }
 
 // Install installs a Googet package.
@@ -75,6 +103,27 @@ func Install(pkg, sources string, reinstall bool, conf *Config) error {
 	return call(cmd, conf)
 }
 
+// InstallFile installs a Googet package from a local .goo file, eg where no repository is
+// reachable. If sha256 is non-empty, the file must match it before it is installed.
+func InstallFile(path, sha256 string, conf *Config) error {
+	if !strings.EqualFold(filepath.Ext(path), ".goo") {
+		return fmt.Errorf("%w: %s is not a .goo file", ErrInvalidFile, path)
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	if !fi.Mode().IsRegular() {
+		return fmt.Errorf("%w: %s is not a regular file", ErrInvalidFile, path)
+	}
+	if sha256 != "" {
+		if err := funcVerify(path, sha256); err != nil {
+			return err
+		}
+	}
+	return call([]string{"-noconfirm", "install", path}, conf)
+}
+
 // PackageVersion attempts to retrieve the current version
 // of the named package from the local system.
 func PackageVersion(pkg string) (string, error) {
@@ -93,10 +142,23 @@ func PackageVersion(pkg string) (string, error) {
 }
 
 // Remove removes a Googet package.
-func Remove(pkg string, dbOnly bool, conf *Config) error {
+//
+// googet removes any packages depending on pkg along with it. Unless removeDependents is
+// set, Remove instead refuses to remove a package with dependents, returning a
+// *DependencyError that lists them.
+func Remove(pkg string, dbOnly, removeDependents bool, conf *Config) error {
 	if conf == nil {
 		conf = NewConfig()
 	}
+	if !removeDependents {
+		deps, err := Dependents(pkg, conf)
+		if err != nil {
+			return fmt.Errorf("listing dependents of %s: %w", pkg, err)
+		}
+		if len(deps) > 0 {
+			return &DependencyError{Package: pkg, Dependents: deps}
+		}
+	}
 
 	args := []string{"-noconfirm", "remove"}
 	if dbOnly {
@@ -107,6 +169,11 @@ func Remove(pkg string, dbOnly bool, conf *Config) error {
 	return call(args, conf)
 }
 
+// Clean removes all cached package files.
+func Clean(conf *Config) error {
+	return call([]string{"-noconfirm", "clean"}, conf)
+}
+
 // Update updates all googet packages.
 func Update(conf *Config) error {
 	if conf == nil {
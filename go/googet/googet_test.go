@@ -16,6 +16,8 @@ package googet
 
 import (
 	"errors"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -62,6 +64,48 @@ func TestInstall(t *testing.T) {
 	}
 }
 
+func TestInstallFile(t *testing.T) {
+	dir := t.TempDir()
+	pkg := filepath.Join(dir, "pkg-one.x86_64.1.0.0@1.goo")
+	if err := os.WriteFile(pkg, []byte("package"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path      string
+		sha256    string
+		verifyErr error
+		wantArg   []string
+		wantErr   error
+	}{
+		{pkg, "", nil, []string{"-noconfirm", "install", pkg}, nil},
+		{pkg, "abc123", nil, []string{"-noconfirm", "install", pkg}, nil},
+		{pkg, "abc123", helpers.ErrHashMismatch, nil, helpers.ErrHashMismatch},
+		{filepath.Join(dir, "missing.goo"), "", nil, nil, ErrInvalidFile},
+		{dir, "", nil, nil, ErrInvalidFile},
+		{filepath.Join(dir, "pkg-one.zip"), "", nil, nil, ErrInvalidFile},
+	}
+	for _, tt := range tests {
+		var a []string
+		funcExec = func(path string, args []string, timeout *time.Duration, v *helpers.ExecVerifier) (helpers.ExecResult, error) {
+			a = args
+			return helpers.ExecResult{}, nil
+		}
+		funcVerify = func(path, expected string) error {
+			if expected != tt.sha256 {
+				t.Errorf("InstallFile(%s) verified against %q, want %q", tt.path, expected, tt.sha256)
+			}
+			return tt.verifyErr
+		}
+		err := InstallFile(tt.path, tt.sha256, nil)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("InstallFile(%s) returned unexpected error %v", tt.path, err)
+		}
+		if diff := cmp.Diff(tt.wantArg, a); diff != "" {
+			t.Errorf("InstallFile(%s) produced unexpected differences (-want +got): %s", tt.path, diff)
+		}
+	}
+}
+
 func TestPackageVersion(t *testing.T) {
 	fail := errors.New("test failure")
 	tests := []struct {
@@ -0,0 +1,144 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googet
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	infoKeyRe = regexp.MustCompile(`^\s*(Name|Arch|Version|Repo|Authors|Owners|Description|Install Date|Installed|Dependencies|Files):\s*(.*)$`)
+	// depRe matches dependencies in either the "name - version" or "name version" form.
+	depRe = regexp.MustCompile(`^(\S+)(?:\s+-?\s*(\S+))?$`)
+
+	installDateLayouts = []string{
+		"2006-01-02 15:04:05.999999999 -0700 MST",
+		time.RFC3339,
+		time.RFC1123Z,
+		time.RFC1123,
+		time.UnixDate,
+		time.ANSIC,
+	}
+)
+
+// PackageInfo describes an installed googet package in detail.
+type PackageInfo struct {
+	Package
+	Description string
+	Authors     string
+	Owners      string
+	// InstallDate is the zero time if googet did not report a recognizable date.
+	InstallDate time.Time
+	// Dependencies maps package names to the version required, if any.
+	Dependencies map[string]string
+	Files        []string
+}
+
+func parseInstallDate(s string) time.Time {
+	for _, l := range installDateLayouts {
+		if t, err := time.Parse(l, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func (i *PackageInfo) addListItem(key, item string) {
+	item = strings.TrimSpace(item)
+	if item == "" || item == "None" {
+		return
+	}
+	switch key {
+	case "Dependencies":
+		if m := depRe.FindStringSubmatch(item); m != nil {
+			i.Dependencies[m[1]] = m[2]
+		}
+	case "Files":
+		i.Files = append(i.Files, item)
+	}
+}
+
+// parseInfos parses the packages in googet installed -info output.
+func parseInfos(out []byte) []PackageInfo {
+	infos := []PackageInfo{}
+	var info *PackageInfo
+	key := ""
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		m := infoKeyRe.FindStringSubmatch(s.Text())
+		if m != nil && m[1] == "Name" {
+			infos = append(infos, PackageInfo{Dependencies: map[string]string{}, Files: []string{}})
+			info = &infos[len(infos)-1]
+		}
+		if info == nil {
+			continue
+		}
+		if m == nil {
+			// Continuation lines belong to the preceding list.
+			info.addListItem(key, s.Text())
+			continue
+		}
+		key = m[1]
+		val := strings.TrimSpace(m[2])
+		switch key {
+		case "Name":
+			info.Name = val
+			if a := archRe.FindStringSubmatch(val); a != nil {
+				info.Name, info.Arch = a[1], a[2]
+			}
+		case "Arch":
+			info.Arch = val
+		case "Version":
+			info.Version = val
+		case "Repo":
+			info.Repo = val
+		case "Authors":
+			info.Authors = val
+		case "Owners":
+			info.Owners = val
+		case "Description":
+			info.Description = val
+		case "Install Date", "Installed":
+			info.InstallDate = parseInstallDate(val)
+		default:
+			info.addListItem(key, val)
+		}
+	}
+	return infos
+}
+
+// Info returns detailed information about the installed package pkg.
+func Info(pkg string, conf *Config) (PackageInfo, error) {
+	res, err := run([]string{"installed", "-info", pkg}, conf)
+	if err != nil {
+		return PackageInfo{}, err
+	}
+	// googet matches pkg as a prefix, so the output may list other packages as well.
+	name, arch := pkg, ""
+	if m := archRe.FindStringSubmatch(pkg); m != nil {
+		name, arch = m[1], m[2]
+	}
+	for _, info := range parseInfos(res.Stdout) {
+		if info.Name == name && (arch == "" || info.Arch == arch) {
+			return info, nil
+		}
+	}
+	return PackageInfo{}, fmt.Errorf("%w: %q is not installed", ErrNotFound, pkg)
+}
@@ -0,0 +1,124 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/glazier/go/helpers"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestInfo(t *testing.T) {
+	fail := errors.New("test failure")
+	tests := []struct {
+		desc    string
+		out     string
+		inErr   error
+		want    PackageInfo
+		wantErr error
+	}{
+		{
+			desc: "full",
+			out: `Installed packages matching "foo":
+
+  Name:          foo.x86_64
+  Version:       2.6.0-20191015@281551337
+  Repo:          stable
+  Authors:       foo-team@example.com
+  Owners:        foo-team
+  Description:   Foo agent
+  Install Date:  2021-06-01 12:30:00 +0000 UTC
+  Dependencies:  bar.x86_64 - 1.0.0@1
+                 baz
+  Files:
+    C:\Program Files\Foo\foo.exe
+    C:\Program Files\Foo\foo.dll
+`,
+			want: PackageInfo{
+				Package:      Package{Name: "foo", Arch: "x86_64", Version: "2.6.0-20191015@281551337", Repo: "stable"},
+				Description:  "Foo agent",
+				Authors:      "foo-team@example.com",
+				Owners:       "foo-team",
+				InstallDate:  time.Date(2021, 6, 1, 12, 30, 0, 0, time.UTC),
+				Dependencies: map[string]string{"bar.x86_64": "1.0.0@1", "baz": ""},
+				Files:        []string{`C:\Program Files\Foo\foo.exe`, `C:\Program Files\Foo\foo.dll`},
+			},
+		},
+		{
+			desc: "minimal",
+			out: `Installed packages matching "foo":
+
+  Name:          foo-tools
+  Arch:          noarch
+
+  Name:          foo
+  Arch:          noarch
+  Version:       1.0.0@1
+  Dependencies:  None
+  Installed:     not a date
+`,
+			want: PackageInfo{
+				Package:      Package{Name: "foo", Arch: "noarch", Version: "1.0.0@1"},
+				Dependencies: map[string]string{},
+				Files:        []string{},
+			},
+		},
+		{
+			desc: "other package only",
+			out: `Installed packages matching "foo":
+
+  Name:          foo-tools
+  Arch:          noarch
+  Version:       1.0.0@1
+`,
+			want:    PackageInfo{},
+			wantErr: ErrNotFound,
+		},
+		{
+			desc:    "not installed",
+			out:     "No package matching filter \"foo\" installed.\n",
+			want:    PackageInfo{},
+			wantErr: ErrNotFound,
+		},
+		{
+			desc:    "failure",
+			inErr:   fail,
+			want:    PackageInfo{},
+			wantErr: fail,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			a := []string{}
+			funcExec = func(path string, args []string, timeout *time.Duration, v *helpers.ExecVerifier) (helpers.ExecResult, error) {
+				a = args
+				return helpers.ExecResult{Stdout: []byte(tt.out)}, tt.inErr
+			}
+			got, err := Info("foo", nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Info(foo) returned unexpected error %v", err)
+			}
+			if diff := cmp.Diff([]string{"installed", "-info", "foo"}, a); diff != "" {
+				t.Errorf("Info(foo) produced unexpected arguments (-want +got): %s", diff)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Info(foo) produced unexpected differences (-want +got): %s", diff)
+			}
+		})
+	}
+}
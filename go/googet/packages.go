@@ -0,0 +1,99 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googet
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrNotFound indicates that no package matched the request.
+	ErrNotFound = errors.New("package not found")
+
+	// packageRe matches package listings, in either the "name.arch version" or the
+	// "name.arch.version" form.
+	packageRe = regexp.MustCompile(`^\s+(\S+?)\.(noarch|x86_32|x86_64|arm64)[.\s]\s*(\S+)\s*$`)
+	repoRe    = regexp.MustCompile(`^\s*\w+://\S+\s*$`)
+	archRe    = regexp.MustCompile(`^(.+)\.(noarch|x86_32|x86_64|arm64)$`)
+)
+
+// Package describes a googet package.
+type Package struct {
+	Name    string
+	Arch    string
+	Version string
+	// Repo is the repository the package is available from, where known.
+	Repo string
+}
+
+// String returns the package in googet's name.arch.version notation.
+func (p Package) String() string {
+	return fmt.Sprintf("%s.%s.%s", p.Name, p.Arch, p.Version)
+}
+
+// parsePackages parses a googet package listing. Repository URLs in the listing apply to
+// the packages following them.
+func parsePackages(out []byte) []Package {
+	pkgs := []Package{}
+	repo := ""
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		line := s.Text()
+		if repoRe.MatchString(line) {
+			repo = strings.TrimSpace(line)
+			continue
+		}
+		if m := packageRe.FindStringSubmatch(line); m != nil {
+			pkgs = append(pkgs, Package{Name: m[1], Arch: m[2], Version: m[3], Repo: repo})
+		}
+	}
+	return pkgs
+}
+
+// Available lists the package versions available from the configured repositories. If
+// filter is non-empty, only packages matching it are listed.
+func Available(filter string, conf *Config) ([]Package, error) {
+	args := []string{"available"}
+	if filter != "" {
+		args = append(args, "-filter", filter)
+	}
+	res, err := run(args, conf)
+	if err != nil {
+		return nil, err
+	}
+	return parsePackages(res.Stdout), nil
+}
+
+// Latest returns the latest version of pkg available from the configured repositories.
+func Latest(pkg string, conf *Config) (Package, error) {
+	p := Package{Name: pkg}
+	if m := archRe.FindStringSubmatch(pkg); m != nil {
+		p.Name, p.Arch = m[1], m[2]
+	}
+	res, err := run([]string{"latest", pkg}, conf)
+	if err != nil {
+		return p, err
+	}
+	p.Version = strings.TrimSpace(string(res.Stdout))
+	if p.Version == "" || strings.ContainsAny(p.Version, " \t\r\n") {
+		return p, fmt.Errorf("%w: %q: %q", ErrNotFound, pkg, p.Version)
+	}
+	return p, nil
+}
@@ -0,0 +1,100 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/glazier/go/helpers"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAvailable(t *testing.T) {
+	tests := []struct {
+		filter  string
+		out     string
+		wantArg []string
+		want    []Package
+	}{
+		{
+			filter: "foo",
+			out: `Searching for available packages in repo(s):
+https://repo.example.com/googet/stable
+  foo.x86_64.2.6.0-20191015@281551337
+  foo.x86_64.2.5.0@1
+https://repo.example.com/googet/beta
+  foo-tools.noarch 20210203@355474486
+`,
+			wantArg: []string{"available", "-filter", "foo"},
+			want: []Package{
+				{Name: "foo", Arch: "x86_64", Version: "2.6.0-20191015@281551337", Repo: "https://repo.example.com/googet/stable"},
+				{Name: "foo", Arch: "x86_64", Version: "2.5.0@1", Repo: "https://repo.example.com/googet/stable"},
+				{Name: "foo-tools", Arch: "noarch", Version: "20210203@355474486", Repo: "https://repo.example.com/googet/beta"},
+			},
+		},
+		{
+			out:     "Searching for available packages in repo(s):\n",
+			wantArg: []string{"available"},
+			want:    []Package{},
+		},
+	}
+	for _, tt := range tests {
+		a := []string{}
+		funcExec = func(path string, args []string, timeout *time.Duration, v *helpers.ExecVerifier) (helpers.ExecResult, error) {
+			a = args
+			return helpers.ExecResult{Stdout: []byte(tt.out)}, nil
+		}
+		got, err := Available(tt.filter, nil)
+		if err != nil {
+			t.Errorf("Available(%q) returned unexpected error %v", tt.filter, err)
+		}
+		if diff := cmp.Diff(tt.wantArg, a); diff != "" {
+			t.Errorf("Available(%q) produced unexpected arguments (-want +got): %s", tt.filter, diff)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("Available(%q) produced unexpected differences (-want +got): %s", tt.filter, diff)
+		}
+	}
+}
+
+func TestLatest(t *testing.T) {
+	fail := errors.New("test failure")
+	tests := []struct {
+		pkg     string
+		out     string
+		inErr   error
+		want    Package
+		wantErr error
+	}{
+		{"foo", "2.6.0-20191015@281551337\n", nil, Package{Name: "foo", Version: "2.6.0-20191015@281551337"}, nil},
+		{"foo.x86_64", "2.6.0@1\r\n", nil, Package{Name: "foo", Arch: "x86_64", Version: "2.6.0@1"}, nil},
+		{"missing", "", nil, Package{Name: "missing"}, ErrNotFound},
+		{"foo", "", fail, Package{Name: "foo"}, fail},
+	}
+	for _, tt := range tests {
+		funcExec = func(path string, args []string, timeout *time.Duration, v *helpers.ExecVerifier) (helpers.ExecResult, error) {
+			return helpers.ExecResult{Stdout: []byte(tt.out)}, tt.inErr
+		}
+		got, err := Latest(tt.pkg, nil)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Latest(%s) returned unexpected error %v", tt.pkg, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("Latest(%s) produced unexpected differences (-want +got): %s", tt.pkg, diff)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googet
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	stageRe   = regexp.MustCompile(`^\s*(Downloading|Installing|Removing|Updating|Extracting|Verifying)\s+(\S+)`)
+	percentRe = regexp.MustCompile(`(\d{1,3})(?:\.\d+)?%`)
+)
+
+// Progress describes the state of a running googet command.
+type Progress struct {
+	// Stage is the current activity, eg "Downloading" or "Installing".
+	Stage string
+	// Package is the package being worked on, as googet names it.
+	Package string
+	// Percent is the completion of the current stage, or -1 if googet has not reported it.
+	Percent int
+	// Line is the line of googet output the update was parsed from.
+	Line string
+}
+
+// newProgressParser returns a line callback that sends progress updates parsed from googet
+// output to ch.
+func newProgressParser(ch chan<- Progress) func(string) {
+	cur := Progress{Percent: -1}
+	return func(line string) {
+		if strings.TrimSpace(line) == "" {
+			return
+		}
+		if m := stageRe.FindStringSubmatch(line); m != nil {
+			cur.Stage = m[1]
+			cur.Package = strings.TrimRight(m[2], ".:,")
+			cur.Percent = -1
+		}
+		if m := percentRe.FindStringSubmatch(line); m != nil {
+			if p, err := strconv.Atoi(m[1]); err == nil && p <= 100 {
+				cur.Percent = p
+			}
+		}
+		cur.Line = line
+		select {
+		case ch <- cur:
+		default:
+		}
+	}
+}
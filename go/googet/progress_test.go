@@ -0,0 +1,85 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/glazier/go/helpers"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestInstallProgress(t *testing.T) {
+	out := []string{
+		"Installing foo and dependencies...",
+		"Downloading foo.x86_64.1.0.0@1 from https://repo.example.com/googet",
+		"  42% complete",
+		"",
+		"  100% complete",
+		"Installing foo.x86_64.1.0.0@1...",
+		"Installation of foo.x86_64.1.0.0@1 and all dependencies completed",
+	}
+	ch := make(chan Progress, len(out))
+	conf := NewConfig()
+	conf.Progress = ch
+	funcExec = func(path string, args []string, timeout *time.Duration, v *helpers.ExecVerifier) (helpers.ExecResult, error) {
+		t.Errorf("Install() did not stream output")
+		return helpers.ExecResult{}, nil
+	}
+	funcExecConf = func(path string, args []string, c *helpers.ExecConfig) (helpers.ExecResult, error) {
+		if c.Verifier == nil || c.Timeout == nil || *c.Timeout != conf.Timeout {
+			t.Errorf("Install() passed unexpected execution config %+v", c)
+		}
+		for _, l := range out {
+			c.OnStdoutLine(l)
+		}
+		return helpers.ExecResult{}, nil
+	}
+	if err := Install("foo", "", false, conf); err != nil {
+		t.Fatalf("Install(foo) returned %v", err)
+	}
+	close(ch)
+	got := []Progress{}
+	for p := range ch {
+		got = append(got, p)
+	}
+	want := []Progress{
+		{"Installing", "foo", -1, out[0]},
+		{"Downloading", "foo.x86_64.1.0.0@1", -1, out[1]},
+		{"Downloading", "foo.x86_64.1.0.0@1", 42, out[2]},
+		{"Downloading", "foo.x86_64.1.0.0@1", 100, out[4]},
+		{"Installing", "foo.x86_64.1.0.0@1", -1, out[5]},
+		{"Installing", "foo.x86_64.1.0.0@1", -1, out[6]},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Install(foo) produced unexpected progress (-want +got): %s", diff)
+	}
+}
+
+func TestProgressDropsWhenBlocked(t *testing.T) {
+	ch := make(chan Progress)
+	parse := newProgressParser(ch)
+	done := make(chan struct{})
+	go func() {
+		parse("Downloading foo")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("progress parser blocked on an unready channel")
+	}
+}
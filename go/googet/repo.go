@@ -0,0 +1,269 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googet
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrRepoExists indicates that a repository of the same name is already configured.
+	ErrRepoExists = errors.New("repository already exists")
+	// ErrInvalidRepo indicates a repository definition that cannot be used.
+	ErrInvalidRepo = errors.New("invalid repository")
+
+	// namedPriorities holds googet's named priority levels.
+	namedPriorities = map[string]int{
+		"default":  500,
+		"canary":   1300,
+		"pin":      1400,
+		"rollback": 1500,
+	}
+)
+
+// Repo describes a googet repository.
+type Repo struct {
+	Name string
+	URL  string
+	// UseOAuth authenticates requests to the repository with the machine's OAuth credentials.
+	UseOAuth bool
+	// Priority orders repositories offering the same package; higher priorities win. Zero
+	// leaves the googet default.
+	Priority int
+}
+
+func (r Repo) validate() error {
+	if r.Name == "" || strings.ContainsAny(r.Name, `\/:*?"<>|`) {
+		return fmt.Errorf("%w: invalid name %q", ErrInvalidRepo, r.Name)
+	}
+	if r.URL == "" {
+		return fmt.Errorf("%w: %s has no URL", ErrInvalidRepo, r.Name)
+	}
+	if r.Priority < 0 {
+		return fmt.Errorf("%w: %s has negative priority", ErrInvalidRepo, r.Name)
+	}
+	return nil
+}
+
+// repoDir returns the directory googet reads repository files from.
+func repoDir(conf *Config) string {
+	if conf == nil {
+		conf = NewConfig()
+	}
+	return filepath.Join(filepath.Dir(conf.GooGetExe), "repos")
+}
+
+func unquote(s string) string {
+	if strings.HasPrefix(s, `"`) {
+		if u, err := strconv.Unquote(s); err == nil {
+			return u
+		}
+	}
+	if len(s) > 1 && strings.HasPrefix(s, "'") && strings.HasSuffix(s, "'") {
+		return strings.ReplaceAll(s[1:len(s)-1], "''", "'")
+	}
+	return s
+}
+
+// parseRepoFile parses the subset of YAML used by googet repository files: a list of
+// entries, or a single entry, of scalar values.
+func parseRepoFile(b []byte) ([]Repo, error) {
+	repos := []Repo{}
+	var cur *Repo
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") || line == "---" {
+			continue
+		}
+		if strings.HasPrefix(line, "- ") || line == "-" {
+			repos = append(repos, Repo{})
+			cur = &repos[len(repos)-1]
+			line = strings.TrimSpace(strings.TrimPrefix(line, "-"))
+			if line == "" {
+				continue
+			}
+		}
+		i := strings.Index(line, ":")
+		if i < 0 {
+			return nil, fmt.Errorf("line %d: expected key: value, got %q", n, line)
+		}
+		if cur == nil {
+			repos = append(repos, Repo{})
+			cur = &repos[len(repos)-1]
+		}
+		val := unquote(strings.TrimSpace(line[i+1:]))
+		switch strings.ToLower(strings.TrimSpace(line[:i])) {
+		case "name":
+			cur.Name = val
+		case "url":
+			cur.URL = val
+		case "useoauth":
+			cur.UseOAuth = strings.EqualFold(val, "true")
+		case "priority":
+			p, ok := namedPriorities[strings.ToLower(val)]
+			if !ok {
+				var err error
+				if p, err = strconv.Atoi(val); err != nil {
+					return nil, fmt.Errorf("line %d: invalid priority %q", n, val)
+				}
+			}
+			cur.Priority = p
+		}
+	}
+	return repos, s.Err()
+}
+
+func formatRepoFile(repos []Repo) []byte {
+	var b bytes.Buffer
+	for _, r := range repos {
+		fmt.Fprintf(&b, "- name: %q\n", r.Name)
+		fmt.Fprintf(&b, "  url: %q\n", r.URL)
+		fmt.Fprintf(&b, "  useoauth: %t\n", r.UseOAuth)
+		if r.Priority != 0 {
+			fmt.Fprintf(&b, "  priority: %d\n", r.Priority)
+		}
+	}
+	return b.Bytes()
+}
+
+// writeRepoFile replaces path with repos. The file is written alongside and renamed into
+// place, so that googet never reads a partially written file.
+func writeRepoFile(path string, repos []Repo) error {
+	if len(repos) == 0 {
+		return os.Remove(path)
+	}
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, formatRepoFile(repos), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// repoFiles returns the repository files in dir, keyed by path.
+func repoFiles(dir string) (map[string][]Repo, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.repo"))
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]Repo{}
+	for _, p := range paths {
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return nil, err
+		}
+		if files[p], err = parseRepoFile(b); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", p, err)
+		}
+	}
+	return files, nil
+}
+
+// findRepo locates the file and index of the repository called name.
+func findRepo(files map[string][]Repo, name string) (string, int, bool) {
+	for p, repos := range files {
+		for i, r := range repos {
+			if strings.EqualFold(r.Name, name) {
+				return p, i, true
+			}
+		}
+	}
+	return "", 0, false
+}
+
+// Repos lists the configured repositories, ordered by descending priority.
+func Repos(conf *Config) ([]Repo, error) {
+	files, err := repoFiles(repoDir(conf))
+	if err != nil {
+		return nil, err
+	}
+	all := []Repo{}
+	for _, repos := range files {
+		all = append(all, repos...)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].Priority != all[j].Priority {
+			return all[i].Priority > all[j].Priority
+		}
+		return all[i].Name < all[j].Name
+	})
+	return all, nil
+}
+
+// AddRepo configures a new repository in its own repository file.
+func AddRepo(r Repo, conf *Config) error {
+	if err := r.validate(); err != nil {
+		return err
+	}
+	dir := repoDir(conf)
+	files, err := repoFiles(dir)
+	if err != nil {
+		return err
+	}
+	if p, _, ok := findRepo(files, r.Name); ok {
+		return fmt.Errorf("%w: %s is defined in %s", ErrRepoExists, r.Name, p)
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	p := filepath.Join(dir, r.Name+".repo")
+	if _, ok := files[p]; ok {
+		return fmt.Errorf("%w: %s already exists", ErrRepoExists, p)
+	}
+	return writeRepoFile(p, []Repo{r})
+}
+
+// UpdateRepo replaces the definition of an existing repository, in place.
+func UpdateRepo(r Repo, conf *Config) error {
+	if err := r.validate(); err != nil {
+		return err
+	}
+	files, err := repoFiles(repoDir(conf))
+	if err != nil {
+		return err
+	}
+	p, i, ok := findRepo(files, r.Name)
+	if !ok {
+		return fmt.Errorf("%w: repository %s", ErrNotFound, r.Name)
+	}
+	files[p][i] = r
+	return writeRepoFile(p, files[p])
+}
+
+// RemoveRepo removes a repository. Repository files left empty are deleted.
+func RemoveRepo(name string, conf *Config) error {
+	files, err := repoFiles(repoDir(conf))
+	if err != nil {
+		return err
+	}
+	p, i, ok := findRepo(files, name)
+	if !ok {
+		return fmt.Errorf("%w: repository %s", ErrNotFound, name)
+	}
+	return writeRepoFile(p, append(files[p][:i], files[p][i+1:]...))
+}
@@ -0,0 +1,135 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googet
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseRepoFile(t *testing.T) {
+	tests := []struct {
+		desc    string
+		in      string
+		want    []Repo
+		wantErr bool
+	}{
+		{
+			desc: "list",
+			in: `# managed
+- name: stable
+  url: https://repo.example.com/googet/stable
+  useoauth: true
+  priority: 1000
+- name: 'o''brien'
+  url: "https://repo.example.com/googet/beta"
+  priority: canary
+`,
+			want: []Repo{
+				{Name: "stable", URL: "https://repo.example.com/googet/stable", UseOAuth: true, Priority: 1000},
+				{Name: "o'brien", URL: "https://repo.example.com/googet/beta", Priority: 1300},
+			},
+		},
+		{
+			desc: "single entry",
+			in:   "name: stable\nurl: https://repo.example.com/googet/stable\n",
+			want: []Repo{{Name: "stable", URL: "https://repo.example.com/googet/stable"}},
+		},
+		{
+			desc:    "invalid priority",
+			in:      "- name: stable\n  priority: high\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got, err := parseRepoFile([]byte(tt.in))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseRepoFile() returned %v, want error %t", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("parseRepoFile() produced unexpected differences (-want +got): %s", diff)
+			}
+			reparsed, err := parseRepoFile(formatRepoFile(got))
+			if err != nil {
+				t.Fatalf("parseRepoFile(formatRepoFile()) returned %v", err)
+			}
+			if diff := cmp.Diff(got, reparsed); diff != "" {
+				t.Errorf("formatRepoFile() did not round trip (-want +got): %s", diff)
+			}
+		})
+	}
+}
+
+func TestRepoManagement(t *testing.T) {
+	root := t.TempDir()
+	conf := &Config{GooGetExe: filepath.Join(root, "googet.exe")}
+	dir := filepath.Join(root, "repos")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	shared := "- name: one\n  url: https://one\n- name: two\n  url: https://two\n  priority: 1300\n"
+	if err := os.WriteFile(filepath.Join(dir, "shared.repo"), []byte(shared), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := AddRepo(Repo{Name: "auth", URL: "https://auth", UseOAuth: true, Priority: 1000}, conf); err != nil {
+		t.Errorf("AddRepo(auth) returned %v", err)
+	}
+	if err := AddRepo(Repo{Name: "TWO", URL: "https://other"}, conf); !errors.Is(err, ErrRepoExists) {
+		t.Errorf("AddRepo(TWO) returned %v, want %v", err, ErrRepoExists)
+	}
+	if err := AddRepo(Repo{Name: "bad"}, conf); !errors.Is(err, ErrInvalidRepo) {
+		t.Errorf("AddRepo(bad) returned %v, want %v", err, ErrInvalidRepo)
+	}
+	if err := UpdateRepo(Repo{Name: "one", URL: "https://one/v2", Priority: 1500}, conf); err != nil {
+		t.Errorf("UpdateRepo(one) returned %v", err)
+	}
+	if err := UpdateRepo(Repo{Name: "missing", URL: "https://missing"}, conf); !errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateRepo(missing) returned %v, want %v", err, ErrNotFound)
+	}
+
+	got, err := Repos(conf)
+	if err != nil {
+		t.Fatalf("Repos() returned %v", err)
+	}
+	want := []Repo{
+		{Name: "one", URL: "https://one/v2", Priority: 1500},
+		{Name: "two", URL: "https://two", Priority: 1300},
+		{Name: "auth", URL: "https://auth", UseOAuth: true, Priority: 1000},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Repos() produced unexpected differences (-want +got): %s", diff)
+	}
+
+	for _, name := range []string{"one", "two", "auth"} {
+		if err := RemoveRepo(name, conf); err != nil {
+			t.Errorf("RemoveRepo(%s) returned %v", name, err)
+		}
+	}
+	if err := RemoveRepo("one", conf); !errors.Is(err, ErrNotFound) {
+		t.Errorf("RemoveRepo(one) returned %v, want %v", err, ErrNotFound)
+	}
+	if left, _ := filepath.Glob(filepath.Join(dir, "*")); len(left) != 0 {
+		t.Errorf("RemoveRepo() left files %v behind", left)
+	}
+}
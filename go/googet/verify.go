@@ -0,0 +1,53 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/glazier/go/helpers"
+	"github.com/google/logger"
+)
+
+var (
+	// ErrVerification indicates that an installed package does not match its recorded state.
+	ErrVerification = errors.New("package verification failed")
+)
+
+// Verify checks the files of the installed package pkg against the hashes googet recorded
+// when installing it.
+func Verify(pkg string, conf *Config) error {
+	res, err := run([]string{"verify", pkg}, conf)
+	if errors.Is(err, helpers.ErrExitCode) {
+		return fmt.Errorf("%w: %s: %s", ErrVerification, pkg, strings.TrimSpace(string(res.Stdout)))
+	}
+	return err
+}
+
+// RepairIfNeeded verifies the installed package pkg and reinstalls it if verification
+// fails. It reports whether the package was reinstalled.
+func RepairIfNeeded(pkg string, conf *Config) (bool, error) {
+	err := Verify(pkg, conf)
+	if err == nil || !errors.Is(err, ErrVerification) {
+		return false, err
+	}
+	logger.Warningf("Reinstalling %s: %v", pkg, err)
+	if err := Install(pkg, "", true, conf); err != nil {
+		return true, fmt.Errorf("reinstalling %s: %w", pkg, err)
+	}
+	return true, Verify(pkg, conf)
+}
@@ -0,0 +1,66 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googet
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/glazier/go/helpers"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRepairIfNeeded(t *testing.T) {
+	fail := errors.New("test failure")
+	corrupt := fmt.Errorf("%w: 1", helpers.ErrExitCode)
+	tests := []struct {
+		desc         string
+		results      []error
+		wantCalls    []string
+		wantRepaired bool
+		wantErr      error
+	}{
+		{"intact", []error{nil}, []string{"verify foo"}, false, nil},
+		{"verify error", []error{fail}, []string{"verify foo"}, false, fail},
+		{"repaired", []error{corrupt, nil, nil}, []string{"verify foo", "-noconfirm install --reinstall foo", "verify foo"}, true, nil},
+		{"reinstall fails", []error{corrupt, fail}, []string{"verify foo", "-noconfirm install --reinstall foo"}, true, fail},
+		{"still corrupt", []error{corrupt, nil, corrupt}, []string{"verify foo", "-noconfirm install --reinstall foo", "verify foo"}, true, ErrVerification},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			calls := []string{}
+			funcExec = func(path string, args []string, timeout *time.Duration, v *helpers.ExecVerifier) (helpers.ExecResult, error) {
+				calls = append(calls, strings.Join(args, " "))
+				if len(calls) > len(tt.results) {
+					t.Fatalf("unexpected call %q", args)
+				}
+				return helpers.ExecResult{Stdout: []byte("C:\\foo.exe: hash mismatch\n")}, tt.results[len(calls)-1]
+			}
+			repaired, err := RepairIfNeeded("foo", nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RepairIfNeeded(foo) returned unexpected error %v", err)
+			}
+			if repaired != tt.wantRepaired {
+				t.Errorf("RepairIfNeeded(foo) = %t, want %t", repaired, tt.wantRepaired)
+			}
+			if diff := cmp.Diff(tt.wantCalls, calls); diff != "" {
+				t.Errorf("RepairIfNeeded(foo) produced unexpected calls (-want +got): %s", diff)
+			}
+		})
+	}
+}
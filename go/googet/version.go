@@ -0,0 +1,174 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googet
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidVersion indicates a version string that could not be parsed.
+	ErrInvalidVersion = errors.New("invalid version")
+)
+
+// Version is a googet package version, of the form x.y.z[-prerelease][+build][@release].
+//
+// The x.y.z part may have any number of numeric components. Versions compare as semantic
+// versions, missing components counting as zero, with ties broken by the release number.
+type Version struct {
+	Components []int
+	PreRelease string
+	Build      string
+	Release    int
+}
+
+// ParseVersion parses a googet version string, eg "2.6.0-20191015@281551337".
+func ParseVersion(s string) (Version, error) {
+	v := Version{}
+	rest := strings.TrimSpace(s)
+	if i := strings.LastIndex(rest, "@"); i >= 0 {
+		r, err := strconv.Atoi(rest[i+1:])
+		if err != nil || r < 0 {
+			return v, fmt.Errorf("%w: %q has invalid release %q", ErrInvalidVersion, s, rest[i+1:])
+		}
+		v.Release = r
+		rest = rest[:i]
+	}
+	if i := strings.Index(rest, "+"); i >= 0 {
+		v.Build = rest[i+1:]
+		rest = rest[:i]
+	}
+	if i := strings.Index(rest, "-"); i >= 0 {
+		v.PreRelease = rest[i+1:]
+		rest = rest[:i]
+		if v.PreRelease == "" {
+			return v, fmt.Errorf("%w: %q has an empty pre-release", ErrInvalidVersion, s)
+		}
+	}
+	if rest == "" {
+		return v, fmt.Errorf("%w: %q", ErrInvalidVersion, s)
+	}
+	for _, c := range strings.Split(rest, ".") {
+		n, err := strconv.Atoi(c)
+		if err != nil || n < 0 {
+			return v, fmt.Errorf("%w: %q has non-numeric component %q", ErrInvalidVersion, s, c)
+		}
+		v.Components = append(v.Components, n)
+	}
+	return v, nil
+}
+
+// String formats the version in googet notation.
+func (v Version) String() string {
+	parts := make([]string, len(v.Components))
+	for i, c := range v.Components {
+		parts[i] = strconv.Itoa(c)
+	}
+	s := strings.Join(parts, ".")
+	if v.PreRelease != "" {
+		s += "-" + v.PreRelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return fmt.Sprintf("%s@%d", s, v.Release)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// comparePreRelease compares pre-release strings by semantic versioning rules: a version
+// without a pre-release sorts after one with, numeric identifiers compare numerically and
+// sort before alphanumeric ones.
+func comparePreRelease(a, b string) int {
+	switch {
+	case a == b:
+		return 0
+	case a == "":
+		return 1
+	case b == "":
+		return -1
+	}
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aerr := strconv.Atoi(as[i])
+		bn, berr := strconv.Atoi(bs[i])
+		var c int
+		switch {
+		case aerr == nil && berr == nil:
+			c = compareInt(an, bn)
+		case aerr == nil:
+			c = -1
+		case berr == nil:
+			c = 1
+		default:
+			c = strings.Compare(as[i], bs[i])
+		}
+		if c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(as), len(bs))
+}
+
+// Compare returns -1, 0 or 1 as v is older than, the same as, or newer than o. Build
+// metadata is ignored.
+func (v Version) Compare(o Version) int {
+	n := len(v.Components)
+	if len(o.Components) > n {
+		n = len(o.Components)
+	}
+	for i := 0; i < n; i++ {
+		var a, b int
+		if i < len(v.Components) {
+			a = v.Components[i]
+		}
+		if i < len(o.Components) {
+			b = o.Components[i]
+		}
+		if c := compareInt(a, b); c != 0 {
+			return c
+		}
+	}
+	if c := comparePreRelease(v.PreRelease, o.PreRelease); c != 0 {
+		return c
+	}
+	return compareInt(v.Release, o.Release)
+}
+
+// CompareVersions parses and compares two version strings. See Version.Compare.
+//
+// Example: if c, err := googet.CompareVersions(installed, latest); err == nil && c < 0 { ... }
+func CompareVersions(a, b string) (int, error) {
+	va, err := ParseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := ParseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	return va.Compare(vb), nil
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package googet
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Version
+		wantErr error
+	}{
+		{"2.6.0-20191015@281551337", Version{Components: []int{2, 6, 0}, PreRelease: "20191015", Release: 281551337}, nil},
+		{"2021.03.01@360244395", Version{Components: []int{2021, 3, 1}, Release: 360244395}, nil},
+		{"1.2.3.4+abc@1", Version{Components: []int{1, 2, 3, 4}, Build: "abc", Release: 1}, nil},
+		{"20210203", Version{Components: []int{20210203}}, nil},
+		{"1.2.3@x", Version{}, ErrInvalidVersion},
+		{"1.2.a@1", Version{}, ErrInvalidVersion},
+		{"1.2-@1", Version{}, ErrInvalidVersion},
+		{"@1", Version{}, ErrInvalidVersion},
+	}
+	for _, tt := range tests {
+		got, err := ParseVersion(tt.in)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("ParseVersion(%q) returned unexpected error %v", tt.in, err)
+		}
+		if err != nil {
+			continue
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("ParseVersion(%q) produced unexpected differences (-want +got): %s", tt.in, diff)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1.0.0@1", "1.0.0@1", 0},
+		{"1.0.0@1", "1.0.0@2", -1},
+		{"1.0.1@1", "1.0.0@9", 1},
+		{"1.0@1", "1.0.0@1", 0},
+		{"1.0.0.1@1", "1.0.0@1", 1},
+		{"1.10.0@1", "1.9.0@1", 1},
+		{"2.6.0-20191015@1", "2.6.0@1", -1},
+		{"2.6.0-20191015@1", "2.6.0-20191016@1", -1},
+		{"1.0.0-alpha@1", "1.0.0-alpha.1@1", -1},
+		{"1.0.0-2@1", "1.0.0-alpha@1", -1},
+		{"1.0.0-beta@1", "1.0.0-alpha@1", 1},
+		{"1.0.0+a@1", "1.0.0+b@1", 0},
+	}
+	for _, tt := range tests {
+		got, err := CompareVersions(tt.a, tt.b)
+		if err != nil {
+			t.Errorf("CompareVersions(%q, %q) returned %v", tt.a, tt.b, err)
+		}
+		if got != tt.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/glazier/go/internal/privilege"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	// ErrInvalidObject indicates a path which does not name a supported securable object.
+	ErrInvalidObject = errors.New("invalid securable object")
+)
+
+// ObjectType identifies the kind of object an ACL helper operates on.
+type ObjectType int
+
+// Object types.
+const (
+	// FileObject is a file or directory.
+	FileObject ObjectType = iota
+	// RegistryObject is a registry key, named with its hive (eg `HKLM\SOFTWARE\Glazier`).
+	RegistryObject
+)
+
+var hiveNames = map[string]string{
+	"HKLM":               "MACHINE",
+	"HKEY_LOCAL_MACHINE": "MACHINE",
+	"HKCU":               "CURRENT_USER",
+	"HKEY_CURRENT_USER":  "CURRENT_USER",
+	"HKCR":               "CLASSES_ROOT",
+	"HKEY_CLASSES_ROOT":  "CLASSES_ROOT",
+	"HKU":                "USERS",
+	"HKEY_USERS":         "USERS",
+}
+
+// securityName converts a path to the object name and type used by the security APIs.
+func securityName(path string, obj ObjectType) (string, windows.SE_OBJECT_TYPE, error) {
+	switch obj {
+	case FileObject:
+		p, err := longPath(path)
+		return p, windows.SE_FILE_OBJECT, err
+	case RegistryObject:
+		parts := strings.SplitN(path, `\`, 2)
+		hive, ok := hiveNames[strings.ToUpper(parts[0])]
+		if !ok {
+			return "", 0, fmt.Errorf("%w: unsupported registry hive in %q", ErrInvalidObject, path)
+		}
+		if len(parts) == 2 {
+			return hive + `\` + parts[1], windows.SE_REGISTRY_KEY, nil
+		}
+		return hive, windows.SE_REGISTRY_KEY, nil
+	}
+	return "", 0, fmt.Errorf("%w: unsupported object type %d", ErrInvalidObject, obj)
+}
+
+// lookupSID resolves an account name (eg `BUILTIN\Administrators`) or SID string.
+func lookupSID(account string) (*windows.SID, error) {
+	if strings.HasPrefix(strings.ToUpper(account), "S-1-") {
+		return windows.StringToSid(account)
+	}
+	sid, _, _, err := windows.LookupSID("", account)
+	if err != nil {
+		return nil, fmt.Errorf("looking up %s: %w", account, err)
+	}
+	return sid, nil
+}
+
+// TakeOwnership makes account the owner of an object, regardless of its current
+// permissions. The caller must hold SeTakeOwnershipPrivilege, and SeRestorePrivilege to
+// assign an owner other than itself, as administrators do.
+//
+// Example: helpers.TakeOwnership(`C:\ProgramData\Agent`, helpers.FileObject, `BUILTIN\Administrators`)
+func TakeOwnership(path string, obj ObjectType, account string) error {
+	name, t, err := securityName(path, obj)
+	if err != nil {
+		return err
+	}
+	sid, err := lookupSID(account)
+	if err != nil {
+		return err
+	}
+	if err := privilege.Enable("SeTakeOwnershipPrivilege", "SeRestorePrivilege"); err != nil {
+		return err
+	}
+	if err := windows.SetNamedSecurityInfo(name, t, windows.OWNER_SECURITY_INFORMATION, sid, nil, nil, nil); err != nil {
+		return fmt.Errorf("setting owner of %s: %w", path, err)
+	}
+	return nil
+}
+
+// modifyDACL merges an explicit access entry into the DACL of an object.
+func modifyDACL(path string, obj ObjectType, account string, ea windows.EXPLICIT_ACCESS) error {
+	name, t, err := securityName(path, obj)
+	if err != nil {
+		return err
+	}
+	sid, err := lookupSID(account)
+	if err != nil {
+		return err
+	}
+	ea.Trustee = windows.TRUSTEE{
+		TrusteeForm:  windows.TRUSTEE_IS_SID,
+		TrusteeType:  windows.TRUSTEE_IS_UNKNOWN,
+		TrusteeValue: windows.TrusteeValueFromSID(sid),
+	}
+	sd, err := windows.GetNamedSecurityInfo(name, t, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return fmt.Errorf("reading security of %s: %w", path, err)
+	}
+	old, _, err := sd.DACL()
+	if err != nil {
+		return fmt.Errorf("reading DACL of %s: %w", path, err)
+	}
+	acl, err := windows.ACLFromEntries([]windows.EXPLICIT_ACCESS{ea}, old)
+	if err != nil {
+		return fmt.Errorf("building DACL for %s: %w", path, err)
+	}
+	if err := windows.SetNamedSecurityInfo(name, t, windows.DACL_SECURITY_INFORMATION, nil, nil, acl, nil); err != nil {
+		return fmt.Errorf("applying DACL to %s: %w", path, err)
+	}
+	return nil
+}
+
+// GrantAccess grants account the given rights (eg windows.GENERIC_READ) on an object, in
+// addition to any it has. If inherit is set, child objects inherit the grant.
+//
+// Example: helpers.GrantAccess(`HKLM\SOFTWARE\Agent`, helpers.RegistryObject, "Users", windows.KEY_READ, true)
+func GrantAccess(path string, obj ObjectType, account string, rights windows.ACCESS_MASK, inherit bool) error {
+	ea := windows.EXPLICIT_ACCESS{
+		AccessPermissions: rights,
+		AccessMode:        windows.GRANT_ACCESS,
+		Inheritance:       windows.NO_INHERITANCE,
+	}
+	if inherit {
+		ea.Inheritance = windows.SUB_CONTAINERS_AND_OBJECTS_INHERIT
+	}
+	return modifyDACL(path, obj, account, ea)
+}
+
+// RevokeAccess removes all explicit entries for account from the DACL of an object.
+// Inherited entries are unaffected.
+func RevokeAccess(path string, obj ObjectType, account string) error {
+	return modifyDACL(path, obj, account, windows.EXPLICIT_ACCESS{AccessMode: windows.REVOKE_ACCESS})
+}
+
+// SetInheritance enables or disables inheritance of permissions from an object's parent.
+// When disabling, the entries currently in effect are kept as explicit entries.
+func SetInheritance(path string, obj ObjectType, enabled bool) error {
+	name, t, err := securityName(path, obj)
+	if err != nil {
+		return err
+	}
+	sd, err := windows.GetNamedSecurityInfo(name, t, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return fmt.Errorf("reading security of %s: %w", path, err)
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return fmt.Errorf("reading DACL of %s: %w", path, err)
+	}
+	info := windows.SECURITY_INFORMATION(windows.DACL_SECURITY_INFORMATION | windows.PROTECTED_DACL_SECURITY_INFORMATION)
+	if enabled {
+		info = windows.DACL_SECURITY_INFORMATION | windows.UNPROTECTED_DACL_SECURITY_INFORMATION
+	}
+	if err := windows.SetNamedSecurityInfo(name, t, info, nil, nil, dacl, nil); err != nil {
+		return fmt.Errorf("setting inheritance of %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/sys/windows"
+)
+
+func TestSecurityName(t *testing.T) {
+	tests := []struct {
+		path     string
+		obj      ObjectType
+		wantName string
+		wantType windows.SE_OBJECT_TYPE
+		wantErr  error
+	}{
+		{`C:\ProgramData`, FileObject, `\\?\C:\ProgramData`, windows.SE_FILE_OBJECT, nil},
+		{`HKLM\SOFTWARE\Glazier`, RegistryObject, `MACHINE\SOFTWARE\Glazier`, windows.SE_REGISTRY_KEY, nil},
+		{`HKEY_CURRENT_USER\Software`, RegistryObject, `CURRENT_USER\Software`, windows.SE_REGISTRY_KEY, nil},
+		{`hku`, RegistryObject, `USERS`, windows.SE_REGISTRY_KEY, nil},
+		{`HKCC\System`, RegistryObject, "", 0, ErrInvalidObject},
+		{`C:\`, ObjectType(9), "", 0, ErrInvalidObject},
+	}
+	for _, tt := range tests {
+		name, typ, err := securityName(tt.path, tt.obj)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("securityName(%q) returned unexpected error %v", tt.path, err)
+		}
+		if name != tt.wantName || typ != tt.wantType {
+			t.Errorf("securityName(%q) = %q, %d, want %q, %d", tt.path, name, typ, tt.wantName, tt.wantType)
+		}
+	}
+}
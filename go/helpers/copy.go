@@ -0,0 +1,242 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"unsafe"
+
+	"github.com/google/logger"
+	"golang.org/x/sys/windows"
+)
+
+var (
+	procCopyFileW = windows.NewLazySystemDLL("kernel32.dll").NewProc("CopyFileW")
+)
+
+// CopyOptions configures CopyTree and MirrorDir. The zero value copies each file once,
+// without verification.
+type CopyOptions struct {
+	// Retries is the number of additional attempts to copy a file that is in use.
+	Retries int
+	// RetryDelay is the delay between attempts.
+	RetryDelay time.Duration
+	// Verify compares the SHA256 of every copied file with that of its source.
+	Verify bool
+}
+
+// longPath converts path to an extended-length path, so it is not limited to MAX_PATH by
+// APIs which do not handle long paths themselves.
+func longPath(path string) (string, error) {
+	if strings.HasPrefix(path, `\\?\`) {
+		return path, nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		return `\\?\UNC\` + abs[2:], nil
+	}
+	return `\\?\` + abs, nil
+}
+
+// copyFile copies a file's data, attributes and modification time, retrying while the
+// source is locked by another process.
+func copyFile(src, dst string, opts CopyOptions) error {
+	s, err := longPath(src)
+	if err != nil {
+		return err
+	}
+	d, err := longPath(dst)
+	if err != nil {
+		return err
+	}
+	sp, err := windows.UTF16PtrFromString(s)
+	if err != nil {
+		return err
+	}
+	dp, err := windows.UTF16PtrFromString(d)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		r, _, err := procCopyFileW.Call(uintptr(unsafe.Pointer(sp)), uintptr(unsafe.Pointer(dp)), 0)
+		if r != 0 {
+			return nil
+		}
+		inUse := errors.Is(err, windows.ERROR_SHARING_VIOLATION) || errors.Is(err, windows.ERROR_LOCK_VIOLATION)
+		if !inUse || attempt >= opts.Retries {
+			return fmt.Errorf("copying %s: %w", src, err)
+		}
+		logger.Warningf("%s is in use, retrying copy in %v.", src, opts.RetryDelay)
+		time.Sleep(opts.RetryDelay)
+	}
+}
+
+// copySecurity applies the DACL of src to dst, including whether inheritance is blocked.
+func copySecurity(src, dst string) error {
+	s, err := longPath(src)
+	if err != nil {
+		return err
+	}
+	d, err := longPath(dst)
+	if err != nil {
+		return err
+	}
+	sd, err := windows.GetNamedSecurityInfo(s, windows.SE_FILE_OBJECT, windows.DACL_SECURITY_INFORMATION)
+	if err != nil {
+		return fmt.Errorf("reading security of %s: %w", src, err)
+	}
+	dacl, _, err := sd.DACL()
+	if err != nil {
+		return fmt.Errorf("reading DACL of %s: %w", src, err)
+	}
+	control, _, err := sd.Control()
+	if err != nil {
+		return fmt.Errorf("reading security of %s: %w", src, err)
+	}
+	info := windows.SECURITY_INFORMATION(windows.DACL_SECURITY_INFORMATION | windows.UNPROTECTED_DACL_SECURITY_INFORMATION)
+	if control&windows.SE_DACL_PROTECTED != 0 {
+		info = windows.DACL_SECURITY_INFORMATION | windows.PROTECTED_DACL_SECURITY_INFORMATION
+	}
+	if err := windows.SetNamedSecurityInfo(d, windows.SE_FILE_OBJECT, info, nil, nil, dacl, nil); err != nil {
+		return fmt.Errorf("applying security to %s: %w", dst, err)
+	}
+	return nil
+}
+
+func verifyCopy(src, dst string) error {
+	want, err := HashFile(src, SHA256)
+	if err != nil {
+		return err
+	}
+	got, err := HashFile(dst, SHA256)
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("%w: copy of %s has SHA256 %s, want %s", ErrHashMismatch, src, got, want)
+	}
+	return nil
+}
+
+// unchanged reports whether dst has the size and modification time of src.
+func unchanged(src os.FileInfo, dst string) bool {
+	di, err := os.Stat(dst)
+	return err == nil && !di.IsDir() && di.Size() == src.Size() && di.ModTime().Equal(src.ModTime())
+}
+
+type dirTime struct {
+	path string
+	mod  time.Time
+}
+
+func copyTree(src, dst string, opts CopyOptions, skipUnchanged bool) error {
+	dirs := []dirTime{}
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		switch {
+		case info.Mode()&os.ModeSymlink != 0:
+			logger.Warningf("Skipping symbolic link %s.", path)
+			return nil
+		case info.IsDir():
+			if err := os.MkdirAll(target, 0755); err != nil {
+				return err
+			}
+			dirs = append(dirs, dirTime{target, info.ModTime()})
+			return copySecurity(path, target)
+		case skipUnchanged && unchanged(info, target):
+			return nil
+		}
+		if err := copyFile(path, target, opts); err != nil {
+			return err
+		}
+		if err := copySecurity(path, target); err != nil {
+			return err
+		}
+		if opts.Verify {
+			return verifyCopy(path, target)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	// Copying into a directory updates its modification time, so directories are dated
+	// last, deepest first.
+	for i := len(dirs) - 1; i >= 0; i-- {
+		if err := os.Chtimes(dirs[i].path, dirs[i].mod, dirs[i].mod); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CopyTree copies the directory tree src into dst, preserving file attributes,
+// modification times and access control lists. Existing files in dst are overwritten.
+// Symbolic links are skipped. opts may be nil.
+//
+// Example: helpers.CopyTree(`\\deploy\share\drivers`, `C:\Drivers`, &helpers.CopyOptions{Retries: 3, RetryDelay: time.Second})
+func CopyTree(src, dst string, opts *CopyOptions) error {
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+	return copyTree(src, dst, *opts, false)
+}
+
+// MirrorDir makes dst a copy of src, like robocopy /MIR: files which differ in size or
+// modification time are copied as with CopyTree, and anything in dst which is not in src
+// is deleted. opts may be nil.
+func MirrorDir(src, dst string, opts *CopyOptions) error {
+	if opts == nil {
+		opts = &CopyOptions{}
+	}
+	if err := copyTree(src, dst, *opts, true); err != nil {
+		return err
+	}
+	return filepath.Walk(dst, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dst, path)
+		if err != nil {
+			return err
+		}
+		if _, err := os.Lstat(filepath.Join(src, rel)); !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		logger.V(2).Infof("Removing %s, which is not in %s.", path, src)
+		if err := os.RemoveAll(path); err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+}
@@ -0,0 +1,98 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestLongPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`C:\Windows\Temp`, `\\?\C:\Windows\Temp`},
+		{`C:\Windows\..\Temp`, `\\?\C:\Temp`},
+		{`\\server\share\dir`, `\\?\UNC\server\share\dir`},
+		{`\\?\C:\already`, `\\?\C:\already`},
+	}
+	for _, tt := range tests {
+		got, err := longPath(tt.in)
+		if err != nil {
+			t.Errorf("longPath(%q) returned unexpected error %v", tt.in, err)
+		}
+		if got != tt.want {
+			t.Errorf("longPath(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+// tree lists the files under root with their contents.
+func tree(t *testing.T, root string) map[string]string {
+	t.Helper()
+	files := map[string]string{}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		b, err := os.ReadFile(path)
+		rel, _ := filepath.Rel(root, path)
+		files[rel] = string(b)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("reading %s: %v", root, err)
+	}
+	return files
+}
+
+func TestCopyTreeAndMirror(t *testing.T) {
+	src, dst := t.TempDir(), t.TempDir()
+	want := map[string]string{
+		"a.txt":                     "a",
+		filepath.Join("b", "c.txt"): "c",
+	}
+	for p, c := range want {
+		os.MkdirAll(filepath.Dir(filepath.Join(src, p)), 0755)
+		if err := os.WriteFile(filepath.Join(src, p), []byte(c), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dst, "extra.txt"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CopyTree(src, dst, &CopyOptions{Verify: true}); err != nil {
+		t.Fatalf("CopyTree() returned unexpected error %v", err)
+	}
+	withExtra := map[string]string{"extra.txt": "x"}
+	for p, c := range want {
+		withExtra[p] = c
+	}
+	if diff := cmp.Diff(withExtra, tree(t, dst)); diff != "" {
+		t.Errorf("CopyTree() produced unexpected diff (-want +got):\n%s", diff)
+	}
+
+	if err := MirrorDir(src, dst, nil); err != nil {
+		t.Fatalf("MirrorDir() returned unexpected error %v", err)
+	}
+	if diff := cmp.Diff(want, tree(t, dst)); diff != "" {
+		t.Errorf("MirrorDir() produced unexpected diff (-want +got):\n%s", diff)
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/google/logger"
+)
+
+var (
+	// ErrDownload indicates a download failed.
+	ErrDownload = errors.New("download failed")
+	// ErrHashMismatch indicates a file did not match its expected hash.
+	ErrHashMismatch = errors.New("hash mismatch")
+)
+
+// DownloadOptions configures DownloadFile.
+type DownloadOptions struct {
+	// Retries is the number of additional attempts after a failed one.
+	Retries int
+	// Backoff is the delay before the first retry. It doubles for every further retry.
+	Backoff time.Duration
+	// SHA256, if set, is the expected hex digest of the file.
+	SHA256 string
+	// Progress, if set, is called as data arrives with the bytes downloaded so far and the
+	// total size, or -1 if the size is unknown.
+	Progress func(done, total int64)
+	// Client is used for requests. If nil, http.DefaultClient is used.
+	Client *http.Client
+}
+
+// retryable marks failures worth another attempt.
+type retryable struct{ err error }
+
+func (r retryable) Error() string { return r.err.Error() }
+func (r retryable) Unwrap() error { return r.err }
+
+// progressWriter reports the bytes written through it.
+type progressWriter struct {
+	done, total int64
+	fn          func(done, total int64)
+}
+
+func (p *progressWriter) Write(b []byte) (int, error) {
+	p.done += int64(len(b))
+	if p.fn != nil {
+		p.fn(p.done, p.total)
+	}
+	return len(b), nil
+}
+
+// DownloadFile downloads url to dest.
+//
+// Data is written to dest.partial first, and an interrupted download resumes from there
+// if the server supports range requests. Connection failures and server errors are
+// retried as configured in opts, which may be nil. Once complete, the file is verified
+// against opts.SHA256 (failing with ErrHashMismatch) and moved to dest.
+//
+// Example: helpers.DownloadFile(ctx, url, `C:\Glazier\driver.zip`, &helpers.DownloadOptions{Retries: 3, Backoff: time.Second})
+func DownloadFile(ctx context.Context, url, dest string, opts *DownloadOptions) error {
+	if opts == nil {
+		opts = &DownloadOptions{}
+	}
+	client := opts.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	partial := dest + ".partial"
+	backoff := opts.Backoff
+	var err error
+	for attempt := 0; attempt <= opts.Retries; attempt++ {
+		if attempt > 0 {
+			logger.Warningf("Download of %s failed (attempt %d of %d), retrying in %v: %v", url, attempt, opts.Retries+1, backoff, err)
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+		err = fetch(ctx, client, url, partial, opts.Progress)
+		var r retryable
+		if err == nil || !errors.As(err, &r) {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if opts.SHA256 != "" {
+		if err := VerifyFile(partial, opts.SHA256); err != nil {
+			os.Remove(partial)
+			return fmt.Errorf("verifying %s: %w", url, err)
+		}
+	}
+	return os.Rename(partial, dest)
+}
+
+// fetch downloads url to path, resuming from the data already in path.
+func fetch(ctx context.Context, client *http.Client, url, path string, progress func(done, total int64)) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	offset, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrDownload, err)
+	}
+	if offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return retryable{fmt.Errorf("%w: %v", ErrDownload, err)}
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusPartialContent:
+	case resp.StatusCode == http.StatusOK:
+		// The server ignored the range (or there was none); start over.
+		offset = 0
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	case resp.StatusCode == http.StatusRequestedRangeNotSatisfiable:
+		// The partial data does not fit the file (eg it changed); start over.
+		if err := f.Truncate(0); err != nil {
+			return err
+		}
+		return retryable{fmt.Errorf("%w: %s: range not satisfiable", ErrDownload, url)}
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return retryable{fmt.Errorf("%w: %s: %s", ErrDownload, url, resp.Status)}
+	default:
+		return fmt.Errorf("%w: %s: %s", ErrDownload, url, resp.Status)
+	}
+
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	pw := &progressWriter{done: offset, total: total, fn: progress}
+	if _, err := io.Copy(f, io.TeeReader(resp.Body, pw)); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return retryable{fmt.Errorf("%w: %v", ErrDownload, err)}
+	}
+	return nil
+}
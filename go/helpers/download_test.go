@@ -0,0 +1,147 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const payload = "The quick brown fox jumps over the lazy dog."
+
+func payloadSum() string {
+	s := sha256.Sum256([]byte(payload))
+	return hex.EncodeToString(s[:])
+}
+
+func TestDownloadFile(t *testing.T) {
+	tests := []struct {
+		desc     string
+		partial  string
+		failures int
+		status   int
+		opts     DownloadOptions
+		wantErr  error
+		wantHits int
+	}{
+		{
+			desc:     "success",
+			opts:     DownloadOptions{SHA256: payloadSum()},
+			wantHits: 1,
+		},
+		{
+			desc:     "resume",
+			partial:  payload[:10],
+			opts:     DownloadOptions{SHA256: strings.ToUpper(payloadSum())},
+			wantHits: 1,
+		},
+		{
+			desc:     "retry",
+			failures: 2,
+			opts:     DownloadOptions{Retries: 2, Backoff: time.Millisecond},
+			wantHits: 3,
+		},
+		{
+			desc:     "retries exhausted",
+			failures: 2,
+			opts:     DownloadOptions{Retries: 1, Backoff: time.Millisecond},
+			wantErr:  ErrDownload,
+			wantHits: 2,
+		},
+		{
+			desc:     "not found",
+			status:   http.StatusNotFound,
+			opts:     DownloadOptions{Retries: 3, Backoff: time.Millisecond},
+			wantErr:  ErrDownload,
+			wantHits: 1,
+		},
+		{
+			desc:     "hash mismatch",
+			opts:     DownloadOptions{SHA256: strings.Repeat("0", 64)},
+			wantErr:  ErrHashMismatch,
+			wantHits: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			hits := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hits++
+				if hits <= tt.failures {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+					return
+				}
+				http.ServeContent(w, r, "payload", time.Time{}, strings.NewReader(payload))
+			}))
+			defer srv.Close()
+
+			dest := filepath.Join(t.TempDir(), "payload.txt")
+			if tt.partial != "" {
+				if err := os.WriteFile(dest+".partial", []byte(tt.partial), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var done, total int64
+			tt.opts.Progress = func(d, tot int64) { done, total = d, tot }
+			err := DownloadFile(context.Background(), srv.URL, dest, &tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DownloadFile() returned unexpected error %v", err)
+			}
+			if hits != tt.wantHits {
+				t.Errorf("DownloadFile() made %d requests, want %d", hits, tt.wantHits)
+			}
+			if err != nil {
+				return
+			}
+			got, err := os.ReadFile(dest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != payload {
+				t.Errorf("DownloadFile() wrote %q, want %q", got, payload)
+			}
+			if want := int64(len(payload)); done != want || total != want {
+				t.Errorf("DownloadFile() reported progress %d/%d, want %d/%d", done, total, want, want)
+			}
+		})
+	}
+}
+
+func TestDownloadFileCancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	dest := filepath.Join(t.TempDir(), "payload.txt")
+	err := DownloadFile(ctx, srv.URL, dest, &DownloadOptions{Retries: 5, Backoff: time.Hour})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("DownloadFile() returned %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// IsElevated reports whether the current process runs with an elevated (administrator)
+// token.
+func IsElevated() bool {
+	return windows.GetCurrentProcessToken().IsElevated()
+}
+
+// commandLine joins args into a command line, quoting them as needed.
+func commandLine(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = syscall.EscapeArg(a)
+	}
+	return strings.Join(quoted, " ")
+}
+
+// RelaunchElevated starts the current executable again with args, prompting for elevation
+// via UAC. It returns once the new process is started; the caller should then exit.
+//
+// Example:
+//
+//	if !helpers.IsElevated() {
+//		if err := helpers.RelaunchElevated(os.Args[1:]); err != nil {
+//			log.Fatal(err)
+//		}
+//		os.Exit(0)
+//	}
+func RelaunchElevated(args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	verb, err := windows.UTF16PtrFromString("runas")
+	if err != nil {
+		return err
+	}
+	file, err := windows.UTF16PtrFromString(exe)
+	if err != nil {
+		return err
+	}
+	params, err := windows.UTF16PtrFromString(commandLine(args))
+	if err != nil {
+		return err
+	}
+	dir, err := windows.UTF16PtrFromString(cwd)
+	if err != nil {
+		return err
+	}
+	if err := windows.ShellExecute(0, verb, file, params, dir, windows.SW_NORMAL); err != nil {
+		return fmt.Errorf("ShellExecute(runas %s): %w", exe, err)
+	}
+	return nil
+}
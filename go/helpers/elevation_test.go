@@ -0,0 +1,33 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import "testing"
+
+func TestCommandLine(t *testing.T) {
+	tests := []struct {
+		in   []string
+		want string
+	}{
+		{[]string{}, ""},
+		{[]string{"-config", `C:\Program Files\glazier.yaml`}, `-config "C:\Program Files\glazier.yaml"`},
+		{[]string{`say "hi"`}, `"say \"hi\""`},
+	}
+	for _, tt := range tests {
+		if got := commandLine(tt.in); got != tt.want {
+			t.Errorf("commandLine(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
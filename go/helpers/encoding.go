@@ -0,0 +1,150 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// OutputEncoding identifies the character encoding a process writes its output in.
+type OutputEncoding int
+
+// Output encodings.
+const (
+	// EncodingUTF8 leaves output as is. It is the default.
+	EncodingUTF8 OutputEncoding = iota
+	// EncodingOEM is the OEM code page, used by most console tools (eg dism.exe).
+	EncodingOEM
+	// EncodingANSI is the ANSI code page.
+	EncodingANSI
+	// EncodingUTF16 is little endian UTF-16, as written by eg wmic.exe.
+	EncodingUTF16
+)
+
+var (
+	procGetOEMCP            = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetOEMCP")
+	procGetACP              = windows.NewLazySystemDLL("kernel32.dll").NewProc("GetACP")
+	procMultiByteToWideChar = windows.NewLazySystemDLL("kernel32.dll").NewProc("MultiByteToWideChar")
+)
+
+// decode transcodes output in the given encoding to UTF-8.
+func decode(b []byte, enc OutputEncoding) ([]byte, error) {
+	switch enc {
+	case EncodingOEM:
+		cp, _, _ := procGetOEMCP.Call()
+		return decodeCodePage(b, uint32(cp))
+	case EncodingANSI:
+		cp, _, _ := procGetACP.Call()
+		return decodeCodePage(b, uint32(cp))
+	case EncodingUTF16:
+		return decodeUTF16(b), nil
+	}
+	return b, nil
+}
+
+func decodeUTF16(b []byte) []byte {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	if len(u) > 0 && u[0] == 0xFEFF {
+		u = u[1:]
+	}
+	return []byte(string(utf16.Decode(u)))
+}
+
+func decodeCodePage(b []byte, cp uint32) ([]byte, error) {
+	if len(b) == 0 {
+		return b, nil
+	}
+	n, _, err := procMultiByteToWideChar.Call(uintptr(cp), 0, uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)), 0, 0)
+	if n == 0 {
+		return b, fmt.Errorf("MultiByteToWideChar(%d): %w", cp, err)
+	}
+	u := make([]uint16, n)
+	n, _, err = procMultiByteToWideChar.Call(uintptr(cp), 0, uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)), uintptr(unsafe.Pointer(&u[0])), n)
+	if n == 0 {
+		return b, fmt.Errorf("MultiByteToWideChar(%d): %w", cp, err)
+	}
+	return []byte(string(utf16.Decode(u[:n]))), nil
+}
+
+// lineReader splits process output into lines. Lines end at a line feed, or at a lone
+// carriage return, which console tools (eg dism.exe) use to redraw progress in place.
+//
+// Carriage returns and line feeds cannot appear within multi-byte characters of the
+// supported code pages, so only UTF-16 needs care: it is read in whole code units.
+type lineReader struct {
+	br  *bufio.Reader
+	enc OutputEncoding
+	buf [2]byte
+	// cr is set when the last line ended at a carriage return, so that a line feed
+	// following it is not taken for an empty line.
+	cr bool
+}
+
+func newLineReader(r io.Reader, enc OutputEncoding) *lineReader {
+	return &lineReader{br: bufio.NewReader(r), enc: enc}
+}
+
+// unit reads the next code unit.
+func (r *lineReader) unit() ([]byte, error) {
+	n := 1
+	if r.enc == EncodingUTF16 {
+		n = 2
+	}
+	u := r.buf[:0]
+	for len(u) < n {
+		c, err := r.br.ReadByte()
+		if err != nil {
+			return u, err
+		}
+		u = append(u, c)
+	}
+	return u, nil
+}
+
+func isUnit(u []byte, c byte) bool {
+	return u[0] == c && (len(u) == 1 || u[1] == 0)
+}
+
+// next reads up to and including the next line terminator. It reports false for the line
+// feed of a CRLF pair, which completes the preceding line rather than starting a new one.
+func (r *lineReader) next() ([]byte, bool, error) {
+	var line []byte
+	for {
+		u, err := r.unit()
+		line = append(line, u...)
+		if err != nil {
+			return line, true, err
+		}
+		cr := r.cr
+		r.cr = false
+		switch {
+		case isUnit(u, '\n'):
+			return line, !cr || len(line) > len(u), nil
+		case isUnit(u, '\r'):
+			r.cr = true
+			return line, true, nil
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/logger"
+)
+
+var (
+	// ErrUnsupportedHash indicates an unknown hash algorithm.
+	ErrUnsupportedHash = errors.New("unsupported hash algorithm")
+)
+
+// HashAlgorithm names a hash function.
+type HashAlgorithm string
+
+// Hash algorithms. MD5 and SHA1 are for matching legacy manifests only.
+const (
+	SHA256 HashAlgorithm = "sha256"
+	SHA512 HashAlgorithm = "sha512"
+	SHA1   HashAlgorithm = "sha1"
+	MD5    HashAlgorithm = "md5"
+)
+
+func newHash(algo HashAlgorithm) (hash.Hash, error) {
+	switch HashAlgorithm(strings.ToLower(string(algo))) {
+	case SHA256:
+		return sha256.New(), nil
+	case SHA512:
+		return sha512.New(), nil
+	case SHA1:
+		return sha1.New(), nil
+	case MD5:
+		return md5.New(), nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnsupportedHash, algo)
+}
+
+// HashFile returns the lower case hex digest of a file.
+func HashFile(path string, algo HashAlgorithm) (string, error) {
+	h, err := newHash(algo)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("reading %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// VerifyFile checks a file against its expected SHA256 digest, ignoring case.
+func VerifyFile(path, expected string) error {
+	got, err := HashFile(path, SHA256)
+	if err != nil {
+		return err
+	}
+	if got != strings.ToLower(expected) {
+		return fmt.Errorf("%w: %s has SHA256 %s, want %s", ErrHashMismatch, path, got, expected)
+	}
+	logger.V(2).Infof("SHA256 hash for %s matched expected hash of %s.", path, got)
+	return nil
+}
+
+// HashDir hashes every file under root, returning digests keyed by path relative to root.
+func HashDir(root string, algo HashAlgorithm) (map[string]string, error) {
+	sums := map[string]string{}
+	if _, err := newHash(algo); err != nil {
+		return sums, err
+	}
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.Mode().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		sums[rel], err = HashFile(path, algo)
+		return err
+	})
+	return sums, err
+}
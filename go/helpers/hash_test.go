@@ -0,0 +1,81 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	helloSHA256 = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	helloMD5    = "5d41402abc4b2a76b9719d911017c592"
+)
+
+func TestHashFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "hello.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		algo    HashAlgorithm
+		want    string
+		wantErr error
+	}{
+		{SHA256, helloSHA256, nil},
+		{"SHA256", helloSHA256, nil},
+		{MD5, helloMD5, nil},
+		{"crc32", "", ErrUnsupportedHash},
+	}
+	for _, tt := range tests {
+		got, err := HashFile(path, tt.algo)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("HashFile(%s) returned unexpected error %v", tt.algo, err)
+		}
+		if got != tt.want {
+			t.Errorf("HashFile(%s) = %q, want %q", tt.algo, got, tt.want)
+		}
+	}
+
+	if err := VerifyFile(path, "2CF24DBA5FB0A30E26E83B2AC5B9E29E1B161E5C1FA7425E73043362938B9824"); err != nil {
+		t.Errorf("VerifyFile() returned unexpected error %v", err)
+	}
+	if err := VerifyFile(path, helloMD5); !errors.Is(err, ErrHashMismatch) {
+		t.Errorf("VerifyFile() returned %v, want %v", err, ErrHashMismatch)
+	}
+
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "sub", "hello.txt"), []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sums, err := HashDir(dir, SHA256)
+	if err != nil {
+		t.Fatalf("HashDir() returned unexpected error %v", err)
+	}
+	want := map[string]string{
+		"hello.txt":                       helloSHA256,
+		filepath.Join("sub", "hello.txt"): helloSHA256,
+	}
+	if diff := cmp.Diff(want, sums); diff != "" {
+		t.Errorf("HashDir() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
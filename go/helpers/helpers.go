@@ -16,23 +16,25 @@
 package helpers
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 	"unsafe"
 
+	"golang.org/x/sys/windows"
 	"golang.org/x/sys/windows/registry"
 	"golang.org/x/sys/windows/svc/mgr"
 	"golang.org/x/sys/windows/svc"
+	"github.com/google/glazier/go/helpers/process"
 	"github.com/google/logger"
-	"github.com/iamacarpet/go-win64api"
 )
 
 // ExecResult holds the output from a subprocess execution.
@@ -57,8 +59,8 @@ var (
 	PsPath = os.ExpandEnv("${windir}\\System32\\WindowsPowerShell\\v1.0\\powershell.exe")
 
 	// TestHelpers
-	fnExec        = execute
-	fnProcessList = winapi.ProcessList
+	fnExec        = executeWithTelemetry
+	fnProcessList = process.List
 )
 
 // ExecConfig provides flexible execution configuration.
@@ -69,6 +71,41 @@ type ExecConfig struct {
 	Retry   *time.Duration
 
 	SpAttr *syscall.SysProcAttr
+	// Token, if set, runs the process as the user the token belongs to.
+	Token syscall.Token
+	// KillTree terminates all descendants of the process along with it on timeout, by
+	// running the process in a job object.
+	KillTree bool
+
+	// Dir is the working directory of the process. If empty, it is inherited.
+	Dir string
+	// Env holds "KEY=value" entries for the environment of the process. If nil, the
+	// environment is inherited (or is the token user's, if Token is set).
+	Env []string
+	// InheritEnv extends the environment of the calling process with Env, rather than
+	// replacing it. Entries in Env take precedence.
+	InheritEnv bool
+
+	// OnStdoutLine and OnStderrLine, if set, are called with each line of output as the
+	// process produces it, eg to follow progress. Output is still captured in ExecResult.
+	// The two may be called concurrently.
+	OnStdoutLine func(line string)
+	OnStderrLine func(line string)
+	// OutputEncoding is the encoding the process writes output in. Output is transcoded to
+	// UTF-8 before it is returned, passed to line callbacks or verified.
+	OutputEncoding OutputEncoding
+	// MaxCapturedOutput, if positive, limits Stdout and Stderr in ExecResult to their last
+	// MaxCapturedOutput bytes each, for processes with large amounts of output. Verifiers
+	// only see the captured output.
+	MaxCapturedOutput int
+
+	// Telemetry emits a structured ExecRecord for the execution through deck once it
+	// completes, so that what ran and when can be reconstructed after the fact.
+	Telemetry bool
+	// CorrelationID ties the records of related executions together, eg the retries of a
+	// single step, which are numbered in ExecRecord.Attempt. If empty, each record gets a
+	// new ID.
+	CorrelationID string
 }
 
 // Exec executes a subprocess and returns the results.
@@ -175,6 +212,14 @@ func execute(path string, args []string, conf *ExecConfig) (ExecResult, error) {
 	} else {
 		cmd = exec.Command(path, args...)
 	}
+	cmd.Dir = conf.Dir
+	cmd.Env = environment(conf)
+	if conf.Token != 0 {
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.Token = conf.Token
+	}
 
 	stdout, err := cmd.StdoutPipe()
 	if err != nil {
@@ -191,21 +236,64 @@ func execute(path string, args []string, conf *ExecConfig) (ExecResult, error) {
 		return result, fmt.Errorf("starting cmd returned error: %s", err)
 	}
 
+	var job windows.Handle
+	if conf.KillTree {
+		if job, err = assignJob(cmd.Process.Pid); err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return result, err
+		}
+		defer windows.CloseHandle(job)
+	}
+
+	// terminate kills the process, or its whole tree. It runs at most once, so that once a
+	// read error has killed the process the timer cannot touch the job handle after it is
+	// closed; later callers wait for a terminate already in progress.
+	var terminated sync.Once
+	terminate := func() {
+		terminated.Do(func() {
+			if job != 0 {
+				windows.TerminateJobObject(job, 1)
+				return
+			}
+			cmd.Process.Kill()
+		})
+	}
+
 	var timer *time.Timer
 	// Create a timer that will kill the process
 	if conf.Timeout != nil {
-		timer = time.AfterFunc(*conf.Timeout, func() {
-			cmd.Process.Kill()
-		})
+		timer = time.AfterFunc(*conf.Timeout, terminate)
+	}
+	// abort stops a process whose output can no longer be read, so that it neither blocks
+	// on a full pipe nor outlives the call.
+	abort := func() {
+		if timer != nil {
+			timer.Stop()
+		}
+		terminate()
 	}
 
-	// Make output human readable
-	result.Stdout, err = ioutil.ReadAll(stdout)
+	// Read both streams concurrently, so neither blocks the process while the other is read.
+	var stderrErr error
+	stderrDone := make(chan struct{})
+	go func() {
+		result.Stderr, stderrErr = readOutput(stderr, conf.OnStderrLine, conf.OutputEncoding, conf.MaxCapturedOutput)
+		if stderrErr != nil {
+			abort()
+		}
+		close(stderrDone)
+	}()
+	result.Stdout, err = readOutput(stdout, conf.OnStdoutLine, conf.OutputEncoding, conf.MaxCapturedOutput)
 	if err != nil {
-		return result, err
+		abort()
+	}
+	<-stderrDone
+	if err == nil {
+		err = stderrErr
 	}
-	result.Stderr, err = ioutil.ReadAll(stderr)
 	if err != nil {
+		cmd.Wait()
 		return result, err
 	}
 
@@ -213,6 +301,8 @@ func execute(path string, args []string, conf *ExecConfig) (ExecResult, error) {
 
 	// when the execution times out return a timeout error
 	if conf.Timeout != nil && !timer.Stop() {
+		// Wait for the timer's terminate to finish before the job handle is closed.
+		terminate()
 		return result, ErrTimeout
 	}
 
@@ -225,6 +315,15 @@ func execute(path string, args []string, conf *ExecConfig) (ExecResult, error) {
 	return result, nil
 }
 
+// environment returns the environment for a process, or nil to use the default.
+func environment(conf *ExecConfig) []string {
+	if conf.InheritEnv {
+		// exec.Cmd keeps the last of any duplicate keys, so Env overrides inherited values.
+		return append(os.Environ(), conf.Env...)
+	}
+	return conf.Env
+}
+
 // GetServiceState interrogates local system services and returns their status and configuration.
 func GetServiceState(name string) (svc.Status, mgr.Config, error) {
 	m, err := mgr.Connect()
@@ -318,24 +417,26 @@ func StartService(name string) error {
 }
 
 func stopService(s *mgr.Service) error {
-	stat, err := s.Control(svc.Stop)
-	if err != nil {
+	if _, err := s.Control(svc.Stop); err != nil {
 		return err
 	}
-	retry := 0
-	for stat.State != svc.Stopped {
-		logger.Infof("Waiting for service to stop.")
-		time.Sleep(5 * time.Second)
-		retry++
-		if retry > 12 {
-			return fmt.Errorf("timed out waiting for service to stop")
-		}
-		stat, err = s.Query()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	err := WaitFor(ctx, 5*time.Second, func() (bool, error) {
+		stat, err := s.Query()
 		if err != nil {
-			return err
+			return false, err
 		}
+		if stat.State != svc.Stopped {
+			logger.Infof("Waiting for service to stop.")
+			return false, nil
+		}
+		return true, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("timed out waiting for service to stop")
 	}
-	return nil
+	return err
 }
 
 // StopService attempts to stop local system services.
@@ -354,32 +455,49 @@ func StopService(name string) error {
 	return stopService(s)
 }
 
-// WaitForProcessExit waits for a process to stop (no longer appear in the process list).
-func WaitForProcessExit(matcher *regexp.Regexp, timeout time.Duration) error {
-	t := time.NewTicker(timeout)
+// WaitFor polls cond every interval until it reports done, returns an error, or ctx is
+// done. cond is first called immediately. The error from ctx is returned if it ends the
+// wait.
+func WaitFor(ctx context.Context, interval time.Duration, cond func() (done bool, err error)) error {
+	t := time.NewTicker(interval)
 	defer t.Stop()
-	r := time.NewTicker(5 * time.Second)
-	defer r.Stop()
-
-loop:
 	for {
+		done, err := cond()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
 		select {
+		case <-ctx.Done():
+			return ctx.Err()
 		case <-t.C:
-			return ErrTimeout
-		case <-r.C:
-			procs, err := fnProcessList()
-			if err != nil {
-				return fmt.Errorf("winapi.ProcessList: %w", err)
-			}
-			for _, p := range procs {
-				if matcher.MatchString(p.Executable) {
-					logger.Warningf("Process %s still running; waiting for exit.", p.Executable)
-					goto loop
-				}
+		}
+	}
+}
+
+// WaitForProcessExit waits for a process to stop (no longer appear in the process list).
+func WaitForProcessExit(matcher *regexp.Regexp, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := WaitFor(ctx, 5*time.Second, func() (bool, error) {
+		procs, err := fnProcessList()
+		if err != nil {
+			return false, fmt.Errorf("process.List: %w", err)
+		}
+		for _, p := range procs {
+			if matcher.MatchString(p.Executable) {
+				logger.Warningf("Process %s still running; waiting for exit.", p.Executable)
+				return false, nil
 			}
-			return nil
 		}
+		return true, nil
+	})
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrTimeout
 	}
+	return err
 }
 
 // ContainsString returns true if a string is in slice and false otherwise.
@@ -15,14 +15,16 @@
 package helpers
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"os"
 	"regexp"
 	"testing"
 	"time"
 
+	"github.com/google/glazier/go/helpers/process"
 	"github.com/google/go-cmp/cmp"
-	so "github.com/iamacarpet/go-win64api/shared"
 )
 
 func TestVerify(t *testing.T) {
@@ -79,65 +81,116 @@ func TestVerify(t *testing.T) {
 	}
 }
 
+func TestEnvironment(t *testing.T) {
+	tests := []struct {
+		desc string
+		in   ExecConfig
+		want []string
+	}{
+		{"default", ExecConfig{}, nil},
+		{"replace", ExecConfig{Env: []string{"A=1"}}, []string{"A=1"}},
+		{"inherit", ExecConfig{InheritEnv: true}, os.Environ()},
+		{"extend", ExecConfig{Env: []string{"A=1"}, InheritEnv: true}, append(os.Environ(), "A=1")},
+	}
+	for _, tt := range tests {
+		if diff := cmp.Diff(tt.want, environment(&tt.in)); diff != "" {
+			t.Errorf("environment(%s) returned unexpected diff (-want +got):\n%s", tt.desc, diff)
+		}
+	}
+}
+
+func TestWaitFor(t *testing.T) {
+	condErr := errors.New("condition failed")
+	tests := []struct {
+		desc    string
+		results []bool
+		err     error
+		want    error
+	}{
+		{"immediate", []bool{true}, nil, nil},
+		{"eventually", []bool{false, false, true}, nil, nil},
+		{"error", []bool{false}, condErr, condErr},
+		{"timeout", []bool{false}, nil, context.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			calls := 0
+			got := WaitFor(ctx, time.Millisecond, func() (bool, error) {
+				calls++
+				if calls > len(tt.results) {
+					return tt.results[len(tt.results)-1], tt.err
+				}
+				return tt.results[calls-1], tt.err
+			})
+			if !errors.Is(got, tt.want) {
+				t.Errorf("WaitFor() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestWaitForProcessExit(t *testing.T) {
 	tests := []struct {
 		match   string
-		plists  [][]so.Process
+		plists  [][]process.Process
 		timeout time.Duration
 		want    error
 	}{
 		// not running
-		{"proc1", [][]so.Process{
-			[]so.Process{
-				so.Process{Executable: "proc2"},
-				so.Process{Executable: "otherproc"},
+		{"proc1", [][]process.Process{
+			[]process.Process{
+				process.Process{Executable: "proc2"},
+				process.Process{Executable: "otherproc"},
 			},
-			[]so.Process{
-				so.Process{Executable: "proc2"},
-				so.Process{Executable: "otherproc"},
+			[]process.Process{
+				process.Process{Executable: "proc2"},
+				process.Process{Executable: "otherproc"},
 			},
 		}, 20 * time.Second, nil},
 		// stops within timeout
-		{"proc2", [][]so.Process{
-			[]so.Process{
-				so.Process{Executable: "otherproc"},
-				so.Process{Executable: "proc2"},
+		{"proc2", [][]process.Process{
+			[]process.Process{
+				process.Process{Executable: "otherproc"},
+				process.Process{Executable: "proc2"},
 			},
-			[]so.Process{
-				so.Process{Executable: "otherproc"},
-				so.Process{Executable: "proc1"},
+			[]process.Process{
+				process.Process{Executable: "otherproc"},
+				process.Process{Executable: "proc1"},
 			},
 		}, 20 * time.Second, nil},
 		// never stops
-		{"proc2", [][]so.Process{
-			[]so.Process{
-				so.Process{Executable: "proc2"},
-				so.Process{Executable: "otherproc"},
+		{"proc2", [][]process.Process{
+			[]process.Process{
+				process.Process{Executable: "proc2"},
+				process.Process{Executable: "otherproc"},
 			},
-			[]so.Process{
-				so.Process{Executable: "proc2"},
-				so.Process{Executable: "otherproc"},
+			[]process.Process{
+				process.Process{Executable: "proc2"},
+				process.Process{Executable: "otherproc"},
 			},
-			[]so.Process{
-				so.Process{Executable: "proc2"},
-				so.Process{Executable: "otherproc"},
+			[]process.Process{
+				process.Process{Executable: "proc2"},
+				process.Process{Executable: "otherproc"},
 			},
-			[]so.Process{
-				so.Process{Executable: "proc2"},
-				so.Process{Executable: "otherproc"},
+			[]process.Process{
+				process.Process{Executable: "proc2"},
+				process.Process{Executable: "otherproc"},
 			},
 		}, 15 * time.Second, ErrTimeout},
 	}
 	for i, tt := range tests {
 		t.Run(fmt.Sprintf("Test%d", i), func(t *testing.T) {
 			ci := 0
-			fnProcessList = func() ([]so.Process, error) {
+			fnProcessList = func() ([]process.Process, error) {
 				if ci >= len(tt.plists) {
 					t.Errorf("ran out of return values...")
+					return tt.plists[len(tt.plists)-1], nil
 				}
-				return tt.plists[ci], nil
+				ci++
+				return tt.plists[ci-1], nil
 			}
-			ci++
 			re := regexp.MustCompile(tt.match)
 			got := WaitForProcessExit(re, tt.timeout)
 			if !errors.Is(got, tt.want) {
@@ -262,3 +315,26 @@ func TestStringToMap(t *testing.T) {
 		}
 	}
 }
+
+func TestExecReadError(t *testing.T) {
+	errDecode := errors.New("decode failed")
+	fnDecode = func([]byte, OutputEncoding) ([]byte, error) {
+		return nil, errDecode
+	}
+	defer func() { fnDecode = decode }()
+
+	timeout := time.Hour
+	start := time.Now()
+	_, err := Exec(`C:\Windows\System32\PING.EXE`, []string{"-n", "30", "127.0.0.1"}, &ExecConfig{
+		Timeout:      &timeout,
+		KillTree:     true,
+		OnStdoutLine: func(string) {},
+		Verifier:     NewExecVerifier(),
+	})
+	if !errors.Is(err, errDecode) {
+		t.Errorf("Exec() returned %v, want %v", err, errDecode)
+	}
+	if elapsed := time.Since(start); elapsed > 15*time.Second {
+		t.Errorf("Exec() returned after %v; the process was not stopped on a read error", elapsed)
+	}
+}
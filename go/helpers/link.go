@@ -0,0 +1,150 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/google/glazier/go/internal/privilege"
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// Allows creating symbolic links without SeCreateSymbolicLinkPrivilege in developer mode.
+	symbolicLinkFlagAllowUnprivilegedCreate = 0x2
+)
+
+// junctionBuffer builds the REPARSE_DATA_BUFFER for a junction to the absolute path target.
+//
+// https://docs.microsoft.com/en-us/windows-hardware/drivers/ddi/ntifs/ns-ntifs-_reparse_data_buffer
+func junctionBuffer(target string) []byte {
+	sub := utf16.Encode([]rune(`\??\` + target))
+	printName := utf16.Encode([]rune(target))
+	// Both names are NUL terminated; the lengths exclude the terminators.
+	pathBuf := make([]uint16, 0, len(sub)+len(printName)+2)
+	pathBuf = append(append(pathBuf, sub...), 0)
+	pathBuf = append(append(pathBuf, printName...), 0)
+
+	b := make([]byte, 16+2*len(pathBuf))
+	binary.LittleEndian.PutUint32(b[0:], windows.IO_REPARSE_TAG_MOUNT_POINT)
+	binary.LittleEndian.PutUint16(b[4:], uint16(8+2*len(pathBuf)))
+	binary.LittleEndian.PutUint16(b[8:], 0)
+	binary.LittleEndian.PutUint16(b[10:], uint16(2*len(sub)))
+	binary.LittleEndian.PutUint16(b[12:], uint16(2*(len(sub)+1)))
+	binary.LittleEndian.PutUint16(b[14:], uint16(2*len(printName)))
+	for i, u := range pathBuf {
+		binary.LittleEndian.PutUint16(b[16+2*i:], u)
+	}
+	return b
+}
+
+// CreateJunction creates a directory junction at link which points to the directory
+// target. Unlike symbolic links, junctions need no privileges, but they can only point to
+// local directories.
+//
+// Example: helpers.CreateJunction(`D:\ProgramData`, `C:\ProgramData\Redirected`)
+func CreateJunction(target, link string) error {
+	abs, err := filepath.Abs(target)
+	if err != nil {
+		return err
+	}
+	if err := os.Mkdir(link, 0755); err != nil {
+		return err
+	}
+	buf := junctionBuffer(abs)
+	if err := setReparsePoint(link, buf); err != nil {
+		os.Remove(link)
+		return fmt.Errorf("creating junction %s: %w", link, err)
+	}
+	return nil
+}
+
+func setReparsePoint(path string, buf []byte) error {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	h, err := windows.CreateFile(p, windows.GENERIC_WRITE, 0, nil, windows.OPEN_EXISTING,
+		windows.FILE_FLAG_OPEN_REPARSE_POINT|windows.FILE_FLAG_BACKUP_SEMANTICS, 0)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+	var returned uint32
+	return windows.DeviceIoControl(h, windows.FSCTL_SET_REPARSE_POINT, &buf[0], uint32(len(buf)), nil, 0, &returned, nil)
+}
+
+// CreateSymlink creates a symbolic link at link which points to target.
+//
+// SeCreateSymbolicLinkPrivilege is enabled if the process holds it (administrators do);
+// otherwise creation only succeeds if Windows permits unprivileged symbolic links (eg in
+// developer mode).
+func CreateSymlink(target, link string) error {
+	// Not holding the privilege is not fatal; creation may still be permitted.
+	if err := privilege.Enable("SeCreateSymbolicLinkPrivilege"); err != nil && !errors.Is(err, privilege.ErrNotHeld) {
+		return err
+	}
+
+	var flags uint32
+	resolved := target
+	if !filepath.IsAbs(target) {
+		resolved = filepath.Join(filepath.Dir(link), target)
+	}
+	if fi, err := os.Stat(resolved); err == nil && fi.IsDir() {
+		flags |= windows.SYMBOLIC_LINK_FLAG_DIRECTORY
+	}
+	t, err := windows.UTF16PtrFromString(target)
+	if err != nil {
+		return err
+	}
+	l, err := windows.UTF16PtrFromString(link)
+	if err != nil {
+		return err
+	}
+	err = windows.CreateSymbolicLink(l, t, flags|symbolicLinkFlagAllowUnprivilegedCreate)
+	if errors.Is(err, windows.ERROR_INVALID_PARAMETER) {
+		// Windows versions before 10 1703 reject the unprivileged flag.
+		err = windows.CreateSymbolicLink(l, t, flags)
+	}
+	if err != nil {
+		return fmt.Errorf("creating symbolic link %s: %w", link, err)
+	}
+	return nil
+}
+
+// CreateHardLink creates a hard link at link to the file target, on the same volume.
+func CreateHardLink(target, link string) error {
+	return os.Link(target, link)
+}
+
+// ReadLink returns the absolute target of a symbolic link or junction. Relative symbolic
+// link targets are resolved against the directory containing the link.
+func ReadLink(path string) (string, error) {
+	target, err := os.Readlink(path)
+	if err != nil {
+		return "", err
+	}
+	target = strings.TrimPrefix(target, `\\?\`)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	return target, nil
+}
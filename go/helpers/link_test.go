@@ -0,0 +1,62 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestJunctionBuffer(t *testing.T) {
+	want := []byte{
+		0x03, 0x00, 0x00, 0xA0, // IO_REPARSE_TAG_MOUNT_POINT
+		0x20, 0x00, 0x00, 0x00, // data length, reserved
+		0x00, 0x00, 0x0E, 0x00, // substitute name offset, length
+		0x10, 0x00, 0x06, 0x00, // print name offset, length
+		'\\', 0, '?', 0, '?', 0, '\\', 0, 'C', 0, ':', 0, 'x', 0, 0, 0,
+		'C', 0, ':', 0, 'x', 0, 0, 0,
+	}
+	if diff := cmp.Diff(want, junctionBuffer(`C:x`)); diff != "" {
+		t.Errorf("junctionBuffer() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestCreateJunction(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "target")
+	if err := os.Mkdir(target, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(target, "file.txt"), []byte("data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link")
+	if err := CreateJunction(target, link); err != nil {
+		t.Fatalf("CreateJunction() returned unexpected error %v", err)
+	}
+	got, err := ReadLink(link)
+	if err != nil {
+		t.Fatalf("ReadLink() returned unexpected error %v", err)
+	}
+	if got != target {
+		t.Errorf("ReadLink() = %q, want %q", got, target)
+	}
+	if b, err := os.ReadFile(filepath.Join(link, "file.txt")); err != nil || string(b) != "data" {
+		t.Errorf("reading through junction = %q, %v, want %q", b, err, "data")
+	}
+}
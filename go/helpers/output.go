@@ -0,0 +1,94 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+)
+
+var (
+	// TestHelpers
+	fnDecode = decode
+)
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	max int
+	buf []byte
+}
+
+func (t *tailBuffer) Write(b []byte) (int, error) {
+	t.buf = append(t.buf, b...)
+	// Trim only once the excess is large, so that trimming is amortized over many writes.
+	if len(t.buf) > 2*t.max {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.max:]...)
+	}
+	return len(b), nil
+}
+
+func (t *tailBuffer) Bytes() []byte {
+	if len(t.buf) > t.max {
+		return t.buf[len(t.buf)-t.max:]
+	}
+	return t.buf
+}
+
+// readOutput reads r until EOF and returns what was read, transcoded from enc to UTF-8.
+// If onLine is set, it is called with each line as soon as the line is complete, without
+// its line terminator. A lone carriage return also completes a line, so that progress
+// redrawn in place is reported as it happens. If max is positive, only the last max bytes are returned.
+func readOutput(r io.Reader, onLine func(string), enc OutputEncoding, max int) ([]byte, error) {
+	var out interface {
+		io.Writer
+		Bytes() []byte
+	} = &bytes.Buffer{}
+	if max > 0 {
+		out = &tailBuffer{max: max}
+	}
+	switch {
+	case onLine == nil && enc == EncodingUTF8:
+		_, err := io.Copy(out, r)
+		return out.Bytes(), err
+	case onLine == nil && max <= 0:
+		b, err := ioutil.ReadAll(r)
+		if err != nil {
+			return b, err
+		}
+		return fnDecode(b, enc)
+	}
+	// Decode line by line, so that multi-byte characters are never split.
+	lr := newLineReader(r, enc)
+	for {
+		raw, isLine, err := lr.next()
+		if len(raw) > 0 {
+			line, derr := fnDecode(raw, enc)
+			if derr != nil {
+				return out.Bytes(), derr
+			}
+			out.Write(line)
+			if onLine != nil && isLine {
+				onLine(string(bytes.TrimRight(line, "\r\n")))
+			}
+		}
+		if err == io.EOF {
+			return out.Bytes(), nil
+		}
+		if err != nil {
+			return out.Bytes(), err
+		}
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestReadOutput(t *testing.T) {
+	tests := []struct {
+		desc      string
+		in        string
+		wantLines []string
+	}{
+		{"empty", "", []string{}},
+		{"crlf", "one\r\ntwo\r\n", []string{"one", "two"}},
+		{"no trailing newline", "one\ntwo", []string{"one", "two"}},
+		{"blank lines", "one\n\nthree\n", []string{"one", "", "three"}},
+		{"blank crlf lines", "one\r\n\r\nthree\r\n", []string{"one", "", "three"}},
+		{"progress", "[==   10.0%   ]\r[=====50.0%   ]\r[====100.0%===]\r\nDone.\r\n",
+			[]string{"[==   10.0%   ]", "[=====50.0%   ]", "[====100.0%===]", "Done."}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			lines := []string{}
+			got, err := readOutput(strings.NewReader(tt.in), func(l string) { lines = append(lines, l) }, EncodingUTF8, 0)
+			if err != nil {
+				t.Fatalf("readOutput() returned unexpected error %v", err)
+			}
+			if string(got) != tt.in {
+				t.Errorf("readOutput() = %q, want %q", got, tt.in)
+			}
+			if diff := cmp.Diff(tt.wantLines, lines); diff != "" {
+				t.Errorf("readOutput() delivered unexpected lines (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func utf16le(s string) string {
+	b := []byte{}
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u), byte(u>>8))
+	}
+	return string(b)
+}
+
+func TestReadOutputUTF16(t *testing.T) {
+	// U+0A0A encodes as 0A 0A, which must not be taken for a line feed.
+	in := "\xff\xfe" + utf16le("caf\u00e9\r\n\u0a0a line\r\n50%\r100%\r\nend")
+	lines := []string{}
+	got, err := readOutput(strings.NewReader(in), func(l string) { lines = append(lines, l) }, EncodingUTF16, 0)
+	if err != nil {
+		t.Fatalf("readOutput() returned unexpected error %v", err)
+	}
+	if want := "caf\u00e9\r\n\u0a0a line\r\n50%\r100%\r\nend"; string(got) != want {
+		t.Errorf("readOutput() = %q, want %q", got, want)
+	}
+	if diff := cmp.Diff([]string{"caf\u00e9", "\u0a0a line", "50%", "100%", "end"}, lines); diff != "" {
+		t.Errorf("readOutput() delivered unexpected lines (-want +got):\n%s", diff)
+	}
+}
+
+func TestDecodeCodePage(t *testing.T) {
+	got, err := decodeCodePage([]byte("caf\x82"), 437)
+	if err != nil {
+		t.Fatalf("decodeCodePage() returned unexpected error %v", err)
+	}
+	if want := "caf\u00e9"; string(got) != want {
+		t.Errorf("decodeCodePage() = %q, want %q", got, want)
+	}
+}
+
+func TestReadOutputMax(t *testing.T) {
+	in := strings.Repeat("0123456789\n", 100)
+	tests := []struct {
+		desc   string
+		onLine func(string)
+		enc    OutputEncoding
+		max    int
+		want   string
+	}{
+		{"unlimited", nil, EncodingUTF8, 0, in},
+		{"tail", nil, EncodingUTF8, 15, "789\n0123456789\n"},
+		{"tail with lines", func(string) {}, EncodingUTF8, 15, "789\n0123456789\n"},
+		{"larger than output", nil, EncodingUTF8, 5000, in},
+	}
+	for _, tt := range tests {
+		got, err := readOutput(strings.NewReader(in), tt.onLine, tt.enc, tt.max)
+		if err != nil {
+			t.Errorf("readOutput(%s) returned unexpected error %v", tt.desc, err)
+		}
+		if string(got) != tt.want {
+			t.Errorf("readOutput(%s) = %q, want %q", tt.desc, got, tt.want)
+		}
+	}
+}
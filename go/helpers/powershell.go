@@ -0,0 +1,115 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+var (
+	// ErrPowerShell indicates a PowerShell script failed or wrote to its error stream.
+	ErrPowerShell = errors.New("powershell script failed")
+
+	// PowerShellTimeout bounds the run time of RunPowerShell scripts.
+	PowerShellTimeout = 30 * time.Minute
+)
+
+// psQuote quotes s as a PowerShell string literal.
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// psCommand builds the script run by RunPowerShell: errors are made terminating, so the
+// script fails as a whole, and output is converted to JSON. If list is set, the output is
+// always converted as an array, as ConvertTo-Json otherwise renders a single object bare.
+func psCommand(script string, args []string, list bool) string {
+	invoke := "& { " + script + " }"
+	if strings.EqualFold(filepath.Ext(script), ".ps1") {
+		invoke = "& " + psQuote(script)
+	}
+	for _, a := range args {
+		invoke += " " + psQuote(a)
+	}
+	convert := invoke + " | ConvertTo-Json -Depth 8 -Compress"
+	if list {
+		convert = "ConvertTo-Json -InputObject @(" + invoke + ") -Depth 8 -Compress"
+	}
+	return "$ErrorActionPreference = 'Stop'\n" +
+		"$ProgressPreference = 'SilentlyContinue'\n" +
+		convert
+}
+
+// isSlice reports whether out points to a slice.
+func isSlice(out interface{}) bool {
+	t := reflect.TypeOf(out)
+	return t != nil && t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Slice
+}
+
+// encodeCommand encodes a script for powershell.exe -EncodedCommand, which avoids any
+// quoting of the script on the command line.
+func encodeCommand(script string) string {
+	var b bytes.Buffer
+	for _, u := range utf16.Encode([]rune(script)) {
+		b.WriteByte(byte(u))
+		b.WriteByte(byte(u >> 8))
+	}
+	return base64.StdEncoding.EncodeToString(b.Bytes())
+}
+
+// RunPowerShell runs a PowerShell script and unmarshals its output into out.
+//
+// script is either the path to a .ps1 file or a script block's contents, and args are
+// passed to it as string arguments. The script's output objects are converted with
+// ConvertTo-Json, so out should be a struct (or slice of structs, for multiple objects)
+// with fields named after the object properties. A slice receives every output object,
+// even if there is only one. If out is nil or the script produces no output, nothing is
+// unmarshalled.
+//
+// Any error record, non-zero exit code or timeout fails the call with ErrPowerShell. The
+// result is returned either way, for access to the exit code and error stream.
+//
+// Example: helpers.RunPowerShell("Get-Service -Name $args[0]", []string{"W32Time"}, &svc)
+func RunPowerShell(script string, args []string, out interface{}) (ExecResult, error) {
+	timeout := PowerShellTimeout
+	conf := &ExecConfig{
+		Timeout: &timeout,
+		Verifier: &ExecVerifier{
+			SuccessCodes: []int{0},
+			StdErrMatch:  regexp.MustCompile(`\S`),
+		},
+	}
+	psArgs := []string{"-NoProfile", "-NoLogo", "-NonInteractive", "-EncodedCommand", encodeCommand(psCommand(script, args, isSlice(out)))}
+	res, err := fnExec(PsPath, psArgs, conf)
+	if err != nil {
+		return res, fmt.Errorf("%w: %v: %s", ErrPowerShell, err, strings.TrimSpace(string(res.Stderr)))
+	}
+	if out == nil || len(bytes.TrimSpace(res.Stdout)) == 0 {
+		return res, nil
+	}
+	if err := json.Unmarshal(res.Stdout, out); err != nil {
+		return res, fmt.Errorf("unmarshalling powershell output: %w", err)
+	}
+	return res, nil
+}
@@ -0,0 +1,136 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPSCommand(t *testing.T) {
+	tests := []struct {
+		script string
+		args   []string
+		list   bool
+		want   string
+	}{
+		{
+			"Get-Service -Name $args[0]",
+			[]string{"W32Time"},
+			false,
+			"$ErrorActionPreference = 'Stop'\n$ProgressPreference = 'SilentlyContinue'\n& { Get-Service -Name $args[0] } 'W32Time' | ConvertTo-Json -Depth 8 -Compress",
+		},
+		{
+			`C:\scripts\it's.ps1`,
+			[]string{"a b", "c"},
+			false,
+			"$ErrorActionPreference = 'Stop'\n$ProgressPreference = 'SilentlyContinue'\n& 'C:\\scripts\\it''s.ps1' 'a b' 'c' | ConvertTo-Json -Depth 8 -Compress",
+		},
+		{
+			"Get-Service",
+			nil,
+			true,
+			"$ErrorActionPreference = 'Stop'\n$ProgressPreference = 'SilentlyContinue'\nConvertTo-Json -InputObject @(& { Get-Service }) -Depth 8 -Compress",
+		},
+	}
+	for _, tt := range tests {
+		if got := psCommand(tt.script, tt.args, tt.list); got != tt.want {
+			t.Errorf("psCommand(%q, %v, %t) = %q, want %q", tt.script, tt.args, tt.list, got, tt.want)
+		}
+	}
+}
+
+func TestEncodeCommand(t *testing.T) {
+	got, err := base64.StdEncoding.DecodeString(encodeCommand("dir"))
+	if err != nil {
+		t.Fatalf("encodeCommand() produced invalid base64: %v", err)
+	}
+	if want := []byte{'d', 0, 'i', 0, 'r', 0}; !cmp.Equal(got, want) {
+		t.Errorf("encodeCommand(dir) decoded to %v, want %v", got, want)
+	}
+}
+
+func TestRunPowerShell(t *testing.T) {
+	type service struct {
+		Name   string
+		Status int
+	}
+	execErr := errors.New("exit status 1")
+	tests := []struct {
+		desc    string
+		res     ExecResult
+		err     error
+		want    service
+		wantErr error
+	}{
+		{
+			desc: "success",
+			res:  ExecResult{Stdout: []byte(`{"Name":"W32Time","Status":4}`)},
+			want: service{Name: "W32Time", Status: 4},
+		},
+		{
+			desc: "no output",
+			res:  ExecResult{Stdout: []byte("\r\n")},
+		},
+		{
+			desc:    "script error",
+			res:     ExecResult{Stderr: []byte("Cannot find any service"), ExitCode: 1},
+			err:     execErr,
+			wantErr: ErrPowerShell,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			fnExec = func(path string, args []string, conf *ExecConfig) (ExecResult, error) {
+				if path != PsPath {
+					t.Errorf("RunPowerShell() executed %q, want %q", path, PsPath)
+				}
+				return tt.res, tt.err
+			}
+			got := service{}
+			_, err := RunPowerShell("Get-Service -Name $args[0]", []string{"W32Time"}, &got)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("RunPowerShell() returned unexpected error %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("RunPowerShell() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+	fnExec = executeWithTelemetry
+}
+
+func TestRunPowerShellList(t *testing.T) {
+	type service struct {
+		Name string
+	}
+	fnExec = func(path string, args []string, conf *ExecConfig) (ExecResult, error) {
+		if want := encodeCommand(psCommand("Get-Service", nil, true)); args[len(args)-1] != want {
+			t.Errorf("RunPowerShell() did not convert output as a list")
+		}
+		return ExecResult{Stdout: []byte(`[{"Name":"W32Time"}]`)}, nil
+	}
+	defer func() { fnExec = executeWithTelemetry }()
+	got := []service{}
+	if _, err := RunPowerShell("Get-Service", nil, &got); err != nil {
+		t.Fatalf("RunPowerShell() returned unexpected error %v", err)
+	}
+	if diff := cmp.Diff([]service{{Name: "W32Time"}}, got); diff != "" {
+		t.Errorf("RunPowerShell() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
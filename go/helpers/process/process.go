@@ -0,0 +1,189 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package process enumerates and terminates processes using the native Windows APIs.
+package process
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	// ErrNotFound indicates that no process matched the request.
+	ErrNotFound = errors.New("process not found")
+
+	// TestHelpers
+	fnList = List
+	fnKill = Kill
+)
+
+// Process describes a running process.
+type Process struct {
+	PID        uint32
+	ParentPID  uint32
+	Executable string
+	Threads    uint32
+	// Created is when the process started, or the zero time if it could not be queried.
+	Created time.Time
+}
+
+// List returns a snapshot of the processes currently running on the system.
+func List() ([]Process, error) {
+	snap, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, fmt.Errorf("CreateToolhelp32Snapshot: %w", err)
+	}
+	defer windows.CloseHandle(snap)
+
+	var e windows.ProcessEntry32
+	e.Size = uint32(unsafe.Sizeof(e))
+	if err := windows.Process32First(snap, &e); err != nil {
+		return nil, fmt.Errorf("Process32First: %w", err)
+	}
+	procs := []Process{}
+	for {
+		procs = append(procs, Process{
+			PID:        e.ProcessID,
+			ParentPID:  e.ParentProcessID,
+			Executable: windows.UTF16ToString(e.ExeFile[:]),
+			Threads:    e.Threads,
+			Created:    creationTime(e.ProcessID),
+		})
+		err := windows.Process32Next(snap, &e)
+		if errors.Is(err, windows.ERROR_NO_MORE_FILES) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Process32Next: %w", err)
+		}
+	}
+	return procs, nil
+}
+
+// creationTime returns when the process identified by pid started, or the zero time if
+// the process cannot be opened.
+func creationTime(pid uint32) time.Time {
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return time.Time{}
+	}
+	defer windows.CloseHandle(h)
+	var created, exited, kernel, user windows.Filetime
+	if err := windows.GetProcessTimes(h, &created, &exited, &kernel, &user); err != nil {
+		return time.Time{}
+	}
+	return time.Unix(0, created.Nanoseconds())
+}
+
+// FindByName returns all running processes whose executable matches name. The comparison
+// is case insensitive and the .exe extension is optional.
+//
+// Example: process.FindByName("msiexec")
+func FindByName(name string) ([]Process, error) {
+	procs, err := fnList()
+	if err != nil {
+		return nil, err
+	}
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".exe") {
+		name += ".exe"
+	}
+	found := []Process{}
+	for _, p := range procs {
+		if strings.ToLower(p.Executable) == name {
+			found = append(found, p)
+		}
+	}
+	return found, nil
+}
+
+// Kill terminates the process identified by pid.
+func Kill(pid uint32) error {
+	h, err := windows.OpenProcess(windows.PROCESS_TERMINATE, false, pid)
+	if err != nil {
+		if errors.Is(err, windows.ERROR_INVALID_PARAMETER) {
+			return fmt.Errorf("%w: pid %d", ErrNotFound, pid)
+		}
+		return fmt.Errorf("OpenProcess(%d): %w", pid, err)
+	}
+	defer windows.CloseHandle(h)
+	if err := windows.TerminateProcess(h, 1); err != nil {
+		return fmt.Errorf("TerminateProcess(%d): %w", pid, err)
+	}
+	return nil
+}
+
+// descendants returns the children of pid, recursively, with the deepest descendants first.
+func descendants(procs []Process, pid uint32) []uint32 {
+	children := map[uint32][]uint32{}
+	created := map[uint32]time.Time{}
+	for _, p := range procs {
+		// The System Idle Process is its own parent.
+		if p.PID != p.ParentPID {
+			children[p.ParentPID] = append(children[p.ParentPID], p.PID)
+		}
+		created[p.PID] = p.Created
+	}
+	// Parent PIDs are not cleared when a parent exits, so a reused PID can make the tree
+	// appear cyclic, and can claim processes that were started by the PID's previous owner.
+	// Those are recognized by having started before their supposed parent.
+	seen := map[uint32]bool{pid: true}
+	var out []uint32
+	var walk func(uint32)
+	walk = func(parent uint32) {
+		for _, c := range children[parent] {
+			if seen[c] {
+				continue
+			}
+			if pc, cc := created[parent], created[c]; !pc.IsZero() && !cc.IsZero() && cc.Before(pc) {
+				continue
+			}
+			seen[c] = true
+			walk(c)
+			out = append(out, c)
+		}
+	}
+	walk(pid)
+	return out
+}
+
+// KillTree terminates the process identified by pid along with all of its descendants.
+// Descendants are terminated first so that they cannot be orphaned mid way.
+//
+// Processes that exit on their own while the tree is being terminated are ignored.
+func KillTree(pid uint32) error {
+	procs, err := fnList()
+	if err != nil {
+		return err
+	}
+	failed := []string{}
+	for _, c := range descendants(procs, pid) {
+		if err := fnKill(c); err != nil && !errors.Is(err, ErrNotFound) {
+			failed = append(failed, err.Error())
+		}
+	}
+	if err := fnKill(pid); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("terminating descendants of %d: %s", pid, strings.Join(failed, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package process
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var started = time.Date(2021, 11, 1, 8, 0, 0, 0, time.UTC)
+
+var testProcs = []Process{
+	{PID: 0, ParentPID: 0, Executable: "[System Process]"},
+	{PID: 4, ParentPID: 0, Executable: "System"},
+	{PID: 100, ParentPID: 4, Executable: "setup.exe"},
+	{PID: 101, ParentPID: 100, Executable: "msiexec.exe"},
+	{PID: 102, ParentPID: 101, Executable: "MSIEXEC.EXE"},
+	{PID: 103, ParentPID: 100, Executable: "conhost.exe"},
+	{PID: 200, ParentPID: 4, Executable: "explorer.exe"},
+	// reused parent pid pointing back into the tree
+	{PID: 300, ParentPID: 300, Executable: "loop.exe"},
+	// 400 reused the pid of the exited parent of 401
+	{PID: 400, ParentPID: 4, Executable: "installer.exe", Created: started.Add(time.Minute)},
+	{PID: 401, ParentPID: 400, Executable: "svchost.exe", Created: started},
+	{PID: 402, ParentPID: 400, Executable: "msiexec.exe", Created: started.Add(2 * time.Minute)},
+}
+
+func TestFindByName(t *testing.T) {
+	fnList = func() ([]Process, error) { return testProcs, nil }
+	defer func() { fnList = List }()
+	tests := []struct {
+		name string
+		want []uint32
+	}{
+		{"msiexec", []uint32{101, 102, 402}},
+		{"Explorer.exe", []uint32{200}},
+		{"notepad", []uint32{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FindByName(tt.name)
+			if err != nil {
+				t.Fatalf("FindByName(%q) returned %v", tt.name, err)
+			}
+			pids := []uint32{}
+			for _, p := range got {
+				pids = append(pids, p.PID)
+			}
+			if diff := cmp.Diff(tt.want, pids); diff != "" {
+				t.Errorf("FindByName(%q) returned unexpected diff (-want +got):\n%s", tt.name, diff)
+			}
+		})
+	}
+}
+
+func TestKillTree(t *testing.T) {
+	errKill := errors.New("access denied")
+	tests := []struct {
+		pid     uint32
+		fail    map[uint32]error
+		want    []uint32
+		wantErr bool
+	}{
+		{100, nil, []uint32{102, 101, 103, 100}, false},
+		{200, nil, []uint32{200}, false},
+		{300, nil, []uint32{300}, false},
+		{400, nil, []uint32{402, 400}, false},
+		{100, map[uint32]error{101: ErrNotFound}, []uint32{102, 101, 103, 100}, false},
+		{100, map[uint32]error{103: errKill}, []uint32{102, 101, 103, 100}, true},
+		{100, map[uint32]error{100: errKill}, []uint32{102, 101, 103, 100}, true},
+	}
+	fnList = func() ([]Process, error) { return testProcs, nil }
+	defer func() {
+		fnList = List
+		fnKill = Kill
+	}()
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("Test%d", i), func(t *testing.T) {
+			killed := []uint32{}
+			fnKill = func(pid uint32) error {
+				killed = append(killed, pid)
+				return tt.fail[pid]
+			}
+			err := KillTree(tt.pid)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("KillTree(%d) returned %v, want error %t", tt.pid, err, tt.wantErr)
+			}
+			if diff := cmp.Diff(tt.want, killed); diff != "" {
+				t.Errorf("KillTree(%d) killed unexpected processes (-want +got):\n%s", tt.pid, diff)
+			}
+		})
+	}
+}
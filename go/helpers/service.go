@@ -0,0 +1,92 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// InstallService registers a new service which runs path with args.
+//
+// conf sets the start type, account (ServiceStartName and Password; LocalSystem if empty),
+// display name, description and dependencies. A zero StartType means mgr.StartAutomatic.
+//
+// Example: helpers.InstallService("agent", `C:\Program Files\Agent\agent.exe`, []string{"-service"}, mgr.Config{DisplayName: "Agent"})
+func InstallService(name, path string, args []string, conf mgr.Config) error {
+	if conf.StartType == 0 {
+		conf.StartType = mgr.StartAutomatic
+	}
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	s, err := m.CreateService(name, path, conf, args...)
+	if err != nil {
+		return fmt.Errorf("creating service %s: %w", name, err)
+	}
+	return s.Close()
+}
+
+// DeleteService stops a service if it is running and removes it.
+//
+// The service is removed once all handles to it are closed, which may be after this
+// returns.
+func DeleteService(name string) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("could not access service: %v", err)
+	}
+	defer s.Close()
+
+	status, err := s.Query()
+	if err != nil {
+		return err
+	}
+	if status.State != svc.Stopped {
+		if err := stopService(s); err != nil {
+			return err
+		}
+	}
+	return s.Delete()
+}
+
+// SetRecoveryActions configures what the service controller does when a service fails,
+// eg restarting it. The failure count is reset after resetPeriod without failures.
+//
+// Example: helpers.SetRecoveryActions("agent", []mgr.RecoveryAction{{Type: mgr.ServiceRestart, Delay: time.Minute}}, 24*time.Hour)
+func SetRecoveryActions(name string, actions []mgr.RecoveryAction, resetPeriod time.Duration) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return err
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService(name)
+	if err != nil {
+		return fmt.Errorf("could not access service: %v", err)
+	}
+	defer s.Close()
+
+	return s.SetRecoveryActions(actions, uint32(resetPeriod.Seconds()))
+}
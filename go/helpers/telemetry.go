@@ -0,0 +1,160 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"container/list"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/google/deck"
+)
+
+const (
+	// telemetryOutputLimit is the number of trailing bytes of each output stream kept in an
+	// ExecRecord.
+	telemetryOutputLimit = 2048
+	// maxAttemptIDs is the number of correlation IDs whose attempts are counted. The least
+	// recently used ID is forgotten beyond it, so its next record restarts from attempt 1.
+	maxAttemptIDs = 1024
+)
+
+// Deck attribute keys under which each ExecRecord is emitted, for backends that store
+// structured fields.
+const (
+	// CorrelationIDAttr holds the ExecRecord.CorrelationID string.
+	CorrelationIDAttr = "glazier.exec.correlation_id"
+	// ExecRecordAttr holds the ExecRecord itself.
+	ExecRecordAttr = "glazier.exec.record"
+)
+
+var (
+	// attempts counts the executions recorded under recently used correlation IDs. order
+	// holds an *attemptCount per ID, most recently used first.
+	attempts = struct {
+		sync.Mutex
+		m     map[string]*list.Element
+		order *list.List
+	}{m: map[string]*list.Element{}, order: list.New()}
+
+	// TestHelpers
+	fnEmitRecord = emitRecord
+)
+
+// ExecRecord is the structured telemetry record emitted for an execution when
+// ExecConfig.Telemetry is set.
+type ExecRecord struct {
+	CorrelationID string    `json:"correlation_id"`
+	Command       string    `json:"command"`
+	Args          []string  `json:"args"`
+	Start         time.Time `json:"start"`
+	DurationMS    int64     `json:"duration_ms"`
+	ExitCode      int       `json:"exit_code"`
+	// Attempt numbers the executions sharing a CorrelationID, starting from 1.
+	Attempt int `json:"attempt"`
+	// Stdout and Stderr hold the tail of the captured output.
+	Stdout string `json:"stdout,omitempty"`
+	Stderr string `json:"stderr,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// NewCorrelationID returns a random identifier suitable for ExecConfig.CorrelationID.
+func NewCorrelationID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return time.Now().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(b)
+}
+
+func tail(b []byte, max int) string {
+	if len(b) > max {
+		b = b[len(b)-max:]
+	}
+	return string(b)
+}
+
+type attemptCount struct {
+	id string
+	n  int
+}
+
+// nextAttempt returns the attempt number of the next execution recorded under id.
+func nextAttempt(id string) int {
+	attempts.Lock()
+	defer attempts.Unlock()
+	if e, ok := attempts.m[id]; ok {
+		attempts.order.MoveToFront(e)
+		c := e.Value.(*attemptCount)
+		c.n++
+		return c.n
+	}
+	attempts.m[id] = attempts.order.PushFront(&attemptCount{id: id, n: 1})
+	if attempts.order.Len() > maxAttemptIDs {
+		oldest := attempts.order.Back()
+		attempts.order.Remove(oldest)
+		delete(attempts.m, oldest.Value.(*attemptCount).id)
+	}
+	return 1
+}
+
+func newExecRecord(path string, args []string, conf *ExecConfig, start time.Time, res ExecResult, err error) ExecRecord {
+	r := ExecRecord{
+		CorrelationID: conf.CorrelationID,
+		Command:       path,
+		Args:          args,
+		Start:         start,
+		DurationMS:    time.Since(start).Milliseconds(),
+		ExitCode:      res.ExitCode,
+		Attempt:       1,
+		Stdout:        tail(res.Stdout, telemetryOutputLimit),
+		Stderr:        tail(res.Stderr, telemetryOutputLimit),
+	}
+	if r.CorrelationID == "" {
+		r.CorrelationID = NewCorrelationID()
+	} else {
+		r.Attempt = nextAttempt(r.CorrelationID)
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+// withExecRecord attaches r to a deck message as structured attributes.
+func withExecRecord(r ExecRecord) func(*deck.AttribStore) {
+	return func(a *deck.AttribStore) {
+		a.Store(CorrelationIDAttr, r.CorrelationID)
+		a.Store(ExecRecordAttr, r)
+	}
+}
+
+func emitRecord(r ExecRecord) {
+	deck.InfofA("Executed %s (attempt %d): exit code %d after %dms", r.Command, r.Attempt, r.ExitCode, r.DurationMS).
+		With(withExecRecord(r)).Go()
+}
+
+// executeWithTelemetry runs execute, emitting an ExecRecord when conf requests telemetry.
+func executeWithTelemetry(path string, args []string, conf *ExecConfig) (ExecResult, error) {
+	if conf == nil || !conf.Telemetry {
+		return execute(path, args, conf)
+	}
+	start := time.Now()
+	res, err := execute(path, args, conf)
+	fnEmitRecord(newExecRecord(path, args, conf, start, res, err))
+	return res, err
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestNewExecRecord(t *testing.T) {
+	start := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	long := strings.Repeat("a", telemetryOutputLimit) + "tail"
+	tests := []struct {
+		desc string
+		conf *ExecConfig
+		res  ExecResult
+		err  error
+		want ExecRecord
+	}{
+		{
+			desc: "success",
+			conf: &ExecConfig{CorrelationID: "success"},
+			res:  ExecResult{Stdout: []byte("done"), ExitCode: 0},
+			want: ExecRecord{CorrelationID: "success", Command: "setup.exe", Args: []string{"/q"}, Start: start, Attempt: 1, Stdout: "done"},
+		},
+		{
+			desc: "truncated failure",
+			conf: &ExecConfig{CorrelationID: "failure"},
+			res:  ExecResult{Stderr: []byte(long), ExitCode: 1603},
+			err:  ErrExitCode,
+			want: ExecRecord{CorrelationID: "failure", Command: "setup.exe", Args: []string{"/q"}, Start: start, Attempt: 1, ExitCode: 1603, Stderr: long[4:], Error: ErrExitCode.Error()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			got := newExecRecord("setup.exe", []string{"/q"}, tt.conf, start, tt.res, tt.err)
+			got.DurationMS = 0
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("newExecRecord() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestNewExecRecordCorrelationID(t *testing.T) {
+	a := newExecRecord("setup.exe", nil, &ExecConfig{}, time.Now(), ExecResult{}, nil)
+	b := newExecRecord("setup.exe", nil, &ExecConfig{}, time.Now(), ExecResult{}, errors.New("failed"))
+	if a.CorrelationID == "" || a.CorrelationID == b.CorrelationID {
+		t.Errorf("newExecRecord() generated correlation IDs %q and %q, want distinct non-empty IDs", a.CorrelationID, b.CorrelationID)
+	}
+}
+
+func TestNewExecRecordAttempt(t *testing.T) {
+	conf := &ExecConfig{CorrelationID: "retried"}
+	for want := 1; want <= 3; want++ {
+		r := newExecRecord("setup.exe", nil, conf, time.Now(), ExecResult{}, nil)
+		if r.Attempt != want {
+			t.Errorf("newExecRecord() attempt = %d, want %d", r.Attempt, want)
+		}
+	}
+	if r := newExecRecord("setup.exe", nil, &ExecConfig{}, time.Now(), ExecResult{}, nil); r.Attempt != 1 {
+		t.Errorf("newExecRecord() without correlation ID attempt = %d, want 1", r.Attempt)
+	}
+}
+
+func TestNextAttemptBounded(t *testing.T) {
+	nextAttempt("first")
+	nextAttempt("recent")
+	for i := 0; i < maxAttemptIDs-1; i++ {
+		nextAttempt(fmt.Sprintf("filler%d", i))
+		// Keep "recent" from becoming the least recently used ID.
+		if i == maxAttemptIDs/2 {
+			nextAttempt("recent")
+		}
+	}
+	if got := len(attempts.m); got > maxAttemptIDs {
+		t.Errorf("nextAttempt() tracks %d correlation IDs, want at most %d", got, maxAttemptIDs)
+	}
+	if got := nextAttempt("first"); got != 1 {
+		t.Errorf("nextAttempt(first) after eviction = %d, want 1", got)
+	}
+	if got := nextAttempt("recent"); got != 3 {
+		t.Errorf("nextAttempt(recent) = %d, want 3", got)
+	}
+}
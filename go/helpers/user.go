@@ -0,0 +1,134 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	logon32LogonInteractive = 2
+	logon32ProviderDefault  = 0
+	piNoUI                  = 1
+)
+
+var (
+	procLogonUserW        = windows.NewLazySystemDLL("advapi32.dll").NewProc("LogonUserW")
+	procLoadUserProfileW  = windows.NewLazySystemDLL("userenv.dll").NewProc("LoadUserProfileW")
+	procUnloadUserProfile = windows.NewLazySystemDLL("userenv.dll").NewProc("UnloadUserProfile")
+)
+
+// https://docs.microsoft.com/en-us/windows/win32/api/profinfo/ns-profinfo-profileinfow
+type profileInfo struct {
+	Size        uint32
+	Flags       uint32
+	UserName    *uint16
+	ProfilePath *uint16
+	DefaultPath *uint16
+	ServerName  *uint16
+	PolicyPath  *uint16
+	Profile     windows.Handle
+}
+
+// splitUser splits an account name into the user and domain arguments of LogonUser.
+// Accounts without a domain are treated as local; UPNs are passed through whole.
+func splitUser(username string) (string, string) {
+	if i := strings.Index(username, `\`); i >= 0 {
+		return username[i+1:], username[:i]
+	}
+	if strings.Contains(username, "@") {
+		return username, ""
+	}
+	return username, "."
+}
+
+func logonUser(username, password string) (windows.Token, error) {
+	user, domain := splitUser(username)
+	u, err := windows.UTF16PtrFromString(user)
+	if err != nil {
+		return 0, err
+	}
+	var d *uint16
+	if domain != "" {
+		if d, err = windows.UTF16PtrFromString(domain); err != nil {
+			return 0, err
+		}
+	}
+	p, err := windows.UTF16PtrFromString(password)
+	if err != nil {
+		return 0, err
+	}
+	var token windows.Token
+	r, _, err := procLogonUserW.Call(uintptr(unsafe.Pointer(u)), uintptr(unsafe.Pointer(d)), uintptr(unsafe.Pointer(p)),
+		logon32LogonInteractive, logon32ProviderDefault, uintptr(unsafe.Pointer(&token)))
+	if r == 0 {
+		return 0, fmt.Errorf("LogonUserW(%s): %w", username, err)
+	}
+	return token, nil
+}
+
+// loadUserProfile loads the profile of the token's user, making their registry hive
+// available to processes started with the token.
+func loadUserProfile(token windows.Token, username string) (windows.Handle, error) {
+	user, _ := splitUser(username)
+	u, err := windows.UTF16PtrFromString(user)
+	if err != nil {
+		return 0, err
+	}
+	pi := profileInfo{Flags: piNoUI, UserName: u}
+	pi.Size = uint32(unsafe.Sizeof(pi))
+	r, _, err := procLoadUserProfileW.Call(uintptr(token), uintptr(unsafe.Pointer(&pi)))
+	if r == 0 {
+		return 0, fmt.Errorf("LoadUserProfileW(%s): %w", username, err)
+	}
+	return pi.Profile, nil
+}
+
+func unloadUserProfile(token windows.Token, profile windows.Handle) {
+	procUnloadUserProfile.Call(uintptr(token), uintptr(profile))
+}
+
+// ExecAsUser executes a subprocess as another user and returns the results.
+//
+// The user is logged on interactively and their profile is loaded for the duration of the
+// process, so per-user settings (eg HKCU) are those of the user. The calling process needs
+// the privileges to start processes as another user, which LocalSystem has. To run with an
+// existing token instead, set ExecConfig.Token and use Exec.
+//
+// Example: helpers.ExecAsUser(`CORP\builder`, password, `C:\setup\configure.exe`, nil, nil)
+func ExecAsUser(username, password, path string, args []string, conf *ExecConfig) (ExecResult, error) {
+	token, err := logonUser(username, password)
+	if err != nil {
+		return ExecResult{}, err
+	}
+	defer token.Close()
+	profile, err := loadUserProfile(token, username)
+	if err != nil {
+		return ExecResult{}, err
+	}
+	defer unloadUserProfile(token, profile)
+
+	c := ExecConfig{Verifier: NewExecVerifier()}
+	if conf != nil {
+		c = *conf
+	}
+	c.Token = syscall.Token(token)
+	return fnExec(path, args, &c)
+}
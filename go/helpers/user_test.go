@@ -0,0 +1,35 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package helpers
+
+import "testing"
+
+func TestSplitUser(t *testing.T) {
+	tests := []struct {
+		in         string
+		wantUser   string
+		wantDomain string
+	}{
+		{`CORP\builder`, "builder", "CORP"},
+		{"builder@corp.example.com", "builder@corp.example.com", ""},
+		{"builder", "builder", "."},
+	}
+	for _, tt := range tests {
+		u, d := splitUser(tt.in)
+		if u != tt.wantUser || d != tt.wantDomain {
+			t.Errorf("splitUser(%q) = %q, %q, want %q, %q", tt.in, u, d, tt.wantUser, tt.wantDomain)
+		}
+	}
+}
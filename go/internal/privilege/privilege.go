@@ -0,0 +1,63 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package privilege adjusts the privileges of the process token.
+package privilege
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	// ErrNotHeld indicates that the process token does not hold a requested privilege, so it
+	// cannot be enabled.
+	ErrNotHeld = errors.New("privilege not held")
+)
+
+// Enable enables privileges held by the process token (eg SeRestorePrivilege). Enabling a
+// privilege the token does not hold fails with ErrNotHeld.
+func Enable(names ...string) error {
+	// AdjustTokenPrivileges reports unassigned privileges through the thread's last error.
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	var token windows.Token
+	if err := windows.OpenProcessToken(windows.CurrentProcess(), windows.TOKEN_ADJUST_PRIVILEGES|windows.TOKEN_QUERY, &token); err != nil {
+		return fmt.Errorf("OpenProcessToken: %w", err)
+	}
+	defer token.Close()
+	for _, name := range names {
+		n, err := windows.UTF16PtrFromString(name)
+		if err != nil {
+			return err
+		}
+		var luid windows.LUID
+		if err := windows.LookupPrivilegeValue(nil, n, &luid); err != nil {
+			return fmt.Errorf("LookupPrivilegeValue(%s): %w", name, err)
+		}
+		tp := windows.Tokenprivileges{PrivilegeCount: 1}
+		tp.Privileges[0] = windows.LUIDAndAttributes{Luid: luid, Attributes: windows.SE_PRIVILEGE_ENABLED}
+		if err := windows.AdjustTokenPrivileges(token, false, &tp, 0, nil, nil); err != nil {
+			return fmt.Errorf("AdjustTokenPrivileges(%s): %w", name, err)
+		}
+		if windows.GetLastError() == windows.ERROR_NOT_ALL_ASSIGNED {
+			return fmt.Errorf("%w: %s", ErrNotHeld, name)
+		}
+	}
+	return nil
+}
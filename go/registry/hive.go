@@ -0,0 +1,108 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/google/glazier/go/internal/privilege"
+	"golang.org/x/sys/windows"
+	reg "golang.org/x/sys/windows/registry"
+)
+
+var (
+	// ErrInvalidMountPoint indicates a hive mount point that is not a direct child of
+	// HKEY_LOCAL_MACHINE or HKEY_USERS.
+	ErrInvalidMountPoint = errors.New("invalid hive mount point")
+
+	procRegLoadKeyW   = windows.NewLazySystemDLL("advapi32.dll").NewProc("RegLoadKeyW")
+	procRegUnLoadKeyW = windows.NewLazySystemDLL("advapi32.dll").NewProc("RegUnLoadKeyW")
+)
+
+// mountKey splits mountPoint into the hive and subkey arguments of RegLoadKey.
+func mountKey(mountPoint string) (reg.Key, string, error) {
+	hive, p := splitHive(mountPoint)
+	if hive != reg.LOCAL_MACHINE && hive != reg.USERS {
+		return 0, "", fmt.Errorf("%w: %s is not under HKLM or HKU", ErrInvalidMountPoint, mountPoint)
+	}
+	if p == "" || strings.Contains(p, `\`) {
+		return 0, "", fmt.Errorf("%w: %s must be a direct child of its hive", ErrInvalidMountPoint, mountPoint)
+	}
+	return hive, p, nil
+}
+
+func enableHivePrivileges() error {
+	return privilege.Enable("SeBackupPrivilege", "SeRestorePrivilege")
+}
+
+// LoadHive loads a registry hive file (eg the SOFTWARE hive of an offline image) under
+// mountPoint, which must be a direct child of HKLM or HKU (eg `HKLM\OFFLINE_SOFTWARE`).
+// The loaded hive can then be edited through the rest of this package by prefixing paths
+// with mountPoint.
+//
+// The caller must hold SeBackupPrivilege and SeRestorePrivilege, as administrators do;
+// they are enabled automatically.
+//
+// Example: registry.LoadHive(`HKLM\OFFLINE_SOFTWARE`, `D:\mount\Windows\System32\config\SOFTWARE`)
+func LoadHive(mountPoint, hiveFile string) error {
+	hive, p, err := mountKey(mountPoint)
+	if err != nil {
+		return err
+	}
+	if err := enableHivePrivileges(); err != nil {
+		return err
+	}
+	k, err := windows.UTF16PtrFromString(p)
+	if err != nil {
+		return err
+	}
+	f, err := windows.UTF16PtrFromString(hiveFile)
+	if err != nil {
+		return err
+	}
+	r, _, _ := procRegLoadKeyW.Call(uintptr(hive), uintptr(unsafe.Pointer(k)), uintptr(unsafe.Pointer(f)))
+	if r != 0 {
+		return fmt.Errorf("RegLoadKeyW(%s, %s): %w", mountPoint, hiveFile, syscall.Errno(r))
+	}
+	return nil
+}
+
+// UnloadHive unloads a hive previously loaded with LoadHive, writing any changes back to
+// its file.
+//
+// Unloading fails while any handle to a key within the hive remains open, including
+// handles held by other processes.
+func UnloadHive(mountPoint string) error {
+	hive, p, err := mountKey(mountPoint)
+	if err != nil {
+		return err
+	}
+	if err := enableHivePrivileges(); err != nil {
+		return err
+	}
+	k, err := windows.UTF16PtrFromString(p)
+	if err != nil {
+		return err
+	}
+	r, _, _ := procRegUnLoadKeyW.Call(uintptr(hive), uintptr(unsafe.Pointer(k)))
+	if r != 0 {
+		return fmt.Errorf("RegUnLoadKeyW(%s): %w", mountPoint, syscall.Errno(r))
+	}
+	return nil
+}
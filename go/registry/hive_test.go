@@ -0,0 +1,47 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"errors"
+	"testing"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func TestMountKey(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantHive registry.Key
+		wantPath string
+		err      error
+	}{
+		{`HKLM\OFFLINE_SOFTWARE`, registry.LOCAL_MACHINE, "OFFLINE_SOFTWARE", nil},
+		{`OFFLINE_SYSTEM`, registry.LOCAL_MACHINE, "OFFLINE_SYSTEM", nil},
+		{`HKEY_USERS\OFFLINE_DEFAULT`, registry.USERS, "OFFLINE_DEFAULT", nil},
+		{`HKCU\OFFLINE`, 0, "", ErrInvalidMountPoint},
+		{`HKLM\SOFTWARE\OFFLINE`, 0, "", ErrInvalidMountPoint},
+		{`HKLM`, 0, "", ErrInvalidMountPoint},
+	}
+	for _, tt := range tests {
+		hive, path, err := mountKey(tt.in)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("mountKey(%s) returned unexpected error %v", tt.in, err)
+		}
+		if hive != tt.wantHive || path != tt.wantPath {
+			t.Errorf("mountKey(%s) = (%v, %s), want (%v, %s)", tt.in, hive, path, tt.wantHive, tt.wantPath)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+
+	reg "golang.org/x/sys/windows/registry"
+)
+
+var (
+	// ErrUnsupportedType indicates a struct field that cannot be stored as the requested
+	// registry value type.
+	ErrUnsupportedType = errors.New("unsupported field type")
+
+	valueTypes = map[string]uint32{
+		"sz":        reg.SZ,
+		"expand_sz": reg.EXPAND_SZ,
+		"multi_sz":  reg.MULTI_SZ,
+		"dword":     reg.DWORD,
+		"qword":     reg.QWORD,
+		"binary":    reg.BINARY,
+	}
+)
+
+// field maps a struct field to a registry value.
+type field struct {
+	Index int
+	Name  string
+	Type  uint32
+}
+
+// defaultType returns the registry value type used for t when a tag does not name one.
+func defaultType(t reflect.Type) (uint32, bool) {
+	switch t.Kind() {
+	case reflect.String:
+		return reg.SZ, true
+	case reflect.Bool, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return reg.DWORD, true
+	case reflect.Int, reflect.Int64, reflect.Uint, reflect.Uint64:
+		return reg.QWORD, true
+	case reflect.Slice:
+		switch t.Elem().Kind() {
+		case reflect.String:
+			return reg.MULTI_SZ, true
+		case reflect.Uint8:
+			return reg.BINARY, true
+		}
+	}
+	return 0, false
+}
+
+// compatible reports whether values of type vt can be stored in fields of type t.
+func compatible(t reflect.Type, vt uint32) bool {
+	switch vt {
+	case reg.SZ, reg.EXPAND_SZ:
+		return t.Kind() == reflect.String
+	case reg.DWORD, reg.QWORD:
+		switch t.Kind() {
+		case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return true
+		}
+	case reg.MULTI_SZ:
+		return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.String
+	case reg.BINARY:
+		return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+	}
+	return false
+}
+
+// parseFields reads the registry tags of struct type t.
+//
+// Tags take the form `registry:"ValueName,type"`, where type is one of sz, expand_sz,
+// multi_sz, dword, qword or binary. Either part may be omitted, in which case the field
+// name and a type derived from the field's Go type are used. Fields tagged "-" and
+// unexported fields are skipped.
+func parseFields(t reflect.Type) ([]field, error) {
+	fields := []field{}
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		tag := sf.Tag.Get("registry")
+		if sf.PkgPath != "" || tag == "-" {
+			continue
+		}
+		f := field{Index: i, Name: sf.Name}
+		parts := strings.SplitN(tag, ",", 2)
+		if parts[0] != "" {
+			f.Name = parts[0]
+		}
+		if len(parts) > 1 && parts[1] != "" {
+			vt, ok := valueTypes[strings.ToLower(parts[1])]
+			if !ok {
+				return nil, fmt.Errorf("%w: field %s has unknown value type %q", ErrUnsupportedType, sf.Name, parts[1])
+			}
+			f.Type = vt
+		} else {
+			vt, ok := defaultType(sf.Type)
+			if !ok {
+				return nil, fmt.Errorf("%w: field %s of type %v", ErrUnsupportedType, sf.Name, sf.Type)
+			}
+			f.Type = vt
+		}
+		if !compatible(sf.Type, f.Type) {
+			return nil, fmt.Errorf("%w: field %s of type %v cannot hold %s", ErrUnsupportedType, sf.Name, sf.Type, parts[1])
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func structValue(v interface{}, ptr bool) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	} else if ptr {
+		return rv, fmt.Errorf("%w: %T is not a pointer to a struct", ErrUnsupportedType, v)
+	}
+	if rv.Kind() != reflect.Struct {
+		return rv, fmt.Errorf("%w: %T is not a struct", ErrUnsupportedType, v)
+	}
+	return rv, nil
+}
+
+// integer returns the value of fv as stored in a registry value of type vt. Signed fields
+// are stored as two's complement, so must fit in 32 bits for a DWORD.
+func integer(fv reflect.Value, vt uint32) (uint64, error) {
+	switch fv.Kind() {
+	case reflect.Bool:
+		if fv.Bool() {
+			return 1, nil
+		}
+		return 0, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := fv.Int()
+		if vt == reg.DWORD {
+			if i < math.MinInt32 || i > math.MaxInt32 {
+				return 0, fmt.Errorf("value %d overflows DWORD", i)
+			}
+			return uint64(uint32(int32(i))), nil
+		}
+		return uint64(i), nil
+	}
+	u := fv.Uint()
+	if vt == reg.DWORD && u > math.MaxUint32 {
+		return 0, fmt.Errorf("value %d overflows DWORD", u)
+	}
+	return u, nil
+}
+
+// setInteger stores i, read from a registry value of type vt, in fv. DWORDs are sign
+// extended for signed fields.
+func setInteger(fv reflect.Value, i uint64, vt uint32) error {
+	switch fv.Kind() {
+	case reflect.Bool:
+		fv.SetBool(i != 0)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		v := int64(i)
+		if vt == reg.DWORD {
+			v = int64(int32(uint32(i)))
+		}
+		if fv.OverflowInt(v) {
+			return fmt.Errorf("value %d overflows %v", v, fv.Type())
+		}
+		fv.SetInt(v)
+	default:
+		if fv.OverflowUint(i) {
+			return fmt.Errorf("value %d overflows %v", i, fv.Type())
+		}
+		fv.SetUint(i)
+	}
+	return nil
+}
+
+// Marshal writes the exported fields of v, a struct or pointer to a struct, as values
+// under root, creating the key if necessary. See Unmarshal for the tag format.
+func Marshal(root string, v interface{}) error {
+	rv, err := structValue(v, false)
+	if err != nil {
+		return err
+	}
+	fields, err := parseFields(rv.Type())
+	if err != nil {
+		return err
+	}
+	hive, p := splitHive(root)
+	k, _, err := reg.CreateKey(hive, p, reg.ALL_ACCESS)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	for _, f := range fields {
+		fv := rv.Field(f.Index)
+		switch f.Type {
+		case reg.SZ:
+			err = k.SetStringValue(f.Name, fv.String())
+		case reg.EXPAND_SZ:
+			err = k.SetExpandStringValue(f.Name, fv.String())
+		case reg.MULTI_SZ:
+			s := make([]string, fv.Len())
+			for i := range s {
+				s[i] = fv.Index(i).String()
+			}
+			err = k.SetStringsValue(f.Name, s)
+		case reg.DWORD:
+			var i uint64
+			if i, err = integer(fv, f.Type); err == nil {
+				err = k.SetDWordValue(f.Name, uint32(i))
+			}
+		case reg.QWORD:
+			var i uint64
+			if i, err = integer(fv, f.Type); err == nil {
+				err = k.SetQWordValue(f.Name, i)
+			}
+		case reg.BINARY:
+			err = k.SetBinaryValue(f.Name, fv.Bytes())
+		}
+		if err != nil {
+			return fmt.Errorf("setting %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
+
+// Unmarshal reads the values under root into the struct pointed to by v.
+//
+// Fields map to values using tags of the form `registry:"ValueName,type"`, where type is
+// one of sz, expand_sz, multi_sz, dword, qword or binary. Either part may be omitted, in
+// which case the field name and a type derived from the field's Go type are used. Fields
+// tagged "-" are skipped.
+//
+// Fields whose values are absent are left unchanged, so v may be pre-populated with
+// defaults.
+//
+// Example:
+//
+//	type settings struct {
+//		Server  string `registry:"ServerURL"`
+//		Retries int    `registry:",dword"`
+//	}
+//	s := settings{Retries: 3}
+//	err := registry.Unmarshal(`SOFTWARE\Glazier\Agent`, &s)
+func Unmarshal(root string, v interface{}) error {
+	rv, err := structValue(v, true)
+	if err != nil {
+		return err
+	}
+	fields, err := parseFields(rv.Type())
+	if err != nil {
+		return err
+	}
+	k, err := openKey(root, reg.READ)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	for _, f := range fields {
+		fv := rv.Field(f.Index)
+		switch f.Type {
+		case reg.SZ, reg.EXPAND_SZ:
+			var s string
+			if s, _, err = k.GetStringValue(f.Name); err == nil {
+				fv.SetString(s)
+			}
+		case reg.MULTI_SZ:
+			var s []string
+			if s, _, err = k.GetStringsValue(f.Name); err == nil {
+				l := reflect.MakeSlice(fv.Type(), len(s), len(s))
+				for i := range s {
+					l.Index(i).SetString(s[i])
+				}
+				fv.Set(l)
+			}
+		case reg.DWORD, reg.QWORD:
+			var i uint64
+			var vt uint32
+			if i, vt, err = k.GetIntegerValue(f.Name); err == nil {
+				err = setInteger(fv, i, vt)
+			}
+		case reg.BINARY:
+			var b []byte
+			if b, _, err = k.GetBinaryValue(f.Name); err == nil {
+				fv.SetBytes(b)
+			}
+		}
+		if errors.Is(err, ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", f.Name, err)
+		}
+	}
+	return nil
+}
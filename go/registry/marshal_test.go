@@ -0,0 +1,158 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/sys/windows/registry"
+)
+
+type testSettings struct {
+	Server   string   `registry:"ServerURL"`
+	Path     string   `registry:",expand_sz"`
+	Suffixes []string `registry:"SearchList"`
+	Retries  int      `registry:",dword"`
+	Enabled  bool
+	Size     uint64
+	Blob     []byte
+	Skipped  string `registry:"-"`
+	internal string
+}
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		desc string
+		in   interface{}
+		want []field
+		err  error
+	}{
+		{"settings", testSettings{}, []field{
+			{0, "ServerURL", registry.SZ},
+			{1, "Path", registry.EXPAND_SZ},
+			{2, "SearchList", registry.MULTI_SZ},
+			{3, "Retries", registry.DWORD},
+			{4, "Enabled", registry.DWORD},
+			{5, "Size", registry.QWORD},
+			{6, "Blob", registry.BINARY},
+		}, nil},
+		{"unknown type", struct {
+			A string `registry:",float"`
+		}{}, nil, ErrUnsupportedType},
+		{"incompatible type", struct {
+			A string `registry:",dword"`
+		}{}, nil, ErrUnsupportedType},
+		{"unsupported field", struct {
+			A float64
+		}{}, nil, ErrUnsupportedType},
+	}
+	for _, tt := range tests {
+		got, err := parseFields(reflect.TypeOf(tt.in))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("parseFields(%s) returned unexpected error %v", tt.desc, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("parseFields(%s) returned unexpected diff (-want +got):\n%s", tt.desc, diff)
+		}
+	}
+}
+
+func TestInteger(t *testing.T) {
+	tests := []struct {
+		desc    string
+		in      interface{}
+		vt      uint32
+		want    uint64
+		wantErr bool
+	}{
+		{"negative dword", int(-1), registry.DWORD, 0xFFFFFFFF, false},
+		{"negative int32", int32(math.MinInt32), registry.DWORD, 0x80000000, false},
+		{"negative qword", int64(-2), registry.QWORD, 0xFFFFFFFFFFFFFFFE, false},
+		{"unsigned dword", uint32(math.MaxUint32), registry.DWORD, 0xFFFFFFFF, false},
+		{"bool", true, registry.DWORD, 1, false},
+		{"signed overflow", int64(math.MaxInt32 + 1), registry.DWORD, 0, true},
+		{"signed underflow", int64(math.MinInt32 - 1), registry.DWORD, 0, true},
+		{"unsigned overflow", uint64(1 << 32), registry.DWORD, 0, true},
+	}
+	for _, tt := range tests {
+		in := reflect.ValueOf(tt.in)
+		got, err := integer(in, tt.vt)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("integer(%s) returned unexpected error %v", tt.desc, err)
+		}
+		if got != tt.want {
+			t.Errorf("integer(%s) = %#x, want %#x", tt.desc, got, tt.want)
+		}
+		if err != nil {
+			continue
+		}
+		out := reflect.New(in.Type()).Elem()
+		if err := setInteger(out, got, tt.vt); err != nil {
+			t.Errorf("setInteger(%s) returned unexpected error %v", tt.desc, err)
+		}
+		if out.Interface() != tt.in {
+			t.Errorf("setInteger(%s) = %v, want %v", tt.desc, out.Interface(), tt.in)
+		}
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	if err := createKey(rootKey); err != nil {
+		t.Fatalf("createKey(%s) produced unexpected error %v", rootKey, err)
+	}
+	defer registry.DeleteKey(registry.LOCAL_MACHINE, rootKey)
+
+	in := testSettings{
+		Server:   "https://glazier.example.com",
+		Path:     `%SystemRoot%\Glazier`,
+		Suffixes: []string{"corp.example.com", "example.com"},
+		Retries:  -1,
+		Enabled:  true,
+		Size:     1 << 40,
+		Blob:     []byte{0x01, 0x02},
+		Skipped:  "skipped",
+	}
+	if err := Marshal(rootKey, in); err != nil {
+		t.Fatalf("Marshal() returned unexpected error %v", err)
+	}
+	if got, err := GetString(rootKey, "ServerURL"); err != nil || got != in.Server {
+		t.Errorf("GetString(ServerURL) = (%s, %v), want %s", got, err, in.Server)
+	}
+
+	got := testSettings{Skipped: "default"}
+	if err := Unmarshal(rootKey, &got); err != nil {
+		t.Fatalf("Unmarshal() returned unexpected error %v", err)
+	}
+	want := in
+	want.Skipped = "default"
+	if diff := cmp.Diff(want, got, cmp.AllowUnexported(testSettings{})); diff != "" {
+		t.Errorf("Unmarshal() returned unexpected diff (-want +got):\n%s", diff)
+	}
+
+	if err := Unmarshal(rootKey, got); !errors.Is(err, ErrUnsupportedType) {
+		t.Errorf("Unmarshal(non-pointer) returned %v, want %v", err, ErrUnsupportedType)
+	}
+
+	overflow := struct {
+		Retries int64 `registry:",dword"`
+	}{1 << 40}
+	if err := Marshal(rootKey, overflow); err == nil {
+		t.Errorf("Marshal(%d as DWORD) returned nil error", overflow.Retries)
+	}
+}
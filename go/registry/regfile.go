@@ -0,0 +1,492 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"unicode/utf16"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	reg "golang.org/x/sys/windows/registry"
+)
+
+const (
+	regHeader  = "Windows Registry Editor Version 5.00"
+	reg4Header = "REGEDIT4"
+)
+
+var (
+	// ErrInvalidRegFile indicates a .reg file that could not be parsed.
+	ErrInvalidRegFile = errors.New("invalid registry file")
+
+	procRegSetValueExW = windows.NewLazySystemDLL("advapi32.dll").NewProc("RegSetValueExW")
+
+	hiveNames = map[reg.Key]string{
+		reg.LOCAL_MACHINE:  "HKEY_LOCAL_MACHINE",
+		reg.CURRENT_USER:   "HKEY_CURRENT_USER",
+		reg.CLASSES_ROOT:   "HKEY_CLASSES_ROOT",
+		reg.USERS:          "HKEY_USERS",
+		reg.CURRENT_CONFIG: "HKEY_CURRENT_CONFIG",
+	}
+)
+
+// regValue is a single value line of a .reg file. Data holds the raw value as stored in
+// the registry.
+type regValue struct {
+	Name   string
+	Type   uint32
+	Data   []byte
+	Delete bool
+}
+
+// regKey is a key section of a .reg file. Path includes the full hive name.
+type regKey struct {
+	Path   string
+	Delete bool
+	Values []regValue
+}
+
+func quoteRegString(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
+}
+
+func hexBytes(b []byte) string {
+	h := make([]string, len(b))
+	for i, c := range b {
+		h[i] = fmt.Sprintf("%02x", c)
+	}
+	return strings.Join(h, ",")
+}
+
+func encodeUTF16(s string) []byte {
+	u := utf16.Encode([]rune(s))
+	b := make([]byte, 2*len(u))
+	for i, c := range u {
+		binary.LittleEndian.PutUint16(b[2*i:], c)
+	}
+	return b
+}
+
+func decodeUTF16(b []byte) string {
+	u := make([]uint16, len(b)/2)
+	for i := range u {
+		u[i] = binary.LittleEndian.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(u))
+}
+
+// plainString returns the string held by REG_SZ data, if it can be written to a .reg file
+// as a quoted string without loss.
+func plainString(b []byte) (string, bool) {
+	if len(b) < 2 || len(b)%2 != 0 {
+		return "", false
+	}
+	s := decodeUTF16(b)
+	if !strings.HasSuffix(s, "\x00") {
+		return "", false
+	}
+	s = s[:len(s)-1]
+	if strings.ContainsAny(s, "\x00\r\n") {
+		return "", false
+	}
+	return s, true
+}
+
+func formatValue(v regValue) string {
+	name := "@"
+	if v.Name != "" {
+		name = quoteRegString(v.Name)
+	}
+	if v.Delete {
+		return name + "=-"
+	}
+	switch v.Type {
+	case reg.SZ:
+		if s, ok := plainString(v.Data); ok {
+			return name + "=" + quoteRegString(s)
+		}
+	case reg.DWORD:
+		if len(v.Data) == 4 {
+			return fmt.Sprintf("%s=dword:%08x", name, binary.LittleEndian.Uint32(v.Data))
+		}
+	case reg.BINARY:
+		return name + "=hex:" + hexBytes(v.Data)
+	}
+	return fmt.Sprintf("%s=hex(%x):%s", name, v.Type, hexBytes(v.Data))
+}
+
+// formatReg renders keys in the .reg format used by regedit.
+func formatReg(keys []regKey) string {
+	var b strings.Builder
+	b.WriteString(regHeader + "\r\n\r\n")
+	for _, k := range keys {
+		if k.Delete {
+			fmt.Fprintf(&b, "[-%s]\r\n\r\n", k.Path)
+			continue
+		}
+		fmt.Fprintf(&b, "[%s]\r\n", k.Path)
+		for _, v := range k.Values {
+			b.WriteString(formatValue(v) + "\r\n")
+		}
+		b.WriteString("\r\n")
+	}
+	return b.String()
+}
+
+// unquoteRegString reads a quoted string from the start of s, returning the string and the
+// remainder of s.
+func unquoteRegString(s string) (string, string, error) {
+	if !strings.HasPrefix(s, `"`) {
+		return "", s, fmt.Errorf("%w: expected quoted string at %q", ErrInvalidRegFile, s)
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+			}
+			b.WriteByte(s[i])
+		case '"':
+			return b.String(), s[i+1:], nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", s, fmt.Errorf("%w: unterminated string %q", ErrInvalidRegFile, s)
+}
+
+func parseHex(s string) ([]byte, error) {
+	b := []byte{}
+	for _, h := range strings.Split(s, ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		c, err := strconv.ParseUint(h, 16, 8)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid hex byte %q", ErrInvalidRegFile, h)
+		}
+		b = append(b, byte(c))
+	}
+	return b, nil
+}
+
+func parseValue(line string, v4 bool) (regValue, error) {
+	v := regValue{}
+	rest := line
+	if strings.HasPrefix(line, "@") {
+		rest = line[1:]
+	} else {
+		var err error
+		if v.Name, rest, err = unquoteRegString(line); err != nil {
+			return v, err
+		}
+	}
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "=") {
+		return v, fmt.Errorf("%w: missing '=' in %q", ErrInvalidRegFile, line)
+	}
+	data := strings.TrimSpace(rest[1:])
+	switch {
+	case data == "-":
+		v.Delete = true
+	case strings.HasPrefix(data, `"`):
+		s, tail, err := unquoteRegString(data)
+		if err != nil {
+			return v, err
+		}
+		if strings.TrimSpace(tail) != "" {
+			return v, fmt.Errorf("%w: trailing data in %q", ErrInvalidRegFile, line)
+		}
+		v.Type = reg.SZ
+		v.Data = encodeUTF16(s + "\x00")
+	case strings.HasPrefix(strings.ToLower(data), "dword:"):
+		d, err := strconv.ParseUint(data[len("dword:"):], 16, 32)
+		if err != nil {
+			return v, fmt.Errorf("%w: invalid dword in %q", ErrInvalidRegFile, line)
+		}
+		v.Type = reg.DWORD
+		v.Data = make([]byte, 4)
+		binary.LittleEndian.PutUint32(v.Data, uint32(d))
+	case strings.HasPrefix(strings.ToLower(data), "hex"):
+		i := strings.Index(data, ":")
+		if i < 0 {
+			return v, fmt.Errorf("%w: invalid hex value in %q", ErrInvalidRegFile, line)
+		}
+		v.Type = reg.BINARY
+		if t := data[len("hex"):i]; t != "" {
+			if !strings.HasPrefix(t, "(") || !strings.HasSuffix(t, ")") {
+				return v, fmt.Errorf("%w: invalid value type in %q", ErrInvalidRegFile, line)
+			}
+			typ, err := strconv.ParseUint(t[1:len(t)-1], 16, 32)
+			if err != nil {
+				return v, fmt.Errorf("%w: invalid value type in %q", ErrInvalidRegFile, line)
+			}
+			v.Type = uint32(typ)
+		}
+		var err error
+		if v.Data, err = parseHex(data[i+1:]); err != nil {
+			return v, err
+		}
+		// REGEDIT4 files store string types as ANSI rather than UTF-16.
+		if v4 && (v.Type == reg.EXPAND_SZ || v.Type == reg.MULTI_SZ) {
+			w := make([]byte, 0, 2*len(v.Data))
+			for _, c := range v.Data {
+				w = append(w, c, 0)
+			}
+			v.Data = w
+		}
+	default:
+		return v, fmt.Errorf("%w: unsupported value %q", ErrInvalidRegFile, line)
+	}
+	return v, nil
+}
+
+func hasHive(path string) bool {
+	prefix := path
+	if i := strings.Index(path, `\`); i >= 0 {
+		prefix = path[:i]
+	}
+	_, ok := hives[strings.ToUpper(prefix)]
+	return ok
+}
+
+// parseReg parses the contents of a .reg file.
+func parseReg(text string) ([]regKey, error) {
+	lines := []string{}
+	for _, l := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		l = strings.TrimSpace(l)
+		if n := len(lines); n > 0 && strings.HasSuffix(lines[n-1], `\`) && !strings.HasPrefix(lines[n-1], "[") {
+			lines[n-1] = strings.TrimSuffix(lines[n-1], `\`) + l
+			continue
+		}
+		if l == "" || strings.HasPrefix(l, ";") {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	if len(lines) == 0 || (lines[0] != regHeader && lines[0] != reg4Header) {
+		return nil, fmt.Errorf("%w: missing header", ErrInvalidRegFile)
+	}
+	v4 := lines[0] == reg4Header
+
+	keys := []regKey{}
+	for _, l := range lines[1:] {
+		if strings.HasPrefix(l, "[") {
+			if !strings.HasSuffix(l, "]") {
+				return nil, fmt.Errorf("%w: invalid key %q", ErrInvalidRegFile, l)
+			}
+			k := regKey{Path: l[1 : len(l)-1]}
+			if strings.HasPrefix(k.Path, "-") {
+				k.Path = k.Path[1:]
+				k.Delete = true
+			}
+			if !hasHive(k.Path) {
+				return nil, fmt.Errorf("%w: unknown hive in %q", ErrInvalidRegFile, l)
+			}
+			keys = append(keys, k)
+			continue
+		}
+		if len(keys) == 0 || keys[len(keys)-1].Delete {
+			return nil, fmt.Errorf("%w: value outside of a key: %q", ErrInvalidRegFile, l)
+		}
+		v, err := parseValue(l, v4)
+		if err != nil {
+			return nil, err
+		}
+		keys[len(keys)-1].Values = append(keys[len(keys)-1].Values, v)
+	}
+	return keys, nil
+}
+
+// decodeRegFile converts the contents of a .reg file to a string. regedit writes UTF-16LE
+// with a byte order mark; UTF-8 and ASCII files are also accepted.
+func decodeRegFile(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xff && b[1] == 0xfe {
+		return decodeUTF16(b[2:])
+	}
+	return strings.TrimPrefix(string(b), "\ufeff")
+}
+
+func fullPath(path string) string {
+	hive, p := splitHive(path)
+	if p == "" {
+		return hiveNames[hive]
+	}
+	return hiveNames[hive] + `\` + p
+}
+
+func sortFold(s []string) {
+	sort.Slice(s, func(i, j int) bool { return strings.ToLower(s[i]) < strings.ToLower(s[j]) })
+}
+
+func readValue(k reg.Key, name string) ([]byte, uint32, error) {
+	n, _, err := k.GetValue(name, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	buf := make([]byte, n)
+	n, typ, err := k.GetValue(name, buf)
+	if err != nil {
+		return nil, 0, err
+	}
+	return buf[:n], typ, nil
+}
+
+func writeValue(k reg.Key, name string, typ uint32, data []byte) error {
+	p, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return err
+	}
+	var d *byte
+	if len(data) > 0 {
+		d = &data[0]
+	}
+	r, _, _ := procRegSetValueExW.Call(uintptr(k), uintptr(unsafe.Pointer(p)), 0, uintptr(typ),
+		uintptr(unsafe.Pointer(d)), uintptr(len(data)))
+	if r != 0 {
+		return syscall.Errno(r)
+	}
+	return nil
+}
+
+// readTree reads the key at path and, if recursive, all of its subkeys, in sorted order.
+func readTree(path string, recursive bool) ([]regKey, error) {
+	k, err := openKey(path, reg.READ)
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+	rk := regKey{Path: fullPath(path)}
+	names, err := k.ReadValueNames(-1)
+	if err != nil {
+		return nil, err
+	}
+	sortFold(names)
+	for _, name := range names {
+		data, typ, err := readValue(k, name)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s\\%s: %w", path, name, err)
+		}
+		rk.Values = append(rk.Values, regValue{Name: name, Type: typ, Data: data})
+	}
+	keys := []regKey{rk}
+	if !recursive {
+		return keys, nil
+	}
+	subkeys, err := k.ReadSubKeyNames(-1)
+	if err != nil {
+		return nil, err
+	}
+	sortFold(subkeys)
+	for _, s := range subkeys {
+		sub, err := readTree(path+`\`+s, true)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, sub...)
+	}
+	return keys, nil
+}
+
+// deleteTree deletes the key at path along with all of its subkeys.
+func deleteTree(path string) error {
+	subkeys, err := GetSubkeys(path)
+	if err != nil {
+		return err
+	}
+	for _, s := range subkeys {
+		if err := deleteTree(path + `\` + s); err != nil {
+			return err
+		}
+	}
+	hive, p := splitHive(path)
+	return reg.DeleteKey(hive, p)
+}
+
+func applyKey(rk regKey) error {
+	if rk.Delete {
+		if err := deleteTree(rk.Path); err != nil && !errors.Is(err, ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	hive, p := splitHive(rk.Path)
+	k, _, err := reg.CreateKey(hive, p, reg.ALL_ACCESS)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	for _, v := range rk.Values {
+		if v.Delete {
+			if err := k.DeleteValue(v.Name); err != nil && !errors.Is(err, ErrNotExist) {
+				return fmt.Errorf("deleting %q: %w", v.Name, err)
+			}
+			continue
+		}
+		if err := writeValue(k, v.Name, v.Type, v.Data); err != nil {
+			return fmt.Errorf("setting %q: %w", v.Name, err)
+		}
+	}
+	return nil
+}
+
+// Export writes the key at root, its values and all of its subkeys to path in the .reg
+// format used by regedit.
+//
+// Keys and values are written in sorted order, so exports of equivalent trees compare
+// equal.
+//
+// Example: registry.Export(`HKLM\SOFTWARE\Glazier`, `C:\baseline.reg`)
+func Export(root, path string) error {
+	keys, err := readTree(root, true)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", root, err)
+	}
+	b := append([]byte{0xff, 0xfe}, encodeUTF16(formatReg(keys))...)
+	return os.WriteFile(path, b, 0644)
+}
+
+// Import applies the .reg file at path to the registry, as regedit /s would.
+//
+// Keys are created as needed. Deletions ([-key] and "value"=-) are honored, and
+// deleting something that does not exist is not an error. The file is parsed in full
+// before any changes are made.
+func Import(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	keys, err := parseReg(decodeRegFile(b))
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", path, err)
+	}
+	for _, k := range keys {
+		if err := applyKey(k); err != nil {
+			return fmt.Errorf("importing %s: %w", k.Path, err)
+		}
+	}
+	return nil
+}
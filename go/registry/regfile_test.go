@@ -0,0 +1,155 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package registry
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"golang.org/x/sys/windows/registry"
+)
+
+var testRegFile = "Windows Registry Editor Version 5.00\r\n" +
+	"\r\n" +
+	"[HKEY_LOCAL_MACHINE\\SOFTWARE\\TEST\\updater]\r\n" +
+	"@=\"default\"\r\n" +
+	"\"Path\"=\"C:\\\\Windows \\\"quoted\\\"\"\r\n" +
+	"\"Count\"=dword:0000002a\r\n" +
+	"\"Blob\"=hex:00,01,\\\r\n" +
+	"  fe,ff\r\n" +
+	"\"Expand\"=hex(2):25,00,54,00,25,00,00,00\r\n" +
+	"\"Gone\"=-\r\n" +
+	"\r\n" +
+	"; comments are ignored\r\n" +
+	"[-HKEY_CURRENT_USER\\Software\\TEST\\updater]\r\n" +
+	"\r\n"
+
+var testRegKeys = []regKey{
+	{
+		Path: `HKEY_LOCAL_MACHINE\SOFTWARE\TEST\updater`,
+		Values: []regValue{
+			{Name: "", Type: registry.SZ, Data: encodeUTF16("default\x00")},
+			{Name: "Path", Type: registry.SZ, Data: encodeUTF16(`C:\Windows "quoted"` + "\x00")},
+			{Name: "Count", Type: registry.DWORD, Data: []byte{0x2a, 0, 0, 0}},
+			{Name: "Blob", Type: registry.BINARY, Data: []byte{0x00, 0x01, 0xfe, 0xff}},
+			{Name: "Expand", Type: registry.EXPAND_SZ, Data: encodeUTF16("%T%\x00")},
+			{Name: "Gone", Delete: true},
+		},
+	},
+	{Path: `HKEY_CURRENT_USER\Software\TEST\updater`, Delete: true},
+}
+
+func TestParseReg(t *testing.T) {
+	tests := []struct {
+		desc string
+		in   string
+		want []regKey
+		err  error
+	}{
+		{"full file", testRegFile, testRegKeys, nil},
+		{"regedit4", "REGEDIT4\n\n[HKEY_USERS\\.DEFAULT]\n\"M\"=hex(7):61,00,00\n", []regKey{
+			{Path: `HKEY_USERS\.DEFAULT`, Values: []regValue{
+				{Name: "M", Type: registry.MULTI_SZ, Data: []byte{0x61, 0, 0, 0, 0, 0}},
+			}},
+		}, nil},
+		{"missing header", "[HKEY_LOCAL_MACHINE\\SOFTWARE]\r\n", nil, ErrInvalidRegFile},
+		{"unknown hive", regHeader + "\r\n[HKEY_NOWHERE\\SOFTWARE]\r\n", nil, ErrInvalidRegFile},
+		{"value outside key", regHeader + "\r\n\"A\"=\"b\"\r\n", nil, ErrInvalidRegFile},
+		{"bad dword", regHeader + "\r\n[HKEY_LOCAL_MACHINE\\SOFTWARE]\r\n\"A\"=dword:xyz\r\n", nil, ErrInvalidRegFile},
+		{"unterminated", regHeader + "\r\n[HKEY_LOCAL_MACHINE\\SOFTWARE]\r\n\"A=\"b\r\n", nil, ErrInvalidRegFile},
+	}
+	for _, tt := range tests {
+		got, err := parseReg(tt.in)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("parseReg(%s) returned unexpected error %v", tt.desc, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("parseReg(%s) returned unexpected diff (-want +got):\n%s", tt.desc, diff)
+		}
+	}
+}
+
+func TestFormatReg(t *testing.T) {
+	keys := []regKey{
+		{
+			Path: `HKEY_LOCAL_MACHINE\SOFTWARE\TEST\updater`,
+			Values: []regValue{
+				{Name: "", Type: registry.SZ, Data: encodeUTF16("default\x00")},
+				{Name: "Path", Type: registry.SZ, Data: encodeUTF16(`C:\Windows "quoted"` + "\x00")},
+				{Name: "Lines", Type: registry.SZ, Data: encodeUTF16("a\r\nb\x00")},
+				{Name: "Count", Type: registry.DWORD, Data: []byte{0x2a, 0, 0, 0}},
+				{Name: "Blob", Type: registry.BINARY, Data: []byte{0x00, 0xff}},
+				{Name: "Big", Type: registry.QWORD, Data: []byte{1, 0, 0, 0, 0, 0, 0, 0}},
+			},
+		},
+		{Path: `HKEY_CURRENT_USER\Software\TEST`, Delete: true},
+	}
+	want := "Windows Registry Editor Version 5.00\r\n" +
+		"\r\n" +
+		"[HKEY_LOCAL_MACHINE\\SOFTWARE\\TEST\\updater]\r\n" +
+		"@=\"default\"\r\n" +
+		"\"Path\"=\"C:\\\\Windows \\\"quoted\\\"\"\r\n" +
+		"\"Lines\"=hex(1):61,00,0d,00,0a,00,62,00,00,00\r\n" +
+		"\"Count\"=dword:0000002a\r\n" +
+		"\"Blob\"=hex:00,ff\r\n" +
+		"\"Big\"=hex(b):01,00,00,00,00,00,00,00\r\n" +
+		"\r\n" +
+		"[-HKEY_CURRENT_USER\\Software\\TEST]\r\n" +
+		"\r\n"
+	got := formatReg(keys)
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("formatReg() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	parsed, err := parseReg(got)
+	if err != nil {
+		t.Fatalf("parseReg(formatReg()) returned unexpected error %v", err)
+	}
+	if diff := cmp.Diff(keys, parsed); diff != "" {
+		t.Errorf("parseReg(formatReg()) returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	sub := rootKey + `\Child`
+	if err := createKey(sub); err != nil {
+		t.Fatalf("createKey(%s) produced unexpected error %v", sub, err)
+	}
+	defer deleteTree(rootKey)
+	if err := SetString(rootKey, "Test1", "one"); err != nil {
+		t.Fatalf("SetString() returned %v", err)
+	}
+	if err := SetQword(sub, "Test2", 1<<40); err != nil {
+		t.Fatalf("SetQword() returned %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "baseline.reg")
+	if err := Export(rootKey, path); err != nil {
+		t.Fatalf("Export(%s) returned %v", rootKey, err)
+	}
+	if err := deleteTree(rootKey); err != nil {
+		t.Fatalf("deleteTree(%s) returned %v", rootKey, err)
+	}
+	if err := Import(path); err != nil {
+		t.Fatalf("Import(%s) returned %v", path, err)
+	}
+	if got, err := GetString(rootKey, "Test1"); err != nil || got != "one" {
+		t.Errorf("GetString() after Import = (%s, %v), want one", got, err)
+	}
+	if got, err := GetQword(sub, "Test2"); err != nil || got != 1<<40 {
+		t.Errorf("GetQword() after Import = (%d, %v), want %d", got, err, uint64(1<<40))
+	}
+}
@@ -16,17 +16,143 @@
 package registry
 
 import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
 	reg "golang.org/x/sys/windows/registry"
 )
 
 var (
 	// ErrNotExist indicates a registry key did not exist
 	ErrNotExist = reg.ErrNotExist
+
+	hives = map[string]reg.Key{
+		"HKLM":                reg.LOCAL_MACHINE,
+		"HKEY_LOCAL_MACHINE":  reg.LOCAL_MACHINE,
+		"HKCU":                reg.CURRENT_USER,
+		"HKEY_CURRENT_USER":   reg.CURRENT_USER,
+		"HKCR":                reg.CLASSES_ROOT,
+		"HKEY_CLASSES_ROOT":   reg.CLASSES_ROOT,
+		"HKU":                 reg.USERS,
+		"HKEY_USERS":          reg.USERS,
+		"HKCC":                reg.CURRENT_CONFIG,
+		"HKEY_CURRENT_CONFIG": reg.CURRENT_CONFIG,
+	}
 )
 
+// splitHive separates an optional hive prefix (eg "HKCU\Software\...") from path.
+//
+// Paths without a recognized prefix are relative to HKEY_LOCAL_MACHINE.
+func splitHive(path string) (reg.Key, string) {
+	prefix := path
+	rest := ""
+	if i := strings.Index(path, `\`); i >= 0 {
+		prefix = path[:i]
+		rest = path[i+1:]
+	}
+	if k, ok := hives[strings.ToUpper(prefix)]; ok {
+		return k, rest
+	}
+	return reg.LOCAL_MACHINE, path
+}
+
+func openKey(path string, access uint32) (reg.Key, error) {
+	hive, p := splitHive(path)
+	return reg.OpenKey(hive, p, access)
+}
+
+// appendUnique appends the values not already in list, preserving order.
+func appendUnique(list []string, values ...string) []string {
+	seen := map[string]bool{}
+	for _, l := range list {
+		seen[l] = true
+	}
+	for _, v := range values {
+		if !seen[v] {
+			list = append(list, v)
+			seen[v] = true
+		}
+	}
+	return list
+}
+
+// removeAll removes every occurrence of values from list, preserving order.
+func removeAll(list []string, values ...string) []string {
+	drop := map[string]bool{}
+	for _, v := range values {
+		drop[v] = true
+	}
+	kept := []string{}
+	for _, l := range list {
+		if !drop[l] {
+			kept = append(kept, l)
+		}
+	}
+	return kept
+}
+
+// updateMultiString applies update to the REG_MULTI_SZ value name under root, treating a
+// missing value as empty. The value is only written if update changes it.
+func updateMultiString(root, name string, update func([]string) []string) error {
+	k, err := openKey(root, reg.QUERY_VALUE|reg.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	list, _, err := k.GetStringsValue(name)
+	if errors.Is(err, ErrNotExist) {
+		list = []string{}
+	} else if err != nil {
+		return err
+	}
+	updated := update(append([]string{}, list...))
+	if reflect.DeepEqual(list, updated) {
+		return nil
+	}
+	return k.SetStringsValue(name, updated)
+}
+
+// AppendMultiString appends values to a multi-string (REG_MULTI_SZ) value, creating it if
+// necessary. Values already present are not added again, and the existing order is kept.
+//
+// Example: registry.AppendMultiString(`SYSTEM\CurrentControlSet\Services\LanmanServer\Parameters`, "NullSessionPipes", "spoolss")
+func AppendMultiString(root, name string, values ...string) error {
+	return updateMultiString(root, name, func(list []string) []string {
+		return appendUnique(list, values...)
+	})
+}
+
+// CopyKey copies the values of the key at srcRoot to dstRoot, creating dstRoot if
+// necessary. If recursive is true, subkeys are copied as well.
+//
+// Values already present under dstRoot are overwritten; others are left in place. The
+// source is read in full before anything is written, so dstRoot may lie beneath srcRoot.
+func CopyKey(srcRoot, dstRoot string, recursive bool) error {
+	keys, err := readTree(srcRoot, recursive)
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", srcRoot, err)
+	}
+	src := fullPath(srcRoot)
+	dst := fullPath(dstRoot)
+	for _, k := range keys {
+		k.Path = dst + k.Path[len(src):]
+		if err := applyKey(k); err != nil {
+			return fmt.Errorf("copying to %s: %w", k.Path, err)
+		}
+	}
+	return nil
+}
+
 // Create a key in the registry.
+//
+// Paths throughout this package may be prefixed with a root hive, as in
+// `HKCU\Software\Google`. Unprefixed paths refer to HKEY_LOCAL_MACHINE.
 func Create(path string) error {
-	k, _, err := reg.CreateKey(reg.LOCAL_MACHINE, path, reg.ALL_ACCESS)
+	hive, p := splitHive(path)
+	k, _, err := reg.CreateKey(hive, p, reg.ALL_ACCESS)
 	if err != nil {
 		return err
 	}
@@ -36,7 +162,7 @@ func Create(path string) error {
 
 // Delete a key from the registry.
 func Delete(root, name string) error {
-	k, err := reg.OpenKey(reg.LOCAL_MACHINE, root, reg.ALL_ACCESS)
+	k, err := openKey(root, reg.ALL_ACCESS)
 	if err != nil {
 		return err
 	}
@@ -44,9 +170,37 @@ func Delete(root, name string) error {
 	return k.DeleteValue(name)
 }
 
+// GetBinary gets a binary (REG_BINARY) value from the registry.
+func GetBinary(root, name string) ([]byte, error) {
+	k, err := openKey(root, reg.READ)
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+	t, _, err := k.GetBinaryValue(name)
+	return t, err
+}
+
+// GetExpandString gets an expandable string (REG_EXPAND_SZ) value from the registry.
+//
+// If expand is true, environment variable references in the value (eg %SystemRoot%) are
+// expanded before it is returned.
+func GetExpandString(root, name string, expand bool) (string, error) {
+	k, err := openKey(root, reg.READ)
+	if err != nil {
+		return "", err
+	}
+	defer k.Close()
+	t, _, err := k.GetStringValue(name)
+	if err != nil || !expand {
+		return t, err
+	}
+	return reg.ExpandString(t)
+}
+
 // GetInteger gets a string key from the registry.
 func GetInteger(root, name string) (uint64, error) {
-	k, err := reg.OpenKey(reg.LOCAL_MACHINE, root, reg.READ)
+	k, err := openKey(root, reg.READ)
 	if err != nil {
 		return 0, err
 	}
@@ -57,7 +211,7 @@ func GetInteger(root, name string) (uint64, error) {
 
 // GetSubkeys gets all the subkey names under root.
 func GetSubkeys(root string) ([]string, error) {
-	k, err := reg.OpenKey(reg.LOCAL_MACHINE, root, reg.ENUMERATE_SUB_KEYS)
+	k, err := openKey(root, reg.ENUMERATE_SUB_KEYS)
 	if err != nil {
 		return []string{}, err
 	}
@@ -65,9 +219,30 @@ func GetSubkeys(root string) ([]string, error) {
 	return k.ReadSubKeyNames(-1)
 }
 
+// GetQword gets a 64-bit integer (REG_QWORD) value from the registry.
+func GetQword(root, name string) (uint64, error) {
+	k, err := openKey(root, reg.READ)
+	if err != nil {
+		return 0, err
+	}
+	defer k.Close()
+	t, _, err := k.GetIntegerValue(name)
+	return t, err
+}
+
+// GetIntegerDefault gets an integer key from the registry, returning def if the key or
+// value does not exist.
+func GetIntegerDefault(root, name string, def uint64) (uint64, error) {
+	v, err := GetInteger(root, name)
+	if errors.Is(err, ErrNotExist) {
+		return def, nil
+	}
+	return v, err
+}
+
 // GetString gets a string key from the registry.
 func GetString(root, name string) (string, error) {
-	k, err := reg.OpenKey(reg.LOCAL_MACHINE, root, reg.READ)
+	k, err := openKey(root, reg.READ)
 	if err != nil {
 		return "", err
 	}
@@ -76,9 +251,19 @@ func GetString(root, name string) (string, error) {
 	return t, err
 }
 
+// GetStringDefault gets a string key from the registry, returning def if the key or value
+// does not exist.
+func GetStringDefault(root, name, def string) (string, error) {
+	v, err := GetString(root, name)
+	if errors.Is(err, ErrNotExist) {
+		return def, nil
+	}
+	return v, err
+}
+
 // GetValues gets all the value names under root.
 func GetValues(root string) ([]string, error) {
-	k, err := reg.OpenKey(reg.LOCAL_MACHINE, root, reg.READ)
+	k, err := openKey(root, reg.READ)
 	if err != nil {
 		return []string{}, err
 	}
@@ -86,9 +271,90 @@ func GetValues(root string) ([]string, error) {
 	return k.ReadValueNames(-1)
 }
 
+// typedValue converts raw registry data to a Go value: string for REG_SZ and
+// REG_EXPAND_SZ, []string for REG_MULTI_SZ, uint64 for REG_DWORD and REG_QWORD, and the
+// raw []byte for anything else.
+func typedValue(typ uint32, data []byte) interface{} {
+	switch typ {
+	case reg.SZ, reg.EXPAND_SZ:
+		return strings.TrimRight(decodeUTF16(data), "\x00")
+	case reg.MULTI_SZ:
+		s := strings.TrimRight(decodeUTF16(data), "\x00")
+		if s == "" {
+			return []string{}
+		}
+		return strings.Split(s, "\x00")
+	case reg.DWORD:
+		if len(data) == 4 {
+			return uint64(binary.LittleEndian.Uint32(data))
+		}
+	case reg.DWORD_BIG_ENDIAN:
+		if len(data) == 4 {
+			return uint64(binary.BigEndian.Uint32(data))
+		}
+	case reg.QWORD:
+		if len(data) == 8 {
+			return binary.LittleEndian.Uint64(data)
+		}
+	}
+	return data
+}
+
+// GetValuesTyped gets all the values under root, keyed by name, opening the key only
+// once. See typedValue for the Go types used.
+func GetValuesTyped(root string) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	k, err := openKey(root, reg.READ)
+	if err != nil {
+		return values, err
+	}
+	defer k.Close()
+	names, err := k.ReadValueNames(-1)
+	if err != nil {
+		return values, err
+	}
+	for _, name := range names {
+		data, typ, err := readValue(k, name)
+		if err != nil {
+			return values, err
+		}
+		values[name] = typedValue(typ, data)
+	}
+	return values, nil
+}
+
+// RemoveFromMultiString removes every occurrence of values from a multi-string
+// (REG_MULTI_SZ) value, keeping the order of the remaining strings. Removing from a value
+// that does not exist is not an error.
+func RemoveFromMultiString(root, name string, values ...string) error {
+	return updateMultiString(root, name, func(list []string) []string {
+		return removeAll(list, values...)
+	})
+}
+
+// SetBinary sets a binary (REG_BINARY) value in the registry.
+func SetBinary(root, name string, value []byte) error {
+	k, err := openKey(root, reg.WRITE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	return k.SetBinaryValue(name, value)
+}
+
+// SetExpandString sets an expandable string (REG_EXPAND_SZ) value in the registry.
+func SetExpandString(root, name, value string) error {
+	k, err := openKey(root, reg.WRITE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	return k.SetExpandStringValue(name, value)
+}
+
 // SetInteger sets a string key in the registry.
 func SetInteger(root, name string, value int) error {
-	k, err := reg.OpenKey(reg.LOCAL_MACHINE, root, reg.WRITE)
+	k, err := openKey(root, reg.WRITE)
 	if err != nil {
 		return err
 	}
@@ -96,9 +362,19 @@ func SetInteger(root, name string, value int) error {
 	return k.SetDWordValue(name, uint32(value))
 }
 
+// SetQword sets a 64-bit integer (REG_QWORD) value in the registry.
+func SetQword(root, name string, value uint64) error {
+	k, err := openKey(root, reg.WRITE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	return k.SetQWordValue(name, value)
+}
+
 // SetString sets a string key in the registry.
 func SetString(root, name, value string) error {
-	k, err := reg.OpenKey(reg.LOCAL_MACHINE, root, reg.WRITE)
+	k, err := openKey(root, reg.WRITE)
 	if err != nil {
 		return err
 	}
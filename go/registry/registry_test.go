@@ -15,10 +15,13 @@
 package registry
 
 import (
+	"bytes"
 	"errors"
+	"os"
 	"syscall"
 	"testing"
 
+	"github.com/google/go-cmp/cmp"
 	"golang.org/x/sys/windows/registry"
 )
 
@@ -103,3 +106,304 @@ func TestSetString(t *testing.T) {
 		registry.DeleteKey(registry.LOCAL_MACHINE, rootKey)
 	}
 }
+
+func TestSplitHive(t *testing.T) {
+	tests := []struct {
+		in       string
+		wantHive registry.Key
+		wantPath string
+	}{
+		{`SOFTWARE\Google`, registry.LOCAL_MACHINE, `SOFTWARE\Google`},
+		{`HKLM\SOFTWARE\Google`, registry.LOCAL_MACHINE, `SOFTWARE\Google`},
+		{`HKEY_CURRENT_USER\Software\Google`, registry.CURRENT_USER, `Software\Google`},
+		{`hkcu\Software`, registry.CURRENT_USER, `Software`},
+		{`HKU\.DEFAULT\Control Panel`, registry.USERS, `.DEFAULT\Control Panel`},
+		{`HKCR`, registry.CLASSES_ROOT, ``},
+		{`HKCUX\Software`, registry.LOCAL_MACHINE, `HKCUX\Software`},
+	}
+	for _, tt := range tests {
+		hive, path := splitHive(tt.in)
+		if hive != tt.wantHive || path != tt.wantPath {
+			t.Errorf("splitHive(%s) = (%v, %s), want (%v, %s)", tt.in, hive, path, tt.wantHive, tt.wantPath)
+		}
+	}
+}
+
+func TestCurrentUser(t *testing.T) {
+	key := `HKCU\` + rootKey
+	if err := Create(key); err != nil {
+		t.Fatalf("Create(%s) produced unexpected error %v", key, err)
+	}
+	defer registry.DeleteKey(registry.CURRENT_USER, rootKey)
+	if err := SetString(key, "Test1", "one"); err != nil {
+		t.Fatalf("SetString(%s) returned %v", key, err)
+	}
+	got, err := GetString(key, "Test1")
+	if err != nil {
+		t.Fatalf("GetString(%s) returned %v", key, err)
+	}
+	if got != "one" {
+		t.Errorf("GetString(%s) = %s, want one", key, got)
+	}
+	if _, err := GetString(rootKey, "Test1"); !errors.Is(err, ErrNotExist) {
+		t.Errorf("GetString(%s) returned %v, want %v", rootKey, err, ErrNotExist)
+	}
+}
+
+func TestSetBinary(t *testing.T) {
+	if err := createKey(rootKey); err != nil {
+		t.Fatalf("createKey(%s) produced unexpected error %v", rootKey, err)
+	}
+	defer registry.DeleteKey(registry.LOCAL_MACHINE, rootKey)
+	in := []byte{0x00, 0x01, 0xfe, 0xff}
+	if err := SetBinary(rootKey, "Test1", in); err != nil {
+		t.Fatalf("SetBinary(%v) returned %v", in, err)
+	}
+	got, err := GetBinary(rootKey, "Test1")
+	if err != nil {
+		t.Fatalf("Verifying SetBinary(%v) returned %v", in, err)
+	}
+	if !bytes.Equal(got, in) {
+		t.Errorf("SetBinary(%v) = %v, want %v", in, got, in)
+	}
+}
+
+func TestSetExpandString(t *testing.T) {
+	if err := createKey(rootKey); err != nil {
+		t.Fatalf("createKey(%s) produced unexpected error %v", rootKey, err)
+	}
+	defer registry.DeleteKey(registry.LOCAL_MACHINE, rootKey)
+	in := `%SystemRoot%\System32`
+	if err := SetExpandString(rootKey, "Test1", in); err != nil {
+		t.Fatalf("SetExpandString(%s) returned %v", in, err)
+	}
+	tests := []struct {
+		expand bool
+		want   string
+	}{
+		{false, in},
+		{true, os.Getenv("SystemRoot") + `\System32`},
+	}
+	for _, tt := range tests {
+		got, err := GetExpandString(rootKey, "Test1", tt.expand)
+		if err != nil {
+			t.Errorf("GetExpandString(%t) returned %v", tt.expand, err)
+		}
+		if got != tt.want {
+			t.Errorf("GetExpandString(%t) = %s, want %s", tt.expand, got, tt.want)
+		}
+	}
+}
+
+func TestSetQword(t *testing.T) {
+	if err := createKey(rootKey); err != nil {
+		t.Fatalf("createKey(%s) produced unexpected error %v", rootKey, err)
+	}
+	defer registry.DeleteKey(registry.LOCAL_MACHINE, rootKey)
+	var in uint64 = 1 << 40
+	if err := SetQword(rootKey, "Test1", in); err != nil {
+		t.Fatalf("SetQword(%d) returned %v", in, err)
+	}
+	got, err := GetQword(rootKey, "Test1")
+	if err != nil {
+		t.Fatalf("Verifying SetQword(%d) returned %v", in, err)
+	}
+	if got != in {
+		t.Errorf("SetQword(%d) = %d, want %d", in, got, in)
+	}
+}
+
+func TestGetDefault(t *testing.T) {
+	if err := createKey(rootKey); err != nil {
+		t.Fatalf("createKey(%s) produced unexpected error %v", rootKey, err)
+	}
+	defer registry.DeleteKey(registry.LOCAL_MACHINE, rootKey)
+	if err := SetString(rootKey, "Test1", "one"); err != nil {
+		t.Fatalf("SetString() returned %v", err)
+	}
+	if err := SetInteger(rootKey, "Test2", 2); err != nil {
+		t.Fatalf("SetInteger() returned %v", err)
+	}
+	tests := []struct {
+		root    string
+		name    string
+		wantStr string
+		wantInt uint64
+	}{
+		{rootKey, "Test1", "one", 0},
+		{rootKey, "Test2", "", 2},
+		{rootKey, "Missing", "default", 42},
+		{rootKey + `\Missing`, "Test1", "default", 42},
+	}
+	for _, tt := range tests {
+		if tt.wantStr != "" {
+			got, err := GetStringDefault(tt.root, tt.name, "default")
+			if err != nil || got != tt.wantStr {
+				t.Errorf("GetStringDefault(%s, %s) = (%s, %v), want %s", tt.root, tt.name, got, err, tt.wantStr)
+			}
+		}
+		if tt.wantInt != 0 {
+			got, err := GetIntegerDefault(tt.root, tt.name, 42)
+			if err != nil || got != tt.wantInt {
+				t.Errorf("GetIntegerDefault(%s, %s) = (%d, %v), want %d", tt.root, tt.name, got, err, tt.wantInt)
+			}
+		}
+	}
+}
+
+func TestTypedValue(t *testing.T) {
+	tests := []struct {
+		typ  uint32
+		data []byte
+		want interface{}
+	}{
+		{registry.SZ, encodeUTF16("one\x00"), "one"},
+		{registry.EXPAND_SZ, encodeUTF16("%T%\x00"), "%T%"},
+		{registry.MULTI_SZ, encodeUTF16("a\x00b\x00\x00"), []string{"a", "b"}},
+		{registry.MULTI_SZ, encodeUTF16("\x00"), []string{}},
+		{registry.DWORD, []byte{0x2a, 0, 0, 0}, uint64(42)},
+		{registry.DWORD_BIG_ENDIAN, []byte{0, 0, 0, 0x2a}, uint64(42)},
+		{registry.QWORD, []byte{0, 0, 0, 0, 0, 1, 0, 0}, uint64(1 << 40)},
+		{registry.BINARY, []byte{0xff}, []byte{0xff}},
+		{registry.DWORD, []byte{0xff}, []byte{0xff}},
+	}
+	for _, tt := range tests {
+		got := typedValue(tt.typ, tt.data)
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("typedValue(%d, %v) returned unexpected diff (-want +got):\n%s", tt.typ, tt.data, diff)
+		}
+	}
+}
+
+func TestGetValuesTyped(t *testing.T) {
+	if err := createKey(rootKey); err != nil {
+		t.Fatalf("createKey(%s) produced unexpected error %v", rootKey, err)
+	}
+	defer registry.DeleteKey(registry.LOCAL_MACHINE, rootKey)
+	if err := SetString(rootKey, "Test1", "one"); err != nil {
+		t.Fatalf("SetString() returned %v", err)
+	}
+	if err := SetInteger(rootKey, "Test2", 2); err != nil {
+		t.Fatalf("SetInteger() returned %v", err)
+	}
+	if err := SetBinary(rootKey, "Test3", []byte{0x03}); err != nil {
+		t.Fatalf("SetBinary() returned %v", err)
+	}
+	want := map[string]interface{}{
+		"Test1": "one",
+		"Test2": uint64(2),
+		"Test3": []byte{0x03},
+	}
+	got, err := GetValuesTyped(rootKey)
+	if err != nil {
+		t.Fatalf("GetValuesTyped(%s) returned %v", rootKey, err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("GetValuesTyped(%s) returned unexpected diff (-want +got):\n%s", rootKey, diff)
+	}
+}
+
+func TestCopyKey(t *testing.T) {
+	src := rootKey + `\Src`
+	dst := rootKey + `\Dst`
+	if err := createKey(src + `\Child`); err != nil {
+		t.Fatalf("createKey(%s) produced unexpected error %v", src, err)
+	}
+	defer deleteTree(rootKey)
+	if err := SetString(src, "Test1", "one"); err != nil {
+		t.Fatalf("SetString() returned %v", err)
+	}
+	if err := SetInteger(src+`\Child`, "Test2", 2); err != nil {
+		t.Fatalf("SetInteger() returned %v", err)
+	}
+
+	tests := []struct {
+		dst       string
+		recursive bool
+		wantChild bool
+	}{
+		{dst, false, false},
+		{dst + `\Recursive`, true, true},
+		// Copying beneath the source only copies what existed beforehand.
+		{src + `\Nested`, true, true},
+	}
+	for _, tt := range tests {
+		if err := CopyKey(src, tt.dst, tt.recursive); err != nil {
+			t.Errorf("CopyKey(%s, %s, %t) returned %v", src, tt.dst, tt.recursive, err)
+			continue
+		}
+		if got, err := GetString(tt.dst, "Test1"); err != nil || got != "one" {
+			t.Errorf("CopyKey(%s, %s, %t): GetString() = (%s, %v), want one", src, tt.dst, tt.recursive, got, err)
+		}
+		_, err := GetInteger(tt.dst+`\Child`, "Test2")
+		if (err == nil) != tt.wantChild {
+			t.Errorf("CopyKey(%s, %s, %t): GetInteger(Child) returned %v, want child copied: %t", src, tt.dst, tt.recursive, err, tt.wantChild)
+		}
+	}
+	if _, err := GetSubkeys(src + `\Nested\Nested`); !errors.Is(err, ErrNotExist) {
+		t.Errorf("CopyKey(%s, %s) recursed into its own destination: %v", src, src+`\Nested`, err)
+	}
+}
+
+func TestAppendUnique(t *testing.T) {
+	tests := []struct {
+		list   []string
+		values []string
+		want   []string
+	}{
+		{[]string{}, []string{"a", "b", "a"}, []string{"a", "b"}},
+		{[]string{"b", "a"}, []string{"a", "c"}, []string{"b", "a", "c"}},
+		{[]string{"a", "a"}, []string{"a"}, []string{"a", "a"}},
+	}
+	for _, tt := range tests {
+		got := appendUnique(tt.list, tt.values...)
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("appendUnique(%v, %v) returned unexpected diff (-want +got):\n%s", tt.list, tt.values, diff)
+		}
+	}
+}
+
+func TestRemoveAll(t *testing.T) {
+	tests := []struct {
+		list   []string
+		values []string
+		want   []string
+	}{
+		{[]string{"a", "b", "a", "c"}, []string{"a"}, []string{"b", "c"}},
+		{[]string{"a", "b"}, []string{"c"}, []string{"a", "b"}},
+		{[]string{"a"}, []string{"a"}, []string{}},
+	}
+	for _, tt := range tests {
+		got := removeAll(tt.list, tt.values...)
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("removeAll(%v, %v) returned unexpected diff (-want +got):\n%s", tt.list, tt.values, diff)
+		}
+	}
+}
+
+func TestMultiString(t *testing.T) {
+	if err := createKey(rootKey); err != nil {
+		t.Fatalf("createKey(%s) produced unexpected error %v", rootKey, err)
+	}
+	defer registry.DeleteKey(registry.LOCAL_MACHINE, rootKey)
+	if err := RemoveFromMultiString(rootKey, "Test1", "a"); err != nil {
+		t.Errorf("RemoveFromMultiString(missing) returned %v", err)
+	}
+	if err := AppendMultiString(rootKey, "Test1", "a", "b"); err != nil {
+		t.Fatalf("AppendMultiString() returned %v", err)
+	}
+	if err := AppendMultiString(rootKey, "Test1", "b", "c"); err != nil {
+		t.Fatalf("AppendMultiString() returned %v", err)
+	}
+	if err := RemoveFromMultiString(rootKey, "Test1", "a"); err != nil {
+		t.Fatalf("RemoveFromMultiString() returned %v", err)
+	}
+	got, err := GetValuesTyped(rootKey)
+	if err != nil {
+		t.Fatalf("GetValuesTyped(%s) returned %v", rootKey, err)
+	}
+	want := map[string]interface{}{"Test1": []string{"b", "c"}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("multi-string updates returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
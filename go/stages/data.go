@@ -0,0 +1,89 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build windows
+
+package stages
+
+import (
+	"fmt"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// regDataKey is the subkey of a stage holding its metadata, kept apart from Start and End.
+const regDataKey = "Data"
+
+func (m *Manager) dataKey(stageID uint64) string {
+	return fmt.Sprintf(`%s\%d\%s`, m.Root, stageID, regDataKey)
+}
+
+// SetStageData records a metadata value for a stage, eg an error message or the version of
+// an artifact it installed, for later stages and reporting to read back.
+func (m *Manager) SetStageData(stageID uint64, key, value string) error {
+	k, _, err := registry.CreateKey(m.Hive, m.dataKey(stageID), registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	return k.SetStringValue(key, value)
+}
+
+// GetStageData returns the metadata value key recorded for a stage. The error is
+// registry.ErrNotExist if no such value has been recorded.
+func (m *Manager) GetStageData(stageID uint64, key string) (string, error) {
+	return readKey(m.Hive, m.dataKey(stageID), key)
+}
+
+// GetAllStageData returns all metadata recorded for a stage.
+func (m *Manager) GetAllStageData(stageID uint64) (map[string]string, error) {
+	k, err := registry.OpenKey(m.Hive, m.dataKey(stageID), registry.QUERY_VALUE)
+	if err == registry.ErrNotExist {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer k.Close()
+	names, err := k.ReadValueNames(-1)
+	if err != nil {
+		return nil, err
+	}
+	data := map[string]string{}
+	for _, n := range names {
+		v, _, err := k.GetStringValue(n)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", n, err)
+		}
+		data[n] = v
+	}
+	return data, nil
+}
+
+// SetStageData records a metadata value for a machine build stage. See
+// Manager.SetStageData.
+func SetStageData(stageID uint64, key, value string) error {
+	return Default.SetStageData(stageID, key, value)
+}
+
+// GetStageData returns a metadata value recorded for a machine build stage. See
+// Manager.GetStageData.
+func GetStageData(stageID uint64, key string) (string, error) {
+	return Default.GetStageData(stageID, key)
+}
+
+// GetAllStageData returns all metadata recorded for a machine build stage.
+func GetAllStageData(stageID uint64) (map[string]string, error) {
+	return Default.GetAllStageData(stageID)
+}
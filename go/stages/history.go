@@ -0,0 +1,125 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build windows
+
+package stages
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// Stage describes a single build stage.
+type Stage struct {
+	ID    uint64
+	Start time.Time
+	// End is the zero time while the stage has not completed.
+	End time.Time
+	// Duration is the time the stage took, or for a stage that has not completed, the time
+	// it has been running for.
+	Duration time.Duration
+	// Substages holds the substages of the stage, ordered by ID.
+	Substages []Stage
+}
+
+// readTime reads the timestamp value name from key, returning the zero time if the value
+// is not set.
+func (m *Manager) readTime(key, name string) (time.Time, error) {
+	v, err := readKey(m.Hive, key, name)
+	if err == registry.ErrNotExist {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := parseTime(v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s\\%s: %w", key, name, err)
+	}
+	return t, nil
+}
+
+// getStage reads the stage id.
+func (m *Manager) getStage(id uint64) (Stage, error) {
+	s := Stage{ID: id}
+	key := m.stageKey(strconv.FormatUint(id, 10))
+	var err error
+	if s.Start, err = m.readTime(key, "Start"); err != nil {
+		return s, err
+	}
+	if s.End, err = m.readTime(key, "End"); err != nil {
+		return s, err
+	}
+	subs, err := m.substages(id).History()
+	if err != nil {
+		return s, err
+	}
+	if len(subs) > 0 {
+		s.Substages = subs
+	}
+	s.Duration = duration(s.Start, s.End, time.Now().UTC())
+	return s, nil
+}
+
+func duration(start, end, now time.Time) time.Duration {
+	switch {
+	case start.IsZero():
+		return 0
+	case end.IsZero():
+		return now.Sub(start)
+	}
+	return end.Sub(start)
+}
+
+// History returns every recorded stage, ordered by stage ID.
+func (m *Manager) History() ([]Stage, error) {
+	k, err := registry.OpenKey(m.Hive, m.Root, registry.ENUMERATE_SUB_KEYS)
+	if err == registry.ErrNotExist {
+		return []Stage{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	names, err := k.ReadSubKeyNames(-1)
+	k.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	stages := []Stage{}
+	for _, n := range names {
+		id, err := strconv.ParseUint(n, 10, 64)
+		if err != nil {
+			// Not a stage.
+			continue
+		}
+		s, err := m.getStage(id)
+		if err != nil {
+			return nil, err
+		}
+		stages = append(stages, s)
+	}
+	sort.Slice(stages, func(i, j int) bool { return stages[i].ID < stages[j].ID })
+	return stages, nil
+}
+
+// History returns every stage recorded for the machine, ordered by stage ID.
+func History() ([]Stage, error) {
+	return Default.History()
+}
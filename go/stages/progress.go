@@ -0,0 +1,84 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build windows
+
+package stages
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrUnknownStage indicates that the active stage is not part of the stage sequence.
+	ErrUnknownStage = errors.New("stage not in sequence")
+)
+
+// ProgressStatus describes how far a build has progressed through its stage sequence.
+type ProgressStatus struct {
+	// Active is the active stage, or 0 if no stage has started.
+	Active uint64
+	// Percent is the share of stages in the sequence that have completed.
+	Percent   int
+	Completed []uint64
+	// Remaining holds the stages yet to complete, including the active stage while it runs.
+	Remaining []uint64
+}
+
+func progress(sequence []uint64, active Stage) (ProgressStatus, error) {
+	p := ProgressStatus{Active: active.ID}
+	if len(sequence) == 0 {
+		return p, fmt.Errorf("%w: empty sequence", ErrUnknownStage)
+	}
+	done := 0
+	if active.ID != 0 {
+		idx := -1
+		for i, id := range sequence {
+			if id == active.ID {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return p, fmt.Errorf("%w: %d", ErrUnknownStage, active.ID)
+		}
+		done = idx
+		if !active.End.IsZero() {
+			done++
+		}
+	}
+	p.Completed = append([]uint64{}, sequence[:done]...)
+	p.Remaining = append([]uint64{}, sequence[done:]...)
+	p.Percent = done * 100 / len(sequence)
+	return p, nil
+}
+
+// Progress reports the progress of the build through sequence, the IDs of its stages in the
+// order they run.
+func (m *Manager) Progress(sequence []uint64) (ProgressStatus, error) {
+	active, err := m.activeStage()
+	if err != nil {
+		return ProgressStatus{}, err
+	}
+	return progress(sequence, active)
+}
+
+// Progress reports the progress of the machine build through sequence. See
+// Manager.Progress.
+//
+// Example: stages.Progress([]uint64{10, 20, 30, 40, 50})
+func Progress(sequence []uint64) (ProgressStatus, error) {
+	return Default.Progress(sequence)
+}
@@ -0,0 +1,181 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build windows
+
+package stages
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+// regTransitionKey records a stage transition while SetStage makes it.
+const regTransitionKey = "_Transition"
+
+// transition describes a change of the active stage from From (0 for none) to To.
+type transition struct {
+	From uint64
+	To   uint64
+	Time time.Time
+}
+
+func (t transition) String() string {
+	return fmt.Sprintf("%d,%d,%s", t.From, t.To, t.Time.UTC().Format(timeFormat))
+}
+
+func parseTransition(s string) (transition, error) {
+	t := transition{}
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return t, fmt.Errorf("invalid transition %q", s)
+	}
+	var err error
+	if t.From, err = strconv.ParseUint(parts[0], 10, 64); err != nil {
+		return t, fmt.Errorf("invalid transition %q: %w", s, err)
+	}
+	if t.To, err = strconv.ParseUint(parts[1], 10, 64); err != nil {
+		return t, fmt.Errorf("invalid transition %q: %w", s, err)
+	}
+	if t.Time, err = parseTime(parts[2]); err != nil {
+		return t, fmt.Errorf("invalid transition %q: %w", s, err)
+	}
+	return t, nil
+}
+
+// apply makes the registry writes of a transition. Each write is idempotent, so apply can
+// be repeated to complete an interrupted transition.
+func (m *Manager) apply(t transition) error {
+	ts := t.Time.UTC().Format(timeFormat)
+	if t.From != 0 {
+		if err := m.endSubstage(t.From, t.Time); err != nil {
+			return err
+		}
+		if err := m.setValue(m.stageKey(strconv.FormatUint(t.From, 10)), "End", ts); err != nil {
+			return fmt.Errorf("ending stage %d: %w", t.From, err)
+		}
+	}
+	to := m.stageKey(strconv.FormatUint(t.To, 10))
+	if err := m.setValue(to, "Start", ts); err != nil {
+		return fmt.Errorf("starting stage %d: %w", t.To, err)
+	}
+	// Clear the End time of an earlier run of the same stage.
+	if err := m.deleteValue(to, "End"); err != nil {
+		return fmt.Errorf("starting stage %d: %w", t.To, err)
+	}
+	if err := m.setValue(m.Root, regActiveKey, strconv.FormatUint(t.To, 10)); err != nil {
+		return fmt.Errorf("activating stage %d: %w", t.To, err)
+	}
+	return nil
+}
+
+// savedValue holds a registry value as it was before a transition.
+type savedValue struct {
+	path, name string
+	value      string
+	exists     bool
+}
+
+// snapshot saves the values a transition changes, for restore to roll back to.
+func (m *Manager) snapshot(t transition) ([]savedValue, error) {
+	saved := []savedValue{{path: m.Root, name: regActiveKey}}
+	to := m.stageKey(strconv.FormatUint(t.To, 10))
+	saved = append(saved, savedValue{path: to, name: "Start"}, savedValue{path: to, name: "End"})
+	if t.From != 0 {
+		saved = append(saved, savedValue{path: m.stageKey(strconv.FormatUint(t.From, 10)), name: "End"})
+	}
+	for i, v := range saved {
+		val, err := readKey(m.Hive, v.path, v.name)
+		switch {
+		case err == nil:
+			saved[i].value, saved[i].exists = val, true
+		case err != registry.ErrNotExist:
+			return nil, err
+		}
+	}
+	return saved, nil
+}
+
+// restore rolls back to a snapshot and discards the recorded transition.
+func (m *Manager) restore(saved []savedValue) error {
+	for _, v := range saved {
+		var err error
+		if v.exists {
+			err = m.setValue(v.path, v.name, v.value)
+		} else {
+			err = m.deleteValue(v.path, v.name)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return m.deleteValue(m.Root, regTransitionKey)
+}
+
+// Repair brings the stage state back to consistency, reporting whether anything needed
+// repairing:
+//
+// - A transition interrupted part way through SetStage is completed.
+// - An active stage that was never started is replaced by the most recently started stage.
+//
+// Repair is safe to call at any time, eg when an agent starts.
+func (m *Manager) Repair() (bool, error) {
+	v, err := readKey(m.Hive, m.Root, regTransitionKey)
+	switch {
+	case err == nil:
+		if t, perr := parseTransition(v); perr == nil {
+			if err := m.apply(t); err != nil {
+				return false, err
+			}
+		}
+		return true, m.deleteValue(m.Root, regTransitionKey)
+	case err != registry.ErrNotExist:
+		return false, err
+	}
+
+	active, err := m.getActiveStage()
+	if err != nil || active == "0" {
+		return false, err
+	}
+	if _, err := m.getActiveTime(active); err != registry.ErrNotExist {
+		// Started, or unreadable for reasons Repair cannot fix.
+		return false, nil
+	}
+	hist, err := m.History()
+	if err != nil {
+		return false, err
+	}
+	var latest *Stage
+	for i, s := range hist {
+		if strconv.FormatUint(s.ID, 10) == active || s.Start.IsZero() {
+			continue
+		}
+		if latest == nil || s.Start.After(latest.Start) {
+			latest = &hist[i]
+		}
+	}
+	if latest == nil {
+		return true, m.deleteValue(m.Root, regActiveKey)
+	}
+	return true, m.setValue(m.Root, regActiveKey, strconv.FormatUint(latest.ID, 10))
+}
+
+// Repair brings the machine build stages back to consistency. See Manager.Repair.
+func Repair() (bool, error) {
+	return Default.Repair()
+}
@@ -29,16 +29,52 @@ const (
 	defaultTimeout = 60 * 24 * 7 * time.Minute // 7 days
 	regStagesRoot  = `SOFTWARE\Glazier\Stages`
 	regActiveKey   = "_Active"
+	// timeFormat matches the timestamps written by the Python stage implementation. Parsing
+	// accepts timestamps with or without fractional seconds.
+	timeFormat = "2006-01-02T15:04:05.000000"
 )
 
-func checkExpiration(stageID string) error {
+// Manager tracks build stages under a registry key. Separate managers let flows such as
+// user-context provisioning track their own stages without colliding with the machine
+// build.
+type Manager struct {
+	// Hive is the registry hive holding the stages, registry.LOCAL_MACHINE or
+	// registry.CURRENT_USER.
+	Hive registry.Key
+	// Root is the path of the stages key within Hive.
+	Root string
+	// EventSource, if set, is the event log source stage transitions are written to, eg
+	// "Glazier". Transitions are then kept in the Windows event log even if the registry is
+	// wiped. The source should be registered with eventlog.InstallAsEventCreate.
+	EventSource string
+
+	// parent labels the stages of a substage manager, eg "40.".
+	parent string
+}
+
+// NewManager returns a Manager for the stages under root in hive.
+//
+// Example: stages.NewManager(registry.CURRENT_USER, `SOFTWARE\Glazier\UserStages`)
+func NewManager(hive registry.Key, root string) *Manager {
+	return &Manager{Hive: hive, Root: root}
+}
+
+// Default manages the machine build stages. The package level functions operate on it.
+var Default = NewManager(registry.LOCAL_MACHINE, regStagesRoot)
+
+// stageKey returns the path of a stage key.
+func (m *Manager) stageKey(stageID string) string {
+	return fmt.Sprintf(`%s\%s`, m.Root, stageID)
+}
+
+func (m *Manager) checkExpiration(stageID string) error {
 	// TODO: Implement stage expiration here
-	_, err := getActiveTime(regStagesRoot, stageID)
+	_, err := m.getActiveTime(stageID)
 	return err
 }
 
-func getActiveStage(root string) (string, error) {
-	active, err := readKey(root, regActiveKey)
+func (m *Manager) getActiveStage() (string, error) {
+	active, err := readKey(m.Hive, m.Root, regActiveKey)
 	if err != nil {
 		if err != registry.ErrNotExist {
 			return "", err
@@ -48,16 +84,20 @@ func getActiveStage(root string) (string, error) {
 	return active, nil
 }
 
-func getActiveTime(root, stageID string) (time.Time, error) {
-	active, err := readKey(fmt.Sprintf(`%s\%s`, root, stageID), "Start")
+func (m *Manager) getActiveTime(stageID string) (time.Time, error) {
+	active, err := readKey(m.Hive, m.stageKey(stageID), "Start")
 	if err != nil {
 		return time.Time{}, err
 	}
-	return time.Parse("2006-01-02T15:04:05.000000", active)
+	return parseTime(active)
 }
 
-func readKey(root, key string) (string, error) {
-	k, err := registry.OpenKey(registry.LOCAL_MACHINE, root, registry.QUERY_VALUE)
+func parseTime(s string) (time.Time, error) {
+	return time.Parse("2006-01-02T15:04:05", s)
+}
+
+func readKey(hive registry.Key, root, key string) (string, error) {
+	k, err := registry.OpenKey(hive, root, registry.QUERY_VALUE)
 	if err != nil {
 		return "", err
 	}
@@ -67,16 +107,21 @@ func readKey(root, key string) (string, error) {
 	return active, err
 }
 
-// GetActiveStage returns the active build stage for the machine.
-func GetActiveStage() (uint64, error) {
-	stage, err := getActiveStage(regStagesRoot)
+// ActiveStage returns the active build stage.
+func (m *Manager) ActiveStage() (uint64, error) {
+	stage, err := m.getActiveStage()
 	if err != nil {
 		return 0, err
 	}
-	err = checkExpiration(stage)
+	err = m.checkExpiration(stage)
 	if err != nil {
 		return 0, err
 	}
 
 	return strconv.ParseUint(stage, 10, 64)
 }
+
+// GetActiveStage returns the active build stage for the machine.
+func GetActiveStage() (uint64, error) {
+	return Default.ActiveStage()
+}
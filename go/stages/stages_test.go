@@ -14,8 +14,13 @@
 package stages
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
 
 	"golang.org/x/sys/windows/registry"
 )
@@ -41,13 +46,17 @@ func createTestKeys(subKeys ...string) error {
 	return nil
 }
 
+func testManager(root string) *Manager {
+	return NewManager(registry.LOCAL_MACHINE, root)
+}
+
 func cleanupTestKey() error {
 	return registry.DeleteKey(registry.LOCAL_MACHINE, testStageRoot)
 }
 
 func TestGetActiveStageNoRootKey(t *testing.T) {
 	testID := "TestGetActiveStageNoRootKey"
-	stage, err := getActiveStage(testStageRoot + `\` + testID)
+	stage, err := testManager(testStageRoot + `\` + testID).getActiveStage()
 	if err != nil {
 		t.Errorf("%s(): raised unexpected error %v", testID, err)
 	}
@@ -63,7 +72,7 @@ func TestGetActiveStageNoActiveKey(t *testing.T) {
 	}
 	defer cleanupTestKey()
 
-	stage, err := getActiveStage(testStageRoot + `\` + testID)
+	stage, err := testManager(testStageRoot + `\` + testID).getActiveStage()
 	if err != nil {
 		t.Errorf("%s(): raised unexpected error %v", testID, err)
 	}
@@ -90,7 +99,7 @@ func TestGetActiveStageInProgress(t *testing.T) {
 	}
 	k.Close()
 
-	stage, err := getActiveStage(subKey)
+	stage, err := testManager(subKey).getActiveStage()
 	if err != nil {
 		t.Errorf("%s(): raised unexpected error %v", testID, err)
 	}
@@ -117,7 +126,7 @@ func TestGetActiveStageTypeError(t *testing.T) {
 	}
 	k.Close()
 
-	if _, err := getActiveStage(subKey); err == nil {
+	if _, err := testManager(subKey).getActiveStage(); err == nil {
 		t.Errorf("%s(): failed to raise expected error", testID)
 	}
 }
@@ -140,7 +149,7 @@ func TestGetActiveTime(t *testing.T) {
 		t.Fatal(err)
 	}
 	k.Close()
-	_, err = getActiveTime(testKey, "5")
+	_, err = testManager(testKey).getActiveTime("5")
 	if err != nil {
 		t.Errorf("%s(): raised unexpected error %v", testID, err)
 	}
@@ -164,7 +173,7 @@ func TestGetActiveTimeParseError(t *testing.T) {
 		t.Fatal(err)
 	}
 	k.Close()
-	_, err = getActiveTime(testKey, "5")
+	_, err = testManager(testKey).getActiveTime("5")
 	if err == nil {
 		t.Errorf("%s(): failed to raise expected error", testID)
 	}
@@ -172,8 +181,456 @@ func TestGetActiveTimeParseError(t *testing.T) {
 
 func TestGetActiveTimeNoKey(t *testing.T) {
 	testID := "TestGetActiveTimeNoKey"
-	_, err := getActiveTime(testStageRoot, "3")
+	_, err := testManager(testStageRoot).getActiveTime("3")
 	if err == nil {
 		t.Errorf("%s(): failed to raise expected error", testID)
 	}
 }
+
+func writeStage(root string, id uint64, start, end string) error {
+	k, _, err := registry.CreateKey(registry.LOCAL_MACHINE, fmt.Sprintf(`%s\%d`, root, id), registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	if start != "" {
+		if err := k.SetStringValue("Start", start); err != nil {
+			return err
+		}
+	}
+	if end != "" {
+		return k.SetStringValue("End", end)
+	}
+	return nil
+}
+
+func TestHistory(t *testing.T) {
+	testID := "TestHistory"
+	testKey := fmt.Sprintf(`%s\%s`, testStageRoot, testID)
+	if err := createTestKeys(testID, "NotAStage"); err != nil {
+		t.Fatal(err)
+	}
+	defer cleanupTestKey()
+	for _, s := range []struct {
+		id         uint64
+		start, end string
+	}{
+		{10, "2019-11-06T17:37:43.279253", "2019-11-06T17:47:43.279253"},
+		{2, "2019-11-06T17:30:00", "2019-11-06T17:37:43.279253"},
+	} {
+		if err := writeStage(testKey, s.id, s.start, s.end); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := testManager(testKey).History()
+	if err != nil {
+		t.Fatalf("%s(): raised unexpected error %v", testID, err)
+	}
+	want := []Stage{
+		{
+			ID:       2,
+			Start:    time.Date(2019, 11, 6, 17, 30, 0, 0, time.UTC),
+			End:      time.Date(2019, 11, 6, 17, 37, 43, 279253000, time.UTC),
+			Duration: 7*time.Minute + 43279253*time.Microsecond,
+		},
+		{
+			ID:       10,
+			Start:    time.Date(2019, 11, 6, 17, 37, 43, 279253000, time.UTC),
+			End:      time.Date(2019, 11, 6, 17, 47, 43, 279253000, time.UTC),
+			Duration: 10 * time.Minute,
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("%s(): returned unexpected diff (-want +got):\n%s", testID, diff)
+	}
+}
+
+func TestHistoryNoRootKey(t *testing.T) {
+	got, err := testManager(testStageRoot + `\TestHistoryNoRootKey`).History()
+	if err != nil || len(got) != 0 {
+		t.Errorf("TestHistoryNoRootKey(): got %v, %v, want no stages", got, err)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	start := time.Date(2019, 11, 6, 17, 0, 0, 0, time.UTC)
+	now := start.Add(time.Hour)
+	tests := []struct {
+		start, end time.Time
+		want       time.Duration
+	}{
+		{start, start.Add(time.Minute), time.Minute},
+		{start, time.Time{}, time.Hour},
+		{time.Time{}, time.Time{}, 0},
+	}
+	for _, tt := range tests {
+		if got := duration(tt.start, tt.end, now); got != tt.want {
+			t.Errorf("duration(%v, %v) = %v, want %v", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	testID := "TestWatch"
+	testKey := fmt.Sprintf(`%s\%s`, testStageRoot, testID)
+	if err := createTestKeys(testID); err != nil {
+		t.Fatal(err)
+	}
+	defer cleanupTestKey()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	ch, err := testManager(testKey).Watch(ctx)
+	if err != nil {
+		t.Fatalf("%s(): raised unexpected error %v", testID, err)
+	}
+	if s := <-ch; s.ID != 0 {
+		t.Errorf("%s(): got initial stage %d, want 0", testID, s.ID)
+	}
+
+	if err := writeStage(testKey, 3, "2019-11-06T17:37:43.279253", ""); err != nil {
+		t.Fatal(err)
+	}
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, testKey, registry.WRITE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = k.SetStringValue("_Active", "3"); err != nil {
+		t.Fatal(err)
+	}
+	k.Close()
+
+	for s := range ch {
+		if s.ID == 3 {
+			cancel()
+			for range ch {
+			}
+			return
+		}
+	}
+	t.Errorf("%s(): channel closed before stage 3 was reported", testID)
+}
+
+func TestStageData(t *testing.T) {
+	testID := "TestStageData"
+	testKey := fmt.Sprintf(`%s\%s`, testStageRoot, testID)
+	if err := createTestKeys(testID); err != nil {
+		t.Fatal(err)
+	}
+	defer cleanupTestKey()
+
+	got, err := testManager(testKey).GetAllStageData(4)
+	if err != nil || len(got) != 0 {
+		t.Errorf("%s(): got %v, %v before any data was set, want no data", testID, got, err)
+	}
+	want := map[string]string{"Error": "install failed", "Retries": "2"}
+	for k, v := range want {
+		if err := testManager(testKey).SetStageData(4, k, v); err != nil {
+			t.Fatalf("%s(): raised unexpected error %v", testID, err)
+		}
+	}
+	got, err = testManager(testKey).GetAllStageData(4)
+	if err != nil {
+		t.Fatalf("%s(): raised unexpected error %v", testID, err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("%s(): returned unexpected diff (-want +got):\n%s", testID, diff)
+	}
+	if v, err := testManager(testKey).GetStageData(4, "Retries"); err != nil || v != "2" {
+		t.Errorf("%s(): got %q, %v, want %q", testID, v, err, "2")
+	}
+}
+
+func TestProgress(t *testing.T) {
+	start := time.Date(2019, 11, 6, 17, 0, 0, 0, time.UTC)
+	seq := []uint64{10, 20, 30, 40}
+	tests := []struct {
+		seq     []uint64
+		active  Stage
+		want    ProgressStatus
+		wantErr error
+	}{
+		{seq, Stage{}, ProgressStatus{Percent: 0, Completed: []uint64{}, Remaining: []uint64{10, 20, 30, 40}}, nil},
+		{seq, Stage{ID: 10, Start: start}, ProgressStatus{Active: 10, Percent: 0, Completed: []uint64{}, Remaining: []uint64{10, 20, 30, 40}}, nil},
+		{seq, Stage{ID: 30, Start: start}, ProgressStatus{Active: 30, Percent: 50, Completed: []uint64{10, 20}, Remaining: []uint64{30, 40}}, nil},
+		{seq, Stage{ID: 30, Start: start, End: start}, ProgressStatus{Active: 30, Percent: 75, Completed: []uint64{10, 20, 30}, Remaining: []uint64{40}}, nil},
+		{seq, Stage{ID: 40, Start: start, End: start}, ProgressStatus{Active: 40, Percent: 100, Completed: []uint64{10, 20, 30, 40}, Remaining: []uint64{}}, nil},
+		{seq, Stage{ID: 25, Start: start}, ProgressStatus{Active: 25}, ErrUnknownStage},
+		{nil, Stage{}, ProgressStatus{}, ErrUnknownStage},
+	}
+	for i, tt := range tests {
+		got, err := progress(tt.seq, tt.active)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("progress() test %d raised unexpected error %v", i, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("progress() test %d returned unexpected diff (-want +got):\n%s", i, diff)
+		}
+	}
+}
+
+func TestStageState(t *testing.T) {
+	start := time.Date(2019, 11, 6, 17, 0, 0, 0, time.UTC)
+	tests := []struct {
+		stage Stage
+		want  State
+	}{
+		{Stage{}, StateUnknown},
+		{Stage{ID: 1, Start: start, Duration: time.Hour}, StateRunning},
+		{Stage{ID: 1, Start: start, End: start.Add(time.Hour), Duration: time.Hour}, StateComplete},
+		{Stage{ID: 1, Start: start, Duration: 8 * 24 * time.Hour}, StateExpired},
+	}
+	for _, tt := range tests {
+		if got := stageState(tt.stage, defaultTimeout); got != tt.want {
+			t.Errorf("stageState(%+v) = %s, want %s", tt.stage, got, tt.want)
+		}
+	}
+}
+
+func TestReport(t *testing.T) {
+	testID := "TestReport"
+	testKey := fmt.Sprintf(`%s\%s`, testStageRoot, testID)
+	if err := createTestKeys(testID); err != nil {
+		t.Fatal(err)
+	}
+	defer cleanupTestKey()
+	if err := writeStage(testKey, 1, "2019-11-06T17:00:00", "2019-11-06T17:30:00"); err != nil {
+		t.Fatal(err)
+	}
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, testKey, registry.WRITE)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = k.SetStringValue("_Active", "1"); err != nil {
+		t.Fatal(err)
+	}
+	k.Close()
+
+	got, err := testManager(testKey).buildReport(time.Date(2019, 11, 6, 18, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("%s(): raised unexpected error %v", testID, err)
+	}
+	want := `{
+  "generated": "2019-11-06T18:00:00Z",
+  "active": {
+    "id": 1,
+    "start": "2019-11-06T17:00:00Z",
+    "end": "2019-11-06T17:30:00Z",
+    "duration_seconds": 1800,
+    "state": "Complete"
+  },
+  "history": [
+    {
+      "id": 1,
+      "start": "2019-11-06T17:00:00Z",
+      "end": "2019-11-06T17:30:00Z",
+      "duration_seconds": 1800
+    }
+  ]
+}`
+	if diff := cmp.Diff(want, string(got)); diff != "" {
+		t.Errorf("%s(): returned unexpected diff (-want +got):\n%s", testID, diff)
+	}
+}
+
+func TestSetStage(t *testing.T) {
+	testID := "TestSetStage"
+	testKey := fmt.Sprintf(`%s\%s`, testStageRoot, testID)
+	if err := createTestKeys(testID); err != nil {
+		t.Fatal(err)
+	}
+	defer cleanupTestKey()
+
+	now := time.Date(2019, 11, 6, 17, 0, 0, 0, time.UTC)
+	fnNow = func() time.Time { return now }
+	events := []string{}
+	fnWriteEvent = func(source string, eid uint32, msg string) error {
+		events = append(events, fmt.Sprintf("%s %d %s", source, eid, msg))
+		return nil
+	}
+	defer func() {
+		fnNow = time.Now
+		fnWriteEvent = writeEvent
+	}()
+
+	m := testManager(testKey)
+	m.EventSource = "Glazier"
+	if err := m.SetStage(1); err != nil {
+		t.Fatalf("%s(): raised unexpected error %v", testID, err)
+	}
+	now = now.Add(time.Hour)
+	if err := m.SetStage(2); err != nil {
+		t.Fatalf("%s(): raised unexpected error %v", testID, err)
+	}
+
+	got, err := testManager(testKey).History()
+	if err != nil {
+		t.Fatalf("%s(): raised unexpected error %v", testID, err)
+	}
+	if len(got) != 2 || !got[0].End.Equal(now) || !got[1].Start.Equal(now) || !got[1].End.IsZero() {
+		t.Errorf("%s(): unexpected history %+v", testID, got)
+	}
+	if stage, _ := testManager(testKey).getActiveStage(); stage != "2" {
+		t.Errorf("%s(): got active stage %s, want 2", testID, stage)
+	}
+	want := []string{
+		"Glazier 100 stage=1\nperiod=Start\ntime=2019-11-06T17:00:00.000000",
+		"Glazier 101 stage=1\nperiod=End\ntime=2019-11-06T18:00:00.000000",
+		"Glazier 100 stage=2\nperiod=Start\ntime=2019-11-06T18:00:00.000000",
+	}
+	if diff := cmp.Diff(want, events); diff != "" {
+		t.Errorf("%s(): wrote unexpected events (-want +got):\n%s", testID, diff)
+	}
+}
+
+func TestManagerUserHive(t *testing.T) {
+	testID := "TestManagerUserHive"
+	m := NewManager(registry.CURRENT_USER, fmt.Sprintf(`%s\%s`, testStageRoot, testID))
+	defer registry.DeleteKey(registry.CURRENT_USER, testStageRoot)
+
+	if err := m.SetStage(7); err != nil {
+		t.Fatalf("%s(): raised unexpected error %v", testID, err)
+	}
+	defer registry.DeleteKey(registry.CURRENT_USER, m.Root)
+	defer registry.DeleteKey(registry.CURRENT_USER, m.Root+`\7`)
+	if stage, err := m.ActiveStage(); err != nil || stage != 7 {
+		t.Errorf("%s(): got active stage %d, %v, want 7", testID, stage, err)
+	}
+	if stage, _ := testManager(m.Root).getActiveStage(); stage != "0" {
+		t.Errorf("%s(): user stage leaked into the machine hive as %s", testID, stage)
+	}
+}
+
+func TestRepair(t *testing.T) {
+	testID := "TestRepair"
+	testKey := fmt.Sprintf(`%s\%s`, testStageRoot, testID)
+	if err := createTestKeys(testID); err != nil {
+		t.Fatal(err)
+	}
+	defer cleanupTestKey()
+	m := testManager(testKey)
+	setRoot := func(name, value string) {
+		if err := m.setValue(testKey, name, value); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A clean state needs no repair.
+	if err := writeStage(testKey, 1, "2019-11-06T17:00:00.000000", ""); err != nil {
+		t.Fatal(err)
+	}
+	setRoot(regActiveKey, "1")
+	if repaired, err := m.Repair(); err != nil || repaired {
+		t.Errorf("%s(): consistent state got %t, %v, want false, nil", testID, repaired, err)
+	}
+
+	// An interrupted transition is completed.
+	setRoot(regTransitionKey, "1,2,2019-11-06T18:00:00.000000")
+	if repaired, err := m.Repair(); err != nil || !repaired {
+		t.Errorf("%s(): interrupted transition got %t, %v, want true, nil", testID, repaired, err)
+	}
+	hist, err := m.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := time.Date(2019, 11, 6, 18, 0, 0, 0, time.UTC)
+	if len(hist) != 2 || !hist[0].End.Equal(at) || !hist[1].Start.Equal(at) {
+		t.Errorf("%s(): interrupted transition left history %+v", testID, hist)
+	}
+	if stage, _ := m.getActiveStage(); stage != "2" {
+		t.Errorf("%s(): got active stage %s, want 2", testID, stage)
+	}
+	if _, err := readKey(m.Hive, testKey, regTransitionKey); err != registry.ErrNotExist {
+		t.Errorf("%s(): transition record was not cleared: %v", testID, err)
+	}
+
+	// An active stage that never started is rolled back to the latest started stage.
+	setRoot(regActiveKey, "3")
+	if repaired, err := m.Repair(); err != nil || !repaired {
+		t.Errorf("%s(): unstarted stage got %t, %v, want true, nil", testID, repaired, err)
+	}
+	if stage, _ := m.getActiveStage(); stage != "2" {
+		t.Errorf("%s(): got active stage %s, want 2", testID, stage)
+	}
+}
+
+func TestParseTransition(t *testing.T) {
+	want := transition{From: 1, To: 2, Time: time.Date(2019, 11, 6, 18, 0, 0, 0, time.UTC)}
+	got, err := parseTransition(want.String())
+	if err != nil {
+		t.Fatalf("parseTransition(%q) raised unexpected error %v", want.String(), err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("parseTransition(%q) returned unexpected diff (-want +got):\n%s", want.String(), diff)
+	}
+	for _, in := range []string{"", "1,2", "a,2,2019-11-06T18:00:00", "1,2,tomorrow"} {
+		if _, err := parseTransition(in); err == nil {
+			t.Errorf("parseTransition(%q) failed to raise expected error", in)
+		}
+	}
+}
+
+func TestSubstages(t *testing.T) {
+	testID := "TestSubstages"
+	testKey := fmt.Sprintf(`%s\%s`, testStageRoot, testID)
+	if err := createTestKeys(testID); err != nil {
+		t.Fatal(err)
+	}
+	defer cleanupTestKey()
+
+	now := time.Date(2019, 11, 6, 17, 0, 0, 0, time.UTC)
+	fnNow = func() time.Time { return now }
+	events := []string{}
+	fnWriteEvent = func(source string, eid uint32, msg string) error {
+		events = append(events, fmt.Sprintf("%d %s", eid, msg))
+		return nil
+	}
+	defer func() {
+		fnNow = time.Now
+		fnWriteEvent = writeEvent
+	}()
+
+	m := testManager(testKey)
+	m.EventSource = "Glazier"
+	steps := []func() error{
+		func() error { return m.SetStage(40) },
+		func() error { return m.SetSubstage(40, 1) },
+		func() error { return m.SetSubstage(40, 2) },
+		func() error { return m.SetStage(50) },
+	}
+	for i, step := range steps {
+		if err := step(); err != nil {
+			t.Fatalf("%s(): step %d raised unexpected error %v", testID, i, err)
+		}
+		now = now.Add(time.Minute)
+	}
+
+	hist, err := m.History()
+	if err != nil {
+		t.Fatalf("%s(): raised unexpected error %v", testID, err)
+	}
+	at := func(min int) time.Time { return time.Date(2019, 11, 6, 17, min, 0, 0, time.UTC) }
+	want := []Stage{
+		{ID: 40, Start: at(0), End: at(3), Duration: 3 * time.Minute, Substages: []Stage{
+			{ID: 1, Start: at(1), End: at(2), Duration: time.Minute},
+			{ID: 2, Start: at(2), End: at(3), Duration: time.Minute},
+		}},
+		{ID: 50, Start: at(3)},
+	}
+	hist[1].Duration = 0
+	if diff := cmp.Diff(want, hist); diff != "" {
+		t.Errorf("%s(): returned unexpected diff (-want +got):\n%s", testID, diff)
+	}
+	wantEvents := []string{
+		"100 stage=40\nperiod=Start\ntime=2019-11-06T17:00:00.000000",
+		"100 stage=40.1\nperiod=Start\ntime=2019-11-06T17:01:00.000000",
+		"101 stage=40.1\nperiod=End\ntime=2019-11-06T17:02:00.000000",
+		"100 stage=40.2\nperiod=Start\ntime=2019-11-06T17:02:00.000000",
+		"101 stage=40\nperiod=End\ntime=2019-11-06T17:03:00.000000",
+		"100 stage=50\nperiod=Start\ntime=2019-11-06T17:03:00.000000",
+	}
+	if diff := cmp.Diff(wantEvents, events); diff != "" {
+		t.Errorf("%s(): wrote unexpected events (-want +got):\n%s", testID, diff)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build windows
+
+package stages
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// State describes the state of a stage.
+type State string
+
+// Stage states.
+const (
+	// StateUnknown indicates that no stage is active.
+	StateUnknown  State = "Unknown"
+	StateRunning  State = "Running"
+	StateComplete State = "Complete"
+	// StateExpired indicates a stage that has run for longer than it is allowed to.
+	StateExpired State = "Expired"
+)
+
+// Status describes the active stage.
+type Status struct {
+	Stage
+	State State
+}
+
+// stageJSON is the stable JSON form of a Stage.
+type stageJSON struct {
+	ID        uint64      `json:"id"`
+	Start     string      `json:"start,omitempty"`
+	End       string      `json:"end,omitempty"`
+	Duration  float64     `json:"duration_seconds"`
+	Substages []stageJSON `json:"substages,omitempty"`
+}
+
+func formatJSONTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func (s Stage) toJSON() stageJSON {
+	j := stageJSON{
+		ID:       s.ID,
+		Start:    formatJSONTime(s.Start),
+		End:      formatJSONTime(s.End),
+		Duration: s.Duration.Seconds(),
+	}
+	for _, sub := range s.Substages {
+		j.Substages = append(j.Substages, sub.toJSON())
+	}
+	return j
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Stage) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.toJSON())
+}
+
+// MarshalJSON implements json.Marshaler.
+func (s Status) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		stageJSON
+		State State `json:"state"`
+	}{s.toJSON(), s.State})
+}
+
+func stageState(s Stage, timeout time.Duration) State {
+	switch {
+	case s.ID == 0:
+		return StateUnknown
+	case !s.End.IsZero():
+		return StateComplete
+	case !s.Start.IsZero() && s.Duration > timeout:
+		return StateExpired
+	}
+	return StateRunning
+}
+
+// ActiveStatus returns the active stage along with its state.
+func (m *Manager) ActiveStatus() (Status, error) {
+	s, err := m.activeStage()
+	if err != nil {
+		return Status{}, err
+	}
+	return Status{Stage: s, State: stageState(s, defaultTimeout)}, nil
+}
+
+// ActiveStatus returns the active machine build stage along with its state.
+func ActiveStatus() (Status, error) {
+	return Default.ActiveStatus()
+}
+
+// report is the JSON document produced by Report.
+type report struct {
+	Generated string  `json:"generated"`
+	Active    Status  `json:"active"`
+	History   []Stage `json:"history"`
+}
+
+func (m *Manager) buildReport(now time.Time) ([]byte, error) {
+	active, err := m.ActiveStatus()
+	if err != nil {
+		return nil, err
+	}
+	hist, err := m.History()
+	if err != nil {
+		return nil, err
+	}
+	return json.MarshalIndent(report{
+		Generated: formatJSONTime(now),
+		Active:    active,
+		History:   hist,
+	}, "", "  ")
+}
+
+// Report returns a JSON document describing the active stage and the stage history, eg to
+// post to a fleet backend or to include in a support bundle.
+func (m *Manager) Report() ([]byte, error) {
+	return m.buildReport(time.Now())
+}
+
+// Report returns a JSON document describing the machine build stages. See Manager.Report.
+func Report() ([]byte, error) {
+	return Default.Report()
+}
@@ -0,0 +1,80 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build windows
+
+package stages
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// substages returns a Manager for the substages of parent, which are kept under the
+// parent's own key in the same layout as top level stages.
+func (m *Manager) substages(parent uint64) *Manager {
+	return &Manager{
+		Hive:        m.Hive,
+		Root:        m.stageKey(strconv.FormatUint(parent, 10)),
+		EventSource: m.EventSource,
+		parent:      fmt.Sprintf("%s%d.", m.parent, parent),
+	}
+}
+
+// endSubstage ends the running substage of parent, if any.
+func (m *Manager) endSubstage(parent uint64, t time.Time) error {
+	sub := m.substages(parent)
+	active, err := sub.activeStage()
+	if err != nil || active.ID == 0 || !active.End.IsZero() {
+		return err
+	}
+	if err := sub.setValue(sub.stageKey(strconv.FormatUint(active.ID, 10)), "End", t.UTC().Format(timeFormat)); err != nil {
+		return fmt.Errorf("ending stage %s%d: %w", sub.parent, active.ID, err)
+	}
+	return sub.logTransition(active.ID, "End", t)
+}
+
+// SetSubstage ends the running substage of parent, if any, and starts substage sub, eg
+// SetSubstage(40, 2) for stage 40.2. Substages break long stages into finer steps, with
+// their own Start and End times.
+func (m *Manager) SetSubstage(parent, sub uint64) error {
+	return m.substages(parent).SetStage(sub)
+}
+
+// ExitSubstage ends substage sub of parent.
+func (m *Manager) ExitSubstage(parent, sub uint64) error {
+	return m.substages(parent).ExitStage(sub)
+}
+
+// ActiveSubstage returns the active substage of parent. ID 0 means that no substage is
+// active.
+func (m *Manager) ActiveSubstage(parent uint64) (Stage, error) {
+	return m.substages(parent).activeStage()
+}
+
+// SetSubstage starts a substage of a machine build stage. See Manager.SetSubstage.
+func SetSubstage(parent, sub uint64) error {
+	return Default.SetSubstage(parent, sub)
+}
+
+// ExitSubstage ends a substage of a machine build stage.
+func ExitSubstage(parent, sub uint64) error {
+	return Default.ExitSubstage(parent, sub)
+}
+
+// ActiveSubstage returns the active substage of a machine build stage.
+func ActiveSubstage(parent uint64) (Stage, error) {
+	return Default.ActiveSubstage(parent)
+}
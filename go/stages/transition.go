@@ -0,0 +1,155 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build windows
+
+package stages
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// Event IDs written for stage transitions.
+const (
+	EventStageStart = 100
+	EventStageEnd   = 101
+)
+
+var (
+	// TestHelpers
+	fnWriteEvent = writeEvent
+	fnNow        = time.Now
+)
+
+// writeEvent writes a stage transition to the event log source.
+func writeEvent(source string, eid uint32, msg string) error {
+	l, err := eventlog.Open(source)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	return l.Info(eid, msg)
+}
+
+// logTransition writes a transition to the event log, if an event source is set.
+func (m *Manager) logTransition(stageID uint64, period string, t time.Time) error {
+	if m.EventSource == "" {
+		return nil
+	}
+	eid := uint32(EventStageStart)
+	if period == "End" {
+		eid = EventStageEnd
+	}
+	msg := fmt.Sprintf("stage=%s%d\nperiod=%s\ntime=%s", m.parent, stageID, period, t.UTC().Format(timeFormat))
+	if err := fnWriteEvent(m.EventSource, eid, msg); err != nil {
+		return fmt.Errorf("writing %s event for stage %s%d: %w", period, m.parent, stageID, err)
+	}
+	return nil
+}
+
+// setValue writes a string value, creating the key if needed.
+func (m *Manager) setValue(path, name, value string) error {
+	k, _, err := registry.CreateKey(m.Hive, path, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	return k.SetStringValue(name, value)
+}
+
+// deleteValue removes a value, if it exists.
+func (m *Manager) deleteValue(path, name string) error {
+	k, err := registry.OpenKey(m.Hive, path, registry.SET_VALUE)
+	if err == registry.ErrNotExist {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	if err := k.DeleteValue(name); err != nil && err != registry.ErrNotExist {
+		return err
+	}
+	return nil
+}
+
+// ExitStage ends stageID, along with its running substage, if any.
+func (m *Manager) ExitStage(stageID uint64) error {
+	now := fnNow()
+	if err := m.endSubstage(stageID, now); err != nil {
+		return err
+	}
+	if err := m.setValue(m.stageKey(strconv.FormatUint(stageID, 10)), "End", now.UTC().Format(timeFormat)); err != nil {
+		return fmt.Errorf("ending stage %d: %w", stageID, err)
+	}
+	return m.logTransition(stageID, "End", now)
+}
+
+// SetStage ends the active stage, if any, and starts stageID.
+//
+// The transition is recorded before it is made. If SetStage fails part way, the values it
+// changed are rolled back; if the process dies part way, Repair (which SetStage also runs
+// first) completes the transition.
+func (m *Manager) SetStage(stageID uint64) error {
+	if _, err := m.Repair(); err != nil {
+		return fmt.Errorf("repairing stages: %w", err)
+	}
+	active, err := m.activeStage()
+	if err != nil {
+		return err
+	}
+	t := transition{To: stageID, Time: fnNow()}
+	if active.ID != 0 && active.ID != stageID && active.End.IsZero() {
+		t.From = active.ID
+	}
+
+	saved, err := m.snapshot(t)
+	if err != nil {
+		return err
+	}
+	if err := m.setValue(m.Root, regTransitionKey, t.String()); err != nil {
+		return fmt.Errorf("recording transition to stage %d: %w", stageID, err)
+	}
+	if err := m.apply(t); err != nil {
+		if rerr := m.restore(saved); rerr != nil {
+			return fmt.Errorf("%w (rollback failed, run Repair: %v)", err, rerr)
+		}
+		return err
+	}
+	if err := m.deleteValue(m.Root, regTransitionKey); err != nil {
+		return err
+	}
+
+	if t.From != 0 {
+		if err := m.logTransition(t.From, "End", t.Time); err != nil {
+			return err
+		}
+	}
+	return m.logTransition(stageID, "Start", t.Time)
+}
+
+// SetStage ends the active machine build stage, if any, and starts stageID.
+func SetStage(stageID uint64) error {
+	return Default.SetStage(stageID)
+}
+
+// ExitStage ends the machine build stage stageID.
+func ExitStage(stageID uint64) error {
+	return Default.ExitStage(stageID)
+}
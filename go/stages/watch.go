@@ -0,0 +1,120 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// +build windows
+
+package stages
+
+import (
+	"context"
+	"runtime"
+	"strconv"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// watchPollMS bounds how long Watch waits on a change notification before checking
+// whether it has been cancelled.
+const watchPollMS = 1000
+
+// activeStage reads the active stage. ID 0 means that no stage is active.
+func (m *Manager) activeStage() (Stage, error) {
+	active, err := m.getActiveStage()
+	if err != nil {
+		return Stage{}, err
+	}
+	id, err := strconv.ParseUint(active, 10, 64)
+	if err != nil {
+		return Stage{}, err
+	}
+	if id == 0 {
+		return Stage{}, nil
+	}
+	return m.getStage(id)
+}
+
+// sameStage compares stages and their substages, ignoring the duration of running stages.
+func sameStage(a, b Stage) bool {
+	if a.ID != b.ID || !a.Start.Equal(b.Start) || !a.End.Equal(b.End) || len(a.Substages) != len(b.Substages) {
+		return false
+	}
+	for i := range a.Substages {
+		if !sameStage(a.Substages[i], b.Substages[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Watch reports the active stage on the returned channel each time it changes, starting
+// with the stage active when it is called. The channel is closed once ctx is done.
+func (m *Manager) Watch(ctx context.Context) (<-chan Stage, error) {
+	k, err := registry.OpenKey(m.Hive, m.Root, registry.NOTIFY|registry.QUERY_VALUE)
+	if err != nil {
+		return nil, err
+	}
+	event, err := windows.CreateEvent(nil, 0, 0, nil)
+	if err != nil {
+		k.Close()
+		return nil, err
+	}
+
+	ch := make(chan Stage)
+	go func() {
+		// Notifications are tied to the registering thread, and are signalled if it exits.
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		defer close(ch)
+		defer windows.CloseHandle(event)
+		defer k.Close()
+
+		var last *Stage
+		for {
+			// Register before reading, so that no change between the two is missed.
+			if err := windows.RegNotifyChangeKeyValue(windows.Handle(k), true,
+				windows.REG_NOTIFY_CHANGE_NAME|windows.REG_NOTIFY_CHANGE_LAST_SET, event, true); err != nil {
+				return
+			}
+			// Stage values may be read mid transition; the next notification catches up.
+			if s, err := m.activeStage(); err == nil && (last == nil || !sameStage(*last, s)) {
+				select {
+				case ch <- s:
+					last = &s
+				case <-ctx.Done():
+					return
+				}
+			}
+			for {
+				ev, err := windows.WaitForSingleObject(event, watchPollMS)
+				if err != nil {
+					return
+				}
+				if ctx.Err() != nil {
+					return
+				}
+				if ev == windows.WAIT_OBJECT_0 {
+					break
+				}
+			}
+		}
+	}()
+	return ch, nil
+}
+
+// Watch reports the active machine build stage on the returned channel each time it
+// changes. See Manager.Watch.
+func Watch(ctx context.Context) (<-chan Stage, error) {
+	return Default.Watch(ctx)
+}
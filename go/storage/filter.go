@@ -0,0 +1,189 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+const storageNamespace = "root/Microsoft/Windows/Storage"
+
+var (
+	// ErrInvalidFilter indicates a filter could not be constructed safely.
+	ErrInvalidFilter = errors.New("invalid filter")
+	// ErrNotFound indicates that no storage object matched a query.
+	ErrNotFound = errors.New("no matching storage object found")
+
+	propertyRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Filter builds WQL WHERE clauses, escaping values as they are added.
+//
+// Example: storage.NewFilter().Eq("SerialNumber", serial).Eq("IsBoot", true)
+type Filter struct {
+	clauses []string
+	err     error
+}
+
+// NewFilter creates an empty Filter.
+func NewFilter() *Filter {
+	return &Filter{}
+}
+
+func wqlValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		r := strings.NewReplacer(`\`, `\\`, `'`, `\'`)
+		return "'" + r.Replace(v) + "'", nil
+	case bool:
+		if v {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprintf("%d", v), nil
+	default:
+		return "", fmt.Errorf("%w: unsupported value type %T", ErrInvalidFilter, value)
+	}
+}
+
+func (f *Filter) add(property, op string, value interface{}) *Filter {
+	if f.err != nil {
+		return f
+	}
+	if !propertyRe.MatchString(property) {
+		f.err = fmt.Errorf("%w: invalid property name %q", ErrInvalidFilter, property)
+		return f
+	}
+	v, err := wqlValue(value)
+	if err != nil {
+		f.err = err
+		return f
+	}
+	f.clauses = append(f.clauses, fmt.Sprintf("%s %s %s", property, op, v))
+	return f
+}
+
+// Eq requires property to equal value.
+func (f *Filter) Eq(property string, value interface{}) *Filter {
+	return f.add(property, "=", value)
+}
+
+// Ne requires property not to equal value.
+func (f *Filter) Ne(property string, value interface{}) *Filter {
+	return f.add(property, "<>", value)
+}
+
+// Build renders the filter as a WQL condition, suitable for a WHERE clause.
+func (f *Filter) Build() (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	return strings.Join(f.clauses, " AND "), nil
+}
+
+// queryStorage populates v with the instances of a storage class matching f.
+func queryStorage(class string, f *Filter, v interface{}) error {
+	cmd := fmt.Sprintf("Get-CimInstance -Namespace %s -ClassName %s", storageNamespace, class)
+	if f != nil {
+		where, err := f.Build()
+		if err != nil {
+			return err
+		}
+		if where != "" {
+			cmd += " -Filter " + psQuote(where)
+		}
+	}
+	return queryList(cmd, v)
+}
+
+func findDisk(f *Filter) (*DiskInfo, error) {
+	disks := []DiskInfo{}
+	if err := queryStorage("MSFT_Disk", f, &disks); err != nil {
+		return &DiskInfo{}, err
+	}
+	if len(disks) < 1 {
+		return &DiskInfo{}, ErrNotFound
+	}
+	return &disks[0], nil
+}
+
+// GetDiskByNumber returns the disk with the given disk number.
+func GetDiskByNumber(num int) (*DiskInfo, error) {
+	return findDisk(NewFilter().Eq("Number", num))
+}
+
+// GetDiskBySerial returns the disk with the given serial number.
+func GetDiskBySerial(serial string) (*DiskInfo, error) {
+	return findDisk(NewFilter().Eq("SerialNumber", serial))
+}
+
+// GetVolumeByDriveLetter returns the volume mounted at the given drive letter.
+//
+// Example: storage.GetVolumeByDriveLetter("C")
+func GetVolumeByDriveLetter(letter string) (*VolumeInfo, error) {
+	letter = strings.TrimSuffix(letter, ":")
+	if len(letter) != 1 {
+		return &VolumeInfo{}, fmt.Errorf("%w: invalid drive letter %q", ErrInvalidFilter, letter)
+	}
+	vols := []VolumeInfo{}
+	if err := queryStorage("MSFT_Volume", NewFilter().Eq("DriveLetter", strings.ToUpper(letter)), &vols); err != nil {
+		return &VolumeInfo{}, err
+	}
+	if len(vols) < 1 {
+		return &VolumeInfo{}, ErrNotFound
+	}
+	return &vols[0], nil
+}
+
+func findPartition(f *Filter) (*PartitionInfo, error) {
+	parts := []PartitionInfo{}
+	if err := queryStorage("MSFT_Partition", f, &parts); err != nil {
+		return &PartitionInfo{}, err
+	}
+	if len(parts) < 1 {
+		return &PartitionInfo{}, ErrNotFound
+	}
+	return &parts[0], nil
+}
+
+// GetSystemDisk returns the disk holding the system partition, which the firmware boots from.
+func GetSystemDisk() (*DiskInfo, error) {
+	return findDisk(NewFilter().Eq("IsSystem", true))
+}
+
+// GetBootVolume returns the volume holding the running Windows installation.
+func GetBootVolume() (*VolumeInfo, error) {
+	p, err := findPartition(NewFilter().Eq("IsBoot", true))
+	if err != nil {
+		return &VolumeInfo{}, err
+	}
+	return p.GetVolume()
+}
+
+// GetEFIPartition returns the EFI system partition of the system disk.
+func GetEFIPartition() (*PartitionInfo, error) {
+	d, err := GetSystemDisk()
+	if err != nil {
+		return &PartitionInfo{}, err
+	}
+	if d.PartitionStyle != StyleGPT {
+		return &PartitionInfo{}, fmt.Errorf("%w: system disk %d is %v", ErrNotFound, d.Number, d.PartitionStyle)
+	}
+	return findPartition(NewFilter().Eq("DiskNumber", d.Number).Eq("GptType", GptTypeSystem))
+}
@@ -0,0 +1,194 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/winops/powershell"
+)
+
+func TestFilterBuild(t *testing.T) {
+	tests := []struct {
+		desc    string
+		in      *Filter
+		want    string
+		wantErr error
+	}{
+		{"empty", NewFilter(), "", nil},
+		{"integer", NewFilter().Eq("Number", 1), "Number = 1", nil},
+		{"boolean", NewFilter().Eq("IsBoot", true).Ne("IsOffline", true), "IsBoot = TRUE AND IsOffline <> TRUE", nil},
+		{"string", NewFilter().Eq("SerialNumber", "ABC 123"), "SerialNumber = 'ABC 123'", nil},
+		{"escaping", NewFilter().Eq("SerialNumber", `x' OR 'a'='a\`), `SerialNumber = 'x\' OR \'a\'=\'a\\'`, nil},
+		{"bad property", NewFilter().Eq("Number=1 OR Number", 2), "", ErrInvalidFilter},
+		{"bad value", NewFilter().Eq("Number", 1.5), "", ErrInvalidFilter},
+		{"sticky error", NewFilter().Eq("a b", 1).Eq("Number", 1), "", ErrInvalidFilter},
+	}
+	for _, tt := range tests {
+		got, err := tt.in.Build()
+		if got != tt.want {
+			t.Errorf("%s: Build() = %q, want %q", tt.desc, got, tt.want)
+		}
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: Build() returned unexpected error %v", tt.desc, err)
+		}
+	}
+}
+
+func TestGetDiskBySerial(t *testing.T) {
+	tests := []struct {
+		serial  string
+		psOut   string
+		wantCmd string
+		want    *DiskInfo
+		wantErr error
+	}{
+		{
+			serial:  "S1234",
+			psOut:   `[{"Number": 1, "SerialNumber": "S1234"}]`,
+			wantCmd: `ConvertTo-JSON -InputObject @(Get-CimInstance -Namespace root/Microsoft/Windows/Storage -ClassName MSFT_Disk -Filter 'SerialNumber = ''S1234''' | Select-Object -Property ` + diskProps + `)`,
+			want:    &DiskInfo{Number: 1, SerialNumber: "S1234"},
+		},
+		{
+			serial:  "S'1",
+			psOut:   `[]`,
+			wantCmd: `ConvertTo-JSON -InputObject @(Get-CimInstance -Namespace root/Microsoft/Windows/Storage -ClassName MSFT_Disk -Filter 'SerialNumber = ''S\''1''' | Select-Object -Property ` + diskProps + `)`,
+			want:    &DiskInfo{},
+			wantErr: ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		gotCmd := ""
+		fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+			gotCmd = psCmd
+			return []byte(tt.psOut), nil
+		}
+		got, err := GetDiskBySerial(tt.serial)
+		if gotCmd != tt.wantCmd {
+			t.Errorf("GetDiskBySerial(%q) ran %q, want %q", tt.serial, gotCmd, tt.wantCmd)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("GetDiskBySerial(%q) returned unexpected diff (-want +got):\n%s", tt.serial, diff)
+		}
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("GetDiskBySerial(%q) returned unexpected error %v", tt.serial, err)
+		}
+	}
+}
+
+func TestGetVolumeByDriveLetter(t *testing.T) {
+	tests := []struct {
+		in      string
+		wantCmd string
+		wantErr error
+	}{
+		{"c:", `ConvertTo-JSON -InputObject @(Get-CimInstance -Namespace root/Microsoft/Windows/Storage -ClassName MSFT_Volume -Filter 'DriveLetter = ''C''' | Select-Object -Property ` + volumeProps + `)`, nil},
+		{"D", `ConvertTo-JSON -InputObject @(Get-CimInstance -Namespace root/Microsoft/Windows/Storage -ClassName MSFT_Volume -Filter 'DriveLetter = ''D''' | Select-Object -Property ` + volumeProps + `)`, nil},
+		{"C:\\", "", ErrInvalidFilter},
+	}
+	for _, tt := range tests {
+		gotCmd := ""
+		fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+			gotCmd = psCmd
+			return []byte(`[{"DriveLetter": "C"}]`), nil
+		}
+		_, err := GetVolumeByDriveLetter(tt.in)
+		if gotCmd != tt.wantCmd {
+			t.Errorf("GetVolumeByDriveLetter(%q) ran %q, want %q", tt.in, gotCmd, tt.wantCmd)
+		}
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("GetVolumeByDriveLetter(%q) returned unexpected error %v", tt.in, err)
+		}
+	}
+}
+
+func TestGetEFIPartition(t *testing.T) {
+	tests := []struct {
+		desc     string
+		diskOut  string
+		wantCmds []string
+		want     *PartitionInfo
+		wantErr  error
+	}{
+		{
+			desc:    "gpt",
+			diskOut: `[{"Number": 0, "PartitionStyle": "GPT", "IsSystem": true}]`,
+			wantCmds: []string{
+				`ConvertTo-JSON -InputObject @(Get-CimInstance -Namespace root/Microsoft/Windows/Storage -ClassName MSFT_Disk -Filter 'IsSystem = TRUE' | Select-Object -Property ` + diskProps + `)`,
+				`ConvertTo-JSON -InputObject @(Get-CimInstance -Namespace root/Microsoft/Windows/Storage -ClassName MSFT_Partition -Filter 'DiskNumber = 0 AND GptType = ''{c12a7328-f81f-11d2-ba4b-00a0c93ec93b}''' | Select-Object -Property ` + partitionProps + `)`,
+			},
+			want: &PartitionInfo{DiskNumber: 0, PartitionNumber: 1},
+		},
+		{
+			desc:    "mbr",
+			diskOut: `[{"Number": 0, "PartitionStyle": "MBR", "IsSystem": true}]`,
+			wantCmds: []string{
+				`ConvertTo-JSON -InputObject @(Get-CimInstance -Namespace root/Microsoft/Windows/Storage -ClassName MSFT_Disk -Filter 'IsSystem = TRUE' | Select-Object -Property ` + diskProps + `)`,
+			},
+			want:    &PartitionInfo{},
+			wantErr: ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			cmds := []string{}
+			fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+				cmds = append(cmds, psCmd)
+				if strings.Contains(psCmd, "MSFT_Disk") {
+					return []byte(tt.diskOut), nil
+				}
+				return []byte(`[{"DiskNumber": 0, "PartitionNumber": 1}]`), nil
+			}
+			got, err := GetEFIPartition()
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetEFIPartition() returned unexpected error %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("GetEFIPartition() returned unexpected diff (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.wantCmds, cmds); diff != "" {
+				t.Errorf("GetEFIPartition() ran unexpected commands (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestGetBootVolume(t *testing.T) {
+	cmds := []string{}
+	fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+		cmds = append(cmds, psCmd)
+		if strings.Contains(psCmd, "MSFT_Partition") {
+			return []byte(`[{"DiskNumber": 0, "PartitionNumber": 3, "IsBoot": true}]`), nil
+		}
+		return []byte(`{"DriveLetter": "C"}`), nil
+	}
+	got, err := GetBootVolume()
+	if err != nil {
+		t.Fatalf("GetBootVolume() returned unexpected error %v", err)
+	}
+	if diff := cmp.Diff(&VolumeInfo{DriveLetter: "C"}, got); diff != "" {
+		t.Errorf("GetBootVolume() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	wantCmds := []string{
+		`ConvertTo-JSON -InputObject @(Get-CimInstance -Namespace root/Microsoft/Windows/Storage -ClassName MSFT_Partition -Filter 'IsBoot = TRUE' | Select-Object -Property ` + partitionProps + `)`,
+		`Get-Partition -DiskNumber 0 -PartitionNumber 3 | Get-Volume | Select-Object -Property ` + volumeProps + ` | ConvertTo-JSON`,
+	}
+	if diff := cmp.Diff(wantCmds, cmds); diff != "" {
+		t.Errorf("GetBootVolume() ran unexpected commands (-want +got):\n%s", diff)
+	}
+}
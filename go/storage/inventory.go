@@ -0,0 +1,91 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"sort"
+	"strings"
+)
+
+// Snapshot is a point in time snapshot of the disks, partitions and volumes on the
+// system. It holds plain data only and can be serialized, compared or stored freely.
+type Snapshot struct {
+	Disks []DiskNode
+}
+
+// DiskNode is a disk and its partitions, ordered by partition number.
+type DiskNode struct {
+	Disk       DiskInfo
+	Partitions []PartitionNode
+}
+
+// PartitionNode is a partition and the volume on it, if any.
+type PartitionNode struct {
+	Partition PartitionInfo
+	Volume    *VolumeInfo `json:",omitempty"`
+}
+
+// Inventory captures the storage layout of the system in three queries, one each for disks,
+// partitions and volumes.
+//
+// Volumes are matched to partitions by volume GUID path. Volumes not backed by a partition
+// (eg optical media) are omitted.
+func Inventory() (*Snapshot, error) {
+	inv := &Snapshot{Disks: []DiskNode{}}
+	disks, err := GetDisks()
+	if err != nil {
+		return inv, err
+	}
+	parts := []PartitionInfo{}
+	if err := queryList("Get-Partition", &parts); err != nil {
+		return inv, err
+	}
+	vols, err := GetVolumes()
+	if err != nil {
+		return inv, err
+	}
+
+	byPath := make(map[string]VolumeInfo)
+	for _, v := range vols {
+		if v.Path != "" {
+			byPath[strings.ToLower(v.Path)] = v
+		}
+	}
+	byDisk := make(map[int][]PartitionNode)
+	for _, p := range parts {
+		n := PartitionNode{Partition: p}
+		for _, a := range p.AccessPaths {
+			if v, ok := byPath[strings.ToLower(a)]; ok {
+				v := v
+				n.Volume = &v
+				break
+			}
+		}
+		byDisk[p.DiskNumber] = append(byDisk[p.DiskNumber], n)
+	}
+
+	sort.Slice(disks, func(i, j int) bool { return disks[i].Number < disks[j].Number })
+	for _, d := range disks {
+		nodes := byDisk[d.Number]
+		if nodes == nil {
+			nodes = []PartitionNode{}
+		}
+		sort.Slice(nodes, func(i, j int) bool {
+			return nodes[i].Partition.PartitionNumber < nodes[j].Partition.PartitionNumber
+		})
+		inv.Disks = append(inv.Disks, DiskNode{Disk: d, Partitions: nodes})
+	}
+	return inv, nil
+}
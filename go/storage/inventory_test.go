@@ -0,0 +1,66 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/winops/powershell"
+)
+
+func TestInventory(t *testing.T) {
+	fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+		switch {
+		case strings.Contains(psCmd, "Get-Disk"):
+			return []byte(`[{"Number": 1}, {"Number": 0}]`), nil
+		case strings.Contains(psCmd, "Get-Partition"):
+			return []byte(`[
+				{"DiskNumber": 0, "PartitionNumber": 2, "AccessPaths": ["C:\\", "\\\\?\\Volume{b}\\"]},
+				{"DiskNumber": 0, "PartitionNumber": 1, "AccessPaths": "\\\\?\\Volume{A}\\"}
+			]`), nil
+		case strings.Contains(psCmd, "Get-Volume"):
+			return []byte(`[{"Path": "\\\\?\\Volume{a}\\", "FileSystem": "FAT32"}, {"Path": "\\\\?\\Volume{b}\\", "DriveLetter": "C"}]`), nil
+		}
+		return nil, errors.New("unexpected command")
+	}
+	got, err := Inventory()
+	if err != nil {
+		t.Fatalf("Inventory() returned unexpected error %v", err)
+	}
+	want := &Snapshot{
+		Disks: []DiskNode{
+			{
+				Disk: DiskInfo{Number: 0},
+				Partitions: []PartitionNode{
+					{
+						Partition: PartitionInfo{DiskNumber: 0, PartitionNumber: 1, AccessPaths: StringList{`\\?\Volume{A}\`}},
+						Volume:    &VolumeInfo{Path: `\\?\Volume{a}\`, FileSystem: "FAT32"},
+					},
+					{
+						Partition: PartitionInfo{DiskNumber: 0, PartitionNumber: 2, AccessPaths: StringList{`C:\`, `\\?\Volume{b}\`}},
+						Volume:    &VolumeInfo{Path: `\\?\Volume{b}\`, DriveLetter: "C"},
+					},
+				},
+			},
+			{Disk: DiskInfo{Number: 1}, Partitions: []PartitionNode{}},
+		},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Inventory() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
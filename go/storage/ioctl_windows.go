@@ -0,0 +1,193 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// https://docs.microsoft.com/en-us/windows/win32/api/winioctl/ni-winioctl-ioctl_storage_query_property
+	ioctlStorageQueryProperty = 0x2D1400
+
+	storageDeviceTrimProperty = 8
+	propertyStandardQuery     = 0
+
+	// https://docs.microsoft.com/en-us/windows/win32/api/winioctl/ni-winioctl-fsctl_is_volume_dirty
+	fsctlIsVolumeDirty = 0x00090078
+	// https://docs.microsoft.com/en-us/windows/win32/api/winioctl/ni-winioctl-fsctl_mark_volume_dirty
+	fsctlMarkVolumeDirty = 0x00090030
+
+	volumeIsDirty = 0x00000001
+
+	// https://docs.microsoft.com/en-us/windows/win32/api/winioctl/ni-winioctl-ioctl_storage_reinitialize_media
+	ioctlStorageReinitializeMedia = 0x2D9640
+
+	storageReinitializeMediaVersion = 1
+	sanitizeTimeout                 = 60 * 60
+)
+
+var (
+	// ErrSystemDisk indicates an operation that refuses to run against the boot or system disk.
+	ErrSystemDisk = errors.New("refusing to operate on the boot or system disk")
+)
+
+// SanitizeMethod selects how a device erases its media.
+type SanitizeMethod uint32
+
+// Sanitize methods.
+const (
+	// SanitizeDefault lets the device choose.
+	SanitizeDefault SanitizeMethod = 0
+	// SanitizeBlockErase erases all user data blocks.
+	SanitizeBlockErase SanitizeMethod = 1
+	// SanitizeCryptoErase discards the media encryption key.
+	SanitizeCryptoErase SanitizeMethod = 2
+)
+
+// https://docs.microsoft.com/en-us/windows/win32/api/winioctl/ns-winioctl-storage_property_query
+type storagePropertyQuery struct {
+	PropertyID           uint32
+	QueryType            uint32
+	AdditionalParameters [1]byte
+}
+
+// https://docs.microsoft.com/en-us/windows/win32/api/winioctl/ns-winioctl-device_trim_descriptor
+type deviceTrimDescriptor struct {
+	Version     uint32
+	Size        uint32
+	TrimEnabled byte
+}
+
+// https://docs.microsoft.com/en-us/windows/win32/api/winioctl/ns-winioctl-storage_reinitialize_media
+type storageReinitializeMedia struct {
+	Version          uint32
+	Size             uint32
+	TimeoutInSeconds uint32
+	SanitizeOption   uint32
+}
+
+func physicalDrivePath(diskNum int) string {
+	return fmt.Sprintf(`\\.\PhysicalDrive%d`, diskNum)
+}
+
+func openDevice(path string, access uint32) (windows.Handle, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+	h, err := windows.CreateFile(p, access, windows.FILE_SHARE_READ|windows.FILE_SHARE_WRITE, nil, windows.OPEN_EXISTING, 0, 0)
+	if err != nil {
+		return windows.InvalidHandle, fmt.Errorf("windows.CreateFile(%s): %w", path, err)
+	}
+	return h, nil
+}
+
+// TrimEnabled reports whether the disk supports TRIM (unmap) and has it enabled.
+func TrimEnabled(diskNum int) (bool, error) {
+	h, err := openDevice(physicalDrivePath(diskNum), 0)
+	if err != nil {
+		return false, err
+	}
+	defer windows.CloseHandle(h)
+
+	q := storagePropertyQuery{PropertyID: storageDeviceTrimProperty, QueryType: propertyStandardQuery}
+	d := deviceTrimDescriptor{}
+	var n uint32
+	err = windows.DeviceIoControl(h, ioctlStorageQueryProperty,
+		(*byte)(unsafe.Pointer(&q)), uint32(unsafe.Sizeof(q)),
+		(*byte)(unsafe.Pointer(&d)), uint32(unsafe.Sizeof(d)), &n, nil)
+	if err != nil {
+		return false, fmt.Errorf("DeviceIoControl(IOCTL_STORAGE_QUERY_PROPERTY): %w", err)
+	}
+	return d.TrimEnabled != 0, nil
+}
+
+// IsDirty reports whether the volume's dirty bit is set, meaning autochk will check the
+// file system on the next boot.
+func (v *VolumeInfo) IsDirty() (bool, error) {
+	path, err := v.devicePath()
+	if err != nil {
+		return false, err
+	}
+	h, err := openDevice(path, windows.GENERIC_READ)
+	if err != nil {
+		return false, err
+	}
+	defer windows.CloseHandle(h)
+
+	var flags, n uint32
+	err = windows.DeviceIoControl(h, fsctlIsVolumeDirty, nil, 0,
+		(*byte)(unsafe.Pointer(&flags)), uint32(unsafe.Sizeof(flags)), &n, nil)
+	if err != nil {
+		return false, fmt.Errorf("DeviceIoControl(FSCTL_IS_VOLUME_DIRTY): %w", err)
+	}
+	return flags&volumeIsDirty != 0, nil
+}
+
+// ScheduleCheck sets the volume's dirty bit, so that autochk checks the file system on
+// the next boot. The bit cannot be cleared again other than by autochk or chkdsk.
+func (v *VolumeInfo) ScheduleCheck() error {
+	path, err := v.devicePath()
+	if err != nil {
+		return err
+	}
+	h, err := openDevice(path, windows.GENERIC_READ|windows.GENERIC_WRITE)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	var n uint32
+	if err := windows.DeviceIoControl(h, fsctlMarkVolumeDirty, nil, 0, nil, 0, &n, nil); err != nil {
+		return fmt.Errorf("DeviceIoControl(FSCTL_MARK_VOLUME_DIRTY): %w", err)
+	}
+	return nil
+}
+
+// Sanitize irrecoverably erases the disk using the device's own sanitize command (NVMe
+// Sanitize, or the SCSI/ATA equivalent), via IOCTL_STORAGE_REINITIALIZE_MEDIA. The call
+// blocks until the device reports completion, which can take a long time for block erase.
+//
+// Sanitize refuses to run against the boot or system disk. Devices without sanitize support
+// return an error; fall back to Clear-Disk or overwriting in that case.
+func (d *DiskInfo) Sanitize(method SanitizeMethod) error {
+	if d.IsBoot || d.IsSystem {
+		return fmt.Errorf("%w: disk %d", ErrSystemDisk, d.Number)
+	}
+	h, err := openDevice(physicalDrivePath(d.Number), windows.GENERIC_READ|windows.GENERIC_WRITE)
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(h)
+
+	in := storageReinitializeMedia{
+		Version:          storageReinitializeMediaVersion,
+		TimeoutInSeconds: sanitizeTimeout,
+		SanitizeOption:   uint32(method) & 0xF,
+	}
+	in.Size = uint32(unsafe.Sizeof(in))
+	var n uint32
+	err = windows.DeviceIoControl(h, ioctlStorageReinitializeMedia,
+		(*byte)(unsafe.Pointer(&in)), in.Size, nil, 0, &n, nil)
+	if err != nil {
+		return fmt.Errorf("DeviceIoControl(IOCTL_STORAGE_REINITIALIZE_MEDIA): %w", err)
+	}
+	return nil
+}
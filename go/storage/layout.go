@@ -0,0 +1,269 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	mb = 1024 * 1024
+)
+
+var (
+	// ErrInvalidLayout indicates a Layout that cannot be applied.
+	ErrInvalidLayout = errors.New("invalid disk layout")
+)
+
+// PartitionSpec describes a single partition within a Layout.
+//
+// A Size of zero consumes all space not claimed by the other partitions in the layout. At
+// most one partition may do so.
+type PartitionSpec struct {
+	// GptType is the partition type for GPT disks (eg GptTypeSystem).
+	GptType string
+	// MbrType is the partition type for MBR disks (eg MbrTypeIFS).
+	MbrType int
+	// IsActive marks the partition active. MBR only.
+	IsActive bool
+	// Size is the size of the partition in bytes.
+	Size int
+
+	// FileSystem, if set, formats the new partition (eg "NTFS", "FAT32").
+	FileSystem string
+	Label      string
+	// DriveLetter, if set, assigns a drive letter to the partition.
+	DriveLetter string
+	// NoDefaultDriveLetter prevents Windows from assigning a drive letter automatically.
+	NoDefaultDriveLetter bool
+}
+
+// Layout declares the partitioning of an entire disk.
+//
+// Alignment, if set, is the partition alignment in bytes. See PhysicalDiskInfo.Alignment.
+type Layout struct {
+	Style      PartitionStyle
+	Partitions []PartitionSpec
+	Alignment  int
+}
+
+// UEFILayout returns the standard GPT layout for UEFI systems: EFI system partition,
+// Microsoft reserved partition, Windows, and a trailing recovery partition.
+func UEFILayout() *Layout {
+	return &Layout{
+		Style: StyleGPT,
+		Partitions: []PartitionSpec{
+			{GptType: GptTypeSystem, Size: 260 * mb, FileSystem: "FAT32", Label: "System", NoDefaultDriveLetter: true},
+			{GptType: GptTypeMSR, Size: 16 * mb},
+			{GptType: GptTypeBasicData, FileSystem: "NTFS", Label: "Windows"},
+			{GptType: GptTypeRecovery, Size: 1024 * mb, FileSystem: "NTFS", Label: "Recovery", NoDefaultDriveLetter: true},
+		},
+	}
+}
+
+// BIOSLayout returns the standard MBR layout for BIOS systems: an active system partition,
+// Windows, and a trailing recovery partition.
+func BIOSLayout() *Layout {
+	return &Layout{
+		Style: StyleMBR,
+		Partitions: []PartitionSpec{
+			{MbrType: MbrTypeIFS, IsActive: true, Size: 100 * mb, FileSystem: "NTFS", Label: "System"},
+			{MbrType: MbrTypeIFS, FileSystem: "NTFS", Label: "Windows"},
+			{MbrType: MbrTypeRecovery, Size: 1024 * mb, FileSystem: "NTFS", Label: "Recovery"},
+		},
+	}
+}
+
+func (l *Layout) validate() error {
+	if l.Style != StyleGPT && l.Style != StyleMBR {
+		return fmt.Errorf("%w: unsupported partition style %v", ErrInvalidLayout, l.Style)
+	}
+	if len(l.Partitions) < 1 {
+		return fmt.Errorf("%w: no partitions specified", ErrInvalidLayout)
+	}
+	fill := 0
+	for i, p := range l.Partitions {
+		if p.Size < 0 {
+			return fmt.Errorf("%w: partition %d has negative size", ErrInvalidLayout, i)
+		}
+		if l.Alignment > 0 && p.Size%l.Alignment != 0 {
+			return fmt.Errorf("%w: partition %d size is not a multiple of the alignment", ErrInvalidLayout, i)
+		}
+		if p.Size == 0 {
+			fill++
+		}
+		if l.Style == StyleGPT && (p.MbrType != 0 || p.IsActive) {
+			return fmt.Errorf("%w: partition %d uses MBR attributes on a GPT disk", ErrInvalidLayout, i)
+		}
+		if l.Style == StyleMBR && p.GptType != "" {
+			return fmt.Errorf("%w: partition %d uses a GPT type on an MBR disk", ErrInvalidLayout, i)
+		}
+		if len(strings.TrimSuffix(p.DriveLetter, ":")) > 1 {
+			return fmt.Errorf("%w: partition %d has invalid drive letter %q", ErrInvalidLayout, i, p.DriveLetter)
+		}
+		if p.FileSystem != "" {
+			if _, err := fileSystemName(p.FileSystem); err != nil {
+				return fmt.Errorf("%w: partition %d: %v", ErrInvalidLayout, i, err)
+			}
+		}
+	}
+	if fill > 1 {
+		return fmt.Errorf("%w: only one partition may consume the remaining space", ErrInvalidLayout)
+	}
+	return nil
+}
+
+// reserved returns the space claimed by the partitions following index i.
+func (l *Layout) reserved(i int) int {
+	r := 0
+	for _, p := range l.Partitions[i+1:] {
+		r += p.Size
+	}
+	return r
+}
+
+func newPartition(diskNum int, spec PartitionSpec, style PartitionStyle, alignment int) (*PartitionInfo, error) {
+	cmd := fmt.Sprintf("New-Partition -DiskNumber %d", diskNum)
+	if spec.Size == 0 {
+		cmd += " -UseMaximumSize"
+	} else {
+		cmd += fmt.Sprintf(" -Size %d", spec.Size)
+	}
+	if alignment > 0 {
+		cmd += fmt.Sprintf(" -Alignment %d", alignment)
+	}
+	if style == StyleGPT && spec.GptType != "" {
+		cmd += " -GptType " + psQuote(spec.GptType)
+	}
+	if style == StyleMBR {
+		if spec.MbrType != 0 {
+			cmd += fmt.Sprintf(" -MbrType %d", spec.MbrType)
+		}
+		if spec.IsActive {
+			cmd += " -IsActive"
+		}
+	}
+	if l := strings.TrimSuffix(spec.DriveLetter, ":"); l != "" {
+		cmd += " -DriveLetter " + strings.ToUpper(l)
+	}
+	p := &PartitionInfo{}
+	err := queryObject(cmd, p)
+	return p, err
+}
+
+func removePartition(p PartitionInfo) error {
+	cmd := fmt.Sprintf("Remove-Partition -DiskNumber %d -PartitionNumber %d -Confirm:$false", p.DiskNumber, p.PartitionNumber)
+	_, err := fnPSCmd(cmd, []string{}, nil)
+	return err
+}
+
+// ApplyLayout wipes a disk and partitions it according to layout, formatting and
+// lettering partitions as requested.
+//
+// If the layout does not specify an alignment, the recommended alignment for the
+// underlying device is used where it can be determined.
+//
+// If any step fails, partitions created so far are removed again before returning. Data
+// that was on the disk beforehand is not recoverable either way.
+//
+// The partitions are returned as created; query them again for up to date state.
+//
+// Example: storage.ApplyLayout(0, storage.UEFILayout())
+func ApplyLayout(diskNum int, layout *Layout) ([]PartitionInfo, error) {
+	created := []PartitionInfo{}
+	if err := layout.validate(); err != nil {
+		return created, err
+	}
+	disk, err := GetDiskByNumber(diskNum)
+	if err != nil {
+		return created, err
+	}
+	if layout.Alignment == 0 {
+		if pd, err := GetPhysicalDisk(diskNum); err == nil {
+			aligned := *layout
+			aligned.Alignment = pd.Alignment()
+			if aligned.validate() == nil {
+				layout = &aligned
+			}
+		}
+	}
+	if disk.PartitionStyle != StyleRAW {
+		cmd := fmt.Sprintf("Clear-Disk -Number %d -RemoveData -RemoveOEM -Confirm:$false", diskNum)
+		if _, err := fnPSCmd(cmd, []string{}, nil); err != nil {
+			return created, fmt.Errorf("clearing disk %d: %w", diskNum, err)
+		}
+	}
+	cmd := fmt.Sprintf("Initialize-Disk -Number %d -PartitionStyle %s", diskNum, layout.Style)
+	if _, err := fnPSCmd(cmd, []string{}, nil); err != nil {
+		return created, fmt.Errorf("initializing disk %d: %w", diskNum, err)
+	}
+
+	for i, spec := range layout.Partitions {
+		if err := applyPartition(diskNum, i, spec, layout, &created); err != nil {
+			return []PartitionInfo{}, rollback(created, err)
+		}
+	}
+	return created, nil
+}
+
+func applyPartition(diskNum, i int, spec PartitionSpec, layout *Layout, created *[]PartitionInfo) error {
+	p, err := newPartition(diskNum, spec, layout.Style, layout.Alignment)
+	if err != nil {
+		return fmt.Errorf("creating partition %d: %w", i, err)
+	}
+	*created = append(*created, *p)
+	if spec.Size == 0 {
+		if r := layout.reserved(i); r > 0 {
+			// Round down so the partitions that follow stay aligned; Windows aligns to 1 MiB
+			// by default.
+			alignment := layout.Alignment
+			if alignment == 0 {
+				alignment = mb
+			}
+			size := (p.Size - r) / alignment * alignment
+			if err := PartitionResize(p.DiskNumber, p.PartitionNumber, size); err != nil {
+				return fmt.Errorf("shrinking partition %d: %w", i, err)
+			}
+			(*created)[len(*created)-1].Size = size
+		}
+	}
+	if spec.FileSystem != "" {
+		if _, err := Format(p.DiskNumber, p.PartitionNumber, spec.FileSystem, spec.Label); err != nil {
+			return fmt.Errorf("formatting partition %d: %w", i, err)
+		}
+	}
+	if spec.NoDefaultDriveLetter {
+		if err := p.SetNoDefaultDriveLetter(true); err != nil {
+			return fmt.Errorf("configuring partition %d: %w", i, err)
+		}
+		(*created)[len(*created)-1].NoDefaultDriveLetter = true
+	}
+	return nil
+}
+
+func rollback(created []PartitionInfo, cause error) error {
+	failed := []string{}
+	for i := len(created) - 1; i >= 0; i-- {
+		if err := removePartition(created[i]); err != nil {
+			failed = append(failed, fmt.Sprintf("partition %d: %v", created[i].PartitionNumber, err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w (rollback failed: %s)", cause, strings.Join(failed, "; "))
+	}
+	return cause
+}
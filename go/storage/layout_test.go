@@ -0,0 +1,167 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/winops/powershell"
+)
+
+// fakeLayoutPS simulates the storage cmdlets used by ApplyLayout, failing any command
+// containing failOn. A partition using the maximum size is created with maxSize bytes.
+func fakeLayoutPS(cmds *[]string, failOn string, maxSize int) func(string, []string, *powershell.PSConfig) ([]byte, error) {
+	part := 0
+	return func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+		*cmds = append(*cmds, psCmd)
+		if failOn != "" && strings.Contains(psCmd, failOn) {
+			return nil, errors.New("simulated failure")
+		}
+		switch {
+		case strings.Contains(psCmd, "MSFT_PhysicalDisk"):
+			return []byte(`[{"DeviceId": "1", "PhysicalSectorSize": 4096}]`), nil
+		case strings.Contains(psCmd, "MSFT_Disk"):
+			return []byte(`[{"Number": 1, "PartitionStyle": "GPT", "Size": 10737418240}]`), nil
+		case strings.HasPrefix(psCmd, "New-Partition"):
+			part++
+			size := 100 * mb
+			if strings.Contains(psCmd, "-UseMaximumSize") {
+				size = maxSize
+			}
+			return []byte(fmt.Sprintf(`{"DiskNumber": 1, "PartitionNumber": %d, "Size": %d}`, part, size)), nil
+		case strings.Contains(psCmd, "Get-Volume"):
+			return []byte(`{}`), nil
+		}
+		return nil, nil
+	}
+}
+
+func TestApplyLayout(t *testing.T) {
+	layout := &Layout{
+		Style: StyleGPT,
+		Partitions: []PartitionSpec{
+			{GptType: GptTypeSystem, Size: 100 * mb, FileSystem: "FAT32", Label: "System", NoDefaultDriveLetter: true},
+			{GptType: GptTypeBasicData, FileSystem: "NTFS", Label: "Windows", DriveLetter: "w:"},
+			{GptType: GptTypeRecovery, Size: 100 * mb},
+		},
+	}
+	cmds := []string{}
+	fnPSCmd = fakeLayoutPS(&cmds, "", 8000*mb)
+	got, err := ApplyLayout(1, layout)
+	if err != nil {
+		t.Fatalf("ApplyLayout() returned unexpected error %v", err)
+	}
+	want := []PartitionInfo{
+		{DiskNumber: 1, PartitionNumber: 1, Size: 100 * mb, NoDefaultDriveLetter: true},
+		{DiskNumber: 1, PartitionNumber: 2, Size: 7900 * mb},
+		{DiskNumber: 1, PartitionNumber: 3, Size: 100 * mb},
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("ApplyLayout() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	wantCmds := []string{
+		`ConvertTo-JSON -InputObject @(Get-CimInstance -Namespace root/Microsoft/Windows/Storage -ClassName MSFT_Disk -Filter 'Number = 1' | Select-Object -Property ` + diskProps + `)`,
+		`ConvertTo-JSON -InputObject @(Get-CimInstance -Namespace root/Microsoft/Windows/Storage -ClassName MSFT_PhysicalDisk -Filter 'DeviceId = ''1''' | Select-Object -Property ` + physicalDiskProps + `)`,
+		`Clear-Disk -Number 1 -RemoveData -RemoveOEM -Confirm:$false`,
+		`Initialize-Disk -Number 1 -PartitionStyle GPT`,
+		`New-Partition -DiskNumber 1 -Size 104857600 -Alignment 1048576 -GptType '{c12a7328-f81f-11d2-ba4b-00a0c93ec93b}' | Select-Object -Property ` + partitionProps + ` | ConvertTo-JSON`,
+		`Get-Partition -DiskNumber 1 -PartitionNumber 1 | Format-Volume -FileSystem FAT32 -NewFileSystemLabel 'System' -Confirm:$false`,
+		`Get-Partition -DiskNumber 1 -PartitionNumber 1 | Get-Volume | Select-Object -Property ` + volumeProps + ` | ConvertTo-JSON`,
+		`Set-Partition -DiskNumber 1 -PartitionNumber 1 -NoDefaultDriveLetter $true`,
+		`New-Partition -DiskNumber 1 -UseMaximumSize -Alignment 1048576 -GptType '{ebd0a0a2-b9e5-4433-87c0-68b6b72699c7}' -DriveLetter W | Select-Object -Property ` + partitionProps + ` | ConvertTo-JSON`,
+		`Resize-Partition -DiskNumber 1 -PartitionNumber 2 -Size 8283750400`,
+		`Get-Partition -DiskNumber 1 -PartitionNumber 2 | Format-Volume -FileSystem NTFS -NewFileSystemLabel 'Windows' -Confirm:$false`,
+		`Get-Partition -DiskNumber 1 -PartitionNumber 2 | Get-Volume | Select-Object -Property ` + volumeProps + ` | ConvertTo-JSON`,
+		`New-Partition -DiskNumber 1 -Size 104857600 -Alignment 1048576 -GptType '{de94bba4-06d1-4d40-a16a-bfd50179d6ac}' | Select-Object -Property ` + partitionProps + ` | ConvertTo-JSON`,
+	}
+	if diff := cmp.Diff(wantCmds, cmds); diff != "" {
+		t.Errorf("ApplyLayout() ran unexpected commands (-want +got):\n%s", diff)
+	}
+}
+
+func TestApplyLayoutUnaligned(t *testing.T) {
+	cmds := []string{}
+	// The space left for the fill partition is rarely a whole number of MiB.
+	fnPSCmd = fakeLayoutPS(&cmds, "", 8000*mb+12345)
+	got, err := ApplyLayout(1, UEFILayout())
+	if err != nil {
+		t.Fatalf("ApplyLayout() returned unexpected error %v", err)
+	}
+	resize := ""
+	for _, c := range cmds {
+		if strings.HasPrefix(c, "Resize-Partition") {
+			resize = c
+		}
+	}
+	if want := `Resize-Partition -DiskNumber 1 -PartitionNumber 3 -Size 7314866176`; resize != want {
+		t.Errorf("ApplyLayout() resized the fill partition with %q, want %q", resize, want)
+	}
+	for _, p := range got {
+		if p.Size%mb != 0 {
+			t.Errorf("ApplyLayout() returned partition %d with unaligned size %d", p.PartitionNumber, p.Size)
+		}
+	}
+}
+
+func TestApplyLayoutRollback(t *testing.T) {
+	cmds := []string{}
+	fnPSCmd = fakeLayoutPS(&cmds, "-FileSystem NTFS", 8000*mb)
+	got, err := ApplyLayout(1, UEFILayout())
+	if err == nil {
+		t.Fatalf("ApplyLayout() returned nil error")
+	}
+	if len(got) != 0 {
+		t.Errorf("ApplyLayout() returned %d partitions after rollback, want 0", len(got))
+	}
+	wantTail := []string{
+		`Remove-Partition -DiskNumber 1 -PartitionNumber 3 -Confirm:$false`,
+		`Remove-Partition -DiskNumber 1 -PartitionNumber 2 -Confirm:$false`,
+		`Remove-Partition -DiskNumber 1 -PartitionNumber 1 -Confirm:$false`,
+	}
+	if len(cmds) < len(wantTail) {
+		t.Fatalf("ApplyLayout() ran %d commands, want at least %d", len(cmds), len(wantTail))
+	}
+	if diff := cmp.Diff(wantTail, cmds[len(cmds)-len(wantTail):]); diff != "" {
+		t.Errorf("ApplyLayout() rolled back unexpectedly (-want +got):\n%s", diff)
+	}
+}
+
+func TestLayoutValidate(t *testing.T) {
+	tests := []struct {
+		desc string
+		in   *Layout
+		want error
+	}{
+		{"uefi", UEFILayout(), nil},
+		{"bios", BIOSLayout(), nil},
+		{"raw", &Layout{Style: StyleRAW, Partitions: []PartitionSpec{{}}}, ErrInvalidLayout},
+		{"empty", &Layout{Style: StyleGPT}, ErrInvalidLayout},
+		{"two fills", &Layout{Style: StyleGPT, Partitions: []PartitionSpec{{}, {}}}, ErrInvalidLayout},
+		{"mbr on gpt", &Layout{Style: StyleGPT, Partitions: []PartitionSpec{{IsActive: true}}}, ErrInvalidLayout},
+		{"gpt on mbr", &Layout{Style: StyleMBR, Partitions: []PartitionSpec{{GptType: GptTypeMSR}}}, ErrInvalidLayout},
+		{"bad letter", &Layout{Style: StyleMBR, Partitions: []PartitionSpec{{DriveLetter: "CD"}}}, ErrInvalidLayout},
+		{"misaligned", &Layout{Style: StyleGPT, Alignment: mb, Partitions: []PartitionSpec{{Size: 1000}}}, ErrInvalidLayout},
+		{"bad file system", &Layout{Style: StyleGPT, Partitions: []PartitionSpec{{FileSystem: "NTFS; Remove-Item C:\\"}}}, ErrInvalidLayout},
+	}
+	for _, tt := range tests {
+		if err := tt.in.validate(); !errors.Is(err, tt.want) {
+			t.Errorf("%s: validate() returned unexpected error %v", tt.desc, err)
+		}
+	}
+}
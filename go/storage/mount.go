@@ -0,0 +1,130 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrInvalidPath indicates a malformed volume or mount point path.
+	ErrInvalidPath = errors.New("invalid path")
+
+	volumeGUIDPathRe = regexp.MustCompile(`(?i)^\\\\\?\\Volume\{[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\}\\$`)
+)
+
+// IsVolumeGUIDPath reports whether path is a volume GUID path (eg \\?\Volume{...}\).
+func IsVolumeGUIDPath(path string) bool {
+	return volumeGUIDPathRe.MatchString(path)
+}
+
+// VolumeGUIDPaths returns the GUID paths of all volumes, including those without a drive
+// letter such as the EFI system and recovery partitions.
+func VolumeGUIDPaths() ([]string, error) {
+	paths := []string{}
+	vols, err := GetVolumes()
+	if err != nil {
+		return paths, err
+	}
+	for _, v := range vols {
+		if IsVolumeGUIDPath(v.Path) {
+			paths = append(paths, v.Path)
+		}
+	}
+	return paths, nil
+}
+
+// GetVolumeByPath returns the volume with the given GUID path. The trailing backslash may
+// be omitted.
+//
+// Example: storage.GetVolumeByPath(`\\?\Volume{26a21bda-a627-11d7-9931-806e6f6e6963}\`)
+func GetVolumeByPath(path string) (*VolumeInfo, error) {
+	if !strings.HasSuffix(path, `\`) {
+		path += `\`
+	}
+	if !IsVolumeGUIDPath(path) {
+		return &VolumeInfo{}, fmt.Errorf("%w: %q is not a volume GUID path", ErrInvalidPath, path)
+	}
+	vols := []VolumeInfo{}
+	if err := queryStorage("MSFT_Volume", NewFilter().Eq("Path", path), &vols); err != nil {
+		return &VolumeInfo{}, err
+	}
+	if len(vols) < 1 {
+		return &VolumeInfo{}, ErrNotFound
+	}
+	return &vols[0], nil
+}
+
+// mountPath normalizes a mount point folder, which must be an absolute path.
+func mountPath(dir string) (string, error) {
+	if len(dir) < 3 || dir[1] != ':' || dir[2] != '\\' {
+		return "", fmt.Errorf("%w: mount point %q must be an absolute path", ErrInvalidPath, dir)
+	}
+	if !strings.HasSuffix(dir, `\`) {
+		dir += `\`
+	}
+	return dir, nil
+}
+
+// MountPoints returns the folders the partition is mounted on, excluding drive letters and
+// the volume GUID path.
+func (p *PartitionInfo) MountPoints() []string {
+	mounts := []string{}
+	for _, a := range p.AccessPaths {
+		if len(a) > 3 && !IsVolumeGUIDPath(a) {
+			mounts = append(mounts, a)
+		}
+	}
+	return mounts
+}
+
+// AddMountPoint mounts the partition on dir, an existing empty folder on an NTFS volume.
+//
+// Example: p.AddMountPoint(`C:\mnt\esp`)
+func (p *PartitionInfo) AddMountPoint(dir string) error {
+	dir, err := mountPath(dir)
+	if err != nil {
+		return err
+	}
+	cmd := fmt.Sprintf("Add-PartitionAccessPath -DiskNumber %d -PartitionNumber %d -AccessPath %s", p.DiskNumber, p.PartitionNumber, psQuote(dir))
+	if _, err := fnPSCmd(cmd, []string{}, nil); err != nil {
+		return err
+	}
+	p.AccessPaths = append(p.AccessPaths, dir)
+	return nil
+}
+
+// RemoveMountPoint unmounts the partition from dir. The folder itself is left in place.
+func (p *PartitionInfo) RemoveMountPoint(dir string) error {
+	dir, err := mountPath(dir)
+	if err != nil {
+		return err
+	}
+	cmd := fmt.Sprintf("Remove-PartitionAccessPath -DiskNumber %d -PartitionNumber %d -AccessPath %s", p.DiskNumber, p.PartitionNumber, psQuote(dir))
+	if _, err := fnPSCmd(cmd, []string{}, nil); err != nil {
+		return err
+	}
+	paths := StringList{}
+	for _, a := range p.AccessPaths {
+		if !strings.EqualFold(a, dir) {
+			paths = append(paths, a)
+		}
+	}
+	p.AccessPaths = paths
+	return nil
+}
@@ -0,0 +1,126 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/winops/powershell"
+)
+
+const testGUIDPath = `\\?\Volume{26a21bda-a627-11d7-9931-806e6f6e6963}\`
+
+func TestIsVolumeGUIDPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want bool
+	}{
+		{testGUIDPath, true},
+		{`\\?\Volume{26A21BDA-A627-11D7-9931-806E6F6E6963}\`, true},
+		{`\\?\Volume{26a21bda-a627-11d7-9931-806e6f6e6963}`, false},
+		{`\\?\Volume{not-a-guid}\`, false},
+		{`C:\`, false},
+	}
+	for _, tt := range tests {
+		if got := IsVolumeGUIDPath(tt.in); got != tt.want {
+			t.Errorf("IsVolumeGUIDPath(%q) = %t, want %t", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestVolumeGUIDPaths(t *testing.T) {
+	fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+		return []byte(`[{"Path": "\\\\?\\Volume{26a21bda-a627-11d7-9931-806e6f6e6963}\\"}, {"Path": "C:\\"}]`), nil
+	}
+	got, err := VolumeGUIDPaths()
+	if err != nil {
+		t.Fatalf("VolumeGUIDPaths() returned unexpected error %v", err)
+	}
+	if diff := cmp.Diff([]string{testGUIDPath}, got); diff != "" {
+		t.Errorf("VolumeGUIDPaths() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetVolumeByPath(t *testing.T) {
+	tests := []struct {
+		in      string
+		psOut   string
+		wantCmd string
+		wantErr error
+	}{
+		{
+			in:      testGUIDPath,
+			psOut:   `[{"Path": "\\\\?\\Volume{26a21bda-a627-11d7-9931-806e6f6e6963}\\"}]`,
+			wantCmd: `ConvertTo-JSON -InputObject @(Get-CimInstance -Namespace root/Microsoft/Windows/Storage -ClassName MSFT_Volume -Filter 'Path = ''\\\\?\\Volume{26a21bda-a627-11d7-9931-806e6f6e6963}\\''' | Select-Object -Property ` + volumeProps + `)`,
+		},
+		{
+			in:      testGUIDPath[:len(testGUIDPath)-1],
+			psOut:   `[]`,
+			wantCmd: `ConvertTo-JSON -InputObject @(Get-CimInstance -Namespace root/Microsoft/Windows/Storage -ClassName MSFT_Volume -Filter 'Path = ''\\\\?\\Volume{26a21bda-a627-11d7-9931-806e6f6e6963}\\''' | Select-Object -Property ` + volumeProps + `)`,
+			wantErr: ErrNotFound,
+		},
+		{
+			in:      `C:\`,
+			wantErr: ErrInvalidPath,
+		},
+	}
+	for _, tt := range tests {
+		gotCmd := ""
+		fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+			gotCmd = psCmd
+			return []byte(tt.psOut), nil
+		}
+		_, err := GetVolumeByPath(tt.in)
+		if gotCmd != tt.wantCmd {
+			t.Errorf("GetVolumeByPath(%q) ran %q, want %q", tt.in, gotCmd, tt.wantCmd)
+		}
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("GetVolumeByPath(%q) returned unexpected error %v", tt.in, err)
+		}
+	}
+}
+
+func TestMountPoints(t *testing.T) {
+	p := &PartitionInfo{DiskNumber: 0, PartitionNumber: 1, AccessPaths: StringList{`E:\`, testGUIDPath}}
+	cmds := []string{}
+	fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+		cmds = append(cmds, psCmd)
+		return nil, nil
+	}
+	if err := p.AddMountPoint(`C:\mnt\esp`); err != nil {
+		t.Fatalf("AddMountPoint() returned unexpected error %v", err)
+	}
+	if diff := cmp.Diff([]string{`C:\mnt\esp\`}, p.MountPoints()); diff != "" {
+		t.Errorf("MountPoints() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if err := p.RemoveMountPoint(`c:\MNT\esp\`); err != nil {
+		t.Fatalf("RemoveMountPoint() returned unexpected error %v", err)
+	}
+	if got := p.MountPoints(); len(got) != 0 {
+		t.Errorf("MountPoints() = %v after removal, want empty", got)
+	}
+	if err := p.AddMountPoint(`mnt\esp`); !errors.Is(err, ErrInvalidPath) {
+		t.Errorf("AddMountPoint(relative) returned %v, want %v", err, ErrInvalidPath)
+	}
+	wantCmds := []string{
+		`Add-PartitionAccessPath -DiskNumber 0 -PartitionNumber 1 -AccessPath 'C:\mnt\esp\'`,
+		`Remove-PartitionAccessPath -DiskNumber 0 -PartitionNumber 1 -AccessPath 'c:\MNT\esp\'`,
+	}
+	if diff := cmp.Diff(wantCmds, cmds); diff != "" {
+		t.Errorf("mount points ran unexpected commands (-want +got):\n%s", diff)
+	}
+}
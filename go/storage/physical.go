@@ -0,0 +1,91 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"strconv"
+)
+
+const (
+	// defaultAlignment matches the partition alignment used by Windows setup.
+	defaultAlignment = 1 * mb
+	spindleUnknown   = 0xFFFFFFFF
+)
+
+// PhysicalDiskInfo holds information about the physical device backing a disk.
+type PhysicalDiskInfo struct {
+	BusType            BusType
+	DeviceID           string `json:"DeviceId"`
+	FriendlyName       string
+	LogicalSectorSize  int
+	MediaType          MediaType
+	PhysicalSectorSize int
+	Size               int
+	SpindleSpeed       uint32
+}
+
+// GetPhysicalDisk returns the physical device backing the given disk number.
+func GetPhysicalDisk(diskNum int) (*PhysicalDiskInfo, error) {
+	disks := []PhysicalDiskInfo{}
+	if err := queryStorage("MSFT_PhysicalDisk", NewFilter().Eq("DeviceId", strconv.Itoa(diskNum)), &disks); err != nil {
+		return &PhysicalDiskInfo{}, err
+	}
+	if len(disks) < 1 {
+		return &PhysicalDiskInfo{}, ErrNotFound
+	}
+	return &disks[0], nil
+}
+
+// IsSSD reports whether the device is solid state.
+//
+// Devices which don't report a media type are treated as solid state if they report no
+// spindle.
+func (p *PhysicalDiskInfo) IsSSD() bool {
+	switch p.MediaType {
+	case MediaSSD, MediaSCM:
+		return true
+	case MediaHDD:
+		return false
+	default:
+		return p.SpindleSpeed == 0
+	}
+}
+
+// Alignment returns the recommended partition alignment for the device in bytes.
+//
+// This is 1MiB, unless the physical sector size demands a larger multiple.
+func (p *PhysicalDiskInfo) Alignment() int {
+	sector := p.PhysicalSectorSize
+	if sector < p.LogicalSectorSize {
+		sector = p.LogicalSectorSize
+	}
+	if sector <= 0 {
+		return defaultAlignment
+	}
+	return Align(defaultAlignment, sector)
+}
+
+// Align rounds n up to the next multiple of alignment.
+//
+// Example: storage.Align(size, disk.Alignment())
+func Align(n, alignment int) int {
+	if alignment <= 0 {
+		return n
+	}
+	if r := n % alignment; r != 0 {
+		return n + alignment - r
+	}
+	return n
+}
@@ -0,0 +1,102 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/winops/powershell"
+)
+
+func TestGetPhysicalDisk(t *testing.T) {
+	tests := []struct {
+		psOut   string
+		want    *PhysicalDiskInfo
+		wantSSD bool
+		wantErr error
+	}{
+		{
+			psOut:   `[{"DeviceId": "0", "MediaType": "SSD", "BusType": "NVMe", "PhysicalSectorSize": 4096, "SpindleSpeed": 0}]`,
+			want:    &PhysicalDiskInfo{DeviceID: "0", MediaType: MediaSSD, BusType: BusNVMe, PhysicalSectorSize: 4096},
+			wantSSD: true,
+		},
+		{
+			psOut:   `[{"DeviceId": "0", "MediaType": 3, "SpindleSpeed": 7200}]`,
+			want:    &PhysicalDiskInfo{DeviceID: "0", MediaType: MediaHDD, SpindleSpeed: 7200},
+			wantSSD: false,
+		},
+		{
+			psOut:   `[{"DeviceId": "0", "MediaType": 0, "SpindleSpeed": 4294967295}]`,
+			want:    &PhysicalDiskInfo{DeviceID: "0", SpindleSpeed: spindleUnknown},
+			wantSSD: false,
+		},
+		{
+			psOut:   `[]`,
+			want:    &PhysicalDiskInfo{},
+			wantErr: ErrNotFound,
+		},
+	}
+	for _, tt := range tests {
+		fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+			return []byte(tt.psOut), nil
+		}
+		got, err := GetPhysicalDisk(0)
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("GetPhysicalDisk(%s) returned unexpected diff (-want +got):\n%s", tt.psOut, diff)
+		}
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("GetPhysicalDisk(%s) returned unexpected error %v", tt.psOut, err)
+		}
+		if err == nil && got.IsSSD() != tt.wantSSD {
+			t.Errorf("GetPhysicalDisk(%s).IsSSD() = %t, want %t", tt.psOut, got.IsSSD(), tt.wantSSD)
+		}
+	}
+}
+
+func TestAlignment(t *testing.T) {
+	tests := []struct {
+		in   PhysicalDiskInfo
+		want int
+	}{
+		{PhysicalDiskInfo{}, 1048576},
+		{PhysicalDiskInfo{LogicalSectorSize: 512, PhysicalSectorSize: 4096}, 1048576},
+		{PhysicalDiskInfo{LogicalSectorSize: 4096, PhysicalSectorSize: 4096}, 1048576},
+		{PhysicalDiskInfo{PhysicalSectorSize: 3 * 1048576}, 3 * 1048576},
+	}
+	for _, tt := range tests {
+		if got := tt.in.Alignment(); got != tt.want {
+			t.Errorf("%+v.Alignment() = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestAlign(t *testing.T) {
+	tests := []struct {
+		n, alignment, want int
+	}{
+		{0, 4096, 0},
+		{1, 4096, 4096},
+		{4096, 4096, 4096},
+		{1048577, 1048576, 2097152},
+		{100, 0, 100},
+	}
+	for _, tt := range tests {
+		if got := Align(tt.n, tt.alignment); got != tt.want {
+			t.Errorf("Align(%d, %d) = %d, want %d", tt.n, tt.alignment, got, tt.want)
+		}
+	}
+}
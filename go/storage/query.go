@@ -0,0 +1,79 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Serializing a storage object in full includes its CIM class definition and a second copy
+// of every property, which makes ConvertTo-Json the dominant cost of most queries. Queries
+// therefore select only the properties of the struct being populated.
+
+// properties returns the names of the properties populated by the struct type underlying v.
+func properties(v interface{}) []string {
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	names := []string{}
+	if t.Kind() != reflect.Struct {
+		return names
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Name
+		if tag := f.Tag.Get("json"); tag != "" {
+			n := strings.Split(tag, ",")[0]
+			if n == "-" {
+				continue
+			}
+			if n != "" {
+				name = n
+			}
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func psJSON(cmd string, v interface{}) error {
+	out, err := fnPSCmd(cmd, []string{}, nil)
+	if err != nil {
+		return err
+	}
+	if err = json.Unmarshal(out, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnmarshal, err)
+	}
+	return nil
+}
+
+// queryObject populates v from the single object emitted by pipeline.
+func queryObject(pipeline string, v interface{}) error {
+	cmd := fmt.Sprintf("%s | Select-Object -Property %s | ConvertTo-JSON", pipeline, strings.Join(properties(v), ","))
+	return psJSON(cmd, v)
+}
+
+// queryList populates the slice pointed to by v from all objects emitted by pipeline.
+func queryList(pipeline string, v interface{}) error {
+	cmd := fmt.Sprintf("ConvertTo-JSON -InputObject @(%s | Select-Object -Property %s)", pipeline, strings.Join(properties(v), ","))
+	return psJSON(cmd, v)
+}
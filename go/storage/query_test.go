@@ -0,0 +1,76 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/winops/powershell"
+)
+
+var (
+	diskProps         = strings.Join(properties(&DiskInfo{}), ",")
+	partitionProps    = strings.Join(properties(&PartitionInfo{}), ",")
+	physicalDiskProps = strings.Join(properties(&PhysicalDiskInfo{}), ",")
+	volumeProps       = strings.Join(properties(&VolumeInfo{}), ",")
+)
+
+func TestProperties(t *testing.T) {
+	type sample struct {
+		Name     string
+		ID       string `json:"Id"`
+		Skipped  string `json:"-"`
+		Options  string `json:",omitempty"`
+		internal string
+	}
+	want := []string{"Name", "Id", "Options"}
+	for _, in := range []interface{}{sample{}, &sample{}, &[]sample{}} {
+		if diff := cmp.Diff(want, properties(in)); diff != "" {
+			t.Errorf("properties(%T) returned unexpected diff (-want +got):\n%s", in, diff)
+		}
+	}
+	if got := properties(""); len(got) != 0 {
+		t.Errorf("properties(string) = %v, want empty", got)
+	}
+}
+
+func TestQueryCommands(t *testing.T) {
+	gotCmd := ""
+	fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+		gotCmd = psCmd
+		return []byte(`[{"DriveLetter": "C"}]`), nil
+	}
+	v := []VolumeInfo{}
+	if err := queryList("Get-Volume", &v); err != nil {
+		t.Fatalf("queryList() returned unexpected error %v", err)
+	}
+	want := "ConvertTo-JSON -InputObject @(Get-Volume | Select-Object -Property DriveLetter,FileSystem,FileSystemLabel,HealthStatus,ObjectId,OperationalStatus,Path,Size,SizeRemaining,UniqueId)"
+	if gotCmd != want {
+		t.Errorf("queryList() ran %q, want %q", gotCmd, want)
+	}
+
+	fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+		gotCmd = psCmd
+		return []byte(`{"NewDiskPolicy": 1}`), nil
+	}
+	if _, err := GetSanPolicy(); err != nil {
+		t.Fatalf("GetSanPolicy() returned unexpected error %v", err)
+	}
+	if want := "Get-StorageSetting | Select-Object -Property NewDiskPolicy | ConvertTo-JSON"; gotCmd != want {
+		t.Errorf("GetSanPolicy() ran %q, want %q", gotCmd, want)
+	}
+}
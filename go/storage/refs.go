@@ -0,0 +1,88 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrInvalidFormat indicates format options that cannot be applied.
+	ErrInvalidFormat = errors.New("invalid format options")
+)
+
+// fileSystems lists the file systems accepted by Format-Volume.
+var fileSystems = []string{"NTFS", "ReFS", "FAT32", "exFAT", "FAT"}
+
+const (
+	// devDriveMinSize is the smallest volume Windows will format as a Dev Drive.
+	devDriveMinSize = 50 * 1024 * mb
+)
+
+// ReFSOptions configures a ReFS volume created by FormatReFS.
+//
+// Block cloning is always available on ReFS and needs no configuration.
+type ReFSOptions struct {
+	// DevDrive formats the volume as a Dev Drive. Requires Windows 11 and at least 50GB.
+	DevDrive bool
+	// IntegrityStreams enables checksums on file data by default. When nil, the file system
+	// default applies.
+	IntegrityStreams *bool
+	// AllocationUnitSize is the cluster size in bytes (4096 or 65536). Zero uses the default.
+	AllocationUnitSize int
+}
+
+func (o *ReFSOptions) args(size int) (string, error) {
+	args := ""
+	if o.AllocationUnitSize != 0 {
+		if o.AllocationUnitSize != 4096 && o.AllocationUnitSize != 65536 {
+			return "", fmt.Errorf("%w: unsupported ReFS allocation unit size %d", ErrInvalidFormat, o.AllocationUnitSize)
+		}
+		args += fmt.Sprintf(" -AllocationUnitSize %d", o.AllocationUnitSize)
+	}
+	if o.IntegrityStreams != nil {
+		args += " -SetIntegrityStreams " + psBool(*o.IntegrityStreams)
+	}
+	if o.DevDrive {
+		if size < devDriveMinSize {
+			return "", fmt.Errorf("%w: a Dev Drive requires at least 50GB, partition is %d bytes", ErrInvalidFormat, size)
+		}
+		args += " -DevDrive"
+	}
+	return args, nil
+}
+
+// FormatReFS formats a partition with ReFS, applying opts if provided.
+//
+// Example: storage.FormatReFS(1, 2, "Dev", &storage.ReFSOptions{DevDrive: true})
+func FormatReFS(diskNum, partNum int, label string, opts *ReFSOptions) (*VolumeInfo, error) {
+	if opts == nil {
+		opts = &ReFSOptions{}
+	}
+	size := 0
+	if opts.DevDrive {
+		p, err := GetPartitionInfo(diskNum, partNum)
+		if err != nil {
+			return &VolumeInfo{}, err
+		}
+		size = p.Size
+	}
+	args, err := opts.args(size)
+	if err != nil {
+		return &VolumeInfo{}, err
+	}
+	return format(diskNum, partNum, "ReFS", label, args)
+}
@@ -0,0 +1,78 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/google/winops/powershell"
+)
+
+func TestFormatReFS(t *testing.T) {
+	enabled := true
+	tests := []struct {
+		desc       string
+		opts       *ReFSOptions
+		partSize   int
+		wantFormat string
+		wantErr    error
+	}{
+		{
+			desc:       "defaults",
+			wantFormat: "Get-Partition -DiskNumber 1 -PartitionNumber 2 | Format-Volume -FileSystem ReFS -NewFileSystemLabel 'Dev' -Confirm:$false",
+		},
+		{
+			desc:       "dev drive",
+			opts:       &ReFSOptions{DevDrive: true, IntegrityStreams: &enabled, AllocationUnitSize: 4096},
+			partSize:   devDriveMinSize,
+			wantFormat: "Get-Partition -DiskNumber 1 -PartitionNumber 2 | Format-Volume -FileSystem ReFS -NewFileSystemLabel 'Dev' -AllocationUnitSize 4096 -SetIntegrityStreams $true -DevDrive -Confirm:$false",
+		},
+		{
+			desc:     "dev drive too small",
+			opts:     &ReFSOptions{DevDrive: true},
+			partSize: devDriveMinSize - mb,
+			wantErr:  ErrInvalidFormat,
+		},
+		{
+			desc:    "bad cluster size",
+			opts:    &ReFSOptions{AllocationUnitSize: 8192},
+			wantErr: ErrInvalidFormat,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			gotFormat := ""
+			fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+				switch {
+				case strings.Contains(psCmd, "Format-Volume"):
+					gotFormat = psCmd
+				case strings.Contains(psCmd, "Get-Volume"):
+					return []byte(`{"FileSystem": "ReFS"}`), nil
+				}
+				return []byte(fmt.Sprintf(`{"DiskNumber": 1, "PartitionNumber": 2, "Size": %d}`, tt.partSize)), nil
+			}
+			_, err := FormatReFS(1, 2, "Dev", tt.opts)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("FormatReFS() returned unexpected error %v", err)
+			}
+			if gotFormat != tt.wantFormat {
+				t.Errorf("FormatReFS() ran %q, want %q", gotFormat, tt.wantFormat)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package shadowcopy creates, lists and deletes Volume Shadow Copy Service snapshots.
+//
+// Snapshots are managed through the Win32_ShadowCopy class, and require administrative
+// privileges.
+package shadowcopy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/google/winops/powershell"
+)
+
+var (
+	// ErrCreate indicates the shadow copy provider rejected a snapshot request.
+	ErrCreate = errors.New("shadow copy creation failed")
+	// ErrInvalidID indicates a malformed shadow copy ID.
+	ErrInvalidID = errors.New("invalid shadow copy ID")
+	// ErrNotFound indicates no shadow copy has the requested ID.
+	ErrNotFound = errors.New("shadow copy not found")
+	// ErrUnmarshal indicates unexpected output from PowerShell.
+	ErrUnmarshal = errors.New("unmarshal failed")
+
+	idRe = regexp.MustCompile(`^\{[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12}\}$`)
+
+	// Test Helpers
+	fnPSCmd = powershell.Command
+)
+
+// https://docs.microsoft.com/en-us/previous-versions/windows/desktop/vsswmi/create-method-in-class-win32-shadowcopy
+var createErrors = map[int]string{
+	1:  "access denied",
+	2:  "invalid argument",
+	3:  "volume not found",
+	4:  "volume not supported",
+	5:  "unsupported shadow copy context",
+	6:  "insufficient storage",
+	7:  "volume is in use",
+	8:  "maximum number of shadow copies reached",
+	9:  "another shadow copy operation is in progress",
+	10: "shadow copy provider vetoed the operation",
+	11: "shadow copy provider not registered",
+	12: "shadow copy provider failure",
+	13: "unknown error",
+}
+
+// ShadowCopy describes a single snapshot.
+//
+// https://docs.microsoft.com/en-us/previous-versions/windows/desktop/vsswmi/win32-shadowcopy
+type ShadowCopy struct {
+	ID               string
+	SetID            string
+	ProviderID       string
+	DeviceObject     string
+	VolumeName       string
+	ClientAccessible bool
+	Persistent       bool
+	NoAutoRelease    bool
+	State            int
+}
+
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func psJSON(cmd string, v interface{}) error {
+	out, err := fnPSCmd(cmd, []string{}, nil)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(out, v); err != nil {
+		return fmt.Errorf("%w: %v", ErrUnmarshal, err)
+	}
+	return nil
+}
+
+const selectProps = "Select-Object -Property ID,SetID,ProviderID,DeviceObject,VolumeName,ClientAccessible,Persistent,NoAutoRelease,State"
+
+// Create takes a persistent, client accessible snapshot of volume and returns its ID.
+//
+// volume is a drive root or volume GUID path, including the trailing backslash.
+//
+// Example: shadowcopy.Create(`D:\`)
+func Create(volume string) (string, error) {
+	if !strings.HasSuffix(volume, `\`) {
+		volume += `\`
+	}
+	cmd := fmt.Sprintf("Invoke-CimMethod -ClassName Win32_ShadowCopy -MethodName Create -Arguments @{Volume=%s; Context='ClientAccessible'} | Select-Object -Property ReturnValue,ShadowID | ConvertTo-JSON", psQuote(volume))
+	res := struct {
+		ReturnValue int
+		ShadowID    string
+	}{}
+	if err := psJSON(cmd, &res); err != nil {
+		return "", err
+	}
+	if res.ReturnValue != 0 {
+		msg, ok := createErrors[res.ReturnValue]
+		if !ok {
+			msg = fmt.Sprintf("code %d", res.ReturnValue)
+		}
+		return "", fmt.Errorf("%w: %s: %s", ErrCreate, volume, msg)
+	}
+	return res.ShadowID, nil
+}
+
+// List returns all shadow copies on the system.
+func List() ([]ShadowCopy, error) {
+	copies := []ShadowCopy{}
+	cmd := fmt.Sprintf("ConvertTo-JSON -InputObject @(Get-CimInstance -ClassName Win32_ShadowCopy | %s)", selectProps)
+	err := psJSON(cmd, &copies)
+	return copies, err
+}
+
+// Get returns the shadow copy with the given ID.
+func Get(id string) (*ShadowCopy, error) {
+	if !idRe.MatchString(id) {
+		return &ShadowCopy{}, fmt.Errorf("%w: %q", ErrInvalidID, id)
+	}
+	copies := []ShadowCopy{}
+	cmd := fmt.Sprintf(`ConvertTo-JSON -InputObject @(Get-CimInstance -ClassName Win32_ShadowCopy -Filter "ID='%s'" | %s)`, id, selectProps)
+	if err := psJSON(cmd, &copies); err != nil {
+		return &ShadowCopy{}, err
+	}
+	if len(copies) < 1 {
+		return &ShadowCopy{}, fmt.Errorf("%w: %s", ErrNotFound, id)
+	}
+	return &copies[0], nil
+}
+
+// Delete removes the shadow copy with the given ID.
+func Delete(id string) error {
+	if !idRe.MatchString(id) {
+		return fmt.Errorf("%w: %q", ErrInvalidID, id)
+	}
+	cmd := fmt.Sprintf(`Get-CimInstance -ClassName Win32_ShadowCopy -Filter "ID='%s'" | Remove-CimInstance`, id)
+	_, err := fnPSCmd(cmd, []string{}, nil)
+	return err
+}
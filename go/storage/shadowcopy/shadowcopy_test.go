@@ -0,0 +1,110 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package shadowcopy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/winops/powershell"
+)
+
+const testID = "{6C0B7F0E-1E2D-4A8B-9F3C-0123456789AB}"
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		volume  string
+		psOut   string
+		wantCmd string
+		want    string
+		wantErr error
+	}{
+		{
+			volume:  "D:",
+			psOut:   `{"ReturnValue": 0, "ShadowID": "` + testID + `"}`,
+			wantCmd: `Invoke-CimMethod -ClassName Win32_ShadowCopy -MethodName Create -Arguments @{Volume='D:\'; Context='ClientAccessible'} | Select-Object -Property ReturnValue,ShadowID | ConvertTo-JSON`,
+			want:    testID,
+		},
+		{
+			volume:  `E:\`,
+			psOut:   `{"ReturnValue": 6, "ShadowID": null}`,
+			wantCmd: `Invoke-CimMethod -ClassName Win32_ShadowCopy -MethodName Create -Arguments @{Volume='E:\'; Context='ClientAccessible'} | Select-Object -Property ReturnValue,ShadowID | ConvertTo-JSON`,
+			wantErr: ErrCreate,
+		},
+		{
+			volume:  `E:\`,
+			psOut:   `not json`,
+			wantCmd: `Invoke-CimMethod -ClassName Win32_ShadowCopy -MethodName Create -Arguments @{Volume='E:\'; Context='ClientAccessible'} | Select-Object -Property ReturnValue,ShadowID | ConvertTo-JSON`,
+			wantErr: ErrUnmarshal,
+		},
+	}
+	for _, tt := range tests {
+		gotCmd := ""
+		fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+			gotCmd = psCmd
+			return []byte(tt.psOut), nil
+		}
+		got, err := Create(tt.volume)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("Create(%q) returned unexpected error %v", tt.volume, err)
+		}
+		if got != tt.want {
+			t.Errorf("Create(%q) = %q, want %q", tt.volume, got, tt.want)
+		}
+		if gotCmd != tt.wantCmd {
+			t.Errorf("Create(%q) ran %q, want %q", tt.volume, gotCmd, tt.wantCmd)
+		}
+	}
+}
+
+func TestList(t *testing.T) {
+	fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+		return []byte(`[{"ID": "` + testID + `", "VolumeName": "\\\\?\\Volume{a}\\", "Persistent": true, "State": 12}]`), nil
+	}
+	got, err := List()
+	if err != nil {
+		t.Fatalf("List() returned unexpected error %v", err)
+	}
+	want := []ShadowCopy{{ID: testID, VolumeName: `\\?\Volume{a}\`, Persistent: true, State: 12}}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("List() returned unexpected diff (-want +got):\n%s", diff)
+	}
+}
+
+func TestGetDelete(t *testing.T) {
+	gotCmd := ""
+	fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+		gotCmd = psCmd
+		return []byte(`[]`), nil
+	}
+	if _, err := Get(testID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get(%q) returned %v, want %v", testID, err, ErrNotFound)
+	}
+	if err := Delete(testID); err != nil {
+		t.Errorf("Delete(%q) returned unexpected error %v", testID, err)
+	}
+	if want := `Get-CimInstance -ClassName Win32_ShadowCopy -Filter "ID='` + testID + `'" | Remove-CimInstance`; gotCmd != want {
+		t.Errorf("Delete(%q) ran %q, want %q", testID, gotCmd, want)
+	}
+	for _, id := range []string{"", "abc", "{x}' OR '1'='1"} {
+		if err := Delete(id); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("Delete(%q) returned %v, want %v", id, err, ErrInvalidID)
+		}
+		if _, err := Get(id); !errors.Is(err, ErrInvalidID) {
+			t.Errorf("Get(%q) returned %v, want %v", id, err, ErrInvalidID)
+		}
+	}
+}
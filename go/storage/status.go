@@ -0,0 +1,421 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/logger"
+)
+
+// Storage cmdlets render enumerated properties as display strings (eg "Healthy"), while the
+// underlying CIM instances carry raw integers. The types below accept either form.
+
+func enumString(v int32, names map[int32]string) string {
+	if n, ok := names[v]; ok {
+		return n
+	}
+	return fmt.Sprintf("Unknown(%d)", v)
+}
+
+// unmarshalEnum decodes an enumerated property. Names not in names, eg from values added in
+// newer versions of Windows, are logged and decoded as unknown.
+func unmarshalEnum(b []byte, names map[int32]string, unknown int32) (int32, error) {
+	var raw interface{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return 0, err
+	}
+	switch v := raw.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		return int32(v), nil
+	case string:
+		for k, n := range names {
+			if strings.EqualFold(n, v) {
+				return k, nil
+			}
+		}
+		logger.Warningf("Unrecognized storage property value %q, treating as %s.", v, enumString(unknown, names))
+		return unknown, nil
+	default:
+		return 0, fmt.Errorf("unsupported value %s", string(b))
+	}
+}
+
+// splitJSONList splits a JSON array into its elements. As ConvertTo-Json collapses single
+// element arrays, scalar values are treated as a list of one.
+func splitJSONList(b []byte) ([]json.RawMessage, error) {
+	var raw json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, err
+	}
+	s := strings.TrimSpace(string(raw))
+	if s == "null" {
+		return nil, nil
+	}
+	if strings.HasPrefix(s, "[") {
+		list := []json.RawMessage{}
+		err := json.Unmarshal(raw, &list)
+		return list, err
+	}
+	return []json.RawMessage{raw}, nil
+}
+
+// HealthStatus describes the health of a storage object.
+//
+// https://docs.microsoft.com/en-us/previous-versions/windows/desktop/stormgmt/msft-disk
+type HealthStatus int32
+
+// Health statuses.
+const (
+	HealthHealthy   HealthStatus = 0
+	HealthWarning   HealthStatus = 1
+	HealthUnhealthy HealthStatus = 2
+	HealthUnknown   HealthStatus = 5
+)
+
+var healthStatusNames = map[int32]string{
+	0: "Healthy",
+	1: "Warning",
+	2: "Unhealthy",
+	5: "Unknown",
+}
+
+func (h HealthStatus) String() string {
+	return enumString(int32(h), healthStatusNames)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (h *HealthStatus) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b, healthStatusNames, int32(HealthUnknown))
+	*h = HealthStatus(v)
+	return err
+}
+
+// OperationalStatus describes the operational state of a storage object.
+//
+// https://docs.microsoft.com/en-us/previous-versions/windows/desktop/stormgmt/msft-disk
+type OperationalStatus int32
+
+// Operational statuses.
+const (
+	OpUnknown                 OperationalStatus = 0
+	OpOther                   OperationalStatus = 1
+	OpOK                      OperationalStatus = 2
+	OpDegraded                OperationalStatus = 3
+	OpStressed                OperationalStatus = 4
+	OpPredictiveFailure       OperationalStatus = 5
+	OpError                   OperationalStatus = 6
+	OpNonRecoverableError     OperationalStatus = 7
+	OpStarting                OperationalStatus = 8
+	OpStopping                OperationalStatus = 9
+	OpStopped                 OperationalStatus = 10
+	OpInService               OperationalStatus = 11
+	OpNoContact               OperationalStatus = 12
+	OpLostCommunication       OperationalStatus = 13
+	OpAborted                 OperationalStatus = 14
+	OpDormant                 OperationalStatus = 15
+	OpSupportingEntityInError OperationalStatus = 16
+	OpCompleted               OperationalStatus = 17
+	OpPowerMode               OperationalStatus = 18
+	OpOnline                  OperationalStatus = 0xD010
+	OpNotReady                OperationalStatus = 0xD011
+	OpNoMedia                 OperationalStatus = 0xD012
+	OpOffline                 OperationalStatus = 0xD013
+	OpFailed                  OperationalStatus = 0xD014
+)
+
+var operationalStatusNames = map[int32]string{
+	0:      "Unknown",
+	1:      "Other",
+	2:      "OK",
+	3:      "Degraded",
+	4:      "Stressed",
+	5:      "Predictive Failure",
+	6:      "Error",
+	7:      "Non-Recoverable Error",
+	8:      "Starting",
+	9:      "Stopping",
+	10:     "Stopped",
+	11:     "In Service",
+	12:     "No Contact",
+	13:     "Lost Communication",
+	14:     "Aborted",
+	15:     "Dormant",
+	16:     "Supporting Entity in Error",
+	17:     "Completed",
+	18:     "Power Mode",
+	0xD010: "Online",
+	0xD011: "Not Ready",
+	0xD012: "No Media",
+	0xD013: "Offline",
+	0xD014: "Failed",
+}
+
+func (o OperationalStatus) String() string {
+	return enumString(int32(o), operationalStatusNames)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (o *OperationalStatus) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b, operationalStatusNames, int32(OpUnknown))
+	*o = OperationalStatus(v)
+	return err
+}
+
+// OperationalStatusList holds the (multi-valued) operational status of a storage object.
+type OperationalStatusList []OperationalStatus
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (l *OperationalStatusList) UnmarshalJSON(b []byte) error {
+	raw, err := splitJSONList(b)
+	if err != nil {
+		return err
+	}
+	if raw == nil {
+		*l = nil
+		return nil
+	}
+	list := make(OperationalStatusList, len(raw))
+	for i, r := range raw {
+		if err := list[i].UnmarshalJSON(r); err != nil {
+			return err
+		}
+	}
+	*l = list
+	return nil
+}
+
+// ProvisioningType describes how the storage for a disk is allocated.
+type ProvisioningType int32
+
+// Provisioning types.
+const (
+	ProvisioningUnknown ProvisioningType = 0
+	ProvisioningThin    ProvisioningType = 1
+	ProvisioningFixed   ProvisioningType = 2
+)
+
+var provisioningTypeNames = map[int32]string{
+	0: "Unknown",
+	1: "Thin",
+	2: "Fixed",
+}
+
+func (p ProvisioningType) String() string {
+	return enumString(int32(p), provisioningTypeNames)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *ProvisioningType) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b, provisioningTypeNames, int32(ProvisioningUnknown))
+	*p = ProvisioningType(v)
+	return err
+}
+
+// OfflineReason describes why a disk is offline.
+type OfflineReason int32
+
+// Offline reasons. OfflineUnknown is not a CIM value; it stands in for reasons this package
+// does not recognize.
+const (
+	OfflineUnknown                   OfflineReason = -1
+	OfflineNone                      OfflineReason = 0
+	OfflinePolicy                    OfflineReason = 1
+	OfflineRedundantPath             OfflineReason = 2
+	OfflineSnapshot                  OfflineReason = 3
+	OfflineCollision                 OfflineReason = 4
+	OfflineResourceExhaustion        OfflineReason = 5
+	OfflineCriticalWriteFailures     OfflineReason = 6
+	OfflineDataIntegrityScanRequired OfflineReason = 7
+)
+
+var offlineReasonNames = map[int32]string{
+	-1: "Unknown",
+	0:  "None",
+	1:  "Policy",
+	2:  "Redundant Path",
+	3:  "Snapshot",
+	4:  "Collision",
+	5:  "Resource Exhaustion",
+	6:  "Critical Write Failures",
+	7:  "Data Integrity Scan Required",
+}
+
+func (o OfflineReason) String() string {
+	return enumString(int32(o), offlineReasonNames)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (o *OfflineReason) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b, offlineReasonNames, int32(OfflineUnknown))
+	*o = OfflineReason(v)
+	return err
+}
+
+// BusType describes the bus a disk is attached through.
+type BusType int32
+
+// Bus types.
+const (
+	BusUnknown           BusType = 0
+	BusSCSI              BusType = 1
+	BusATAPI             BusType = 2
+	BusATA               BusType = 3
+	Bus1394              BusType = 4
+	BusSSA               BusType = 5
+	BusFibreChannel      BusType = 6
+	BusUSB               BusType = 7
+	BusRAID              BusType = 8
+	BusISCSI             BusType = 9
+	BusSAS               BusType = 10
+	BusSATA              BusType = 11
+	BusSD                BusType = 12
+	BusMMC               BusType = 13
+	BusVirtual           BusType = 14
+	BusFileBackedVirtual BusType = 15
+	BusStorageSpaces     BusType = 16
+	BusNVMe              BusType = 17
+	BusSCM               BusType = 18
+	BusUFS               BusType = 19
+)
+
+var busTypeNames = map[int32]string{
+	0:  "Unknown",
+	1:  "SCSI",
+	2:  "ATAPI",
+	3:  "ATA",
+	4:  "1394",
+	5:  "SSA",
+	6:  "Fibre Channel",
+	7:  "USB",
+	8:  "RAID",
+	9:  "iSCSI",
+	10: "SAS",
+	11: "SATA",
+	12: "SD",
+	13: "MMC",
+	14: "Virtual",
+	15: "File Backed Virtual",
+	16: "Storage Spaces",
+	17: "NVMe",
+	18: "SCM",
+	19: "UFS",
+}
+
+func (b BusType) String() string {
+	return enumString(int32(b), busTypeNames)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (b *BusType) UnmarshalJSON(data []byte) error {
+	v, err := unmarshalEnum(data, busTypeNames, int32(BusUnknown))
+	*b = BusType(v)
+	return err
+}
+
+// PartitionStyle describes the partitioning scheme of a disk.
+type PartitionStyle int32
+
+// Partition styles.
+const (
+	StyleUnknown PartitionStyle = 0
+	StyleMBR     PartitionStyle = 1
+	StyleGPT     PartitionStyle = 2
+	StyleRAW     PartitionStyle = 3
+)
+
+var partitionStyleNames = map[int32]string{
+	0: "Unknown",
+	1: "MBR",
+	2: "GPT",
+	3: "RAW",
+}
+
+func (p PartitionStyle) String() string {
+	return enumString(int32(p), partitionStyleNames)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (p *PartitionStyle) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b, partitionStyleNames, int32(StyleUnknown))
+	*p = PartitionStyle(v)
+	return err
+}
+
+// SanPolicy controls how newly discovered disks are brought online.
+//
+// https://docs.microsoft.com/en-us/powershell/module/storage/set-storagesetting
+type SanPolicy int32
+
+// SAN policies.
+const (
+	SanUnknown         SanPolicy = 0
+	SanOnlineAll       SanPolicy = 1
+	SanOfflineShared   SanPolicy = 2
+	SanOfflineAll      SanPolicy = 3
+	SanOfflineInternal SanPolicy = 4
+)
+
+var sanPolicyNames = map[int32]string{
+	0: "Unknown",
+	1: "OnlineAll",
+	2: "OfflineShared",
+	3: "OfflineAll",
+	4: "OfflineInternal",
+}
+
+func (s SanPolicy) String() string {
+	return enumString(int32(s), sanPolicyNames)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (s *SanPolicy) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b, sanPolicyNames, int32(SanUnknown))
+	*s = SanPolicy(v)
+	return err
+}
+
+// MediaType describes the physical media of a disk.
+type MediaType int32
+
+// Media types.
+const (
+	MediaUnspecified MediaType = 0
+	MediaHDD         MediaType = 3
+	MediaSSD         MediaType = 4
+	MediaSCM         MediaType = 5
+)
+
+var mediaTypeNames = map[int32]string{
+	0: "Unspecified",
+	3: "HDD",
+	4: "SSD",
+	5: "SCM",
+}
+
+func (m MediaType) String() string {
+	return enumString(int32(m), mediaTypeNames)
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (m *MediaType) UnmarshalJSON(b []byte) error {
+	v, err := unmarshalEnum(b, mediaTypeNames, int32(MediaUnspecified))
+	*m = MediaType(v)
+	return err
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStatusString(t *testing.T) {
+	tests := []struct {
+		in   fmt.Stringer
+		want string
+	}{
+		{HealthHealthy, "Healthy"},
+		{HealthStatus(3), "Unknown(3)"},
+		{OpOnline, "Online"},
+		{ProvisioningThin, "Thin"},
+		{OfflinePolicy, "Policy"},
+		{OfflineUnknown, "Unknown"},
+		{BusNVMe, "NVMe"},
+		{BusFileBackedVirtual, "File Backed Virtual"},
+		{StyleGPT, "GPT"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("String() = %q, want %q", got, tt.want)
+		}
+	}
+}
+
+func TestStatusUnmarshal(t *testing.T) {
+	type status struct {
+		BusType           BusType
+		HealthStatus      HealthStatus
+		OperationalStatus OperationalStatusList
+		OfflineReason     OfflineReason
+	}
+	tests := []struct {
+		in      string
+		want    status
+		wantErr bool
+	}{
+		{
+			in:   `{"BusType": "NVMe", "HealthStatus": "Healthy", "OperationalStatus": "Online", "OfflineReason": "Policy"}`,
+			want: status{BusNVMe, HealthHealthy, OperationalStatusList{OpOnline}, OfflinePolicy},
+		},
+		{
+			in:   `{"BusType": 7, "HealthStatus": 1, "OperationalStatus": [53264, 3], "OfflineReason": 4}`,
+			want: status{BusUSB, HealthWarning, OperationalStatusList{OpOnline, OpDegraded}, OfflineCollision},
+		},
+		{
+			in:   `{"BusType": "usb", "HealthStatus": null, "OperationalStatus": null}`,
+			want: status{BusType: BusUSB},
+		},
+		{
+			in:   `{"BusType": "Carrier Pigeon", "HealthStatus": "Exploded", "OperationalStatus": ["Online", "Teleporting"], "OfflineReason": "Bored"}`,
+			want: status{BusUnknown, HealthUnknown, OperationalStatusList{OpOnline, OpUnknown}, OfflineUnknown},
+		},
+		{
+			in:      `{"OperationalStatus": [true]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		got := status{}
+		err := json.Unmarshal([]byte(tt.in), &got)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("json.Unmarshal(%s) returned unexpected error %v", tt.in, err)
+		}
+		if err != nil {
+			continue
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("json.Unmarshal(%s) returned unexpected diff (-want +got):\n%s", tt.in, diff)
+		}
+	}
+}
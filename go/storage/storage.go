@@ -19,39 +19,296 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/google/winops/powershell"
 )
 
 var (
+	// ErrNoPath indicates a volume has neither a drive letter nor a volume path.
+	ErrNoPath = errors.New("volume has no usable path")
+	// ErrInvalidPolicy indicates an unsupported SAN policy was requested.
+	ErrInvalidPolicy = errors.New("invalid SAN policy")
 	// ErrUnmarshal indicates an error attempting to unmarshal a response from a PowerShell cmdlet.
 	ErrUnmarshal = errors.New("unable to unmarshal powershell output")
 
 	fnPSCmd = powershell.Command
 )
 
+// Well-known GPT partition types.
+//
+// https://docs.microsoft.com/en-us/windows/win32/api/winioctl/ns-winioctl-partition_information_gpt
+const (
+	GptTypeBasicData = "{ebd0a0a2-b9e5-4433-87c0-68b6b72699c7}"
+	GptTypeMSR       = "{e3c9e316-0b5c-4db8-817d-f92df00215ae}"
+	GptTypeRecovery  = "{de94bba4-06d1-4d40-a16a-bfd50179d6ac}"
+	GptTypeSystem    = "{c12a7328-f81f-11d2-ba4b-00a0c93ec93b}"
+)
+
+// Well-known MBR partition types.
+//
+// https://docs.microsoft.com/en-us/windows/win32/fileio/disk-partition-types
+const (
+	MbrTypeFAT12    = 1
+	MbrTypeFAT16    = 4
+	MbrTypeExtended = 5
+	MbrTypeHuge     = 6
+	MbrTypeIFS      = 7
+	MbrTypeFAT32    = 12
+	MbrTypeRecovery = 39
+)
+
+// StringList holds a multi-valued property.
+//
+// ConvertTo-Json collapses single element arrays into scalar values, so StringList
+// accepts either form when unmarshalling.
+type StringList []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (l *StringList) UnmarshalJSON(b []byte) error {
+	raw, err := splitJSONList(b)
+	if err != nil {
+		return err
+	}
+	if raw == nil {
+		*l = nil
+		return nil
+	}
+	list := make(StringList, len(raw))
+	for i, r := range raw {
+		var v interface{}
+		if err := json.Unmarshal(r, &v); err != nil {
+			return err
+		}
+		list[i] = fmt.Sprint(v)
+	}
+	*l = list
+	return nil
+}
+
+// DiskInfo holds information about a disk.
+type DiskInfo struct {
+	BusType            BusType
+	FriendlyName       string
+	HealthStatus       HealthStatus
+	IsBoot             bool
+	IsOffline          bool
+	IsReadOnly         bool
+	IsSystem           bool
+	LogicalSectorSize  int
+	Number             int
+	OfflineReason      OfflineReason
+	OperationalStatus  OperationalStatusList
+	PartitionStyle     PartitionStyle
+	Path               string
+	PhysicalSectorSize int
+	ProvisioningType   ProvisioningType
+	SerialNumber       string
+	Size               int
+	UniqueID           string `json:"UniqueId"`
+}
+
+// GetDisks returns information about all disks attached to the system.
+func GetDisks() ([]DiskInfo, error) {
+	d := []DiskInfo{}
+	err := queryList("Get-Disk", &d)
+	return d, err
+}
+
+// GetPartitions returns the partitions on the disk.
+func (d *DiskInfo) GetPartitions() ([]PartitionInfo, error) {
+	p := []PartitionInfo{}
+	err := queryList(fmt.Sprintf("Get-Disk -Number %d | Get-Partition", d.Number), &p)
+	return p, err
+}
+
+// VolumeInfo holds information about a volume.
+type VolumeInfo struct {
+	DriveLetter       string
+	FileSystem        string
+	FileSystemLabel   string
+	HealthStatus      HealthStatus
+	ObjectID          string `json:"ObjectId"`
+	OperationalStatus OperationalStatusList
+	Path              string
+	Size              int
+	SizeRemaining     int
+	UniqueID          string `json:"UniqueId"`
+}
+
+// GetVolumes returns information about all volumes on the system.
+func GetVolumes() ([]VolumeInfo, error) {
+	v := []VolumeInfo{}
+	err := queryList("Get-Volume", &v)
+	return v, err
+}
+
 // PartitionInfo holds information about a disk partition.
 type PartitionInfo struct {
-	DiskNumber      int
-	IsBoot          bool
-	GUID            string
-	PartitionNumber int
-	Size            int
-	Type            string
+	AccessPaths          StringList
+	DiskNumber           int
+	IsActive             bool
+	IsBoot               bool
+	IsHidden             bool
+	GptType              string
+	GUID                 string
+	MbrType              int
+	NoDefaultDriveLetter bool
+	OperationalStatus    OperationalStatusList
+	PartitionNumber      int
+	Size                 int
+	Type                 string
 }
 
 // GetPartitionInfo returns information about a specific disk partition.
 func GetPartitionInfo(diskNum, partNum int) (*PartitionInfo, error) {
 	p := &PartitionInfo{}
-	cmd := fmt.Sprintf("Get-Partition -DiskNumber %d -PartitionNumber %d | ConvertTo-JSON", diskNum, partNum)
-	out, err := fnPSCmd(cmd, []string{}, nil)
+	err := queryObject(fmt.Sprintf("Get-Partition -DiskNumber %d -PartitionNumber %d", diskNum, partNum), p)
+	return p, err
+}
+
+// psQuote renders s as a single-quoted PowerShell string literal.
+func psQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func psBool(b bool) string {
+	if b {
+		return "$true"
+	}
+	return "$false"
+}
+
+func setPartition(diskNum, partNum int, attr string) error {
+	cmd := fmt.Sprintf("Set-Partition -DiskNumber %d -PartitionNumber %d %s", diskNum, partNum, attr)
+	_, err := fnPSCmd(cmd, []string{}, nil)
+	return err
+}
+
+// SetGptType changes the GPT type of the partition.
+//
+// Example: p.SetGptType(storage.GptTypeRecovery)
+func (p *PartitionInfo) SetGptType(gptType string) error {
+	if err := setPartition(p.DiskNumber, p.PartitionNumber, "-GptType "+psQuote(gptType)); err != nil {
+		return err
+	}
+	p.GptType = gptType
+	return nil
+}
+
+// SetMbrType changes the MBR type of the partition.
+//
+// Example: p.SetMbrType(storage.MbrTypeRecovery)
+func (p *PartitionInfo) SetMbrType(mbrType int) error {
+	if err := setPartition(p.DiskNumber, p.PartitionNumber, fmt.Sprintf("-MbrType %d", mbrType)); err != nil {
+		return err
+	}
+	p.MbrType = mbrType
+	return nil
+}
+
+// SetIsActive marks an MBR partition as active (or inactive).
+func (p *PartitionInfo) SetIsActive(active bool) error {
+	if err := setPartition(p.DiskNumber, p.PartitionNumber, "-IsActive "+psBool(active)); err != nil {
+		return err
+	}
+	p.IsActive = active
+	return nil
+}
+
+// SetIsHidden hides (or unhides) the partition.
+func (p *PartitionInfo) SetIsHidden(hidden bool) error {
+	if err := setPartition(p.DiskNumber, p.PartitionNumber, "-IsHidden "+psBool(hidden)); err != nil {
+		return err
+	}
+	p.IsHidden = hidden
+	return nil
+}
+
+// SetNoDefaultDriveLetter controls whether the partition receives a drive letter automatically.
+func (p *PartitionInfo) SetNoDefaultDriveLetter(noLetter bool) error {
+	if err := setPartition(p.DiskNumber, p.PartitionNumber, "-NoDefaultDriveLetter "+psBool(noLetter)); err != nil {
+		return err
+	}
+	p.NoDefaultDriveLetter = noLetter
+	return nil
+}
+
+// GetVolume returns the volume hosted on the partition.
+func (p *PartitionInfo) GetVolume() (*VolumeInfo, error) {
+	v := &VolumeInfo{}
+	err := queryObject(fmt.Sprintf("Get-Partition -DiskNumber %d -PartitionNumber %d | Get-Volume", p.DiskNumber, p.PartitionNumber), v)
+	return v, err
+}
+
+// GetDisk returns the disk hosting the partition.
+func (p *PartitionInfo) GetDisk() (*DiskInfo, error) {
+	d := &DiskInfo{}
+	err := queryObject(fmt.Sprintf("Get-Partition -DiskNumber %d -PartitionNumber %d | Get-Disk", p.DiskNumber, p.PartitionNumber), d)
+	return d, err
+}
+
+// GetPartitions returns the partitions backing the volume.
+func (v *VolumeInfo) GetPartitions() ([]PartitionInfo, error) {
+	p := []PartitionInfo{}
+	err := queryList(fmt.Sprintf("Get-Volume -UniqueId %s | Get-Partition", psQuote(v.UniqueID)), &p)
+	return p, err
+}
+
+// Format formats a partition with the given file system and label. The file system must be
+// one of NTFS, ReFS, FAT32, exFAT or FAT.
+//
+// The volume is queried again once formatting completes, so the returned VolumeInfo
+// reflects the new file system and can be used for further operations immediately.
+//
+// Example: storage.Format(0, 3, "NTFS", "Windows")
+func Format(diskNum, partNum int, fileSystem, label string) (*VolumeInfo, error) {
+	return format(diskNum, partNum, fileSystem, label, "")
+}
+
+// fileSystemName returns the Format-Volume spelling of a supported file system.
+func fileSystemName(fileSystem string) (string, error) {
+	for _, fs := range fileSystems {
+		if strings.EqualFold(fs, fileSystem) {
+			return fs, nil
+		}
+	}
+	return "", fmt.Errorf("%w: unsupported file system %q", ErrInvalidFormat, fileSystem)
+}
+
+func format(diskNum, partNum int, fileSystem, label, args string) (*VolumeInfo, error) {
+	fs, err := fileSystemName(fileSystem)
 	if err != nil {
-		return p, err
+		return &VolumeInfo{}, err
+	}
+	cmd := fmt.Sprintf("Get-Partition -DiskNumber %d -PartitionNumber %d | Format-Volume -FileSystem %s -NewFileSystemLabel %s%s -Confirm:$false",
+		diskNum, partNum, fs, psQuote(label), args)
+	if _, err := fnPSCmd(cmd, []string{}, nil); err != nil {
+		return &VolumeInfo{}, err
 	}
-	if err = json.Unmarshal(out, p); err != nil {
-		return p, fmt.Errorf("%w: %v", ErrUnmarshal, err)
+	p := &PartitionInfo{DiskNumber: diskNum, PartitionNumber: partNum}
+	return p.GetVolume()
+}
+
+// devicePath returns a path to the volume which can be opened with CreateFile.
+func (v *VolumeInfo) devicePath() (string, error) {
+	if l := strings.TrimSuffix(v.DriveLetter, ":"); len(l) == 1 {
+		return `\\.\` + strings.ToUpper(l) + ":", nil
+	}
+	if v.Path != "" {
+		return strings.TrimSuffix(v.Path, `\`), nil
+	}
+	return "", ErrNoPath
+}
+
+// SetFileSystemLabel changes the file system label of the volume.
+func (v *VolumeInfo) SetFileSystemLabel(label string) error {
+	cmd := fmt.Sprintf("Set-Volume -UniqueId %s -NewFileSystemLabel %s", psQuote(v.UniqueID), psQuote(label))
+	if _, err := fnPSCmd(cmd, []string{}, nil); err != nil {
+		return err
 	}
-	return p, nil
+	v.FileSystemLabel = label
+	return nil
 }
 
 // PartitionResize attempts to resize a given disk/partition.
@@ -70,13 +327,30 @@ type PartitionSupportedSize struct {
 // GetPartitionSupportedSize returns the supported minimum and maximum sizes for a given disk/partition.
 func GetPartitionSupportedSize(diskNum, partNum int) (*PartitionSupportedSize, error) {
 	p := &PartitionSupportedSize{}
-	cmd := fmt.Sprintf("Get-PartitionSupportedSize -DiskNumber %d -PartitionNumber %d | ConvertTo-JSON", diskNum, partNum)
-	out, err := fnPSCmd(cmd, []string{}, nil)
-	if err != nil {
-		return p, err
+	err := queryObject(fmt.Sprintf("Get-PartitionSupportedSize -DiskNumber %d -PartitionNumber %d", diskNum, partNum), p)
+	return p, err
+}
+
+type storageSetting struct {
+	NewDiskPolicy SanPolicy
+}
+
+// GetSanPolicy returns the SAN policy applied to newly discovered disks.
+func GetSanPolicy() (SanPolicy, error) {
+	s := &storageSetting{}
+	if err := queryObject("Get-StorageSetting", s); err != nil {
+		return SanUnknown, err
 	}
-	if err = json.Unmarshal(out, p); err != nil {
-		return p, fmt.Errorf("%w: %v", ErrUnmarshal, err)
+	return s.NewDiskPolicy, nil
+}
+
+// SetSanPolicy changes the SAN policy applied to newly discovered disks.
+//
+// Example: storage.SetSanPolicy(storage.SanOfflineShared)
+func SetSanPolicy(policy SanPolicy) error {
+	if policy < SanOnlineAll || policy > SanOfflineInternal {
+		return fmt.Errorf("%w: %d", ErrInvalidPolicy, policy)
 	}
-	return p, nil
+	_, err := fnPSCmd("Set-StorageSetting -NewDiskPolicy "+policy.String(), []string{}, nil)
+	return err
 }
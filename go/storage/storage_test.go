@@ -38,10 +38,14 @@ func TestGetPartitionInfo(t *testing.T) {
 	}{
 		{"partinfo.txt",
 			&PartitionInfo{
-				GUID:            "{09eb89b8-1595-4b70-b056-a3adbbb33255}",
-				PartitionNumber: 1,
-				Size:            524288000,
-				Type:            "Recovery"},
+				AccessPaths:          StringList{`\\?\Volume{09eb89b8-1595-4b70-b056-a3adbbb33255}\`},
+				GptType:              GptTypeRecovery,
+				GUID:                 "{09eb89b8-1595-4b70-b056-a3adbbb33255}",
+				NoDefaultDriveLetter: true,
+				OperationalStatus:    OperationalStatusList{OpOnline},
+				PartitionNumber:      1,
+				Size:                 524288000,
+				Type:                 "Recovery"},
 			nil, nil,
 		},
 		{"invalid.txt",
@@ -75,6 +79,31 @@ func TestGetPartitionInfo(t *testing.T) {
 	}
 }
 
+func TestStringListUnmarshal(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    StringList
+		wantErr bool
+	}{
+		{`"Online"`, StringList{"Online"}, false},
+		{`["Online", "Degraded"]`, StringList{"Online", "Degraded"}, false},
+		{`[2, 10]`, StringList{"2", "10"}, false},
+		{`null`, nil, false},
+		{`[]`, StringList{}, false},
+		{`[`, nil, true},
+	}
+	for _, tt := range tests {
+		var got StringList
+		err := got.UnmarshalJSON([]byte(tt.in))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("UnmarshalJSON(%s) returned unexpected error %v", tt.in, err)
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("UnmarshalJSON(%s) returned unexpected diff (-want +got):\n%s", tt.in, diff)
+		}
+	}
+}
+
 func TestGetPartitionSupportedSize(t *testing.T) {
 	tests := []struct {
 		psOut   string
@@ -113,3 +142,288 @@ func TestGetPartitionSupportedSize(t *testing.T) {
 		})
 	}
 }
+
+func TestSetPartitionAttributes(t *testing.T) {
+	psErr := errors.New("powershell failed")
+	tests := []struct {
+		desc    string
+		fn      func(p *PartitionInfo) error
+		psErr   error
+		wantCmd string
+		want    *PartitionInfo
+		wantErr error
+	}{
+		{
+			desc:    "gpt type",
+			fn:      func(p *PartitionInfo) error { return p.SetGptType(GptTypeBasicData) },
+			wantCmd: "Set-Partition -DiskNumber 1 -PartitionNumber 4 -GptType '{ebd0a0a2-b9e5-4433-87c0-68b6b72699c7}'",
+			want:    &PartitionInfo{DiskNumber: 1, PartitionNumber: 4, GptType: GptTypeBasicData},
+		},
+		{
+			desc:    "mbr type",
+			fn:      func(p *PartitionInfo) error { return p.SetMbrType(MbrTypeRecovery) },
+			wantCmd: "Set-Partition -DiskNumber 1 -PartitionNumber 4 -MbrType 39",
+			want:    &PartitionInfo{DiskNumber: 1, PartitionNumber: 4, MbrType: MbrTypeRecovery},
+		},
+		{
+			desc:    "active",
+			fn:      func(p *PartitionInfo) error { return p.SetIsActive(true) },
+			wantCmd: "Set-Partition -DiskNumber 1 -PartitionNumber 4 -IsActive $true",
+			want:    &PartitionInfo{DiskNumber: 1, PartitionNumber: 4, IsActive: true},
+		},
+		{
+			desc:    "hidden",
+			fn:      func(p *PartitionInfo) error { return p.SetIsHidden(true) },
+			wantCmd: "Set-Partition -DiskNumber 1 -PartitionNumber 4 -IsHidden $true",
+			want:    &PartitionInfo{DiskNumber: 1, PartitionNumber: 4, IsHidden: true},
+		},
+		{
+			desc:    "no default drive letter",
+			fn:      func(p *PartitionInfo) error { return p.SetNoDefaultDriveLetter(false) },
+			wantCmd: "Set-Partition -DiskNumber 1 -PartitionNumber 4 -NoDefaultDriveLetter $false",
+			want:    &PartitionInfo{DiskNumber: 1, PartitionNumber: 4},
+		},
+		{
+			desc:    "powershell error",
+			fn:      func(p *PartitionInfo) error { return p.SetIsHidden(true) },
+			psErr:   psErr,
+			wantCmd: "Set-Partition -DiskNumber 1 -PartitionNumber 4 -IsHidden $true",
+			want:    &PartitionInfo{DiskNumber: 1, PartitionNumber: 4},
+			wantErr: psErr,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			var gotCmd string
+			fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+				gotCmd = psCmd
+				return nil, tt.psErr
+			}
+			p := &PartitionInfo{DiskNumber: 1, PartitionNumber: 4}
+			err := tt.fn(p)
+			if gotCmd != tt.wantCmd {
+				t.Errorf("%s: got command %q, want %q", tt.desc, gotCmd, tt.wantCmd)
+			}
+			if diff := cmp.Diff(tt.want, p); diff != "" {
+				t.Errorf("%s: returned unexpected diff (-want +got):\n%s", tt.desc, diff)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("%s: returned unexpected error %v", tt.desc, err)
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	psErr := errors.New("powershell failed")
+	tests := []struct {
+		desc       string
+		fileSystem string
+		label      string
+		psOut      []string
+		psErr      []error
+		wantCmds   []string
+		want       *VolumeInfo
+		wantErr    error
+	}{
+		{
+			desc:       "success",
+			fileSystem: "NTFS",
+			label:      "Windows",
+			psOut:      []string{"", `{"DriveLetter": "C", "FileSystem": "NTFS", "FileSystemLabel": "Windows", "UniqueId": "\\\\?\\Volume{1234}\\"}`},
+			psErr:      []error{nil, nil},
+			wantCmds: []string{
+				"Get-Partition -DiskNumber 0 -PartitionNumber 3 | Format-Volume -FileSystem NTFS -NewFileSystemLabel 'Windows' -Confirm:$false",
+				"Get-Partition -DiskNumber 0 -PartitionNumber 3 | Get-Volume | Select-Object -Property " + volumeProps + " | ConvertTo-JSON",
+			},
+			want: &VolumeInfo{DriveLetter: "C", FileSystem: "NTFS", FileSystemLabel: "Windows", UniqueID: `\\?\Volume{1234}\`},
+		},
+		{
+			desc:       "quoted label",
+			fileSystem: "ntfs",
+			label:      "Bob's Disk",
+			psOut:      []string{"", `{"FileSystemLabel": "Bob's Disk"}`},
+			psErr:      []error{nil, nil},
+			wantCmds: []string{
+				"Get-Partition -DiskNumber 0 -PartitionNumber 3 | Format-Volume -FileSystem NTFS -NewFileSystemLabel 'Bob''s Disk' -Confirm:$false",
+				"Get-Partition -DiskNumber 0 -PartitionNumber 3 | Get-Volume | Select-Object -Property " + volumeProps + " | ConvertTo-JSON",
+			},
+			want: &VolumeInfo{FileSystemLabel: "Bob's Disk"},
+		},
+		{
+			desc:       "unsupported file system",
+			fileSystem: "NTFS -Force",
+			label:      "Windows",
+			wantCmds:   []string{},
+			want:       &VolumeInfo{},
+			wantErr:    ErrInvalidFormat,
+		},
+		{
+			desc:       "format error",
+			fileSystem: "NTFS",
+			label:      "Windows",
+			psOut:      []string{""},
+			psErr:      []error{psErr},
+			wantCmds: []string{
+				"Get-Partition -DiskNumber 0 -PartitionNumber 3 | Format-Volume -FileSystem NTFS -NewFileSystemLabel 'Windows' -Confirm:$false",
+			},
+			want:    &VolumeInfo{},
+			wantErr: psErr,
+		},
+		{
+			desc:       "query error",
+			fileSystem: "NTFS",
+			label:      "Windows",
+			psOut:      []string{"", "not json"},
+			psErr:      []error{nil, nil},
+			wantCmds: []string{
+				"Get-Partition -DiskNumber 0 -PartitionNumber 3 | Format-Volume -FileSystem NTFS -NewFileSystemLabel 'Windows' -Confirm:$false",
+				"Get-Partition -DiskNumber 0 -PartitionNumber 3 | Get-Volume | Select-Object -Property " + volumeProps + " | ConvertTo-JSON",
+			},
+			want:    &VolumeInfo{},
+			wantErr: ErrUnmarshal,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			cmds := []string{}
+			fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+				i := len(cmds)
+				cmds = append(cmds, psCmd)
+				return []byte(tt.psOut[i]), tt.psErr[i]
+			}
+			got, err := Format(0, 3, tt.fileSystem, tt.label)
+			if diff := cmp.Diff(tt.wantCmds, cmds); diff != "" {
+				t.Errorf("Format() ran unexpected commands (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("Format() returned unexpected diff (-want +got):\n%s", diff)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Format() returned unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestGetPartitions(t *testing.T) {
+	psErr := errors.New("powershell failed")
+	tests := []struct {
+		psOut   string
+		psErr   error
+		want    []PartitionInfo
+		wantErr error
+	}{
+		{
+			psOut: `[{"DiskNumber": 2, "PartitionNumber": 1, "GptType": "{c12a7328-f81f-11d2-ba4b-00a0c93ec93b}"},
+				{"DiskNumber": 2, "PartitionNumber": 2, "GptType": "{ebd0a0a2-b9e5-4433-87c0-68b6b72699c7}"}]`,
+			want: []PartitionInfo{
+				{DiskNumber: 2, PartitionNumber: 1, GptType: GptTypeSystem},
+				{DiskNumber: 2, PartitionNumber: 2, GptType: GptTypeBasicData},
+			},
+		},
+		{
+			psOut: `[]`,
+			want:  []PartitionInfo{},
+		},
+		{
+			psErr:   psErr,
+			want:    []PartitionInfo{},
+			wantErr: psErr,
+		},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("Test%d", i), func(t *testing.T) {
+			var gotCmd string
+			fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+				gotCmd = psCmd
+				return []byte(tt.psOut), tt.psErr
+			}
+			d := &DiskInfo{Number: 2}
+			got, err := d.GetPartitions()
+			if want := "ConvertTo-JSON -InputObject @(Get-Disk -Number 2 | Get-Partition | Select-Object -Property " + partitionProps + ")"; gotCmd != want {
+				t.Errorf("GetPartitions() ran %q, want %q", gotCmd, want)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("GetPartitions() returned unexpected diff (-want +got):\n%s", diff)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("GetPartitions() returned unexpected error %v", err)
+			}
+		})
+	}
+}
+
+func TestGetSanPolicy(t *testing.T) {
+	tests := []struct {
+		psOut   string
+		want    SanPolicy
+		wantErr error
+	}{
+		{`{"NewDiskPolicy": "OfflineShared"}`, SanOfflineShared, nil},
+		{`{"NewDiskPolicy": 1}`, SanOnlineAll, nil},
+		{`{"NewDiskPolicy": "Sideways"}`, SanUnknown, nil},
+		{`not json`, SanUnknown, ErrUnmarshal},
+	}
+	for _, tt := range tests {
+		fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+			return []byte(tt.psOut), nil
+		}
+		got, err := GetSanPolicy()
+		if got != tt.want {
+			t.Errorf("GetSanPolicy(%s) = %v, want %v", tt.psOut, got, tt.want)
+		}
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("GetSanPolicy(%s) returned unexpected error %v", tt.psOut, err)
+		}
+	}
+}
+
+func TestSetSanPolicy(t *testing.T) {
+	tests := []struct {
+		in      SanPolicy
+		wantCmd string
+		wantErr error
+	}{
+		{SanOfflineShared, "Set-StorageSetting -NewDiskPolicy OfflineShared", nil},
+		{SanOnlineAll, "Set-StorageSetting -NewDiskPolicy OnlineAll", nil},
+		{SanUnknown, "", ErrInvalidPolicy},
+		{SanPolicy(9), "", ErrInvalidPolicy},
+	}
+	for _, tt := range tests {
+		gotCmd := ""
+		fnPSCmd = func(psCmd string, s []string, c *powershell.PSConfig) ([]byte, error) {
+			gotCmd = psCmd
+			return nil, nil
+		}
+		err := SetSanPolicy(tt.in)
+		if gotCmd != tt.wantCmd {
+			t.Errorf("SetSanPolicy(%v) ran %q, want %q", tt.in, gotCmd, tt.wantCmd)
+		}
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("SetSanPolicy(%v) returned unexpected error %v", tt.in, err)
+		}
+	}
+}
+
+func TestVolumeDevicePath(t *testing.T) {
+	tests := []struct {
+		in      VolumeInfo
+		want    string
+		wantErr error
+	}{
+		{VolumeInfo{DriveLetter: "c"}, `\\.\C:`, nil},
+		{VolumeInfo{DriveLetter: "D:", Path: `\\?\Volume{1234}\`}, `\\.\D:`, nil},
+		{VolumeInfo{Path: `\\?\Volume{1234}\`}, `\\?\Volume{1234}`, nil},
+		{VolumeInfo{}, "", ErrNoPath},
+	}
+	for _, tt := range tests {
+		got, err := tt.in.devicePath()
+		if got != tt.want {
+			t.Errorf("devicePath(%+v) = %q, want %q", tt.in, got, tt.want)
+		}
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("devicePath(%+v) returned unexpected error %v", tt.in, err)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/go-ole/go-ole"
+	"github.com/go-ole/go-ole/oleutil"
+	"github.com/google/logger"
+)
+
+const (
+	// WITHIN sets how often (in seconds) WMI polls the storage provider for changes.
+	diskEventQuery = `SELECT * FROM __InstanceOperationEvent WITHIN 2 WHERE TargetInstance ISA 'MSFT_Disk' ` +
+		`AND (__Class = '__InstanceCreationEvent' OR __Class = '__InstanceDeletionEvent')`
+
+	// How long each wait for an event blocks before checking for cancellation.
+	watchPoll = 500 * time.Millisecond
+
+	// https://docs.microsoft.com/en-us/windows/win32/wmisdk/wmi-error-constants
+	wbemErrTimedOut = 0x80043001
+)
+
+var (
+	errWatchTimeout = errors.New("timed out waiting for disk event")
+
+	// diskProperties lists the MSFT_Disk properties carried by DiskInfo.
+	diskProperties = []string{
+		"BusType", "FriendlyName", "HealthStatus", "IsBoot", "IsOffline", "IsReadOnly", "IsSystem",
+		"LogicalSectorSize", "Number", "OfflineReason", "OperationalStatus", "PartitionStyle", "Path",
+		"PhysicalSectorSize", "ProvisioningType", "SerialNumber", "Size", "UniqueId",
+	}
+
+	// Test Helpers
+	fnSubscribeDisks = subscribeDisks
+)
+
+// DiskEventType distinguishes disk arrival from removal.
+type DiskEventType int
+
+const (
+	// DiskArrived indicates a disk was attached.
+	DiskArrived DiskEventType = iota
+	// DiskRemoved indicates a disk was detached.
+	DiskRemoved
+)
+
+func (t DiskEventType) String() string {
+	switch t {
+	case DiskArrived:
+		return "Arrived"
+	case DiskRemoved:
+		return "Removed"
+	default:
+		return fmt.Sprintf("Unknown(%d)", int(t))
+	}
+}
+
+// DiskEvent describes a change to the set of attached disks.
+type DiskEvent struct {
+	Type DiskEventType
+	Disk DiskInfo
+}
+
+// diskSubscription delivers disk events from a WMI event query.
+type diskSubscription interface {
+	// Next waits up to timeout for an event, returning errWatchTimeout if none arrives.
+	Next(timeout time.Duration) (DiskEvent, error)
+	Close()
+}
+
+// diskFromProperties converts MSFT_Disk property values to a DiskInfo.
+func diskFromProperties(props map[string]interface{}) (DiskInfo, error) {
+	d := DiskInfo{}
+	// The WMI scripting API returns 64 bit integers as strings.
+	if s, ok := props["Size"].(string); ok {
+		size, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return d, fmt.Errorf("parsing Size %q: %w", s, err)
+		}
+		props["Size"] = size
+	}
+	b, err := json.Marshal(props)
+	if err != nil {
+		return d, err
+	}
+	if err := json.Unmarshal(b, &d); err != nil {
+		return d, fmt.Errorf("%w: %v", ErrUnmarshal, err)
+	}
+	return d, nil
+}
+
+// wmiDisks is a diskSubscription backed by an SWbemEventSource. It must be used from the
+// thread that created it.
+type wmiDisks struct {
+	locator *ole.IDispatch
+	svc     *ole.IDispatch
+	source  *ole.IDispatch
+}
+
+func subscribeDisks() (diskSubscription, error) {
+	ole.CoInitialize(0)
+	w := &wmiDisks{}
+	unknown, err := oleutil.CreateObject("WbemScripting.SWbemLocator")
+	if err != nil {
+		w.Close()
+		return nil, fmt.Errorf("unable to create SWbemLocator: %w", err)
+	}
+	defer unknown.Release()
+	if w.locator, err = unknown.QueryInterface(ole.IID_IDispatch); err != nil {
+		w.Close()
+		return nil, fmt.Errorf("unable to create SWbemLocator: %w", err)
+	}
+	svc, err := oleutil.CallMethod(w.locator, "ConnectServer", nil, storageNamespace)
+	if err != nil {
+		w.Close()
+		return nil, fmt.Errorf("ConnectServer(%s): %w", storageNamespace, err)
+	}
+	w.svc = svc.ToIDispatch()
+	source, err := oleutil.CallMethod(w.svc, "ExecNotificationQuery", diskEventQuery)
+	if err != nil {
+		w.Close()
+		return nil, fmt.Errorf("ExecNotificationQuery: %w", err)
+	}
+	w.source = source.ToIDispatch()
+	return w, nil
+}
+
+func isTimeout(err error) bool {
+	oleErr, ok := err.(*ole.OleError)
+	if !ok {
+		return false
+	}
+	if info, ok := oleErr.SubError().(ole.EXCEPINFO); ok && info.SCODE() == wbemErrTimedOut {
+		return true
+	}
+	return uint32(oleErr.Code()) == wbemErrTimedOut
+}
+
+func variantValue(v *ole.VARIANT) interface{} {
+	if v.VT&ole.VT_ARRAY != 0 {
+		return v.ToArray().ToValueArray()
+	}
+	return v.Value()
+}
+
+func (w *wmiDisks) Next(timeout time.Duration) (DiskEvent, error) {
+	e := DiskEvent{}
+	raw, err := oleutil.CallMethod(w.source, "NextEvent", int32(timeout/time.Millisecond))
+	if isTimeout(err) {
+		return e, errWatchTimeout
+	}
+	if err != nil {
+		return e, fmt.Errorf("NextEvent: %w", err)
+	}
+	event := raw.ToIDispatch()
+	defer event.Release()
+
+	path, err := oleutil.GetProperty(event, "Path_")
+	if err != nil {
+		return e, fmt.Errorf("reading event class: %w", err)
+	}
+	defer path.Clear()
+	class, err := oleutil.GetProperty(path.ToIDispatch(), "Class")
+	if err != nil {
+		return e, fmt.Errorf("reading event class: %w", err)
+	}
+	defer class.Clear()
+	if class.ToString() == "__InstanceDeletionEvent" {
+		e.Type = DiskRemoved
+	}
+
+	target, err := oleutil.GetProperty(event, "TargetInstance")
+	if err != nil {
+		return e, fmt.Errorf("reading TargetInstance: %w", err)
+	}
+	defer target.Clear()
+	props := make(map[string]interface{}, len(diskProperties))
+	for _, p := range diskProperties {
+		v, err := oleutil.GetProperty(target.ToIDispatch(), p)
+		if err != nil {
+			return e, fmt.Errorf("reading %s: %w", p, err)
+		}
+		props[p] = variantValue(v)
+		v.Clear()
+	}
+	e.Disk, err = diskFromProperties(props)
+	return e, err
+}
+
+func (w *wmiDisks) Close() {
+	for _, d := range []*ole.IDispatch{w.source, w.svc, w.locator} {
+		if d != nil {
+			d.Release()
+		}
+	}
+	ole.CoUninitialize()
+}
+
+// Watch reports disks as they are attached to or removed from the system, using WMI
+// intrinsic events on MSFT_Disk.
+//
+// Disks present when Watch is called are not reported. The returned channel is closed once
+// ctx is cancelled, or if the subscription fails.
+func Watch(ctx context.Context) (<-chan DiskEvent, error) {
+	events := make(chan DiskEvent)
+	started := make(chan error)
+
+	go func() {
+		// COM objects must be used from the thread that created them.
+		runtime.LockOSThread()
+		defer runtime.UnlockOSThread()
+		sub, err := fnSubscribeDisks()
+		started <- err
+		if err != nil {
+			return
+		}
+		defer close(events)
+		defer sub.Close()
+		for ctx.Err() == nil {
+			e, err := sub.Next(watchPoll)
+			if errors.Is(err, errWatchTimeout) {
+				continue
+			}
+			if err != nil {
+				logger.Errorf("storage.Watch: %v", err)
+				return
+			}
+			select {
+			case events <- e:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	if err := <-started; err != nil {
+		return nil, err
+	}
+	return events, nil
+}
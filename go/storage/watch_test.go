@@ -0,0 +1,159 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type fakeSubscription struct {
+	mu     sync.Mutex
+	events []DiskEvent
+	errs   []error
+	closed bool
+}
+
+func (f *fakeSubscription) Next(timeout time.Duration) (DiskEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return DiskEvent{}, err
+	}
+	if len(f.events) == 0 {
+		return DiskEvent{}, errWatchTimeout
+	}
+	e := f.events[0]
+	f.events = f.events[1:]
+	return e, nil
+}
+
+func (f *fakeSubscription) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.closed = true
+}
+
+func TestWatch(t *testing.T) {
+	usb := DiskInfo{Number: 1, UniqueID: "usb", BusType: BusUSB}
+	want := []DiskEvent{
+		{Type: DiskArrived, Disk: usb},
+		{Type: DiskRemoved, Disk: usb},
+	}
+	sub := &fakeSubscription{events: want, errs: []error{errWatchTimeout}}
+	fnSubscribeDisks = func() (diskSubscription, error) {
+		return sub, nil
+	}
+	defer func() { fnSubscribeDisks = subscribeDisks }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	events, err := Watch(ctx)
+	if err != nil {
+		t.Fatalf("Watch() returned unexpected error %v", err)
+	}
+	got := []DiskEvent{<-events, <-events}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("Watch() produced unexpected events (-want +got):\n%s", diff)
+	}
+
+	cancel()
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Errorf("Watch() produced unexpected event after cancellation")
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Watch() did not close channel after cancellation")
+	}
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if !sub.closed {
+		t.Errorf("Watch() did not close the subscription")
+	}
+}
+
+func TestWatchSubscriptionError(t *testing.T) {
+	sub := &fakeSubscription{errs: []error{errors.New("NextEvent failed")}}
+	fnSubscribeDisks = func() (diskSubscription, error) {
+		return sub, nil
+	}
+	defer func() { fnSubscribeDisks = subscribeDisks }()
+	events, err := Watch(context.Background())
+	if err != nil {
+		t.Fatalf("Watch() returned unexpected error %v", err)
+	}
+	select {
+	case _, ok := <-events:
+		if ok {
+			t.Errorf("Watch() produced unexpected event after subscription error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("Watch() did not close channel after subscription error")
+	}
+}
+
+func TestWatchInitialError(t *testing.T) {
+	queryErr := errors.New("query failed")
+	fnSubscribeDisks = func() (diskSubscription, error) {
+		return nil, queryErr
+	}
+	defer func() { fnSubscribeDisks = subscribeDisks }()
+	if _, err := Watch(context.Background()); !errors.Is(err, queryErr) {
+		t.Errorf("Watch() returned unexpected error %v", err)
+	}
+}
+
+func TestDiskFromProperties(t *testing.T) {
+	props := map[string]interface{}{
+		"BusType":           uint16(7),
+		"FriendlyName":      "USB Flash",
+		"HealthStatus":      uint16(0),
+		"IsBoot":            false,
+		"Number":            int32(1),
+		"OperationalStatus": []interface{}{uint16(0xD010)},
+		"PartitionStyle":    uint16(2),
+		"Size":              "16008609792",
+		"UniqueId":          "usb",
+		"SerialNumber":      nil,
+	}
+	want := DiskInfo{
+		BusType:           BusUSB,
+		FriendlyName:      "USB Flash",
+		HealthStatus:      HealthHealthy,
+		Number:            1,
+		OperationalStatus: OperationalStatusList{OpOnline},
+		PartitionStyle:    StyleGPT,
+		Size:              16008609792,
+		UniqueID:          "usb",
+	}
+	got, err := diskFromProperties(props)
+	if err != nil {
+		t.Fatalf("diskFromProperties() returned unexpected error %v", err)
+	}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Errorf("diskFromProperties() returned unexpected diff (-want +got):\n%s", diff)
+	}
+	if _, err := diskFromProperties(map[string]interface{}{"Size": "big"}); err == nil {
+		t.Errorf("diskFromProperties(Size: big) returned nil error")
+	}
+}
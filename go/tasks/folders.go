@@ -0,0 +1,134 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/capnspacehook/taskmaster"
+	"github.com/go-ole/go-ole"
+	"github.com/go-ole/go-ole/oleutil"
+)
+
+var (
+	// ErrFolderNotEmpty indicates a folder that still holds tasks or subfolders.
+	ErrFolderNotEmpty = errors.New("task folder is not empty")
+)
+
+// folderPath normalizes a task folder path to the form `\Glazier\Sub`.
+func folderPath(path string) string {
+	p := strings.Trim(path, `\`)
+	return `\` + p
+}
+
+// withRootFolder connects to the Task Scheduler COM interface and calls fn with its root
+// folder, for operations that taskmaster does not provide.
+func withRootFolder(fn func(root *ole.IDispatch) error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	ole.CoInitialize(0)
+	defer ole.CoUninitialize()
+	unknown, err := oleutil.CreateObject("Schedule.Service")
+	if err != nil {
+		return fmt.Errorf("unable to create Schedule.Service: %w", err)
+	}
+	defer unknown.Release()
+	sched, err := unknown.QueryInterface(ole.IID_IDispatch)
+	if err != nil {
+		return fmt.Errorf("unable to create Schedule.Service: %w", err)
+	}
+	defer sched.Release()
+	if _, err := oleutil.CallMethod(sched, "Connect"); err != nil {
+		return fmt.Errorf("Connect: %w", err)
+	}
+	rootRaw, err := oleutil.CallMethod(sched, "GetFolder", `\`)
+	if err != nil {
+		return fmt.Errorf("GetFolder: %w", err)
+	}
+	root := rootRaw.ToIDispatch()
+	defer root.Release()
+	return fn(root)
+}
+
+// CreateFolder creates a task folder, along with any missing parents. Creating a folder
+// that already exists is not an error.
+//
+// Example: tasks.CreateFolder(`\Glazier`)
+func CreateFolder(path string) error {
+	path = folderPath(path)
+	svc, err := taskmaster.Connect()
+	if err != nil {
+		return err
+	}
+	defer svc.Disconnect()
+	if f, err := svc.GetTaskFolder(path); err == nil {
+		f.Release()
+		return nil
+	}
+
+	// taskmaster does not expose folder creation, so call the Task Scheduler directly.
+	return withRootFolder(func(root *ole.IDispatch) error {
+		folder, err := oleutil.CallMethod(root, "CreateFolder", path)
+		if err != nil {
+			return fmt.Errorf("CreateFolder(%s): %w", path, err)
+		}
+		folder.Clear()
+		return nil
+	})
+}
+
+// DeleteFolder deletes a task folder. If recursive is true, the tasks and subfolders it
+// contains are deleted as well; otherwise deleting a folder that is not empty fails with
+// ErrFolderNotEmpty.
+func DeleteFolder(path string, recursive bool) error {
+	path = folderPath(path)
+	svc, err := taskmaster.Connect()
+	if err != nil {
+		return err
+	}
+	defer svc.Disconnect()
+	deleted, err := svc.DeleteFolder(path, recursive)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return fmt.Errorf("%w: %s", ErrFolderNotEmpty, path)
+	}
+	return nil
+}
+
+// ListTasksInFolder gathers details about the tasks registered directly within a task
+// folder. Tasks in subfolders are not included.
+func ListTasksInFolder(path string) ([]TaskInfo, error) {
+	infos := []TaskInfo{}
+	path = folderPath(path)
+	svc, err := taskmaster.Connect()
+	if err != nil {
+		return infos, err
+	}
+	defer svc.Disconnect()
+	folder, err := svc.GetTaskFolder(path)
+	if err != nil {
+		return infos, err
+	}
+	defer folder.Release()
+	for _, t := range folder.RegisteredTasks {
+		infos = append(infos, newTaskInfo(t))
+	}
+	return infos, nil
+}
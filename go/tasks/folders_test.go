@@ -0,0 +1,35 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import "testing"
+
+func TestFolderPath(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{`Glazier`, `\Glazier`},
+		{`\Glazier`, `\Glazier`},
+		{`\Glazier\Sub\`, `\Glazier\Sub`},
+		{`\`, `\`},
+		{``, `\`},
+	}
+	for _, tt := range tests {
+		if got := folderPath(tt.in); got != tt.want {
+			t.Errorf("folderPath(%s) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
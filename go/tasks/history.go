@@ -0,0 +1,185 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const historyChannel = "Microsoft-Windows-TaskScheduler/Operational"
+
+var (
+	fnQueryEvents = queryEvents
+)
+
+// EventKind classifies a Task Scheduler history event.
+type EventKind string
+
+// Event kinds.
+const (
+	EventTriggered       EventKind = "Triggered"
+	EventStarted         EventKind = "Started"
+	EventActionStarted   EventKind = "ActionStarted"
+	EventActionCompleted EventKind = "ActionCompleted"
+	EventCompleted       EventKind = "Completed"
+	EventFailed          EventKind = "Failed"
+	EventOther           EventKind = "Other"
+)
+
+// https://docs.microsoft.com/en-us/windows/win32/taskschd/task-scheduler-events
+var eventKinds = map[int]EventKind{
+	100: EventStarted,
+	101: EventFailed,
+	102: EventCompleted,
+	103: EventFailed,
+	107: EventTriggered,
+	108: EventTriggered,
+	109: EventTriggered,
+	110: EventTriggered,
+	111: EventFailed,
+	117: EventTriggered,
+	118: EventTriggered,
+	119: EventTriggered,
+	200: EventActionStarted,
+	201: EventActionCompleted,
+	202: EventFailed,
+	203: EventFailed,
+	204: EventFailed,
+	322: EventFailed,
+	329: EventFailed,
+	332: EventFailed,
+}
+
+// HistoryRecord is a single event from the history of a scheduled task.
+type HistoryRecord struct {
+	Time       time.Time
+	EventID    int
+	Kind       EventKind
+	InstanceID string
+	// ResultCode is the result reported by the event, where it has one.
+	ResultCode uint32
+	Message    string
+}
+
+type eventXML struct {
+	System struct {
+		EventID     int `xml:"EventID"`
+		TimeCreated struct {
+			SystemTime string `xml:"SystemTime,attr"`
+		} `xml:"TimeCreated"`
+	} `xml:"System"`
+	Data []struct {
+		Name  string `xml:"Name,attr"`
+		Value string `xml:",chardata"`
+	} `xml:"EventData>Data"`
+	Message string `xml:"RenderingInfo>Message"`
+}
+
+// parseHistory parses the output of wevtutil qe /f:RenderedXml, which is a sequence of
+// Event elements without an enclosing root.
+func parseHistory(out []byte) ([]HistoryRecord, error) {
+	var events struct {
+		Events []eventXML `xml:"Event"`
+	}
+	doc := "<Events>" + string(out) + "</Events>"
+	if err := xml.Unmarshal([]byte(doc), &events); err != nil {
+		return nil, fmt.Errorf("parsing events: %w", err)
+	}
+	records := []HistoryRecord{}
+	for _, e := range events.Events {
+		r := HistoryRecord{
+			EventID: e.System.EventID,
+			Kind:    EventOther,
+			Message: strings.TrimSpace(e.Message),
+		}
+		if k, ok := eventKinds[r.EventID]; ok {
+			r.Kind = k
+		}
+		t, err := time.Parse(time.RFC3339Nano, e.System.TimeCreated.SystemTime)
+		if err != nil {
+			return nil, fmt.Errorf("parsing event time %q: %w", e.System.TimeCreated.SystemTime, err)
+		}
+		r.Time = t
+		for _, d := range e.Data {
+			switch d.Name {
+			case "InstanceId", "TaskInstanceId":
+				r.InstanceID = d.Value
+			case "ResultCode":
+				c, err := strconv.ParseUint(d.Value, 0, 32)
+				if err != nil {
+					return nil, fmt.Errorf("parsing result code %q: %w", d.Value, err)
+				}
+				r.ResultCode = uint32(c)
+			}
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+// xpathLiteral quotes s for use in an XPath expression.
+func xpathLiteral(s string) string {
+	if strings.Contains(s, "'") {
+		return `"` + s + `"`
+	}
+	return "'" + s + "'"
+}
+
+func queryEvents(channel, query string, limit int) ([]byte, error) {
+	args := []string{"qe", channel, "/q:" + query, "/f:RenderedXml", "/rd:true"}
+	if limit > 0 {
+		args = append(args, fmt.Sprintf("/c:%d", limit))
+	}
+	out, err := exec.Command("wevtutil.exe", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("wevtutil qe %s: %w", channel, err)
+	}
+	return out, nil
+}
+
+// History returns up to limit of the most recent Task Scheduler events for a task, oldest
+// first. A limit of zero or less returns all of them.
+//
+// History is only recorded while the Microsoft-Windows-TaskScheduler/Operational log is
+// enabled, which it is not by default (see wevtutil sl /e).
+func History(name string, limit int) ([]HistoryRecord, error) {
+	path := taskPath(name)
+	task, err := fnGetTask(name)
+	if err == nil {
+		path = task.Path
+		fnReleaseTask(task)
+	} else if !errors.Is(err, ErrNotRegistered) {
+		return nil, err
+	}
+	query := fmt.Sprintf("*[EventData[Data[@Name='TaskName']=%s]]", xpathLiteral(path))
+	out, err := fnQueryEvents(historyChannel, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	records, err := parseHistory(out)
+	if err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+		records[i], records[j] = records[j], records[i]
+	}
+	return records, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/capnspacehook/taskmaster"
+	"github.com/google/go-cmp/cmp"
+)
+
+var errTest = errors.New("scheduled task lookup failed")
+
+// Events as returned by wevtutil, newest first.
+const testEvents = `<Event xmlns='http://schemas.microsoft.com/win/2004/08/events/event'><System><Provider Name='Microsoft-Windows-TaskScheduler'/><EventID>101</EventID><TimeCreated SystemTime='2021-06-01T03:05:00.1234567Z'/></System><EventData Name='TaskStartFailedEvent'><Data Name='TaskName'>\Glazier\Resume</Data><Data Name='UserContext'>NT AUTHORITY\SYSTEM</Data><Data Name='ResultCode'>2147942402</Data></EventData><RenderingInfo Culture='en-US'><Message>Task Scheduler failed to start "\Glazier\Resume" task for user "NT AUTHORITY\SYSTEM". Additional Data: Error Value: 2147942402.</Message></RenderingInfo></Event>
+<Event xmlns='http://schemas.microsoft.com/win/2004/08/events/event'><System><Provider Name='Microsoft-Windows-TaskScheduler'/><EventID>102</EventID><TimeCreated SystemTime='2021-06-01T03:01:00.5Z'/></System><EventData Name='TaskSuccessEvent'><Data Name='TaskName'>\Glazier\Resume</Data><Data Name='UserContext'>NT AUTHORITY\SYSTEM</Data><Data Name='InstanceId'>{8d0b6c8e-1b5e-4a5c-9d2b-0a0e2b5b1a01}</Data></EventData></Event>
+<Event xmlns='http://schemas.microsoft.com/win/2004/08/events/event'><System><Provider Name='Microsoft-Windows-TaskScheduler'/><EventID>118</EventID><TimeCreated SystemTime='2021-06-01T03:00:00Z'/></System><EventData Name='BootTrigger'><Data Name='TaskName'>\Glazier\Resume</Data><Data Name='InstanceId'>{8d0b6c8e-1b5e-4a5c-9d2b-0a0e2b5b1a01}</Data></EventData></Event>
+`
+
+func TestHistory(t *testing.T) {
+	instance := "{8d0b6c8e-1b5e-4a5c-9d2b-0a0e2b5b1a01}"
+	tests := []struct {
+		desc      string
+		in        string
+		fakeTask  func(name string) (taskmaster.RegisteredTask, error)
+		out       string
+		wantQuery string
+		want      []HistoryRecord
+		wantErr   error
+	}{
+		{
+			desc: "registered task",
+			in:   "Resume",
+			fakeTask: func(name string) (taskmaster.RegisteredTask, error) {
+				return taskmaster.RegisteredTask{Name: name, Path: `\Glazier\Resume`}, nil
+			},
+			out:       testEvents,
+			wantQuery: `*[EventData[Data[@Name='TaskName']='\Glazier\Resume']]`,
+			want: []HistoryRecord{
+				{
+					Time:       time.Date(2021, 6, 1, 3, 0, 0, 0, time.UTC),
+					EventID:    118,
+					Kind:       EventTriggered,
+					InstanceID: instance,
+				},
+				{
+					Time:       time.Date(2021, 6, 1, 3, 1, 0, 500000000, time.UTC),
+					EventID:    102,
+					Kind:       EventCompleted,
+					InstanceID: instance,
+				},
+				{
+					Time:       time.Date(2021, 6, 1, 3, 5, 0, 123456700, time.UTC),
+					EventID:    101,
+					Kind:       EventFailed,
+					ResultCode: 0x80070002,
+					Message:    `Task Scheduler failed to start "\Glazier\Resume" task for user "NT AUTHORITY\SYSTEM". Additional Data: Error Value: 2147942402.`,
+				},
+			},
+		},
+		{
+			desc: "deleted task",
+			in:   "Resume",
+			fakeTask: func(name string) (taskmaster.RegisteredTask, error) {
+				return taskmaster.RegisteredTask{}, ErrNotRegistered
+			},
+			out:       "",
+			wantQuery: `*[EventData[Data[@Name='TaskName']='\Resume']]`,
+			want:      []HistoryRecord{},
+		},
+		{
+			desc: "lookup error",
+			in:   "Resume",
+			fakeTask: func(name string) (taskmaster.RegisteredTask, error) {
+				return taskmaster.RegisteredTask{}, errTest
+			},
+			wantErr: errTest,
+		},
+	}
+	fnReleaseTask = func(taskmaster.RegisteredTask) {}
+	defer func() {
+		fnGetTask = GetTask
+		fnReleaseTask = releaseTask
+		fnQueryEvents = queryEvents
+	}()
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			fnGetTask = tt.fakeTask
+			query := ""
+			fnQueryEvents = func(channel, q string, limit int) ([]byte, error) {
+				query = q
+				return []byte(tt.out), nil
+			}
+			got, err := History(tt.in, 10)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("History(%s) returned unexpected error %v", tt.in, err)
+			}
+			if query != tt.wantQuery {
+				t.Errorf("History(%s) queried %s, want %s", tt.in, query, tt.wantQuery)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("History(%s) returned unexpected diff (-want +got):\n%s", tt.in, diff)
+			}
+		})
+	}
+}
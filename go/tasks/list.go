@@ -0,0 +1,98 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/capnspacehook/taskmaster"
+)
+
+var (
+	fnRegisteredTasks = registeredTasks
+)
+
+// TaskInfo summarizes a registered task.
+type TaskInfo struct {
+	Name           string
+	Path           string
+	Enabled        bool
+	State          taskmaster.TaskState
+	LastRunTime    time.Time
+	NextRunTime    time.Time
+	LastTaskResult uint32
+	MissedRuns     int
+}
+
+func newTaskInfo(t taskmaster.RegisteredTask) TaskInfo {
+	return TaskInfo{
+		Name:           t.Name,
+		Path:           t.Path,
+		Enabled:        t.Enabled,
+		State:          t.State,
+		LastRunTime:    t.LastRunTime,
+		NextRunTime:    t.NextRunTime,
+		LastTaskResult: uint32(t.LastTaskResult),
+		MissedRuns:     int(t.MissedRuns),
+	}
+}
+
+// Filter selects tasks for List. The zero value matches every task.
+type Filter struct {
+	// PathPrefix matches tasks whose path begins with it, ignoring case (eg `\Glazier\`).
+	PathPrefix string
+	// Name, if set, matches tasks whose name it matches.
+	Name *regexp.Regexp
+}
+
+func (f Filter) match(t taskmaster.RegisteredTask) bool {
+	if !strings.HasPrefix(strings.ToLower(t.Path), strings.ToLower(f.PathPrefix)) {
+		return false
+	}
+	return f.Name == nil || f.Name.MatchString(t.Name)
+}
+
+func registeredTasks() ([]taskmaster.RegisteredTask, error) {
+	svc, err := taskmaster.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer svc.Disconnect()
+	tasks, err := svc.GetRegisteredTasks()
+	if err != nil {
+		return nil, err
+	}
+	defer tasks.Release()
+	return append([]taskmaster.RegisteredTask{}, tasks...), nil
+}
+
+// List returns information about every registered task matching filter, in every folder.
+//
+// Example: tasks.List(tasks.Filter{PathPrefix: `\Glazier\`})
+func List(filter Filter) ([]TaskInfo, error) {
+	infos := []TaskInfo{}
+	tasks, err := fnRegisteredTasks()
+	if err != nil {
+		return infos, err
+	}
+	for _, t := range tasks {
+		if filter.match(t) {
+			infos = append(infos, newTaskInfo(t))
+		}
+	}
+	return infos, nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/capnspacehook/taskmaster"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestList(t *testing.T) {
+	lastRun := time.Date(2021, 6, 1, 3, 0, 0, 0, time.UTC)
+	registered := []taskmaster.RegisteredTask{
+		{Name: "Resume", Path: `\Glazier\Resume`, Enabled: true, State: taskmaster.TASK_STATE_READY, LastRunTime: lastRun, LastTaskResult: 1},
+		{Name: "Cleanup", Path: `\glazier\Cleanup`, State: taskmaster.TASK_STATE_DISABLED},
+		{Name: "GoogleUpdateTaskMachineUA", Path: `\GoogleUpdateTaskMachineUA`, Enabled: true, MissedRuns: 2},
+	}
+	resume := TaskInfo{Name: "Resume", Path: `\Glazier\Resume`, Enabled: true, State: taskmaster.TASK_STATE_READY, LastRunTime: lastRun, LastTaskResult: 1}
+	cleanup := TaskInfo{Name: "Cleanup", Path: `\glazier\Cleanup`, State: taskmaster.TASK_STATE_DISABLED}
+	update := TaskInfo{Name: "GoogleUpdateTaskMachineUA", Path: `\GoogleUpdateTaskMachineUA`, Enabled: true, MissedRuns: 2}
+	tests := []struct {
+		desc    string
+		in      Filter
+		tasks   []taskmaster.RegisteredTask
+		err     error
+		want    []TaskInfo
+		wantErr error
+	}{
+		{"all", Filter{}, registered, nil, []TaskInfo{resume, cleanup, update}, nil},
+		{"path prefix", Filter{PathPrefix: `\Glazier\`}, registered, nil, []TaskInfo{resume, cleanup}, nil},
+		{"name", Filter{Name: regexp.MustCompile(`^Google`)}, registered, nil, []TaskInfo{update}, nil},
+		{"path and name", Filter{PathPrefix: `\Glazier\`, Name: regexp.MustCompile(`Res`)}, registered, nil, []TaskInfo{resume}, nil},
+		{"no match", Filter{PathPrefix: `\Microsoft\`}, registered, nil, []TaskInfo{}, nil},
+		{"error", Filter{}, nil, errTest, []TaskInfo{}, errTest},
+	}
+	defer func() { fnRegisteredTasks = registeredTasks }()
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			fnRegisteredTasks = func() ([]taskmaster.RegisteredTask, error) {
+				return tt.tasks, tt.err
+			}
+			got, err := List(tt.in)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("List() returned unexpected error %v", err)
+			}
+			if diff := cmp.Diff(tt.want, got); diff != "" {
+				t.Errorf("List() returned unexpected diff (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
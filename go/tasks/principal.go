@@ -0,0 +1,137 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/capnspacehook/taskmaster"
+)
+
+// Logon types for Principal.
+//
+// https://docs.microsoft.com/en-us/windows/win32/taskschd/principal-logontype
+const (
+	// LogonPassword stores the user's password with the task, so it can run whether or not
+	// the user is logged on.
+	LogonPassword = taskmaster.TASK_LOGON_PASSWORD
+	// LogonS4U runs the task without storing a password. The task has no access to network
+	// resources or encrypted files.
+	LogonS4U = taskmaster.TASK_LOGON_S4U
+	// LogonInteractive runs the task only while the user is logged on, in their session.
+	LogonInteractive = taskmaster.TASK_LOGON_INTERACTIVE_TOKEN
+	// LogonGroup runs the task for any logged on member of a group.
+	LogonGroup = taskmaster.TASK_LOGON_GROUP
+	// LogonServiceAccount runs the task as SYSTEM, LOCAL SERVICE or NETWORK SERVICE.
+	LogonServiceAccount = taskmaster.TASK_LOGON_SERVICE_ACCOUNT
+)
+
+var (
+	// ErrInvalidPrincipal indicates a Principal that cannot be registered.
+	ErrInvalidPrincipal = errors.New("invalid task principal")
+
+	serviceAccounts = map[string]bool{
+		"SYSTEM":                       true,
+		`NT AUTHORITY\SYSTEM`:          true,
+		"S-1-5-18":                     true,
+		"LOCAL SERVICE":                true,
+		`NT AUTHORITY\LOCAL SERVICE`:   true,
+		"S-1-5-19":                     true,
+		"NETWORK SERVICE":              true,
+		`NT AUTHORITY\NETWORK SERVICE`: true,
+		"S-1-5-20":                     true,
+	}
+)
+
+// Principal describes the account a task runs as.
+type Principal struct {
+	// User is the account to run as (eg `CORP\user` or "SYSTEM").
+	User string
+	// Password is the user's password, required by LogonPassword.
+	Password string
+	// Group is the group to run the task for (eg `BUILTIN\Users`). Group and User are
+	// mutually exclusive.
+	Group string
+	// LogonType is one of the Logon* constants. It defaults to LogonGroup for groups and
+	// LogonServiceAccount otherwise.
+	LogonType taskmaster.TaskLogonType
+	// Highest runs the task with the highest privileges available to the account, rather
+	// than a limited token.
+	Highest bool
+}
+
+// SystemPrincipal runs a task as SYSTEM with the highest run level. It is used when no
+// other principal is specified.
+var SystemPrincipal = Principal{User: "SYSTEM", LogonType: LogonServiceAccount, Highest: true}
+
+// resolve validates p and fills in its defaults.
+func (p Principal) resolve() (Principal, error) {
+	if p.Group != "" {
+		if p.User != "" {
+			return p, fmt.Errorf("%w: both user %q and group %q specified", ErrInvalidPrincipal, p.User, p.Group)
+		}
+		if p.LogonType == taskmaster.TASK_LOGON_NONE {
+			p.LogonType = LogonGroup
+		}
+		if p.LogonType != LogonGroup {
+			return p, fmt.Errorf("%w: group %q requires LogonGroup", ErrInvalidPrincipal, p.Group)
+		}
+		return p, nil
+	}
+	if p.LogonType == taskmaster.TASK_LOGON_NONE {
+		p.LogonType = LogonServiceAccount
+	}
+	switch p.LogonType {
+	case LogonServiceAccount:
+		if p.User == "" {
+			p.User = "SYSTEM"
+		}
+		if !serviceAccounts[strings.ToUpper(p.User)] {
+			return p, fmt.Errorf("%w: %q is not a service account", ErrInvalidPrincipal, p.User)
+		}
+	case LogonPassword:
+		if p.User == "" || p.Password == "" {
+			return p, fmt.Errorf("%w: LogonPassword requires a user and password", ErrInvalidPrincipal)
+		}
+	case LogonS4U, LogonInteractive:
+		if p.User == "" {
+			return p, fmt.Errorf("%w: logon type %d requires a user", ErrInvalidPrincipal, p.LogonType)
+		}
+	default:
+		return p, fmt.Errorf("%w: unsupported logon type %d", ErrInvalidPrincipal, p.LogonType)
+	}
+	if p.Password != "" && p.LogonType != LogonPassword {
+		return p, fmt.Errorf("%w: a password is only used with LogonPassword", ErrInvalidPrincipal)
+	}
+	return p, nil
+}
+
+// apply sets the principal of def and returns the account and password to register it
+// with.
+func (p Principal) apply(def *taskmaster.Definition) (string, string) {
+	def.Principal.LogonType = p.LogonType
+	def.Principal.UserID = p.User
+	def.Principal.GroupID = p.Group
+	def.Principal.RunLevel = taskmaster.TASK_RUNLEVEL_LUA
+	if p.Highest {
+		def.Principal.RunLevel = taskmaster.TASK_RUNLEVEL_HIGHEST
+	}
+	if p.Group != "" {
+		return p.Group, ""
+	}
+	return p.User, p.Password
+}
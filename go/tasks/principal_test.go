@@ -0,0 +1,93 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/capnspacehook/taskmaster"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestPrincipalResolve(t *testing.T) {
+	tests := []struct {
+		desc    string
+		in      Principal
+		want    Principal
+		wantErr error
+	}{
+		{"zero value", Principal{}, Principal{User: "SYSTEM", LogonType: LogonServiceAccount}, nil},
+		{"system", SystemPrincipal, SystemPrincipal, nil},
+		{"network service", Principal{User: `NT AUTHORITY\Network Service`}, Principal{User: `NT AUTHORITY\Network Service`, LogonType: LogonServiceAccount}, nil},
+		{"group", Principal{Group: `BUILTIN\Users`}, Principal{Group: `BUILTIN\Users`, LogonType: LogonGroup}, nil},
+		{"password", Principal{User: `CORP\user`, Password: "secret", LogonType: LogonPassword}, Principal{User: `CORP\user`, Password: "secret", LogonType: LogonPassword}, nil},
+		{"s4u", Principal{User: `CORP\user`, LogonType: LogonS4U}, Principal{User: `CORP\user`, LogonType: LogonS4U}, nil},
+		{"user and group", Principal{User: `CORP\user`, Group: `BUILTIN\Users`}, Principal{}, ErrInvalidPrincipal},
+		{"group with other logon", Principal{Group: `BUILTIN\Users`, LogonType: LogonS4U}, Principal{}, ErrInvalidPrincipal},
+		{"user as service account", Principal{User: `CORP\user`}, Principal{}, ErrInvalidPrincipal},
+		{"missing password", Principal{User: `CORP\user`, LogonType: LogonPassword}, Principal{}, ErrInvalidPrincipal},
+		{"interactive without user", Principal{LogonType: LogonInteractive}, Principal{}, ErrInvalidPrincipal},
+		{"unused password", Principal{User: `CORP\user`, Password: "secret", LogonType: LogonS4U}, Principal{}, ErrInvalidPrincipal},
+	}
+	for _, tt := range tests {
+		got, err := tt.in.resolve()
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: resolve() returned unexpected error %v", tt.desc, err)
+		}
+		if err != nil {
+			continue
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("%s: resolve() returned unexpected diff (-want +got):\n%s", tt.desc, diff)
+		}
+	}
+}
+
+func TestPrincipalApply(t *testing.T) {
+	tests := []struct {
+		desc         string
+		in           Principal
+		wantUser     string
+		wantPassword string
+		want         taskmaster.Principal
+	}{
+		{"system", SystemPrincipal, "SYSTEM", "", taskmaster.Principal{
+			UserID:    "SYSTEM",
+			LogonType: LogonServiceAccount,
+			RunLevel:  taskmaster.TASK_RUNLEVEL_HIGHEST,
+		}},
+		{"password", Principal{User: `CORP\user`, Password: "secret", LogonType: LogonPassword}, `CORP\user`, "secret", taskmaster.Principal{
+			UserID:    `CORP\user`,
+			LogonType: LogonPassword,
+			RunLevel:  taskmaster.TASK_RUNLEVEL_LUA,
+		}},
+		{"group", Principal{Group: `BUILTIN\Users`, LogonType: LogonGroup}, `BUILTIN\Users`, "", taskmaster.Principal{
+			GroupID:   `BUILTIN\Users`,
+			LogonType: LogonGroup,
+			RunLevel:  taskmaster.TASK_RUNLEVEL_LUA,
+		}},
+	}
+	for _, tt := range tests {
+		def := taskmaster.Definition{}
+		user, password := tt.in.apply(&def)
+		if user != tt.wantUser || password != tt.wantPassword {
+			t.Errorf("%s: apply() = (%q, %q), want (%q, %q)", tt.desc, user, password, tt.wantUser, tt.wantPassword)
+		}
+		if diff := cmp.Diff(tt.want, def.Principal); diff != "" {
+			t.Errorf("%s: apply() set unexpected principal (-want +got):\n%s", tt.desc, diff)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/capnspacehook/taskmaster"
+)
+
+var (
+	// ErrUnsupportedTrigger indicates a trigger type that settings cannot be applied to.
+	ErrUnsupportedTrigger = errors.New("unsupported trigger type")
+)
+
+// Settings controls when and how a task runs.
+//
+// https://docs.microsoft.com/en-us/windows/win32/taskschd/tasksettings
+type Settings struct {
+	// DisallowStartOnBatteries prevents the task from starting while on battery power.
+	DisallowStartOnBatteries bool
+	// StopOnBatteries stops the task if the system switches to battery power.
+	StopOnBatteries bool
+	// RunOnlyIfNetworkAvailable delays the task until a network is available.
+	RunOnlyIfNetworkAvailable bool
+	// ExecutionTimeLimit stops the task after it has run this long. Zero allows it to
+	// run indefinitely.
+	ExecutionTimeLimit time.Duration
+	// RestartCount restarts the task up to this many times if it fails, waiting
+	// RestartInterval (at least one minute) between attempts.
+	RestartCount    int
+	RestartInterval time.Duration
+	// RepetitionInterval repeats the task at this interval each time one of its triggers
+	// fires, for up to RepetitionDuration (indefinitely if zero).
+	RepetitionInterval time.Duration
+	RepetitionDuration time.Duration
+}
+
+// withRepetition returns t with its repetition pattern replaced by rp.
+func withRepetition(t taskmaster.Trigger, rp taskmaster.RepetitionPattern) (taskmaster.Trigger, error) {
+	switch tt := t.(type) {
+	case taskmaster.BootTrigger:
+		tt.RepetitionPattern = rp
+		return tt, nil
+	case taskmaster.DailyTrigger:
+		tt.RepetitionPattern = rp
+		return tt, nil
+	case taskmaster.EventTrigger:
+		tt.RepetitionPattern = rp
+		return tt, nil
+	case taskmaster.IdleTrigger:
+		tt.RepetitionPattern = rp
+		return tt, nil
+	case taskmaster.LogonTrigger:
+		tt.RepetitionPattern = rp
+		return tt, nil
+	case taskmaster.TimeTrigger:
+		tt.RepetitionPattern = rp
+		return tt, nil
+	case taskmaster.WeeklyTrigger:
+		tt.RepetitionPattern = rp
+		return tt, nil
+	}
+	return t, fmt.Errorf("%w: %T", ErrUnsupportedTrigger, t)
+}
+
+// apply sets the settings of def, including the repetition of each of its triggers.
+func (s Settings) apply(def *taskmaster.Definition) error {
+	if s.RestartCount > 0 && s.RestartInterval < time.Minute {
+		return fmt.Errorf("restart interval %v is less than one minute", s.RestartInterval)
+	}
+	if s.RepetitionDuration > 0 && s.RepetitionDuration < s.RepetitionInterval {
+		return fmt.Errorf("repetition duration %v is shorter than the interval %v", s.RepetitionDuration, s.RepetitionInterval)
+	}
+	def.Settings.DontStartOnBatteries = s.DisallowStartOnBatteries
+	def.Settings.StopIfGoingOnBatteries = s.StopOnBatteries
+	def.Settings.RunOnlyIfNetworkAvailable = s.RunOnlyIfNetworkAvailable
+	def.Settings.TimeLimit = toPeriod(s.ExecutionTimeLimit)
+	def.Settings.RestartCount = uint(s.RestartCount)
+	def.Settings.RestartInterval = toPeriod(s.RestartInterval)
+	if s.RepetitionInterval == 0 {
+		return nil
+	}
+	rp := taskmaster.RepetitionPattern{
+		RepetitionInterval: toPeriod(s.RepetitionInterval),
+		RepetitionDuration: toPeriod(s.RepetitionDuration),
+	}
+	for i, t := range def.Triggers {
+		r, err := withRepetition(t, rp)
+		if err != nil {
+			return err
+		}
+		def.Triggers[i] = r
+	}
+	return nil
+}
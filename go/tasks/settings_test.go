@@ -0,0 +1,90 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/capnspacehook/taskmaster"
+)
+
+type fakeTrigger struct {
+	taskmaster.TaskTrigger
+}
+
+func (fakeTrigger) GetType() taskmaster.TaskTriggerType { return 0 }
+
+func TestSettingsApply(t *testing.T) {
+	s := Settings{
+		DisallowStartOnBatteries:  true,
+		RunOnlyIfNetworkAvailable: true,
+		ExecutionTimeLimit:        2 * time.Hour,
+		RestartCount:              3,
+		RestartInterval:           5 * time.Minute,
+		RepetitionInterval:        15 * time.Minute,
+		RepetitionDuration:        time.Hour,
+	}
+	def := taskmaster.Definition{Triggers: []taskmaster.Trigger{OnBoot(0), OnLogon("", time.Minute)}}
+	if err := s.apply(&def); err != nil {
+		t.Fatalf("apply() returned unexpected error %v", err)
+	}
+	want := taskmaster.TaskSettings{
+		DontStartOnBatteries:      true,
+		RunOnlyIfNetworkAvailable: true,
+		TimeLimit:                 toPeriod(2 * time.Hour),
+		RestartCount:              3,
+		RestartInterval:           toPeriod(5 * time.Minute),
+	}
+	if !reflect.DeepEqual(def.Settings, want) {
+		t.Errorf("apply() set settings %+v, want %+v", def.Settings, want)
+	}
+	rp := taskmaster.RepetitionPattern{
+		RepetitionInterval: toPeriod(15 * time.Minute),
+		RepetitionDuration: toPeriod(time.Hour),
+	}
+	wantTriggers := []taskmaster.Trigger{
+		taskmaster.BootTrigger{TaskTrigger: taskmaster.TaskTrigger{Enabled: true, RepetitionPattern: rp}},
+		taskmaster.LogonTrigger{TaskTrigger: taskmaster.TaskTrigger{Enabled: true, RepetitionPattern: rp}, Delay: toPeriod(time.Minute)},
+	}
+	if !reflect.DeepEqual(def.Triggers, wantTriggers) {
+		t.Errorf("apply() set triggers %+v, want %+v", def.Triggers, wantTriggers)
+	}
+}
+
+func TestSettingsApplyErrors(t *testing.T) {
+	tests := []struct {
+		desc     string
+		in       Settings
+		triggers []taskmaster.Trigger
+		wantErr  error
+	}{
+		{"short restart interval", Settings{RestartCount: 1, RestartInterval: time.Second}, nil, nil},
+		{"short repetition duration", Settings{RepetitionInterval: time.Hour, RepetitionDuration: time.Minute}, nil, nil},
+		{"unsupported trigger", Settings{RepetitionInterval: time.Hour}, []taskmaster.Trigger{fakeTrigger{}}, ErrUnsupportedTrigger},
+	}
+	for _, tt := range tests {
+		def := taskmaster.Definition{Triggers: tt.triggers}
+		err := tt.in.apply(&def)
+		if err == nil {
+			t.Errorf("%s: apply() returned nil, want error", tt.desc)
+		}
+		if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+			t.Errorf("%s: apply() returned %v, want %v", tt.desc, err, tt.wantErr)
+		}
+	}
+}
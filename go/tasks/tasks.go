@@ -30,15 +30,82 @@ var (
 	ErrNotRegistered = errors.New("scheduled task is not registered")
 
 	// Test Helpers
-	fnGetTask = GetTask
+	fnGetTask     = GetTask
+	fnReleaseTask = releaseTask
 )
 
+// releaseTask releases a task returned by GetTask.
+func releaseTask(t taskmaster.RegisteredTask) {
+	t.Release()
+}
+
 func setEnabled(name string, enabled bool) error {
-	task, err := GetTask(name)
+	return Update(name, func(def *taskmaster.Definition) error {
+		def.Settings.Enabled = enabled
+		return nil
+	})
+}
+
+// taskPath returns the full path of a task, which may be given with or without its folder.
+func taskPath(name string) string {
+	if strings.HasPrefix(name, `\`) {
+		return name
+	}
+	return `\` + name
+}
+
+// findTask returns the index of the task identified by name. Names starting with `\` are
+// matched against the full path of a task, others against its name in any folder, in which
+// case the first match is returned.
+func findTask(tasks taskmaster.RegisteredTaskCollection, name string) (int, bool) {
+	for i, t := range tasks {
+		if matchesTask(t, name) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func matchesTask(t taskmaster.RegisteredTask, name string) bool {
+	if strings.HasPrefix(name, `\`) {
+		return strings.EqualFold(t.Path, name)
+	}
+	return strings.EqualFold(t.Name, name)
+}
+
+// Options customizes the registration of a task.
+type Options struct {
+	// Principal is the account the task runs as. If nil, SystemPrincipal is used.
+	Principal *Principal
+	// Settings controls when and how the task runs. If nil, the Task Scheduler defaults
+	// are used.
+	Settings *Settings
+}
+
+// Create registers a scheduled task that runs path with args on the given triggers,
+// replacing any existing task of the same name. name may include a folder, as in
+// `\Glazier\Resume`. The task runs as SYSTEM with the highest run level.
+//
+// Example: tasks.Create("GlazierResume", `C:\Glazier\autobuild.exe`, "--resume", tasks.OnBoot(time.Minute))
+func Create(name, path, args string, triggers ...taskmaster.Trigger) error {
+	return CreateWithOptions(name, path, args, Options{}, triggers...)
+}
+
+// CreateWithOptions registers a scheduled task as Create does, customized by opts.
+//
+// Example:
+//
+//	p := &tasks.Principal{Group: `BUILTIN\Users`}
+//	err := tasks.CreateWithOptions("FirstLogon", `C:\Glazier\firstlogon.exe`, "", tasks.Options{Principal: p}, tasks.OnLogon("", 0))
+func CreateWithOptions(name, path, args string, opts Options, triggers ...taskmaster.Trigger) error {
+	principal := SystemPrincipal
+	if opts.Principal != nil {
+		principal = *opts.Principal
+	}
+	principal, err := principal.resolve()
 	if err != nil {
 		return err
 	}
-	defer task.Release()
 
 	svc, err := taskmaster.Connect()
 	if err != nil {
@@ -46,8 +113,19 @@ func setEnabled(name string, enabled bool) error {
 	}
 	defer svc.Disconnect()
 
-	task.Definition.Settings.Enabled = enabled
-	_, err = svc.UpdateTask(task.Path, task.Definition)
+	def := svc.NewTaskDefinition()
+	def.AddExecAction(path, args, "", "")
+	for _, t := range triggers {
+		def.AddTrigger(t)
+	}
+	user, password := principal.apply(&def)
+	if opts.Settings != nil {
+		if err := opts.Settings.apply(&def); err != nil {
+			return err
+		}
+	}
+	def.Settings.Enabled = true
+	_, _, err = svc.CreateTaskEx(taskPath(name), def, user, password, principal.LogonType, true)
 	return err
 }
 
@@ -61,7 +139,9 @@ func Enable(name string) error {
 	return setEnabled(name, true)
 }
 
-// GetTask gathers details about a Windows Scheduled Task.
+// GetTask gathers details about a Windows Scheduled Task. name may be the full path of the
+// task, as in `\Glazier\Resume`, to select it among tasks of the same name in other folders.
+// The caller must Release the returned task.
 func GetTask(name string) (taskmaster.RegisteredTask, error) {
 	svc, err := taskmaster.Connect()
 	if err != nil {
@@ -73,13 +153,17 @@ func GetTask(name string) (taskmaster.RegisteredTask, error) {
 	if err != nil {
 		return taskmaster.RegisteredTask{}, err
 	}
-	defer tasks.Release()
 
-	for _, t := range tasks {
-		if strings.EqualFold(t.Name, name) {
-			return t, nil
+	// Release every task but the one returned, which the caller releases.
+	i, ok := findTask(tasks, name)
+	for j := range tasks {
+		if !ok || j != i {
+			tasks[j].Release()
 		}
 	}
+	if ok {
+		return tasks[i], nil
+	}
 
 	return taskmaster.RegisteredTask{}, ErrNotRegistered
 }
@@ -91,15 +175,18 @@ func TaskExists(name string) (bool, error) {
 	if err != nil && !errors.Is(err, ErrNotRegistered) {
 		return false, err
 	}
+	if err == nil {
+		defer fnReleaseTask(task)
+	}
 
-	if strings.EqualFold(task.Name, name) {
+	if matchesTask(task, name) {
 		return true, nil
 	}
 
 	return false, nil
 }
 
-// Delete attempts to delete a scheduled task.
+// Delete attempts to delete a scheduled task, given by name or full path as for GetTask.
 func Delete(name string) error {
 	svc, err := taskmaster.Connect()
 	if err != nil {
@@ -111,10 +198,8 @@ func Delete(name string) error {
 		return err
 	}
 	defer tasks.Release()
-	for _, t := range tasks {
-		if strings.EqualFold(t.Name, name) {
-			return svc.DeleteTask(t.Path)
-		}
+	if i, ok := findTask(tasks, name); ok {
+		return svc.DeleteTask(tasks[i].Path)
 	}
 	return ErrTaskNotFound
 }
@@ -57,6 +57,18 @@ func TestTaskExists(t *testing.T) {
 			want:    false,
 			wantErr: nil,
 		},
+		{
+			desc: "task exists in folder",
+			in:   `\Glazier\task5`,
+			fakeTask: func(name string) (taskmaster.RegisteredTask, error) {
+				return taskmaster.RegisteredTask{
+					Name: "task5",
+					Path: `\Glazier\task5`,
+				}, nil
+			},
+			want:    true,
+			wantErr: nil,
+		},
 		{
 			desc: "task does exist",
 			in:   "task4",
@@ -70,6 +82,11 @@ func TestTaskExists(t *testing.T) {
 		},
 	}
 
+	fnReleaseTask = func(taskmaster.RegisteredTask) {}
+	defer func() {
+		fnGetTask = GetTask
+		fnReleaseTask = releaseTask
+	}()
 	for _, tt := range tests {
 		t.Run(tt.desc, func(t *testing.T) {
 			fnGetTask = tt.fakeTask
@@ -84,3 +101,30 @@ func TestTaskExists(t *testing.T) {
 		})
 	}
 }
+
+func TestFindTask(t *testing.T) {
+	tasks := taskmaster.RegisteredTaskCollection{
+		{Name: "Resume", Path: `\Other\Resume`},
+		{Name: "Resume", Path: `\Glazier\Resume`},
+		{Name: "Cleanup", Path: `\Cleanup`},
+	}
+	tests := []struct {
+		in     string
+		want   int
+		wantOK bool
+	}{
+		{"Resume", 0, true},
+		{"cleanup", 2, true},
+		{`\Glazier\Resume`, 1, true},
+		{`\glazier\resume`, 1, true},
+		{`\Cleanup`, 2, true},
+		{`\Resume`, 0, false},
+		{"Missing", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := findTask(tasks, tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("findTask(%s) = (%d, %t), want (%d, %t)", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/capnspacehook/taskmaster"
+	"github.com/rickb777/date/period"
+)
+
+// toPeriod converts a duration to the period type used by taskmaster. Zero durations
+// leave the corresponding setting unset.
+func toPeriod(d time.Duration) period.Period {
+	p, _ := period.NewOf(d)
+	return p
+}
+
+func xmlEscape(s string) string {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// OnBoot returns a trigger that fires delay after the system starts.
+func OnBoot(delay time.Duration) taskmaster.Trigger {
+	return taskmaster.BootTrigger{
+		TaskTrigger: taskmaster.TaskTrigger{Enabled: true},
+		Delay:       toPeriod(delay),
+	}
+}
+
+// OnLogon returns a trigger that fires delay after user logs on. An empty user matches
+// any user.
+func OnLogon(user string, delay time.Duration) taskmaster.Trigger {
+	return taskmaster.LogonTrigger{
+		TaskTrigger: taskmaster.TaskTrigger{Enabled: true},
+		Delay:       toPeriod(delay),
+		UserID:      user,
+	}
+}
+
+// OnIdle returns a trigger that fires when the system becomes idle.
+func OnIdle() taskmaster.Trigger {
+	return taskmaster.IdleTrigger{
+		TaskTrigger: taskmaster.TaskTrigger{Enabled: true},
+	}
+}
+
+// Daily returns a trigger that fires every interval days from start, offset by a random
+// delay of up to randomDelay.
+func Daily(start time.Time, interval int, randomDelay time.Duration) taskmaster.Trigger {
+	return taskmaster.DailyTrigger{
+		TaskTrigger: taskmaster.TaskTrigger{Enabled: true, StartBoundary: start},
+		DayInterval: taskmaster.DayInterval(interval),
+		RandomDelay: toPeriod(randomDelay),
+	}
+}
+
+// Weekly returns a trigger that fires on the given days every interval weeks from start,
+// offset by a random delay of up to randomDelay.
+//
+// Example: tasks.Weekly(start, []time.Weekday{time.Saturday, time.Sunday}, 1, time.Hour)
+func Weekly(start time.Time, days []time.Weekday, interval int, randomDelay time.Duration) taskmaster.Trigger {
+	var mask taskmaster.DayOfWeek
+	for _, d := range days {
+		mask |= taskmaster.Sunday << uint(d)
+	}
+	return taskmaster.WeeklyTrigger{
+		TaskTrigger:  taskmaster.TaskTrigger{Enabled: true, StartBoundary: start},
+		DaysOfWeek:   mask,
+		RandomDelay:  toPeriod(randomDelay),
+		WeekInterval: taskmaster.WeekInterval(interval),
+	}
+}
+
+// OnEvent returns a trigger that fires delay after an event with the given ID is written
+// to channel (eg "System") by source.
+func OnEvent(channel, source string, eventID int, delay time.Duration) taskmaster.Trigger {
+	c := xmlEscape(channel)
+	query := fmt.Sprintf(`<QueryList><Query Id="0" Path="%s"><Select Path="%s">*[System[Provider[@Name='%s'] and EventID=%d]]</Select></Query></QueryList>`,
+		c, c, xmlEscape(source), eventID)
+	return taskmaster.EventTrigger{
+		TaskTrigger:  taskmaster.TaskTrigger{Enabled: true},
+		Delay:        toPeriod(delay),
+		Subscription: query,
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/capnspacehook/taskmaster"
+)
+
+func TestTriggers(t *testing.T) {
+	start := time.Date(2021, 6, 1, 3, 0, 0, 0, time.UTC)
+	enabled := taskmaster.TaskTrigger{Enabled: true}
+	scheduled := taskmaster.TaskTrigger{Enabled: true, StartBoundary: start}
+	tests := []struct {
+		desc string
+		in   taskmaster.Trigger
+		want taskmaster.Trigger
+	}{
+		{"boot", OnBoot(time.Minute), taskmaster.BootTrigger{TaskTrigger: enabled, Delay: toPeriod(time.Minute)}},
+		{"logon", OnLogon(`CORP\user`, 0), taskmaster.LogonTrigger{TaskTrigger: enabled, UserID: `CORP\user`}},
+		{"idle", OnIdle(), taskmaster.IdleTrigger{TaskTrigger: enabled}},
+		{"daily", Daily(start, 2, time.Hour), taskmaster.DailyTrigger{
+			TaskTrigger: scheduled,
+			DayInterval: taskmaster.EveryOtherDay,
+			RandomDelay: toPeriod(time.Hour),
+		}},
+		{"weekly", Weekly(start, []time.Weekday{time.Sunday, time.Wednesday, time.Saturday}, 1, 0), taskmaster.WeeklyTrigger{
+			TaskTrigger:  scheduled,
+			DaysOfWeek:   taskmaster.Sunday | taskmaster.Wednesday | taskmaster.Saturday,
+			WeekInterval: taskmaster.EveryWeek,
+		}},
+		{"event", OnEvent("System", "Service Control Manager", 7036, 30*time.Second), taskmaster.EventTrigger{
+			TaskTrigger:  enabled,
+			Delay:        toPeriod(30 * time.Second),
+			Subscription: `<QueryList><Query Id="0" Path="System"><Select Path="System">*[System[Provider[@Name='Service Control Manager'] and EventID=7036]]</Select></Query></QueryList>`,
+		}},
+		{"event escaping", OnEvent("Application", "O'Brien & Co", 1, 0), taskmaster.EventTrigger{
+			TaskTrigger:  enabled,
+			Subscription: `<QueryList><Query Id="0" Path="Application"><Select Path="Application">*[System[Provider[@Name='O&#39;Brien &amp; Co'] and EventID=1]]</Select></Query></QueryList>`,
+		}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.in, tt.want) {
+			t.Errorf("%s trigger = %+v, want %+v", tt.desc, tt.in, tt.want)
+		}
+	}
+}
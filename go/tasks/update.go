@@ -0,0 +1,89 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"fmt"
+
+	"github.com/capnspacehook/taskmaster"
+)
+
+var (
+	fnUpdateTask = updateTask
+)
+
+// updateTask re-registers the task at path with def. If p is set, the task is registered
+// with its credentials; otherwise those of the existing registration are kept.
+func updateTask(path string, def taskmaster.Definition, p *Principal) error {
+	svc, err := taskmaster.Connect()
+	if err != nil {
+		return err
+	}
+	defer svc.Disconnect()
+	if p == nil {
+		_, err = svc.UpdateTask(path, def)
+		return err
+	}
+	user, password := p.apply(&def)
+	_, err = svc.UpdateTaskEx(path, def, user, password, p.LogonType)
+	return err
+}
+
+// Update modifies a registered task in place. fn receives the task's current definition
+// and may change its actions, triggers or settings; the result is registered over the
+// existing task. Returning an error from fn leaves the task unchanged.
+//
+// Example:
+//
+//	err := tasks.Update("GlazierResume", func(def *taskmaster.Definition) error {
+//		def.Actions = []taskmaster.Action{taskmaster.ExecAction{Path: `D:\Glazier\autobuild.exe`, Args: "--resume"}}
+//		return nil
+//	})
+func Update(name string, fn func(def *taskmaster.Definition) error) error {
+	return UpdateWithOptions(name, Options{}, fn)
+}
+
+// UpdateWithOptions modifies a registered task in place as Update does. opts.Settings, if
+// set, is applied before fn is called. opts.Principal, if set, replaces the account the
+// task runs as; it is required to update tasks registered with LogonPassword.
+func UpdateWithOptions(name string, opts Options, fn func(def *taskmaster.Definition) error) error {
+	var principal *Principal
+	if opts.Principal != nil {
+		p, err := opts.Principal.resolve()
+		if err != nil {
+			return err
+		}
+		principal = &p
+	}
+
+	task, err := fnGetTask(name)
+	if err != nil {
+		return err
+	}
+	defer fnReleaseTask(task)
+
+	def := task.Definition
+	if opts.Settings != nil {
+		if err := opts.Settings.apply(&def); err != nil {
+			return err
+		}
+	}
+	if fn != nil {
+		if err := fn(&def); err != nil {
+			return fmt.Errorf("updating %s: %w", task.Path, err)
+		}
+	}
+	return fnUpdateTask(task.Path, def, principal)
+}
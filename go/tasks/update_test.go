@@ -0,0 +1,134 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/capnspacehook/taskmaster"
+)
+
+func TestUpdate(t *testing.T) {
+	existing := taskmaster.RegisteredTask{
+		Name: "Resume",
+		Path: `\Glazier\Resume`,
+		Definition: taskmaster.Definition{
+			Actions:  []taskmaster.Action{taskmaster.ExecAction{Path: `C:\Glazier\autobuild.exe`, Args: "--resume"}},
+			Settings: taskmaster.TaskSettings{Enabled: true},
+		},
+	}
+	moved := []taskmaster.Action{taskmaster.ExecAction{Path: `D:\Glazier\autobuild.exe`, Args: "--resume"}}
+	tests := []struct {
+		desc          string
+		fakeTask      func(name string) (taskmaster.RegisteredTask, error)
+		opts          Options
+		fn            func(def *taskmaster.Definition) error
+		wantUpdated   bool
+		wantDef       taskmaster.Definition
+		wantPrincipal *Principal
+		wantErr       error
+	}{
+		{
+			desc: "move action",
+			fakeTask: func(name string) (taskmaster.RegisteredTask, error) {
+				return existing, nil
+			},
+			fn: func(def *taskmaster.Definition) error {
+				def.Actions = moved
+				return nil
+			},
+			wantUpdated: true,
+			wantDef: taskmaster.Definition{
+				Actions:  moved,
+				Settings: taskmaster.TaskSettings{Enabled: true},
+			},
+		},
+		{
+			desc: "settings and principal",
+			fakeTask: func(name string) (taskmaster.RegisteredTask, error) {
+				return existing, nil
+			},
+			opts: Options{
+				Principal: &Principal{User: `CORP\user`, LogonType: LogonS4U},
+				Settings:  &Settings{RunOnlyIfNetworkAvailable: true},
+			},
+			wantUpdated: true,
+			wantDef: taskmaster.Definition{
+				Actions:  existing.Definition.Actions,
+				Settings: taskmaster.TaskSettings{Enabled: true, RunOnlyIfNetworkAvailable: true},
+			},
+			wantPrincipal: &Principal{User: `CORP\user`, LogonType: LogonS4U},
+		},
+		{
+			desc: "mutator error",
+			fakeTask: func(name string) (taskmaster.RegisteredTask, error) {
+				return existing, nil
+			},
+			fn: func(def *taskmaster.Definition) error {
+				return errTest
+			},
+			wantErr: errTest,
+		},
+		{
+			desc: "invalid principal",
+			fakeTask: func(name string) (taskmaster.RegisteredTask, error) {
+				return existing, nil
+			},
+			opts:    Options{Principal: &Principal{LogonType: LogonPassword}},
+			wantErr: ErrInvalidPrincipal,
+		},
+		{
+			desc: "task is not registered",
+			fakeTask: func(name string) (taskmaster.RegisteredTask, error) {
+				return taskmaster.RegisteredTask{}, ErrNotRegistered
+			},
+			wantErr: ErrNotRegistered,
+		},
+	}
+	fnReleaseTask = func(taskmaster.RegisteredTask) {}
+	defer func() {
+		fnGetTask = GetTask
+		fnReleaseTask = releaseTask
+		fnUpdateTask = updateTask
+	}()
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			fnGetTask = tt.fakeTask
+			updated := false
+			fnUpdateTask = func(path string, def taskmaster.Definition, p *Principal) error {
+				updated = true
+				if path != existing.Path {
+					t.Errorf("UpdateWithOptions() registered %s, want %s", path, existing.Path)
+				}
+				if !reflect.DeepEqual(def, tt.wantDef) {
+					t.Errorf("UpdateWithOptions() registered %+v, want %+v", def, tt.wantDef)
+				}
+				if !reflect.DeepEqual(p, tt.wantPrincipal) {
+					t.Errorf("UpdateWithOptions() registered as %+v, want %+v", p, tt.wantPrincipal)
+				}
+				return nil
+			}
+			err := UpdateWithOptions("Resume", tt.opts, tt.fn)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("UpdateWithOptions() returned unexpected error %v", err)
+			}
+			if updated != tt.wantUpdated {
+				t.Errorf("UpdateWithOptions() updated = %t, want %t", updated, tt.wantUpdated)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/capnspacehook/taskmaster"
+)
+
+var (
+	// waitInterval is how often WaitForState polls the task.
+	waitInterval = time.Second
+)
+
+// WaitForState waits until a task reaches state (eg taskmaster.TASK_STATE_READY once a
+// run has finished), or until ctx is done.
+//
+// A task that is not yet registered is waited for, so tasks created by other components
+// can be awaited before they exist.
+//
+// Example:
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+//	defer cancel()
+//	err := tasks.WaitForState(ctx, "WindowsUpdateRemediation", taskmaster.TASK_STATE_READY)
+func WaitForState(ctx context.Context, name string, state taskmaster.TaskState) error {
+	ticker := time.NewTicker(waitInterval)
+	defer ticker.Stop()
+	last := "not registered"
+	for {
+		task, err := fnGetTask(name)
+		if err == nil {
+			fnReleaseTask(task)
+			if task.State == state {
+				return nil
+			}
+			last = fmt.Sprintf("in state %v", task.State)
+		} else if !errors.Is(err, ErrNotRegistered) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("waiting for %s to reach state %v (last %s): %w", name, state, last, ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/capnspacehook/taskmaster"
+)
+
+func TestWaitForState(t *testing.T) {
+	notRegistered := func() (taskmaster.RegisteredTask, error) {
+		return taskmaster.RegisteredTask{}, ErrNotRegistered
+	}
+	inState := func(s taskmaster.TaskState) func() (taskmaster.RegisteredTask, error) {
+		return func() (taskmaster.RegisteredTask, error) {
+			return taskmaster.RegisteredTask{Name: "task1", State: s}, nil
+		}
+	}
+	failed := func() (taskmaster.RegisteredTask, error) {
+		return taskmaster.RegisteredTask{}, errTest
+	}
+	tests := []struct {
+		desc    string
+		polls   []func() (taskmaster.RegisteredTask, error)
+		want    taskmaster.TaskState
+		wantErr error
+	}{
+		{"already in state", []func() (taskmaster.RegisteredTask, error){inState(taskmaster.TASK_STATE_READY)}, taskmaster.TASK_STATE_READY, nil},
+		{"reaches state", []func() (taskmaster.RegisteredTask, error){
+			notRegistered,
+			inState(taskmaster.TASK_STATE_READY),
+			inState(taskmaster.TASK_STATE_RUNNING),
+		}, taskmaster.TASK_STATE_RUNNING, nil},
+		{"lookup error", []func() (taskmaster.RegisteredTask, error){notRegistered, failed}, taskmaster.TASK_STATE_READY, errTest},
+		{"timeout", []func() (taskmaster.RegisteredTask, error){inState(taskmaster.TASK_STATE_RUNNING)}, taskmaster.TASK_STATE_DISABLED, context.DeadlineExceeded},
+	}
+	waitInterval = time.Millisecond
+	fnReleaseTask = func(taskmaster.RegisteredTask) {}
+	defer func() {
+		fnGetTask = GetTask
+		fnReleaseTask = releaseTask
+		waitInterval = time.Second
+	}()
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			i := 0
+			fnGetTask = func(name string) (taskmaster.RegisteredTask, error) {
+				poll := tt.polls[i]
+				if i < len(tt.polls)-1 {
+					i++
+				}
+				return poll()
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			err := WaitForState(ctx, "task1", tt.want)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("WaitForState() returned unexpected error %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"fmt"
+
+	"github.com/capnspacehook/taskmaster"
+	"github.com/go-ole/go-ole"
+	"github.com/go-ole/go-ole/oleutil"
+)
+
+const (
+	// https://docs.microsoft.com/en-us/windows/win32/api/taskschd/ne-taskschd-task_creation
+	taskCreateOrUpdate = 0x6
+)
+
+// ExportXML returns the XML definition of a scheduled task, as shown by schtasks /query /xml.
+func ExportXML(name string) (string, error) {
+	task, err := fnGetTask(name)
+	if err != nil {
+		return "", err
+	}
+	defer fnReleaseTask(task)
+	return task.Definition.XMLText, nil
+}
+
+// CreateFromXML registers a scheduled task verbatim from its XML definition, replacing any
+// existing task of the same name. name may include a folder, as in `\Glazier\Resume`. The
+// task runs as the principal named in the XML.
+func CreateFromXML(name, xml string) error {
+	path := taskPath(name)
+	// taskmaster can only register definitions it has built, so call the Task Scheduler
+	// directly.
+	return withRootFolder(func(root *ole.IDispatch) error {
+		task, err := oleutil.CallMethod(root, "RegisterTask", path, xml, taskCreateOrUpdate,
+			nil, nil, int32(taskmaster.TASK_LOGON_NONE), nil)
+		if err != nil {
+			return fmt.Errorf("RegisterTask(%s): %w", path, err)
+		}
+		task.Clear()
+		return nil
+	})
+}
@@ -0,0 +1,69 @@
+// Copyright 2021 Google LLC
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//      http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package tasks
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/capnspacehook/taskmaster"
+)
+
+func TestExportXML(t *testing.T) {
+	xml := `<?xml version="1.0" encoding="UTF-16"?><Task version="1.2"></Task>`
+	tests := []struct {
+		desc     string
+		fakeTask func(name string) (taskmaster.RegisteredTask, error)
+		want     string
+		wantErr  error
+	}{
+		{
+			desc: "task is not registered",
+			fakeTask: func(name string) (taskmaster.RegisteredTask, error) {
+				return taskmaster.RegisteredTask{}, ErrNotRegistered
+			},
+			want:    "",
+			wantErr: ErrNotRegistered,
+		},
+		{
+			desc: "task does exist",
+			fakeTask: func(name string) (taskmaster.RegisteredTask, error) {
+				return taskmaster.RegisteredTask{
+					Name:       name,
+					Definition: taskmaster.Definition{XMLText: xml},
+				}, nil
+			},
+			want:    xml,
+			wantErr: nil,
+		},
+	}
+	fnReleaseTask = func(taskmaster.RegisteredTask) {}
+	defer func() {
+		fnGetTask = GetTask
+		fnReleaseTask = releaseTask
+	}()
+	for _, tt := range tests {
+		t.Run(tt.desc, func(t *testing.T) {
+			fnGetTask = tt.fakeTask
+			got, err := ExportXML("task1")
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("ExportXML() returned unexpected error %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("ExportXML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}